@@ -0,0 +1,240 @@
+// Package client - Go-клиент API задач для других сервисов и утилит: типы
+// ответов, вход по паролю с обновлением токена, повторы при перегрузке и
+// сетевых сбоях. Ходит в /api/v1.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMFARequired - у пользователя включена 2FA, вход по паролю не завершить;
+// таким сервисам нужен API-ключ (WithAPIKey)
+var ErrMFARequired = errors.New("client: two-factor authentication required")
+
+// APIError - ответ API с кодом не 2xx
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound - задачи нет или она не видна пользователю
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+type Client struct {
+	baseURL string
+	http    *http.Client
+	apiKey  string
+	retries int
+	backoff time.Duration
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient - свой http.Client, например с таймаутом или транспортом
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithToken - готовый access-токен (Authorization: Bearer)
+func WithToken(token string) Option {
+	return func(c *Client) { c.accessToken = token }
+}
+
+// WithAPIKey - вход по API-ключу (X-API-Key), удобнее всего для сервисов
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithRetries - сколько раз повторить запрос после 429, 502-504 или сетевой
+// ошибки и пауза перед первым повтором; дальше пауза удваивается. По
+// умолчанию 3 повтора с 200ms, 0 выключает повторы.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = n, backoff }
+}
+
+// New - клиент сервера по адресу baseURL вида https://todo.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		http:    http.DefaultClient,
+		retries: 3,
+		backoff: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Login входит по email и паролю. Полученный refresh-токен клиент сам
+// использует, когда access-токен истечёт.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	var login loginResponse
+	err := c.send(ctx, http.MethodPost, "/auth/login", map[string]string{"email": email, "password": password}, &login)
+	if err != nil {
+		return err
+	}
+	if login.MFARequired {
+		return ErrMFARequired
+	}
+	c.setTokens(login)
+	return nil
+}
+
+type loginResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	MFARequired  bool   `json:"mfa_required"`
+}
+
+func (c *Client) setTokens(login loginResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken, c.refreshToken = login.AccessToken, login.RefreshToken
+}
+
+// refresh обновляет access-токен; false - обновлять нечем
+func (c *Client) refresh(ctx context.Context, expired string) (bool, error) {
+	c.mu.Lock()
+	token, current := c.refreshToken, c.accessToken
+	c.mu.Unlock()
+	if token == "" {
+		return false, nil
+	}
+	// токен уже обновил параллельный запрос
+	if current != expired {
+		return true, nil
+	}
+	var login loginResponse
+	if err := c.send(ctx, http.MethodPost, "/auth/refresh", map[string]string{"refresh_token": token}, &login); err != nil {
+		return false, err
+	}
+	c.setTokens(login)
+	return true, nil
+}
+
+// do - запрос с авторизацией: при 401 один раз обновляет токен и повторяет
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	err := c.send(ctx, method, path, body, out)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+	c.mu.Lock()
+	expired := c.accessToken
+	c.mu.Unlock()
+	if ok, rerr := c.refresh(ctx, expired); rerr != nil || !ok {
+		return err
+	}
+	return c.send(ctx, method, path, body, out)
+}
+
+// send отправляет запрос с повторами. POST повторяется только после 429:
+// такой запрос сервер отклонил до обработки, а после 5xx задача могла
+// создаться.
+func (c *Client) send(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.roundTrip(ctx, method, path, payload)
+		retry := err != nil && ctx.Err() == nil && method != http.MethodPost
+		if err == nil {
+			retry = resp.StatusCode == http.StatusTooManyRequests ||
+				method != http.MethodPost && (resp.StatusCode == http.StatusBadGateway ||
+					resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout)
+		}
+		if !retry || attempt >= c.retries {
+			if err != nil {
+				return err
+			}
+			return decode(resp, out)
+		}
+
+		delay := wait
+		if resp != nil {
+			if s, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+				delay = time.Duration(s) * time.Second
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var r io.Reader
+	if payload != nil {
+		r = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.mu.Lock()
+	token := c.accessToken
+	c.mu.Unlock()
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.http.Do(req)
+}
+
+func decode(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		// ошибки квот приходят JSON-объектом с полем error, остальные - текстом
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(msg, &body) == nil && body.Error != "" {
+			msg = []byte(body.Error)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
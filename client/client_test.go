@@ -0,0 +1,94 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/client"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	var getCalls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/login", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "old", "refresh_token": "r1"})
+	})
+	mux.HandleFunc("POST /api/v1/auth/refresh", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "new", "refresh_token": "r2"})
+	})
+	mux.HandleFunc("GET /api/v1/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		getCalls++
+		switch {
+		case r.Header.Get("Authorization") != "Bearer new":
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		case getCalls == 2:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		case r.PathValue("id") == "404":
+			http.Error(w, "Task not found", http.StatusNotFound)
+		default:
+			_, _ = w.Write([]byte(`{"id": 42, "title": "Buy milk", "status": "todo", "_links": {}}`))
+		}
+	})
+	mux.HandleFunc("GET /api/v1/tasks", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"id": 1}, {"id": 2}, {"id": 3}]`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	c := client.New(srv.URL, client.WithRetries(3, time.Millisecond))
+	if err := c.Login(ctx, "user@example.com", "password1"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// 401 с истёкшим токеном -> refresh, затем 503 -> повтор
+	task, err := c.GetTask(ctx, 42)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if task.ID != 42 || task.Title != "Buy milk" || getCalls != 3 {
+		t.Fatalf("task = %+v after %d calls", task, getCalls)
+	}
+
+	_, err = c.GetTask(ctx, 404)
+	if !client.IsNotFound(err) || err.Error() != "client: 404 Task not found" {
+		t.Fatalf("err = %v, want 404 Task not found", err)
+	}
+
+	var ids []int
+	for task, err := range c.Tasks(ctx) {
+		if err != nil {
+			t.Fatalf("tasks: %v", err)
+		}
+		if ids = append(ids, task.ID); len(ids) == 2 {
+			break
+		}
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("ids = %v, want [1 2]", ids)
+	}
+}
+
+func TestClientDoesNotRetryCreate(t *testing.T) {
+	t.Parallel()
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := client.New(srv.URL, client.WithAPIKey("key"), client.WithRetries(3, time.Millisecond))
+	if _, err := c.CreateTask(context.Background(), client.Task{Title: "Buy milk"}); err == nil {
+		t.Fatal("create succeeded, want 503")
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1: a retried POST could create the task twice", calls)
+	}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Операции локальных изменений для Sync
+const (
+	SyncUpsert = "upsert"
+	SyncDelete = "delete"
+)
+
+// SyncChange - изменение, сделанное офлайн: upsert создаёт или заменяет
+// задачу с этим UID, delete удаляет её. Передайте в Base задачу из прошлой
+// синхронизации, чтобы сервер слил правку с чужими по полям, а не затёр их.
+type SyncChange struct {
+	Op        string     `json:"op"`
+	UID       string     `json:"uid"`
+	Task      *Task      `json:"task,omitempty"`
+	Base      *Task      `json:"base,omitempty"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+}
+
+// Conflict - поле, изменённое и офлайн, и на сервере: в задаче осталось
+// Kept, а Discarded сохранено, чтобы его можно было вернуть вручную
+type Conflict struct {
+	ID         int             `json:"id"`
+	TaskID     int             `json:"task_id"`
+	Field      string          `json:"field"`
+	Kept       json.RawMessage `json:"kept"`
+	Discarded  json.RawMessage `json:"discarded"`
+	ActorID    *int            `json:"actor_id"`
+	DetectedAt time.Time       `json:"detected_at"`
+}
+
+// Tombstone - задача, удалённая после прошлой синхронизации
+type Tombstone struct {
+	ID        int       `json:"id"`
+	UID       string    `json:"uid"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncResult - итог одного изменения: HTTP-код, как у отдельного запроса
+type SyncResult struct {
+	UID       string     `json:"uid"`
+	Status    int        `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// SyncResponse - изменения на сервере и токен для следующего Sync
+type SyncResponse struct {
+	Token   string       `json:"token"`
+	Tasks   []Task       `json:"tasks"`
+	Deleted []Tombstone  `json:"deleted"`
+	Results []SyncResult `json:"results"`
+}
+
+// Sync отправляет локальные изменения и получает всё, что изменилось после
+// token; для первой синхронизации token пустой. Задачи применяйте по UID:
+// одна и та же может прийти в нескольких ответах подряд.
+func (c *Client) Sync(ctx context.Context, token string, changes []SyncChange) (SyncResponse, error) {
+	req := struct {
+		Token   string       `json:"token"`
+		Changes []SyncChange `json:"changes"`
+	}{token, changes}
+	var resp SyncResponse
+	err := c.do(ctx, http.MethodPost, "/sync", req, &resp)
+	return resp, err
+}
+
+// Conflicts - неразобранные конфликты синхронизации, новые первыми
+func (c *Client) Conflicts(ctx context.Context) ([]Conflict, error) {
+	var conflicts []Conflict
+	err := c.do(ctx, http.MethodGet, "/sync/conflicts", nil, &conflicts)
+	return conflicts, err
+}
+
+// ResolveConflict отмечает конфликт разобранным
+func (c *Client) ResolveConflict(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/sync/conflicts/"+strconv.Itoa(id), nil, nil)
+}
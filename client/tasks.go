@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// Task - задача в том виде, в каком её отдаёт и принимает API
+type Task struct {
+	ID int `json:"id"`
+	// UID - глобальный идентификатор; задайте его при создании, чтобы
+	// повтор запроса после обрыва связи не создал вторую задачу
+	UID         string     `json:"uid,omitempty"`
+	OwnerID     *int       `json:"owner_id"`
+	ParentID    *int       `json:"parent_id"`
+	ProjectID   *int       `json:"project_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	DueAt       *time.Time `json:"due_at"`
+	// Location - место, где задачу удобно сделать
+	Location *Location `json:"location,omitempty"`
+	// SnoozedUntil - до какого момента задача отложена; меняют SnoozeTask
+	// и UnsnoozeTask
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// Progress - доля завершённых подзадач в процентах; nil без подзадач
+	Progress *int `json:"progress,omitempty"`
+	// Pinned - закреплена ли задача вами; меняют PinTask и UnpinTask
+	Pinned bool `json:"pinned,omitempty"`
+	// DescriptionHTML - описание в HTML, только при запросе с ?render=html
+	DescriptionHTML string `json:"description_html,omitempty"`
+}
+
+// Location - точка в градусах и её подпись
+type Location struct {
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	Label string  `json:"label,omitempty"`
+}
+
+// TaskStats - число задач по статусам
+type TaskStats struct {
+	Todo       int        `json:"todo"`
+	InProgress int        `json:"in_progress"`
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+func taskPath(id int) string {
+	return "/tasks/" + strconv.Itoa(id)
+}
+
+func (c *Client) CreateTask(ctx context.Context, task Task) (Task, error) {
+	var created Task
+	err := c.do(ctx, http.MethodPost, "/tasks", task, &created)
+	return created, err
+}
+
+func (c *Client) GetTask(ctx context.Context, id int) (Task, error) {
+	var task Task
+	err := c.do(ctx, http.MethodGet, taskPath(id), nil, &task)
+	return task, err
+}
+
+// GetTasks получает задачи ids одним запросом в том же порядке; missing -
+// id, которых нет или которые не видны. Сервер принимает до 100 id.
+func (c *Client) GetTasks(ctx context.Context, ids []int) (tasks []Task, missing []int, err error) {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	var batch struct {
+		Tasks   []Task `json:"tasks"`
+		Missing []int  `json:"missing"`
+	}
+	err = c.do(ctx, http.MethodGet, "/tasks?"+url.Values{"ids": {strings.Join(parts, ",")}}.Encode(), nil, &batch)
+	return batch.Tasks, batch.Missing, err
+}
+
+// UpdateTask заменяет задачу task.ID целиком, как PUT /tasks/:id
+func (c *Client) UpdateTask(ctx context.Context, task Task) (Task, error) {
+	var updated Task
+	err := c.do(ctx, http.MethodPut, taskPath(task.ID), task, &updated)
+	return updated, err
+}
+
+func (c *Client) DeleteTask(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, taskPath(id), nil, nil)
+}
+
+// ListTasks - все задачи, видимые пользователю, кроме отложенных;
+// закреплённые первыми
+func (c *Client) ListTasks(ctx context.Context) ([]Task, error) {
+	var tasks []Task
+	err := c.do(ctx, http.MethodGet, "/tasks", nil, &tasks)
+	return tasks, err
+}
+
+// Tasks перебирает задачи пользователя:
+//
+//	for task, err := range c.Tasks(ctx) { ... }
+//
+// Сейчас сервер отдаёт список одним ответом, и итератор делает один запрос;
+// когда у /tasks появятся страницы, вызывающий код менять не придётся.
+// Ошибка приходит последним элементом.
+func (c *Client) Tasks(ctx context.Context) iter.Seq2[Task, error] {
+	return func(yield func(Task, error) bool) {
+		tasks, err := c.ListTasks(ctx)
+		if err != nil {
+			yield(Task{}, err)
+			return
+		}
+		for _, t := range tasks {
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SnoozeTask откладывает задачу до until: до тех пор её нет в ListTasks
+func (c *Client) SnoozeTask(ctx context.Context, id int, until time.Time) (Task, error) {
+	var task Task
+	err := c.do(ctx, http.MethodPost, taskPath(id)+"/snooze", map[string]time.Time{"until": until}, &task)
+	return task, err
+}
+
+// UnsnoozeTask возвращает отложенную задачу раньше срока
+func (c *Client) UnsnoozeTask(ctx context.Context, id int) (Task, error) {
+	var task Task
+	err := c.do(ctx, http.MethodDelete, taskPath(id)+"/snooze", nil, &task)
+	return task, err
+}
+
+// PinTask закрепляет задачу только для вас
+func (c *Client) PinTask(ctx context.Context, id int) (Task, error) {
+	var task Task
+	err := c.do(ctx, http.MethodPost, taskPath(id)+"/pin", nil, &task)
+	return task, err
+}
+
+func (c *Client) UnpinTask(ctx context.Context, id int) (Task, error) {
+	var task Task
+	err := c.do(ctx, http.MethodDelete, taskPath(id)+"/pin", nil, &task)
+	return task, err
+}
+
+// PinnedTasks - закреплённые вами задачи, последние закреплённые первыми
+func (c *Client) PinnedTasks(ctx context.Context) ([]Task, error) {
+	var tasks []Task
+	err := c.do(ctx, http.MethodGet, "/tasks/pinned", nil, &tasks)
+	return tasks, err
+}
+
+// Delegation - поручение задачи: исполнитель сдаёт работу, поручивший её
+// принимает или возвращает с комментарием
+type Delegation struct {
+	TaskID      int    `json:"task_id"`
+	TaskTitle   string `json:"task_title"`
+	DelegatorID int    `json:"delegator_id"`
+	DelegateID  int    `json:"delegate_id"`
+	// State - delegated, pending_review, approved или rejected
+	State     string    `json:"state"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DelegateTask поручает задачу проекта его редактору userID. Завершить её
+// исполнитель не может - только сдать через SubmitTaskForReview.
+func (c *Client) DelegateTask(ctx context.Context, id, userID int) (Delegation, error) {
+	var d Delegation
+	err := c.do(ctx, http.MethodPost, taskPath(id)+"/delegation", map[string]int{"user_id": userID}, &d)
+	return d, err
+}
+
+func (c *Client) TaskDelegation(ctx context.Context, id int) (Delegation, error) {
+	var d Delegation
+	err := c.do(ctx, http.MethodGet, taskPath(id)+"/delegation", nil, &d)
+	return d, err
+}
+
+func (c *Client) CancelDelegation(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, taskPath(id)+"/delegation", nil, nil)
+}
+
+func (c *Client) SubmitTaskForReview(ctx context.Context, id int) (Delegation, error) {
+	var d Delegation
+	err := c.do(ctx, http.MethodPost, taskPath(id)+"/delegation/submit", nil, &d)
+	return d, err
+}
+
+// ApproveTask принимает сданную работу и завершает задачу
+func (c *Client) ApproveTask(ctx context.Context, id int) (Delegation, error) {
+	var d Delegation
+	err := c.do(ctx, http.MethodPost, taskPath(id)+"/delegation/approve", nil, &d)
+	return d, err
+}
+
+func (c *Client) RejectTask(ctx context.Context, id int, comment string) (Delegation, error) {
+	var d Delegation
+	err := c.do(ctx, http.MethodPost, taskPath(id)+"/delegation/reject", map[string]string{"comment": comment}, &d)
+	return d, err
+}
+
+// Delegations - поручения от вас и вам, новые первыми
+func (c *Client) Delegations(ctx context.Context) ([]Delegation, error) {
+	var list []Delegation
+	err := c.do(ctx, http.MethodGet, "/delegations", nil, &list)
+	return list, err
+}
+
+// SearchTasks - полнотекстовый поиск; limit <= 0 - ограничение сервера
+func (c *Client) SearchTasks(ctx context.Context, query string, limit int) ([]Task, error) {
+	q := url.Values{"q": {query}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var tasks []Task
+	err := c.do(ctx, http.MethodGet, "/tasks/search?"+q.Encode(), nil, &tasks)
+	return tasks, err
+}
+
+// NearbyTasks - незавершённые задачи с местом в radius метрах от точки,
+// ближайшие первыми; radius 0 - по умолчанию сервера
+func (c *Client) NearbyTasks(ctx context.Context, lat, lon, radius float64) ([]Task, error) {
+	q := url.Values{"lat": {strconv.FormatFloat(lat, 'f', -1, 64)}, "lon": {strconv.FormatFloat(lon, 'f', -1, 64)}}
+	if radius > 0 {
+		q.Set("radius", strconv.FormatFloat(radius, 'f', -1, 64))
+	}
+	var tasks []Task
+	err := c.do(ctx, http.MethodGet, "/tasks/nearby?"+q.Encode(), nil, &tasks)
+	return tasks, err
+}
+
+// CountTasks - число задач в статусе status, пустой - всех
+func (c *Client) CountTasks(ctx context.Context, status string) (int, error) {
+	path := "/tasks/count"
+	if status != "" {
+		path += "?" + url.Values{"status": {status}}.Encode()
+	}
+	var resp struct {
+		Count int `json:"count"`
+	}
+	err := c.do(ctx, http.MethodGet, path, nil, &resp)
+	return resp.Count, err
+}
+
+func (c *Client) TaskStats(ctx context.Context) (TaskStats, error) {
+	var stats TaskStats
+	err := c.do(ctx, http.MethodGet, "/tasks/stats", nil, &stats)
+	return stats, err
+}
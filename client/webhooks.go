@@ -0,0 +1,32 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Заголовки запроса исходящего вебхука
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// VerifyWebhookSignature проверяет заголовок X-Webhook-Signature ("sha256=<hex>")
+// тела вебхука секретом, выданным при его создании. Повторно доставленное
+// событие приходит с тем же X-Webhook-Delivery: по нему отбрасывают дубли.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/blob"
+	"github.com/Upiter5/todo-app/internal/config"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/google"
+	apihttp "github.com/Upiter5/todo-app/internal/http"
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/mqtt"
+	"github.com/Upiter5/todo-app/internal/ratelimit"
+	"github.com/Upiter5/todo-app/internal/search"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// app - собранные сервисы процесса. serve и worker строят одно и то же и
+// различаются тем, что запускают.
+type app struct {
+	srv     *apihttp.Server
+	limiter *ratelimit.Limiter
+	closers []func()
+	// jobs - фоновая работа по базе и внешним API; её можно вынести в
+	// отдельный процесс worker
+	jobs []func(ctx context.Context)
+	// live - то, что обслуживает события и счётчики этого процесса и
+	// работает только рядом с HTTP-сервером
+	live []func(ctx context.Context)
+}
+
+func (a *app) close() {
+	for _, f := range a.closers {
+		f()
+	}
+}
+
+func newApp(cfg config.Config, db *pgxpool.Pool) (*app, error) {
+	a := &app{}
+
+	// Предохранитель: при недоступной базе отвечаем 503 сразу,
+	// не копя запросы в ожидании соединения из пула
+	pg := storage.NewPostgres(db)
+	var (
+		taskDB    storage.TaskStore = pg
+		history   storage.TaskHistory
+		snapshots storage.SnapshotStore = pg
+	)
+	switch cfg.StorageMode {
+	case config.StorageCRUD:
+	case config.StorageEvents:
+		es := storage.NewEventStore(db)
+		taskDB, history, snapshots = es, es, es
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_MODE %q", cfg.StorageMode)
+	}
+	store := storage.NewBreakerStore(taskDB, cfg.BreakerFailures, cfg.BreakerCooldown)
+
+	// Аутентификация: Bearer JWT, X-API-Key и cookie сессии
+	if cfg.JWTSecret == config.DevJWTSecret {
+		log.Warn().Msg("JWT_SECRET is not set, using insecure development secret")
+	}
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+
+	// Письма: SMTP, если настроен, иначе в лог
+	var mailer mail.Mailer = mail.Log{Logger: log.Logger}
+	if cfg.SMTPAddr != "" {
+		mailer = mail.SMTP{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.MailFrom}
+	}
+	users := service.NewAuthService(pg, pg, jwt, cfg.SessionTTL,
+		service.WithMailer(mailer, cfg.PublicURL),
+		service.WithAuthLogger(log.Logger))
+
+	var blobs blob.Store = blob.Filesystem{Dir: cfg.BlobDir}
+	if cfg.S3Bucket != "" {
+		blobs = &blob.S3{Endpoint: cfg.S3Endpoint, Region: cfg.S3Region, Bucket: cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey, SecretKey: cfg.S3SecretKey}
+	}
+	profiles := service.NewProfileService(pg, blobs, log.Logger)
+
+	// Подписчики событий задач; интеграции добавляются ниже, после настроек
+	// сервиса задач, которые они разделяют с API
+	var publishers events.Fanout
+
+	// MQTT - для Home Assistant и табло
+	if cfg.MQTTAddr != "" {
+		broker := &mqtt.Client{Addr: cfg.MQTTAddr, ClientID: "todo-app",
+			Username: cfg.MQTTUsername, Password: cfg.MQTTPassword}
+		if cfg.MQTTTLS {
+			broker.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		a.closers = append(a.closers, func() { broker.Close() })
+		pub := service.NewMQTTPublisher(broker, store, pg, pg, cfg.MQTTTopicPrefix, log.Logger)
+		a.live = append(a.live, func(ctx context.Context) { pub.Run(ctx, time.Minute) })
+		publishers = append(publishers, pub)
+	}
+
+	// Поток событий для аналитики и других сервисов: NATS JetStream или Kafka
+	if cfg.EventStreamURL != "" {
+		sink, err := newEventSink(cfg.EventStreamURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENT_STREAM_URL: %w", err)
+		}
+		stream := events.NewStream(sink, cfg.EventStreamPrefix, log.Logger)
+		a.live = append(a.live, stream.Run)
+		publishers = append(publishers, stream)
+	}
+
+	// Внешний поиск: индекс пополняется событиями задач, первое наполнение -
+	// командой reindex
+	var engine search.Engine
+	if cfg.SearchURL != "" {
+		var err error
+		if engine, err = newSearchEngine(cfg.SearchURL); err != nil {
+			return nil, fmt.Errorf("invalid SEARCH_URL: %w", err)
+		}
+		indexer := search.NewIndexer(engine, log.Logger)
+		a.live = append(a.live, indexer.Run)
+		publishers = append(publishers, indexer)
+	}
+
+	// Исходящие вебхуки: очередь доставок в базе, повторы с паузой
+	outbound := service.NewOutboundWebhookService(pg, log.Logger)
+	a.jobs = append(a.jobs, func(ctx context.Context) { outbound.Run(ctx, cfg.WebhookDeliveryInterval) })
+	publishers = append(publishers, outbound)
+
+	// Ограничение частоты по тарифам пользователей и API-ключей. Лимитер
+	// есть всегда: RATE_LIMITS_DISABLED переключается перезагрузкой настроек
+	a.limiter = ratelimit.New(pg, log.Logger)
+	a.limiter.SetDisabled(cfg.RateLimitsDisabled)
+	a.live = append(a.live, func(ctx context.Context) { a.limiter.Run(ctx, cfg.RateLimitFlushInterval) })
+
+	taskOpts := []service.Option{service.WithHistory(history),
+		service.WithReadModels(pg), service.WithQuotas(pg, cfg.MaxActiveTasks), service.WithSync(pg),
+		service.WithLocations(pg), service.WithSnooze(pg), service.WithFlags(pg),
+		service.WithDelegations(pg, pg, mailer, cfg.PublicURL, log.Logger)}
+	if cfg.DuplicateCheck {
+		taskOpts = append(taskOpts, service.WithDuplicateCheck(pg))
+	}
+	if engine != nil {
+		taskOpts = append(taskOpts, service.WithSearchEngine(engine))
+	} else if cfg.SearchFuzzyThreshold > 0 {
+		taskOpts = append(taskOpts, service.WithFuzzySearch(pg, cfg.SearchFuzzyThreshold))
+	}
+	if cfg.AutoCompleteParents {
+		taskOpts = append(taskOpts, service.WithAutoCompleteParents())
+	}
+
+	// GitHub, Jira и Google Calendar (только с настроенным OAuth-клиентом):
+	// изменения задач уходят в связанные issues и события через публикатор, а
+	// изменения из них применяются с теми же проверками, что и из API, и
+	// публикуются всем, кроме источника
+	gh := service.NewGitHubSync(pg, store, pg, cfg.PublicURL, log.Logger,
+		service.WithGitHubAPI(cfg.GitHubAPIURL, &http.Client{Timeout: 15 * time.Second}),
+		service.WithGitHubTasks(&publishers, taskOpts...))
+	jira := service.NewJiraSync(pg, store, pg, cfg.PublicURL, log.Logger, service.WithJiraTasks(&publishers, taskOpts...))
+	a.jobs = append(a.jobs,
+		func(ctx context.Context) { gh.Run(ctx, cfg.GitHubSyncInterval) },
+		func(ctx context.Context) { jira.Run(ctx, cfg.JiraSyncInterval) })
+	publishers = append(publishers, gh, jira)
+
+	var calendar *service.CalendarSync
+	if cfg.GoogleClientID != "" {
+		oauth := &google.OAuth{ClientID: cfg.GoogleClientID, ClientSecret: cfg.GoogleClientSecret,
+			RedirectURL: strings.TrimRight(cfg.PublicURL, "/") + "/integrations/google/callback",
+			HTTP:        &http.Client{Timeout: 15 * time.Second}}
+		calendar = service.NewCalendarSync(pg, store, oauth, cfg.JWTSecret, log.Logger,
+			service.WithCalendarTasks(&publishers, taskOpts...))
+		a.jobs = append(a.jobs, func(ctx context.Context) { calendar.Run(ctx, cfg.CalendarSyncInterval) })
+		publishers = append(publishers, calendar)
+	}
+
+	tasks := service.NewTaskService(store, append(taskOpts, service.WithPublisher(publishers))...)
+	waker := service.NewSnoozeWaker(tasks, log.Logger)
+	a.jobs = append(a.jobs, func(ctx context.Context) { waker.Run(ctx, cfg.SnoozeWakeInterval) })
+	workspaces := service.NewWorkspaceService(pg,
+		service.WithInviteMailer(mailer, cfg.PublicURL, log.Logger),
+		service.WithSnapshots(snapshots),
+		service.WithArchives(blobs, pg))
+	a.srv = apihttp.NewServer(cfg, tasks,
+		apihttp.WithLogger(log.Logger),
+		apihttp.WithAuthService(users),
+		apihttp.WithProfiles(profiles),
+		apihttp.WithAPIKeyLookup(pg.PrincipalByAPIKey),
+		apihttp.WithShares(service.NewShareService(pg, store, pg, cfg.PublicURL)),
+		apihttp.WithWebhooks(service.NewWebhookService(pg, tasks, pg, cfg.PublicURL)),
+		apihttp.WithOutboundWebhooks(outbound),
+		apihttp.WithGitHub(gh),
+		apihttp.WithJira(jira),
+		apihttp.WithCalendar(calendar),
+		apihttp.WithWorkspaces(workspaces),
+		apihttp.WithMicrosoftImport(service.NewMicrosoftImport(tasks, workspaces, log.Logger)),
+		apihttp.WithRateLimits(a.limiter, service.NewRateLimitService(pg)),
+		apihttp.WithConfigReload(config.Load),
+		apihttp.WithAuthenticators(jwt, auth.NewAPIKey(pg.PrincipalByAPIKey), auth.NewSession(pg.PrincipalBySession)))
+	return a, nil
+}
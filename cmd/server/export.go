@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+func (c *cli) exportCmd() *cobra.Command {
+	var (
+		format, output string
+		userID         int
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write tasks to a JSON or CSV file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "json" && format != "csv" {
+				return fmt.Errorf("unknown format %q, expected json or csv", format)
+			}
+			db, err := c.connect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			ctx := auth.WithPrincipal(cmd.Context(), auth.System)
+			tasks, err := storage.NewPostgres(db).ListTasks(ctx)
+			if err != nil {
+				return err
+			}
+			// --user - только задачи этого владельца
+			if userID != 0 {
+				owned := tasks[:0]
+				for _, t := range tasks {
+					if t.OwnerID != nil && *t.OwnerID == userID {
+						owned = append(owned, t)
+					}
+				}
+				tasks = owned
+			}
+
+			var w io.Writer = os.Stdout
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			if format == "csv" {
+				err = writeTasksCSV(w, tasks)
+			} else {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(tasks)
+			}
+			if err != nil {
+				return err
+			}
+			log.Info().Int("tasks", len(tasks)).Str("output", output).Msg("Export completed")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "json", "output format: json or csv")
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "output file, - for stdout")
+	cmd.Flags().IntVar(&userID, "user", 0, "export only tasks owned by this user id")
+	return cmd
+}
+
+func writeTasksCSV(w io.Writer, tasks []model.Task) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"id", "uid", "owner_id", "parent_id", "project_id", "title", "description", "status",
+		"due_at", "completed_at", "created_at", "updated_at"})
+	for _, t := range tasks {
+		_ = cw.Write([]string{strconv.Itoa(t.ID), t.UID, optionalInt(t.OwnerID), optionalInt(t.ParentID), optionalInt(t.ProjectID),
+			t.Title, t.Description, t.Status, optionalTime(t.DueAt), optionalTime(t.CompletedAt),
+			t.CreatedAt.Format(time.RFC3339), t.UpdatedAt.Format(time.RFC3339)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func optionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/Upiter5/todo-app/client"
+)
+
+// loadOps - операции нагрузки: чтения и записи. Записи трогают только
+// задачи, созданные этим прогоном.
+var loadOps = []string{"list", "get", "search", "stats", "create", "update", "delete"}
+
+type loadOptions struct {
+	target      string
+	token       string
+	apiKey      string
+	email       string
+	password    string
+	mix         string
+	duration    time.Duration
+	concurrency int
+	rate        int
+	warmup      int
+	seed        uint64
+	keep        bool
+}
+
+func (c *cli) loadtestCmd() *cobra.Command {
+	var opts loadOptions
+	cmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Drive a read/write mix against a running instance and report latencies",
+		Long: `Sends requests through the Go client with --concurrency workers for --duration and
+prints per-operation throughput, error rate and latency percentiles. Pick an
+operation for every request by the --mix weights, e.g. --mix list=60,get=25,create=10,update=5.
+Retries are off: 429 and 5xx answers count as errors.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.target == "" {
+				opts.target = c.cfg.PublicURL
+			}
+			mix, err := parseLoadMix(opts.mix)
+			if err != nil {
+				return err
+			}
+			return runLoadtest(cmd.Context(), cmd.OutOrStdout(), opts, mix)
+		},
+	}
+	cmd.Flags().StringVar(&opts.target, "target", "", "base URL of the instance, PUBLIC_URL by default")
+	cmd.Flags().StringVar(&opts.token, "token", os.Getenv("LOADTEST_TOKEN"), "access token, LOADTEST_TOKEN by default")
+	cmd.Flags().StringVar(&opts.apiKey, "api-key", os.Getenv("LOADTEST_API_KEY"), "API key, LOADTEST_API_KEY by default")
+	cmd.Flags().StringVar(&opts.email, "email", "", "log in with this email instead of a token")
+	cmd.Flags().StringVar(&opts.password, "password", os.Getenv("LOADTEST_PASSWORD"), "password for --email, LOADTEST_PASSWORD by default")
+	cmd.Flags().StringVar(&opts.mix, "mix", "list=50,get=30,search=5,stats=5,create=5,update=4,delete=1",
+		"operation weights: "+strings.Join(loadOps, ", "))
+	cmd.Flags().DurationVar(&opts.duration, "duration", 30*time.Second, "how long to send requests")
+	cmd.Flags().IntVar(&opts.concurrency, "concurrency", 16, "parallel workers")
+	cmd.Flags().IntVar(&opts.rate, "rate", 0, "total requests per second, 0 - as fast as the workers go")
+	cmd.Flags().IntVar(&opts.warmup, "warmup-tasks", 50, "tasks to create before measuring, for get, update and delete")
+	cmd.Flags().Uint64Var(&opts.seed, "seed", 1, "random seed for the operation sequence")
+	cmd.Flags().BoolVar(&opts.keep, "keep", false, "do not delete tasks created by the run")
+	return cmd
+}
+
+type loadWeight struct {
+	op     string
+	weight int
+}
+
+func parseLoadMix(s string) ([]loadWeight, error) {
+	var mix []loadWeight
+	for _, part := range strings.Split(s, ",") {
+		op, w, ok := strings.Cut(strings.TrimSpace(part), "=")
+		n, err := strconv.Atoi(w)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid --mix entry %q, expected op=weight", part)
+		}
+		if !slices.Contains(loadOps, op) {
+			return nil, fmt.Errorf("unknown operation %q in --mix, expected one of %s", op, strings.Join(loadOps, ", "))
+		}
+		if n > 0 {
+			mix = append(mix, loadWeight{op, n})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, errors.New("--mix has no operations with a positive weight")
+	}
+	return mix, nil
+}
+
+func pickLoadOp(r *rand.Rand, mix []loadWeight) string {
+	total := 0
+	for _, w := range mix {
+		total += w.weight
+	}
+	n := r.IntN(total)
+	for _, w := range mix {
+		if n < w.weight {
+			return w.op
+		}
+		n -= w.weight
+	}
+	return mix[len(mix)-1].op
+}
+
+// loadPool - id задач, созданных прогоном: на них идут get, update и delete
+type loadPool struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+func (p *loadPool) add(id int) {
+	p.mu.Lock()
+	p.ids = append(p.ids, id)
+	p.mu.Unlock()
+}
+
+// pick - случайная задача; take ещё и убирает её из пула
+func (p *loadPool) pick(r *rand.Rand, take bool) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.ids) == 0 {
+		return 0, false
+	}
+	i := r.IntN(len(p.ids))
+	id := p.ids[i]
+	if take {
+		p.ids[i] = p.ids[len(p.ids)-1]
+		p.ids = p.ids[:len(p.ids)-1]
+	}
+	return id, true
+}
+
+// loadStats - задержки и ошибки одной операции
+type loadStats struct {
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+type loadRecorder struct {
+	mu    sync.Mutex
+	stats map[string]*loadStats
+}
+
+func (r *loadRecorder) record(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats[op]
+	if st == nil {
+		st = &loadStats{errors: map[string]int{}}
+		r.stats[op] = st
+	}
+	if err != nil {
+		st.errors[loadErrorKind(err)]++
+		return
+	}
+	st.latencies = append(st.latencies, d)
+}
+
+// loadErrorKind - HTTP-статус ответа с ошибкой или timeout/network
+func loadErrorKind(err error) string {
+	var apiErr *client.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return "network"
+}
+
+func runLoadtest(ctx context.Context, out io.Writer, opts loadOptions, mix []loadWeight) error {
+	if opts.concurrency <= 0 || opts.duration <= 0 {
+		return errors.New("--concurrency and --duration must be positive")
+	}
+	hc := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: opts.concurrency, MaxConnsPerHost: opts.concurrency},
+	}
+	clientOpts := []client.Option{client.WithHTTPClient(hc), client.WithRetries(0, 0)}
+	switch {
+	case opts.apiKey != "":
+		clientOpts = append(clientOpts, client.WithAPIKey(opts.apiKey))
+	case opts.token != "":
+		clientOpts = append(clientOpts, client.WithToken(opts.token))
+	case opts.email == "":
+		return errors.New("set --token, --api-key or --email to authenticate")
+	}
+	api := client.New(opts.target, clientOpts...)
+	if opts.email != "" {
+		if err := api.Login(ctx, opts.email, opts.password); err != nil {
+			return fmt.Errorf("log in: %w", err)
+		}
+	}
+
+	pool := &loadPool{}
+	created := &loadPool{}
+	newTask := func(r *rand.Rand) client.Task {
+		return client.Task{Title: "loadtest " + seedVerbs[r.IntN(len(seedVerbs))] + " " + seedObjects[r.IntN(len(seedObjects))],
+			Description: seedDetails[r.IntN(len(seedDetails))], Status: "todo"}
+	}
+	warm := rand.New(rand.NewPCG(opts.seed, 0))
+	for range opts.warmup {
+		t, err := api.CreateTask(ctx, newTask(warm))
+		if err != nil {
+			return fmt.Errorf("create warm-up task: %w", err)
+		}
+		pool.add(t.ID)
+		created.add(t.ID)
+	}
+
+	// --rate раздаёт разрешения на запросы всем работникам сразу
+	var tokens <-chan time.Time
+	if opts.rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	log.Info().Str("target", opts.target).Int("concurrency", opts.concurrency).Dur("duration", opts.duration).
+		Str("mix", opts.mix).Msg("Load test started")
+	rec := &loadRecorder{stats: map[string]*loadStats{}}
+	runCtx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(opts.seed, uint64(w)+1))
+			for {
+				if tokens != nil {
+					select {
+					case <-runCtx.Done():
+						return
+					case <-tokens:
+					}
+				}
+				if runCtx.Err() != nil {
+					return
+				}
+				op := pickLoadOp(r, mix)
+				began := time.Now()
+				err := runLoadOp(runCtx, api, r, op, pool, created, newTask)
+				// запрос, прерванный концом прогона, не считается
+				if runCtx.Err() != nil {
+					return
+				}
+				rec.record(op, time.Since(began), err)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	writeLoadReport(out, rec, elapsed)
+
+	if !opts.keep {
+		// удаляем без отмены прогона: его контекст уже истёк
+		for _, id := range created.ids {
+			if err := api.DeleteTask(ctx, id); err != nil && !client.IsNotFound(err) {
+				log.Warn().Err(err).Int("task_id", id).Msg("Failed to delete load test task")
+			}
+		}
+	}
+	return nil
+}
+
+func runLoadOp(ctx context.Context, api *client.Client, r *rand.Rand, op string, pool, created *loadPool,
+	newTask func(*rand.Rand) client.Task) error {
+	switch op {
+	case "list":
+		_, err := api.ListTasks(ctx)
+		return err
+	case "search":
+		_, err := api.SearchTasks(ctx, seedObjects[r.IntN(len(seedObjects))], 20)
+		return err
+	case "stats":
+		_, err := api.TaskStats(ctx)
+		return err
+	case "create":
+		t, err := api.CreateTask(ctx, newTask(r))
+		if err == nil {
+			pool.add(t.ID)
+			created.add(t.ID)
+		}
+		return err
+	}
+
+	id, ok := pool.pick(r, op == "delete")
+	if !ok {
+		// пул пуст: все задачи удалены - создаём новую вместо операции
+		t, err := api.CreateTask(ctx, newTask(r))
+		if err == nil {
+			pool.add(t.ID)
+			created.add(t.ID)
+		}
+		return err
+	}
+	switch op {
+	case "get":
+		_, err := api.GetTask(ctx, id)
+		return err
+	case "update":
+		t, err := api.GetTask(ctx, id)
+		if err != nil {
+			return err
+		}
+		t.Status = []string{"todo", "in_progress", "done"}[r.IntN(3)]
+		_, err = api.UpdateTask(ctx, t)
+		return err
+	default:
+		return api.DeleteTask(ctx, id)
+	}
+}
+
+func writeLoadReport(out io.Writer, rec *loadRecorder, elapsed time.Duration) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "op\trequests\treq/s\terrors\terror %\tp50\tp90\tp99\tmax\terror kinds\t")
+	var failed int
+	var all []time.Duration
+	for _, op := range loadOps {
+		st := rec.stats[op]
+		if st == nil {
+			continue
+		}
+		n := 0
+		for _, c := range st.errors {
+			n += c
+		}
+		failed += n
+		all = append(all, st.latencies...)
+		writeLoadRow(w, op, st.latencies, n, elapsed, st.errors)
+	}
+	writeLoadRow(w, "total", all, failed, elapsed, nil)
+	w.Flush()
+}
+
+func writeLoadRow(w io.Writer, op string, latencies []time.Duration, failed int, elapsed time.Duration,
+	kinds map[string]int) {
+	sum := summarizeLoad(latencies, failed, elapsed)
+	var errs []string
+	for kind, n := range kinds {
+		errs = append(errs, fmt.Sprintf("%s×%d", kind, n))
+	}
+	slices.Sort(errs)
+	fmt.Fprintf(w, "%s\t%d\t%.1f\t%d\t%.2f\t%s\t%s\t%s\t%s\t%s\t\n", op, sum.requests,
+		sum.throughput, sum.failed, sum.errorRate, sum.p50, sum.p90, sum.p99, sum.max, strings.Join(errs, " "))
+}
+
+// loadSummary - строка отчёта: запросы в секунду, доля ошибок в процентах и
+// перцентили задержки успешных запросов
+type loadSummary struct {
+	requests, failed      int
+	throughput, errorRate float64
+	p50, p90, p99, max    time.Duration
+}
+
+// summarizeLoad считает строку отчёта по задержкам успешных запросов и
+// числу ошибок за elapsed; latencies не меняет
+func summarizeLoad(latencies []time.Duration, failed int, elapsed time.Duration) loadSummary {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	sum := loadSummary{
+		requests: len(sorted) + failed,
+		failed:   failed,
+		p50:      percentile(sorted, 0.50),
+		p90:      percentile(sorted, 0.90),
+		p99:      percentile(sorted, 0.99),
+		max:      percentile(sorted, 1),
+	}
+	if elapsed > 0 {
+		sum.throughput = float64(sum.requests) / elapsed.Seconds()
+	}
+	if sum.requests > 0 {
+		sum.errorRate = 100 * float64(failed) / float64(sum.requests)
+	}
+	return sum
+}
+
+// percentile - задержка, которой не превышает доля q запросов; sorted
+// отсортирован по возрастанию
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)].Round(10 * time.Microsecond)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSummarizeLoad(t *testing.T) {
+	// 1..100 мс вразнобой, 25 ошибок за 10 секунд
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	input := slices.Clone(latencies)
+
+	got := summarizeLoad(latencies, 25, 10*time.Second)
+	want := loadSummary{
+		requests: 125, failed: 25, throughput: 12.5, errorRate: 20,
+		p50: 50 * time.Millisecond, p90: 90 * time.Millisecond, p99: 99 * time.Millisecond, max: 100 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("summarizeLoad = %+v, want %+v", got, want)
+	}
+	if !slices.Equal(latencies, input) {
+		t.Error("summarizeLoad reordered its input")
+	}
+
+	// перцентиль округляется до ближайшего ранга, задержка - до 10 мкс
+	got = summarizeLoad([]time.Duration{3 * time.Millisecond, 1234567 * time.Nanosecond, 2 * time.Millisecond}, 0, time.Second)
+	want = loadSummary{requests: 3, throughput: 3,
+		p50: 2 * time.Millisecond, p90: 3 * time.Millisecond, p99: 3 * time.Millisecond, max: 3 * time.Millisecond}
+	if got != want {
+		t.Errorf("summarizeLoad = %+v, want %+v", got, want)
+	}
+	if got := summarizeLoad([]time.Duration{1234567 * time.Nanosecond}, 0, time.Second).p50; got != 1230*time.Microsecond {
+		t.Errorf("p50 = %v, want 1.23ms", got)
+	}
+
+	// одни ошибки и нулевая длительность не делят на ноль
+	if got := summarizeLoad(nil, 4, 0); got != (loadSummary{requests: 4, failed: 4, errorRate: 100}) {
+		t.Errorf("summarizeLoad(nil) = %+v", got)
+	}
+	if got := summarizeLoad(nil, 0, time.Second); got != (loadSummary{}) {
+		t.Errorf("summarizeLoad(empty) = %+v", got)
+	}
+}
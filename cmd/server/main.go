@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/Upiter5/todo-app/internal/config"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// cli - общее для всех подкоманд: настройки из окружения и CONFIG_FILE и
+// флаги корня
+type cli struct {
+	cfg            config.Config
+	skipMigrations bool
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		log.Fatal().Err(err).Msg("Command failed")
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	c := &cli{}
+	root := &cobra.Command{
+		Use:           "server",
+		Short:         "Todo API server and operational commands",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(*cobra.Command, []string) (err error) {
+			// Инициализация логгера
+			log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+			if c.cfg, err = config.Load(); err != nil {
+				return err
+			}
+			level, _ := zerolog.ParseLevel(c.cfg.LogLevel)
+			zerolog.SetGlobalLevel(level)
+			return nil
+		},
+	}
+	root.PersistentFlags().BoolVar(&c.skipMigrations, "skip-migrations", false,
+		"do not apply pending migrations on start; apply them with the migrate command")
+
+	serve := c.serveCmd()
+	root.AddCommand(
+		serve,
+		c.workerCmd(),
+		c.migrateCmd(),
+		c.seedCmd(),
+		c.exportCmd(),
+		c.rebuildProjectionsCmd(),
+		c.reindexCmd(),
+		c.loadtestCmd(),
+	)
+	// без подкоманды - сервер, как раньше
+	root.RunE = serve.RunE
+	root.Flags().AddFlagSet(serve.Flags())
+	return root
+}
+
+// connect подключается к PostgreSQL и применяет миграции, если их не
+// отключили флагом
+func (c *cli) connect(ctx context.Context) (*pgxpool.Pool, error) {
+	db, err := storage.Connect(ctx, c.cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	if c.skipMigrations {
+		return db, nil
+	}
+	if err := storage.Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("apply migrations: %w", err)
+	}
+	return db, nil
+}
+
+func (c *cli) migrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending database migrations and exit",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := storage.Connect(cmd.Context(), c.cfg.DatabaseURL)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			defer db.Close()
+			if err := storage.Migrate(cmd.Context(), db); err != nil {
+				return fmt.Errorf("apply migrations: %w", err)
+			}
+			log.Info().Msg("Migrations applied")
+			return nil
+		},
+	}
+}
+
+func (c *cli) rebuildProjectionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rebuild-projections",
+		Short: "Recompute task projections from the task_events log",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := c.connect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			n, err := storage.NewEventStore(db).Rebuild(cmd.Context())
+			if err != nil {
+				return err
+			}
+			log.Info().Int("tasks", n).Msg("Projections rebuilt from task events")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"strings"
+	"testing"
+)
+
+// execute запускает корневую команду с args, как из терминала
+func execute(args ...string) (string, error) {
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestCommandsHelp(t *testing.T) {
+	out, err := execute("--help")
+	if err != nil {
+		t.Fatalf("server --help: %v", err)
+	}
+	for _, cmd := range newRootCmd().Commands() {
+		if !strings.Contains(out, cmd.Name()) {
+			t.Errorf("server --help does not list %s:\n%s", cmd.Name(), out)
+		}
+
+		out, err := execute(cmd.Name(), "--help")
+		if err != nil {
+			t.Errorf("%s --help: %v", cmd.Name(), err)
+			continue
+		}
+		// справка начинается с Long, а без него - с Short
+		if !strings.Contains(out, "server "+cmd.Name()) || !strings.Contains(out, cmp.Or(cmd.Long, cmd.Short)) {
+			t.Errorf("%s --help:\n%s", cmd.Name(), out)
+		}
+	}
+}
+
+// Ошибка разбора флагов возвращается до PersistentPreRunE, то есть до
+// чтения настроек и подключения к базе
+func TestCommandsRejectInvalidFlags(t *testing.T) {
+	for _, cmd := range newRootCmd().Commands() {
+		if _, err := execute(cmd.Name(), "--no-such-flag"); err == nil || !strings.Contains(err.Error(), "unknown flag") {
+			t.Errorf("%s --no-such-flag: err = %v, want unknown flag", cmd.Name(), err)
+		}
+	}
+
+	tests := [][]string{
+		{"--jobs=maybe"},
+		{"serve", "--jobs=maybe"},
+		{"seed", "--tasks=many"},
+		{"seed", "--seed=-1"},
+		{"export", "--user=me"},
+		{"loadtest", "--duration=forever"},
+		{"loadtest", "--concurrency=1.5"},
+		{"migrate", "--skip-migrations=perhaps"},
+	}
+	for _, args := range tests {
+		if _, err := execute(args...); err == nil || !strings.Contains(err.Error(), "invalid argument") {
+			t.Errorf("%s: err = %v, want invalid argument", strings.Join(args, " "), err)
+		}
+	}
+}
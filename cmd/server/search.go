@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/search"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// defaultSearchIndex - индекс, если в SEARCH_URL нет пути
+const defaultSearchIndex = "tasks"
+
+// newSearchEngine выбирает движок по схеме адреса. У Elasticsearch и
+// OpenSearch логин и пароль - из userinfo, у Meilisearch имя без пароля -
+// API-ключ.
+func newSearchEngine(raw string) (search.Engine, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	engine, scheme, _ := strings.Cut(u.Scheme, "+")
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("unsupported search engine scheme %q", u.Scheme)
+	}
+	index := strings.Trim(u.Path, "/")
+	if index == "" {
+		index = defaultSearchIndex
+	}
+	pass, _ := u.User.Password()
+	base := url.URL{Scheme: scheme, Host: u.Host}
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	switch engine {
+	case "elasticsearch", "opensearch":
+		return &search.Elasticsearch{BaseURL: base.String(), IndexName: index,
+			Username: u.User.Username(), Password: pass, HTTP: client}, nil
+	case "meilisearch":
+		return &search.Meilisearch{BaseURL: base.String(), IndexName: index, APIKey: u.User.Username(), HTTP: client}, nil
+	}
+	return nil, fmt.Errorf("unsupported search engine scheme %q", u.Scheme)
+}
+
+func (c *cli) reindexCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex",
+		Short: "Write all tasks to the external search index from SEARCH_URL",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if c.cfg.SearchURL == "" {
+				return fmt.Errorf("SEARCH_URL is not set")
+			}
+			engine, err := newSearchEngine(c.cfg.SearchURL)
+			if err != nil {
+				return fmt.Errorf("invalid SEARCH_URL: %w", err)
+			}
+			db, err := c.connect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			ctx := auth.WithPrincipal(cmd.Context(), auth.System)
+			tasks, err := storage.NewPostgres(db).ListTasks(ctx)
+			if err != nil {
+				return err
+			}
+			if err := search.NewIndexer(engine, log.Logger).Reindex(ctx, tasks); err != nil {
+				return err
+			}
+			log.Info().Int("tasks", len(tasks)).Msg("Search index rebuilt")
+			return nil
+		},
+	}
+}
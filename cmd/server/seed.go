@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var (
+	seedVerbs = []string{
+		"Buy", "Call", "Fix", "Review", "Write", "Plan", "Book", "Clean",
+		"Prepare", "Order", "Update", "Schedule", "Refactor", "Email", "Test",
+	}
+	seedObjects = []string{
+		"groceries", "dentist appointment", "login page bug", "quarterly report",
+		"team offsite", "flight to Berlin", "garage", "release notes",
+		"birthday gift for mom", "car insurance", "onboarding docs", "database backup",
+		"pull request #42", "weekly sync", "tax return", "conference talk",
+	}
+	seedDetails = []string{
+		"",
+		"Don't forget to check the budget first.",
+		"Ask Anna for the latest numbers.",
+		"Blocked until the vendor replies.",
+		"Low priority, do it when there is spare time.",
+		"Needs to be done before the end of the sprint.",
+		"See the notes from the last meeting.",
+	}
+	seedProjects = []string{"Website relaunch", "Mobile app", "Office move", "Hiring", "Marketing", "Infrastructure"}
+	// Статусы с весами: старых выполненных задач больше, чем начатых
+	seedStatuses = []struct {
+		status string
+		weight int
+	}{
+		{model.StatusTodo, 4}, {model.StatusInProgress, 2}, {model.StatusDone, 5},
+	}
+)
+
+func (c *cli) seedCmd() *cobra.Command {
+	var opts seedOptions
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill the database with a demo workspace: users, projects and random realistic tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := c.connect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			pg := storage.NewPostgres(db)
+			return runSeed(cmd.Context(), seeder{users: pg, workspaces: pg, writeTasks: copyTasks(db)}, opts)
+		},
+	}
+	cmd.Flags().IntVar(&opts.count, "tasks", 5000, "number of tasks to generate")
+	cmd.Flags().IntVar(&opts.users, "users", 10, "number of workspace members to create")
+	cmd.Flags().IntVar(&opts.projects, "projects", 4, "number of projects in the workspace")
+	cmd.Flags().IntVar(&opts.days, "days", 365, "spread created_at over this many past days")
+	cmd.Flags().Uint64Var(&opts.seed, "seed", uint64(time.Now().UnixNano()), "random seed for reproducible data")
+	return cmd
+}
+
+type seedOptions struct {
+	count, users, projects, days int
+	seed                         uint64
+}
+
+// seeder - куда seed пишет данные. Пользователи, пространство и проекты
+// создаются через хранилища, задачи - writeTasks целиком, с владельцем и
+// датами: TaskStore.CreateTask ставит created_at сам.
+type seeder struct {
+	users      storage.UserStore
+	workspaces storage.WorkspaceStore
+	writeTasks func(ctx context.Context, tasks []model.Task) (int64, error)
+}
+
+// seedPassword - пароль всех созданных пользователей, чтобы под ними можно
+// было войти
+const seedPassword = "password"
+
+// runSeed создаёт пространство с участниками и проектами и заполняет его
+// правдоподобными случайными задачами. Адреса пользователей включают seed,
+// поэтому повторный запуск с тем же seed упрётся в занятый email.
+func runSeed(ctx context.Context, s seeder, opts seedOptions) error {
+	if opts.count <= 0 || opts.users <= 0 || opts.projects <= 0 || opts.days <= 0 {
+		return fmt.Errorf("tasks, users, projects and days must be positive")
+	}
+
+	rng := rand.New(rand.NewPCG(opts.seed, opts.seed))
+	now := time.Now()
+
+	hash, err := auth.HashPassword(seedPassword)
+	if err != nil {
+		return err
+	}
+	users := make([]int, opts.users)
+	for i := range users {
+		u := model.User{Email: fmt.Sprintf("seed-%d-%d@example.com", opts.seed, i+1), PasswordHash: hash, Role: model.RoleUser}
+		if err := s.users.CreateUser(ctx, &u); err != nil {
+			return fmt.Errorf("create user %s: %w", u.Email, err)
+		}
+		users[i] = u.ID
+	}
+
+	ws := model.Workspace{Name: fmt.Sprintf("Demo workspace %d", opts.seed)}
+	if err := s.workspaces.CreateWorkspace(ctx, &ws, users[0]); err != nil {
+		return fmt.Errorf("create workspace: %w", err)
+	}
+	for _, id := range users[1:] {
+		if err := s.workspaces.SetWorkspaceMember(ctx, ws.ID, id, model.WorkspaceMember); err != nil {
+			return fmt.Errorf("add workspace member: %w", err)
+		}
+	}
+	projects := make([]int, opts.projects)
+	for i := range projects {
+		p := model.Project{WorkspaceID: ws.ID, Name: seedProjects[i%len(seedProjects)], Visibility: model.VisibilityWorkspace}
+		if i >= len(seedProjects) {
+			p.Name = fmt.Sprintf("%s %d", p.Name, i/len(seedProjects)+1)
+		}
+		if err := s.workspaces.CreateProject(ctx, &p); err != nil {
+			return fmt.Errorf("create project: %w", err)
+		}
+		projects[i] = p.ID
+	}
+
+	tasks := make([]model.Task, 0, opts.count)
+	for range opts.count {
+		createdAt := now.Add(-time.Duration(rng.Int64N(int64(opts.days) * int64(24*time.Hour))))
+		status := seedStatus(rng)
+		updatedAt := createdAt
+		if status != model.StatusTodo {
+			// Начатые и выполненные задачи обновлялись позже создания
+			updatedAt = createdAt.Add(time.Duration(rng.Int64N(int64(now.Sub(createdAt)) + 1)))
+		}
+		var completedAt *time.Time
+		if status == model.StatusDone {
+			completedAt = &updatedAt
+		}
+		owner := users[rng.IntN(len(users))]
+		// каждая пятая задача - личная, вне проектов
+		var projectID *int
+		if rng.IntN(5) > 0 {
+			projectID = &projects[rng.IntN(len(projects))]
+		}
+		tasks = append(tasks, model.Task{
+			OwnerID:     &owner,
+			ProjectID:   projectID,
+			Title:       fmt.Sprintf("%s %s", seedVerbs[rng.IntN(len(seedVerbs))], seedObjects[rng.IntN(len(seedObjects))]),
+			Description: seedDetails[rng.IntN(len(seedDetails))],
+			Status:      status,
+			CompletedAt: completedAt,
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+		})
+	}
+	n, err := s.writeTasks(ctx, tasks)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int64("tasks", n).Int("users", len(users)).Int("projects", len(projects)).Int("workspace_id", ws.ID).
+		Uint64("seed", opts.seed).Str("password", seedPassword).Msg("Seed completed")
+	return nil
+}
+
+// copyTasks пишет задачи в Postgres одним COPY. workspace_id - ключ секций
+// tasks, COPY передаёт его сам, по проектам задач; uid - значение по умолчанию.
+func copyTasks(db *pgxpool.Pool) func(context.Context, []model.Task) (int64, error) {
+	return func(ctx context.Context, tasks []model.Task) (int64, error) {
+		var ids []int
+		for _, t := range tasks {
+			if t.ProjectID != nil {
+				ids = append(ids, *t.ProjectID)
+			}
+		}
+		rows, err := db.Query(ctx, "SELECT id, workspace_id FROM projects WHERE id = ANY($1)", ids)
+		if err != nil {
+			return 0, err
+		}
+		workspaces := map[int]int{}
+		var projectID, workspaceID int
+		if _, err := pgx.ForEachRow(rows, []any{&projectID, &workspaceID}, func() error {
+			workspaces[projectID] = workspaceID
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+
+		return db.CopyFrom(ctx, pgx.Identifier{"tasks"},
+			[]string{"user_id", "project_id", "workspace_id", "title", "description", "status", "completed_at",
+				"created_at", "updated_at"},
+			pgx.CopyFromSlice(len(tasks), func(i int) ([]any, error) {
+				t := tasks[i]
+				ws := 0
+				if t.ProjectID != nil {
+					ws = workspaces[*t.ProjectID]
+				}
+				return []any{t.OwnerID, t.ProjectID, ws, t.Title, t.Description, t.Status, t.CompletedAt,
+					t.CreatedAt, t.UpdatedAt}, nil
+			}))
+	}
+}
+
+func seedStatus(rng *rand.Rand) string {
+	total := 0
+	for _, s := range seedStatuses {
+		total += s.weight
+	}
+	n := rng.IntN(total)
+	for _, s := range seedStatuses {
+		if n < s.weight {
+			return s.status
+		}
+		n -= s.weight
+	}
+	return seedStatuses[0].status
+}
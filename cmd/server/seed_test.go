@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// memUsers и memWorkspaces - ровно то, что нужно seed: у storage.Memory
+// есть только задачи
+type memUsers struct {
+	storage.UserStore
+	emails map[string]int
+}
+
+func (m *memUsers) CreateUser(_ context.Context, u *model.User) error {
+	if _, ok := m.emails[u.Email]; ok {
+		return storage.ErrEmailTaken
+	}
+	u.ID = len(m.emails) + 1
+	m.emails[u.Email] = u.ID
+	return nil
+}
+
+type memWorkspaces struct {
+	storage.WorkspaceStore
+	members  map[int]string // user -> роль в единственном пространстве
+	projects []model.Project
+}
+
+func (m *memWorkspaces) CreateWorkspace(_ context.Context, ws *model.Workspace, ownerID int) error {
+	ws.ID = 1
+	m.members[ownerID] = model.WorkspaceOwner
+	return nil
+}
+
+func (m *memWorkspaces) SetWorkspaceMember(_ context.Context, _, userID int, role string) error {
+	m.members[userID] = role
+	return nil
+}
+
+func (m *memWorkspaces) CreateProject(_ context.Context, p *model.Project) error {
+	p.ID = len(m.projects) + 1
+	m.projects = append(m.projects, *p)
+	return nil
+}
+
+// memoryTasks создаёт задачи от имени их владельцев
+func memoryTasks(store *storage.Memory) func(context.Context, []model.Task) (int64, error) {
+	return func(ctx context.Context, tasks []model.Task) (int64, error) {
+		for _, t := range tasks {
+			owner := auth.WithPrincipal(ctx, &auth.Principal{UserID: *t.OwnerID, Scheme: auth.SchemeJWT})
+			if err := store.CreateTask(owner, &t); err != nil {
+				return 0, err
+			}
+		}
+		return int64(len(tasks)), nil
+	}
+}
+
+func TestSeed(t *testing.T) {
+	users := &memUsers{emails: map[string]int{}}
+	workspaces := &memWorkspaces{members: map[int]string{}}
+	tasks := storage.NewMemory()
+	s := seeder{users: users, workspaces: workspaces, writeTasks: memoryTasks(tasks)}
+	opts := seedOptions{count: 200, users: 3, projects: 8, days: 30, seed: 42}
+	ctx := context.Background()
+
+	if err := runSeed(ctx, s, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users.emails) != 3 {
+		t.Fatalf("users = %d, want 3", len(users.emails))
+	}
+	if workspaces.members[1] != model.WorkspaceOwner || workspaces.members[2] != model.WorkspaceMember ||
+		workspaces.members[3] != model.WorkspaceMember {
+		t.Errorf("workspace members = %v, want user 1 owner and the rest members", workspaces.members)
+	}
+	names := map[string]bool{}
+	for _, p := range workspaces.projects {
+		names[p.Name] = true
+	}
+	if len(names) != 8 {
+		t.Errorf("project names = %v, want 8 distinct", names)
+	}
+
+	all, err := tasks.ListTasks(auth.WithPrincipal(ctx, auth.System))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 200 {
+		t.Fatalf("tasks = %d, want 200", len(all))
+	}
+	owners, inProjects := map[int]bool{}, 0
+	for _, task := range all {
+		if task.OwnerID == nil || *task.OwnerID < 1 || *task.OwnerID > 3 {
+			t.Fatalf("task %d owner = %v, want a seeded user", task.ID, task.OwnerID)
+		}
+		owners[*task.OwnerID] = true
+		if task.ProjectID != nil {
+			if *task.ProjectID < 1 || *task.ProjectID > 8 {
+				t.Fatalf("task %d project = %d, want a seeded project", task.ID, *task.ProjectID)
+			}
+			inProjects++
+		}
+		if (task.Status == model.StatusDone) != (task.CompletedAt != nil) {
+			t.Errorf("task %d: status %s with completed_at %v", task.ID, task.Status, task.CompletedAt)
+		}
+	}
+	if len(owners) != 3 || inProjects == 0 || inProjects == len(all) {
+		t.Errorf("owners = %d, tasks in projects = %d of %d; want tasks spread over users, projects and personal lists",
+			len(owners), inProjects, len(all))
+	}
+
+	// тот же seed - те же адреса, второй раз их не создать
+	if err := runSeed(ctx, s, opts); !errors.Is(err, storage.ErrEmailTaken) {
+		t.Errorf("second run with the same seed: err = %v, want ErrEmailTaken", err)
+	}
+	if err := runSeed(ctx, s, seedOptions{count: 1, users: 0, projects: 1, days: 1}); err == nil {
+		t.Error("seed without users succeeded")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	apihttp "github.com/Upiter5/todo-app/internal/http"
+	"github.com/Upiter5/todo-app/internal/systemd"
+)
+
+func (c *cli) serveCmd() *cobra.Command {
+	var jobs bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP API (the default command)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := c.connect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			a, err := newApp(c.cfg, db)
+			if err != nil {
+				return err
+			}
+			defer a.close()
+
+			ctx, stop := context.WithCancel(context.Background())
+			defer stop()
+			for _, run := range a.live {
+				go run(ctx)
+			}
+			if jobs {
+				for _, run := range a.jobs {
+					go run(ctx)
+				}
+			}
+
+			// systemd: READY, когда порт открыт, и watchdog по /healthz
+			a.srv.App().Hooks().OnListen(func(fiber.ListenData) error {
+				if err := systemd.Notify(systemd.Ready); err != nil {
+					log.Error().Err(err).Msg("Failed to notify systemd")
+				}
+				return nil
+			})
+			if interval := systemd.WatchdogInterval(); interval > 0 {
+				go systemd.RunWatchdog(ctx, interval, healthCheck(c.cfg.Addr), log.Logger)
+			}
+
+			go reloadOnHangup(ctx, a.srv)
+
+			// Graceful Shutdown
+			go func() {
+				if err := a.srv.Listen(); err != nil {
+					log.Fatal().Err(err).Msg("Server error")
+				}
+			}()
+			waitForSignal()
+
+			log.Info().Msg("Shutting down server...")
+			_ = systemd.Notify(systemd.Stopping)
+			if err := a.srv.Shutdown(); err != nil {
+				log.Error().Err(err).Msg("Server shutdown error")
+			}
+			if a.limiter != nil {
+				a.limiter.Flush(context.Background())
+			}
+			log.Info().Msg("Server stopped")
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&jobs, "jobs", true,
+		"run background jobs (integration sync, webhook delivery) in this process; disable when a separate worker runs them")
+	return cmd
+}
+
+func (c *cli) workerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "worker",
+		Short: "Run background jobs without the HTTP API",
+		Long: "Runs GitHub, Jira and Google Calendar sync and outbound webhook delivery. " +
+			"Start API instances with `serve --jobs=false` so the jobs run only here.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := c.connect(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			a, err := newApp(c.cfg, db)
+			if err != nil {
+				return err
+			}
+			defer a.close()
+
+			ctx, stop := context.WithCancel(context.Background())
+			for _, run := range a.jobs {
+				go run(ctx)
+			}
+			if interval := systemd.WatchdogInterval(); interval > 0 {
+				go systemd.RunWatchdog(ctx, interval, nil, log.Logger)
+			}
+			_ = systemd.Notify(systemd.Ready)
+			log.Info().Int("jobs", len(a.jobs)).Msg("Worker started")
+			waitForSignal()
+			_ = systemd.Notify(systemd.Stopping)
+			stop()
+			log.Info().Msg("Worker stopped")
+			return nil
+		},
+	}
+}
+
+// healthCheck запрашивает /healthz через сетевой стек, как внешний клиент:
+// так видно и зависший цикл приёма соединений
+func healthCheck(addr string) func(context.Context) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = "", "8080"
+	}
+	switch host {
+	case "", "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	url := "http://" + net.JoinHostPort(host, port) + "/healthz"
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			return fmt.Errorf("health check: unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// reloadOnHangup перечитывает настройки по SIGHUP (systemctl reload).
+// Ошибка в файле настроек не роняет сервер: остаются прежние значения.
+func reloadOnHangup(ctx context.Context, srv *apihttp.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if _, err := srv.Reload(); err != nil {
+				log.Error().Err(err).Msg("Failed to reload configuration")
+			}
+		}
+	}
+}
+
+func waitForSignal() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/kafka"
+	"github.com/Upiter5/todo-app/internal/nats"
+)
+
+// newEventSink выбирает брокер по схеме адреса; логин и пароль берутся из
+// userinfo, у NATS одиночное имя без пароля - токен
+func newEventSink(raw string) (events.Sink, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	pass, hasPass := u.User.Password()
+	switch u.Scheme {
+	case "nats", "tls":
+		host := u.Host
+		if u.Port() == "" {
+			host += ":4222"
+		}
+		js := &nats.JetStream{Addr: host, User: u.User.Username(), Pass: pass}
+		if !hasPass {
+			js.User, js.Token = "", u.User.Username()
+		}
+		if u.Scheme == "tls" {
+			js.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		return js, nil
+	case "kafka+http", "kafka+https":
+		base := *u
+		base.Scheme = strings.TrimPrefix(u.Scheme, "kafka+")
+		base.User = nil
+		return &kafka.RESTProxy{BaseURL: base.String(), Username: u.User.Username(), Password: pass,
+			HTTP: &http.Client{Timeout: 15 * time.Second}}, nil
+	}
+	return nil, fmt.Errorf("unsupported event stream scheme %q", u.Scheme)
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+type claims struct {
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWT выпускает и проверяет короткоживущие access-токены (HS256)
+type JWT struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewJWT(secret string, ttl time.Duration) *JWT {
+	return &JWT{secret: []byte(secret), ttl: ttl, now: time.Now}
+}
+
+// Issue выпускает токен пользователя u в рамках сессии sessionID
+func (j *JWT) Issue(u model.User, sessionID int) (string, time.Time, error) {
+	now := j.now()
+	expiresAt := now.Add(j.ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Email:     u.Email,
+		Role:      u.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(u.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	signed, err := token.SignedString(j.secret)
+	return signed, expiresAt, err
+}
+
+func (j *JWT) Parse(token string) (*Principal, error) {
+	var cl claims
+	if err := j.parse(token, &cl); err != nil {
+		return nil, err
+	}
+	// Токены второго шага входа и magic-link не дают доступа к API
+	if len(cl.Audience) > 0 {
+		return nil, errors.New("token is not an access token")
+	}
+	id, err := strconv.Atoi(cl.Subject)
+	if err != nil {
+		return nil, errors.New("invalid subject")
+	}
+	return &Principal{UserID: id, Email: cl.Email, Role: cl.Role, Scheme: SchemeJWT, SessionID: cl.SessionID}, nil
+}
+
+const challengeAudience = "mfa"
+
+// IssueChallenge - токен между вводом пароля и второго фактора
+func (j *JWT) IssueChallenge(userID int, ttl time.Duration) (string, error) {
+	now := j.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(userID),
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	})
+	return token.SignedString(j.secret)
+}
+
+// ParseChallenge возвращает пользователя, прошедшего первый шаг входа
+func (j *JWT) ParseChallenge(token string) (int, error) {
+	var cl claims
+	if err := j.parse(token, &cl, jwt.WithAudience(challengeAudience)); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(cl.Subject)
+}
+
+const magicLinkAudience = "magic-link"
+
+// IssueMagicLink - подписанный одноразовый токен для входа по ссылке;
+// одноразовость обеспечивает вызывающий по возвращаемому jti
+func (j *JWT) IssueMagicLink(userID int, ttl time.Duration) (string, error) {
+	jti, err := NewToken("")
+	if err != nil {
+		return "", err
+	}
+	now := j.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        jti,
+		Subject:   strconv.Itoa(userID),
+		Audience:  jwt.ClaimStrings{magicLinkAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	})
+	return token.SignedString(j.secret)
+}
+
+// ParseMagicLink возвращает пользователя, jti и срок действия токена
+func (j *JWT) ParseMagicLink(token string) (userID int, jti string, expiresAt time.Time, err error) {
+	var cl claims
+	if err = j.parse(token, &cl, jwt.WithAudience(magicLinkAudience), jwt.WithExpirationRequired()); err != nil {
+		return 0, "", time.Time{}, err
+	}
+	if cl.ID == "" {
+		return 0, "", time.Time{}, errors.New("missing jti")
+	}
+	userID, err = strconv.Atoi(cl.Subject)
+	return userID, cl.ID, cl.ExpiresAt.Time, err
+}
+
+func (j *JWT) parse(token string, cl *claims, opts ...jwt.ParserOption) error {
+	opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithTimeFunc(j.now))
+	_, err := jwt.ParseWithClaims(token, cl, func(*jwt.Token) (any, error) {
+		return j.secret, nil
+	}, opts...)
+	return err
+}
+
+// Authenticate принимает заголовок "Authorization: Bearer <jwt>"
+func (j *JWT) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	header := c.Get(fiber.HeaderAuthorization)
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return nil, ErrNoCredentials
+	}
+	return j.Parse(token)
+}
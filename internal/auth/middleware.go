@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrNoCredentials - в запросе нет данных для этой схемы, пробуем следующую
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator - одна схема аутентификации
+type Authenticator interface {
+	Authenticate(c *fiber.Ctx) (*Principal, error)
+}
+
+const localsKey = "principal"
+
+// Middleware опрашивает схемы по порядку. Первая узнавшая запрос решает:
+// принятые данные дают Principal, отвергнутые - 401 без проверки остальных
+// (или готовый *fiber.Error схемы, например 429 от ограничения частоты).
+// Ключи, ограниченные областями, сюда не допускаются - см. ScopedMiddleware.
+func Middleware(authenticators ...Authenticator) fiber.Handler {
+	return middleware("", authenticators)
+}
+
+// ScopedMiddleware - Middleware, который пускает и ключи с областью scope
+func ScopedMiddleware(scope string, authenticators ...Authenticator) fiber.Handler {
+	return middleware(scope, authenticators)
+}
+
+func middleware(scope string, authenticators []Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, a := range authenticators {
+			p, err := a.Authenticate(c)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			var ferr *fiber.Error
+			if errors.As(err, &ferr) {
+				return ferr
+			}
+			if err != nil {
+				return fiber.NewError(fiber.StatusUnauthorized, "Invalid credentials")
+			}
+			if p.Restricted() && (scope == "" || !p.HasScope(scope)) {
+				return fiber.NewError(fiber.StatusForbidden, "API key scope does not allow this endpoint")
+			}
+			c.Locals(localsKey, p)
+			c.SetUserContext(WithPrincipal(c.UserContext(), p))
+			return c.Next()
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+}
+
+// Current - Principal запроса, прошедшего Middleware
+func Current(c *fiber.Ctx) *Principal {
+	p, _ := c.Locals(localsKey).(*Principal)
+	return p
+}
+
+// RequireRole пропускает только Principal с одной из ролей; ставится после Middleware
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := Current(c)
+		if p == nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+		}
+		for _, role := range roles {
+			if p.Role == role {
+				return c.Next()
+			}
+		}
+		return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
+	}
+}
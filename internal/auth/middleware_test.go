@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/testutil"
+)
+
+type fakeAuthenticator struct {
+	p   *auth.Principal
+	err error
+}
+
+func (f fakeAuthenticator) Authenticate(*fiber.Ctx) (*auth.Principal, error) { return f.p, f.err }
+
+func newApp(authenticators ...auth.Authenticator) *fiber.App {
+	app := fiber.New()
+	app.Get("/whoami", auth.Middleware(authenticators...), func(c *fiber.Ctx) error {
+		fromCtx, _ := auth.FromContext(c.UserContext())
+		if fromCtx != auth.Current(c) {
+			return errors.New("principal in context differs from locals")
+		}
+		return c.JSON(auth.Current(c))
+	})
+	return app
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	t.Parallel()
+	skip := fakeAuthenticator{err: auth.ErrNoCredentials}
+	reject := fakeAuthenticator{err: errors.New("revoked")}
+	alice := fakeAuthenticator{p: &auth.Principal{UserID: 1, Scheme: auth.SchemeAPIKey}}
+
+	tests := []struct {
+		name           string
+		authenticators []auth.Authenticator
+		wantStatus     int
+		wantBody       string
+	}{
+		{"no schemes", nil, fiber.StatusUnauthorized, ""},
+		{"no credentials", []auth.Authenticator{skip, skip}, fiber.StatusUnauthorized, ""},
+		{"falls through to matching scheme", []auth.Authenticator{skip, alice}, fiber.StatusOK, `{"user_id":1,"scheme":"api_key"}`},
+		{"rejected credentials stop the chain", []auth.Authenticator{reject, alice}, fiber.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := testutil.New(t, newApp(tt.authenticators...)).Get("/whoami").AssertStatus(tt.wantStatus)
+			if tt.wantBody != "" {
+				resp.AssertJSON(tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestJWT(t *testing.T) {
+	t.Parallel()
+	jwt := auth.NewJWT("secret", time.Minute)
+	token, _, err := jwt.Issue(model.User{ID: 7, Email: "a@example.com", Role: model.RoleUser}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := testutil.New(t, newApp(jwt))
+	h.WithToken(token).Get("/whoami").
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"user_id":7,"email":"a@example.com","scheme":"jwt"}`)
+
+	other, _, _ := auth.NewJWT("other-secret", time.Minute).Issue(model.User{ID: 7}, 0)
+	h.WithToken(other).Get("/whoami").AssertStatus(fiber.StatusUnauthorized)
+
+	expired, _, _ := auth.NewJWT("secret", -time.Minute).Issue(model.User{ID: 7}, 0)
+	h.WithToken(expired).Get("/whoami").AssertStatus(fiber.StatusUnauthorized)
+
+	challenge, _ := jwt.IssueChallenge(7, time.Minute)
+	h.WithToken(challenge).Get("/whoami").AssertStatus(fiber.StatusUnauthorized)
+	if id, err := jwt.ParseChallenge(challenge); err != nil || id != 7 {
+		t.Fatalf("ParseChallenge = %d, %v", id, err)
+	}
+	if _, err := jwt.ParseChallenge(token); err == nil {
+		t.Fatal("access token accepted as challenge")
+	}
+
+	link, _ := jwt.IssueMagicLink(7, time.Minute)
+	h.WithToken(link).Get("/whoami").AssertStatus(fiber.StatusUnauthorized)
+	if id, jti, _, err := jwt.ParseMagicLink(link); err != nil || id != 7 || jti == "" {
+		t.Fatalf("ParseMagicLink = %d, %q, %v", id, jti, err)
+	}
+	if _, _, _, err := jwt.ParseMagicLink(challenge); err == nil {
+		t.Fatal("challenge token accepted as magic link")
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	t.Parallel()
+	newAdminApp := func(p *auth.Principal) *fiber.App {
+		app := fiber.New()
+		app.Get("/admin", auth.Middleware(fakeAuthenticator{p: p}), auth.RequireRole(model.RoleAdmin),
+			func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+		return app
+	}
+
+	testutil.New(t, newAdminApp(&auth.Principal{UserID: 1, Role: model.RoleUser})).
+		Get("/admin").AssertStatus(fiber.StatusForbidden)
+	testutil.New(t, newAdminApp(&auth.Principal{UserID: 2, Role: model.RoleAdmin})).
+		Get("/admin").AssertStatus(fiber.StatusNoContent)
+}
+
+func TestScopedAPIKeys(t *testing.T) {
+	t.Parallel()
+	keys := map[string]*auth.Principal{
+		auth.HashToken("full"):   {UserID: 1},
+		auth.HashToken("widget"): {UserID: 1, Scopes: []string{auth.ScopeWidget}},
+	}
+	lookup := func(_ context.Context, hash string) (*auth.Principal, error) {
+		if p, ok := keys[hash]; ok {
+			cp := *p
+			return &cp, nil
+		}
+		return nil, errors.New("unknown key")
+	}
+
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) }
+	app.Get("/tasks", auth.Middleware(auth.NewAPIKey(lookup)), ok)
+	app.Get("/widget", auth.ScopedMiddleware(auth.ScopeWidget, auth.NewAPIKeyQuery(lookup, "token")), ok)
+	h := testutil.New(t, app)
+
+	h.WithHeader(auth.APIKeyHeader, "full").Get("/tasks").AssertStatus(fiber.StatusNoContent)
+	h.WithHeader(auth.APIKeyHeader, "widget").Get("/tasks").AssertStatus(fiber.StatusForbidden)
+	h.Get("/widget?token=widget").AssertStatus(fiber.StatusNoContent)
+	h.Get("/widget?token=full").AssertStatus(fiber.StatusNoContent)
+	h.Get("/widget?token=nope").AssertStatus(fiber.StatusUnauthorized)
+	h.Get("/tasks?token=full").AssertStatus(fiber.StatusUnauthorized)
+}
@@ -0,0 +1,86 @@
+// Package auth - аутентификация запросов и Principal текущего пользователя
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+const (
+	SchemeJWT     = "jwt"
+	SchemeAPIKey  = "api_key"
+	SchemeSession = "session"
+	SchemeSystem  = "system"
+	// SchemeShare - чтение по публичной ссылке от имени её автора
+	SchemeShare = "share"
+	// SchemeWebhook - входящий вебхук от имени его владельца
+	SchemeWebhook = "webhook"
+	// SchemeIntegration - синхронизация с внешним сервисом от имени владельца связи
+	SchemeIntegration = "integration"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal - от чьего имени выполняется запрос
+type Principal struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	Scheme string `json:"scheme"`
+	// SessionID - сессия, из которой выдан токен или cookie; 0 для API-ключей
+	SessionID int `json:"session_id,omitempty"`
+	// APIKeyID - ключ, которым вошли; 0 для остальных схем
+	APIKeyID int `json:"api_key_id,omitempty"`
+	// Scopes ограничивают API-ключ отдельными областями; пусто - полный доступ
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Области API-ключей
+const (
+	// ScopeWidget - только данные встраиваемого виджета
+	ScopeWidget = "widget"
+	// ScopeFeed - только ленты активности (RSS/Atom)
+	ScopeFeed = "feed"
+)
+
+// KnownScopes - области, которые можно выдать ключу
+var KnownScopes = []string{ScopeWidget, ScopeFeed}
+
+// Restricted - ключ ограничен областями и не годится для остального API
+func (p *Principal) Restricted() bool { return len(p.Scopes) > 0 }
+
+// HasScope - доступна ли область; у неограниченного Principal доступны все
+func (p *Principal) HasScope(scope string) bool {
+	return !p.Restricted() || slices.Contains(p.Scopes, scope)
+}
+
+// System - Principal фоновых задач и CLI: не ограничен владельцем
+var System = &Principal{Role: SchemeSystem, Scheme: SchemeSystem}
+
+func (p *Principal) IsSystem() bool { return p.Scheme == SchemeSystem }
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// OwnerID - владелец, которым ограничиваются запросы к данным;
+// nil для System. Без Principal доступ запрещён.
+func OwnerID(ctx context.Context) (*int, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	if p.IsSystem() {
+		return nil, nil
+	}
+	id := p.UserID
+	return &id, nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	APIKeyHeader      = "X-API-Key"
+	SessionCookieName = "session"
+)
+
+// NewToken - случайный непрозрачный токен с префиксом вида, например "tdk_"
+func NewToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken - в базе хранятся только хеши токенов
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// TokenLookup находит Principal по хешу токена
+type TokenLookup func(ctx context.Context, hash string) (*Principal, error)
+
+type apiKeyAuthenticator struct {
+	lookup TokenLookup
+	query  string
+}
+
+// NewAPIKey - схема "X-API-Key: <key>"
+func NewAPIKey(lookup TokenLookup) Authenticator {
+	return &apiKeyAuthenticator{lookup: lookup}
+}
+
+// NewAPIKeyQuery - ключ в параметре запроса param, для встраиваемых страниц,
+// которые не умеют слать заголовки. Ставить только перед ScopedMiddleware:
+// ключ в URL оседает в логах и истории браузера.
+func NewAPIKeyQuery(lookup TokenLookup, param string) Authenticator {
+	return &apiKeyAuthenticator{lookup: lookup, query: param}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	key := c.Get(APIKeyHeader)
+	if key == "" && a.query != "" {
+		key = c.Query(a.query)
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	p, err := a.lookup(c.UserContext(), HashToken(key))
+	if err != nil {
+		return nil, err
+	}
+	p.Scheme = SchemeAPIKey
+	return p, nil
+}
+
+type sessionAuthenticator struct {
+	lookup TokenLookup
+}
+
+// NewSession - схема с cookie сессии, выданной при входе
+func NewSession(lookup TokenLookup) Authenticator {
+	return &sessionAuthenticator{lookup: lookup}
+}
+
+func (a *sessionAuthenticator) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	token := c.Cookies(SessionCookieName)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	p, err := a.lookup(c.UserContext(), HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	p.Scheme = SchemeSession
+	return p, nil
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Тестовые векторы RFC 6238 (SHA1), последние 6 цифр
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTPWindow(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / totpPeriod
+
+	prev, _ := TOTPCode(secret, step-1)
+	if got, ok := VerifyTOTP(secret, prev, now); !ok || got != step-1 {
+		t.Fatalf("previous step code rejected: step=%d ok=%v", got, ok)
+	}
+	old, _ := TOTPCode(secret, step-2)
+	if _, ok := VerifyTOTP(secret, old, now); ok {
+		t.Fatal("code two steps old accepted")
+	}
+}
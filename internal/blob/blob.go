@@ -0,0 +1,83 @@
+// Package blob - хранение файлов (аватары и т.п.) в файловой системе или S3
+package blob
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get возвращает содержимое и Content-Type
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Filesystem хранит объекты файлами в Dir; Content-Type - в соседнем файле
+type Filesystem struct {
+	Dir string
+}
+
+func (f Filesystem) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if strings.Contains(key, "..") || clean == "/" {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(f.Dir, filepath.FromSlash(clean)), nil
+}
+
+func (f Filesystem) Put(_ context.Context, key, contentType string, data []byte) error {
+	p, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p+".type", []byte(contentType), 0o644); err != nil {
+		return err
+	}
+	// Запись через временный файл: читатель не увидит половину объекта
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (f Filesystem) Get(_ context.Context, key string) ([]byte, string, error) {
+	p, err := f.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	contentType, err := os.ReadFile(p + ".type")
+	if err != nil {
+		contentType = []byte("application/octet-stream")
+	}
+	return data, string(contentType), nil
+}
+
+func (f Filesystem) Delete(_ context.Context, key string) error {
+	p, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{p, p + ".type"} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
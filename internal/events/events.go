@@ -0,0 +1,63 @@
+// Package events - доменные события задач и их публикация
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+const (
+	TaskCreated   = "task.created"
+	TaskUpdated   = "task.updated"
+	TaskCompleted = "task.completed"
+	TaskDeleted   = "task.deleted"
+)
+
+type Event struct {
+	Type       string      `json:"type"`
+	TaskID     int         `json:"task_id"`
+	Task       *model.Task `json:"task,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// Publisher доставляет события подписчикам. Ошибки доставки - забота
+// реализации: публикация не должна ломать уже выполненную операцию.
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
+
+// Nop молча отбрасывает события
+type Nop struct{}
+
+func (Nop) Publish(context.Context, Event) {}
+
+// Fanout публикует событие в каждый из публикаторов по порядку
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, e Event) {
+	for _, p := range f {
+		p.Publish(ctx, e)
+	}
+}
+
+// Except публикует во все публикаторы f, кроме skip. Список читается при
+// каждой публикации, поэтому видны и добавленные позже: так интеграция
+// получает общий список, в который потом попадает сама.
+func (f *Fanout) Except(skip Publisher) Publisher {
+	return except{fanout: f, skip: skip}
+}
+
+type except struct {
+	fanout *Fanout
+	skip   Publisher
+}
+
+func (e except) Publish(ctx context.Context, ev Event) {
+	for _, p := range *e.fanout {
+		if p != e.skip {
+			p.Publish(ctx, ev)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// SchemaVersion - версия формата Envelope. Поля только добавляются;
+// переименование или удаление поля - новая версия.
+const SchemaVersion = 1
+
+// Envelope - событие в потоке для внешних потребителей
+type Envelope struct {
+	SchemaVersion int `json:"schema_version"`
+	// ID уникален для события: потребители и JetStream отбрасывают повторы
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	TaskID     int         `json:"task_id"`
+	Task       *model.Task `json:"task,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+func NewEnvelope(e Event) Envelope {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return Envelope{
+		SchemaVersion: SchemaVersion,
+		ID:            hex.EncodeToString(b),
+		Type:          e.Type,
+		TaskID:        e.TaskID,
+		Task:          e.Task,
+		OccurredAt:    e.OccurredAt,
+	}
+}
+
+// Sink - брокер потока (NATS JetStream, Kafka). key - ключ партиционирования,
+// id - ключ дедупликации.
+type Sink interface {
+	Send(ctx context.Context, subject, key, id string, payload []byte) error
+}
+
+const streamQueueSize = 1024
+
+// Stream публикует события задач в Sink в порядке возникновения; тема -
+// prefix и тип события: todo.task.completed
+type Stream struct {
+	sink   Sink
+	prefix string
+	log    zerolog.Logger
+	queue  chan Envelope
+}
+
+func NewStream(sink Sink, prefix string, logger zerolog.Logger) *Stream {
+	return &Stream{sink: sink, prefix: strings.Trim(prefix, "."), log: logger, queue: make(chan Envelope, streamQueueSize)}
+}
+
+// Publish не ждёт брокера: при переполненной очереди событие теряется
+func (s *Stream) Publish(_ context.Context, e Event) {
+	select {
+	case s.queue <- NewEnvelope(e):
+	default:
+		s.log.Warn().Str("type", e.Type).Int("task_id", e.TaskID).Msg("Event stream queue is full, event dropped")
+	}
+}
+
+// Run отправляет события, повторяя неудачные с паузой, пока ctx не отменён
+func (s *Stream) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case env := <-s.queue:
+			s.send(ctx, env)
+		}
+	}
+}
+
+func (s *Stream) send(ctx context.Context, env Envelope) {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return
+	}
+	subject := s.prefix + "." + env.Type
+	for delay := time.Second; ; delay = min(2*delay, time.Minute) {
+		err := s.sink.Send(ctx, subject, strconv.Itoa(env.TaskID), env.ID, payload)
+		if err == nil {
+			return
+		}
+		s.log.Error().Err(err).Str("subject", subject).Int("task_id", env.TaskID).Msg("Failed to stream event, retrying")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
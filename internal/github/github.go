@@ -0,0 +1,206 @@
+// Package github - минимальный клиент GitHub REST API для синхронизации issues
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultBaseURL = "https://api.github.com"
+
+// Client ходит в API с токеном пользователя (personal access token)
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+type User struct {
+	Login string `json:"login"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type Issue struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	State     string    `json:"state"`
+	HTMLURL   string    `json:"html_url"`
+	Assignees []User    `json:"assignees"`
+	Labels    []Label   `json:"labels"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// PullRequest не пуст у pull request: API отдаёт их вместе с issues
+	PullRequest *struct{} `json:"pull_request,omitempty"`
+}
+
+// AssignedTo - назначен ли issue пользователю login
+func (i Issue) AssignedTo(login string) bool {
+	for _, a := range i.Assignees {
+		if strings.EqualFold(a.Login, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// Состояния issue
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+)
+
+// StatusError - ответ API с кодом не 2xx
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("github: %d %s", e.Code, e.Message)
+}
+
+// CurrentUser - владелец токена
+func (c *Client) CurrentUser(ctx context.Context) (User, error) {
+	var u User
+	err := c.do(ctx, http.MethodGet, "/user", nil, &u)
+	return u, err
+}
+
+// AssignedIssues - issues репозитория (owner/name), назначенные login и
+// изменённые после since, включая закрытые; pull request пропускаются
+func (c *Client) AssignedIssues(ctx context.Context, repo, login string, since time.Time) ([]Issue, error) {
+	q := url.Values{"assignee": {login}, "state": {"all"}, "per_page": {"100"}}
+	if !since.IsZero() {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+
+	var all []Issue
+	for page := 1; ; page++ {
+		q.Set("page", fmt.Sprint(page))
+		var issues []Issue
+		if err := c.do(ctx, http.MethodGet, "/repos/"+repo+"/issues?"+q.Encode(), nil, &issues); err != nil {
+			return nil, err
+		}
+		for _, i := range issues {
+			if i.PullRequest == nil {
+				all = append(all, i)
+			}
+		}
+		if len(issues) < 100 {
+			return all, nil
+		}
+	}
+}
+
+func (c *Client) Issue(ctx context.Context, repo string, number int) (Issue, error) {
+	var i Issue
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d", repo, number), nil, &i)
+	return i, err
+}
+
+// SetIssueState открывает или закрывает issue
+func (c *Client) SetIssueState(ctx context.Context, repo string, number int, state string) error {
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repo, number),
+		map[string]string{"state": state}, nil)
+}
+
+func (c *Client) AddLabel(ctx context.Context, repo string, number int, label string) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/labels", repo, number),
+		map[string][]string{"labels": {label}}, nil)
+}
+
+// RemoveLabel снимает метку; отсутствие метки не ошибка
+func (c *Client) RemoveLabel(ctx context.Context, repo string, number int, label string) error {
+	err := c.do(ctx, http.MethodDelete,
+		fmt.Sprintf("/repos/%s/issues/%d/labels/%s", repo, number, url.PathEscape(label)), nil, nil)
+	var e *StatusError
+	if errors.As(err, &e) && e.Code == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&e)
+		return &StatusError{Code: resp.StatusCode, Message: e.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// VerifySignature проверяет заголовок X-Hub-Signature-256 вебхука
+func VerifySignature(secret string, body []byte, signature string) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// IssuesEvent - тело вебхука с событием "issues"
+type IssuesEvent struct {
+	Action     string `json:"action"`
+	Issue      Issue  `json:"issue"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
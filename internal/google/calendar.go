@@ -0,0 +1,174 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const calendarURL = "https://www.googleapis.com/calendar/v3"
+
+// ErrSyncTokenExpired - Google отверг sync token (410): нужна полная синхронизация
+var ErrSyncTokenExpired = errors.New("google: sync token expired")
+
+// Calendar - клиент Calendar API с access-токеном пользователя
+type Calendar struct {
+	// BaseURL переопределяется в тестах
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// EventTime - либо DateTime (RFC 3339), либо Date целого дня
+type EventTime struct {
+	DateTime string `json:"dateTime,omitempty"`
+	Date     string `json:"date,omitempty"`
+}
+
+func NewEventTime(t time.Time) EventTime {
+	return EventTime{DateTime: t.Format(time.RFC3339)}
+}
+
+// Time - начало слота; день без времени - полночь UTC
+func (t EventTime) Time() (time.Time, error) {
+	if t.DateTime != "" {
+		return time.Parse(time.RFC3339, t.DateTime)
+	}
+	return time.Parse(time.DateOnly, t.Date)
+}
+
+type Event struct {
+	ID                 string              `json:"id,omitempty"`
+	Status             string              `json:"status,omitempty"`
+	Summary            string              `json:"summary,omitempty"`
+	Description        string              `json:"description,omitempty"`
+	Start              *EventTime          `json:"start,omitempty"`
+	End                *EventTime          `json:"end,omitempty"`
+	ExtendedProperties *ExtendedProperties `json:"extendedProperties,omitempty"`
+}
+
+type ExtendedProperties struct {
+	Private map[string]string `json:"private,omitempty"`
+}
+
+// StatusCancelled - удалённое событие в инкрементальной выдаче
+const StatusCancelled = "cancelled"
+
+// CreateCalendar создаёт отдельный календарь и возвращает его id
+func (c *Calendar) CreateCalendar(ctx context.Context, summary string) (string, error) {
+	var cal struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/calendars", map[string]string{"summary": summary}, &cal)
+	return cal.ID, err
+}
+
+func (c *Calendar) GetEvent(ctx context.Context, calendarID, eventID string) (Event, error) {
+	var e Event
+	err := c.do(ctx, http.MethodGet, eventPath(calendarID, eventID), nil, &e)
+	return e, err
+}
+
+func (c *Calendar) InsertEvent(ctx context.Context, calendarID string, e Event) (Event, error) {
+	var out Event
+	err := c.do(ctx, http.MethodPost, "/calendars/"+url.PathEscape(calendarID)+"/events", e, &out)
+	return out, err
+}
+
+func (c *Calendar) UpdateEvent(ctx context.Context, calendarID string, e Event) (Event, error) {
+	var out Event
+	err := c.do(ctx, http.MethodPut, eventPath(calendarID, e.ID), e, &out)
+	return out, err
+}
+
+// DeleteEvent: уже удалённое событие (404, 410) - не ошибка
+func (c *Calendar) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	err := c.do(ctx, http.MethodDelete, eventPath(calendarID, eventID), nil, nil)
+	if IsGone(err) {
+		return nil
+	}
+	return err
+}
+
+// ListEvents возвращает изменения после syncToken (все события, если он пуст)
+// и токен для следующего вызова
+func (c *Calendar) ListEvents(ctx context.Context, calendarID, syncToken string) ([]Event, string, error) {
+	q := url.Values{"maxResults": {"250"}, "singleEvents": {"true"}}
+	if syncToken != "" {
+		q.Set("syncToken", syncToken)
+	}
+
+	var all []Event
+	for {
+		var page struct {
+			Items         []Event `json:"items"`
+			NextPageToken string  `json:"nextPageToken"`
+			NextSyncToken string  `json:"nextSyncToken"`
+		}
+		err := c.do(ctx, http.MethodGet, "/calendars/"+url.PathEscape(calendarID)+"/events?"+q.Encode(), nil, &page)
+		var se *StatusError
+		if errors.As(err, &se) && se.Code == http.StatusGone {
+			return nil, "", ErrSyncTokenExpired
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		all = append(all, page.Items...)
+		if page.NextPageToken == "" {
+			return all, page.NextSyncToken, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// IsGone - событие или календарь удалены
+func IsGone(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && (se.Code == http.StatusNotFound || se.Code == http.StatusGone)
+}
+
+func eventPath(calendarID, eventID string) string {
+	return "/calendars/" + url.PathEscape(calendarID) + "/events/" + url.PathEscape(eventID)
+}
+
+func (c *Calendar) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = calendarURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient(c.HTTP).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
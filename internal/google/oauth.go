@@ -0,0 +1,128 @@
+// Package google - OAuth 2.0 и Calendar API v3 без клиентских библиотек Google
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	authURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	tokenURL = "https://oauth2.googleapis.com/token"
+
+	// CalendarScope - доступ к календарям пользователя
+	CalendarScope = "https://www.googleapis.com/auth/calendar"
+)
+
+// OAuth - веб-клиент OAuth: код авторизации в обмен на access и refresh токены
+type OAuth struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// TokenURL переопределяется в тестах
+	TokenURL string
+	HTTP     *http.Client
+}
+
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// AuthCodeURL - адрес согласия; offline и prompt=consent нужны, чтобы Google
+// выдал refresh-токен и при повторном подключении
+func (o *OAuth) AuthCodeURL(state string) string {
+	q := url.Values{
+		"client_id":     {o.ClientID},
+		"redirect_uri":  {o.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {CalendarScope},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+		"state":         {state},
+	}
+	return authURL + "?" + q.Encode()
+}
+
+func (o *OAuth) Exchange(ctx context.Context, code string) (Token, error) {
+	return o.token(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {o.RedirectURL},
+	})
+}
+
+// Refresh выдаёт новый access-токен; refresh-токен Google обычно не меняет
+func (o *OAuth) Refresh(ctx context.Context, refreshToken string) (Token, error) {
+	t, err := o.token(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}})
+	if err == nil && t.RefreshToken == "" {
+		t.RefreshToken = refreshToken
+	}
+	return t, err
+}
+
+func (o *OAuth) token(ctx context.Context, form url.Values) (Token, error) {
+	form.Set("client_id", o.ClientID)
+	form.Set("client_secret", o.ClientSecret)
+	endpoint := o.TokenURL
+	if endpoint == "" {
+		endpoint = tokenURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient(o.HTTP).Do(req)
+	if err != nil {
+		return Token{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, statusError(resp)
+	}
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Token{}, err
+	}
+	return Token{
+		AccessToken:  body.AccessToken,
+		RefreshToken: body.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// StatusError - ответ Google с кодом не 2xx
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("google: %d %s", e.Code, e.Body)
+}
+
+func statusError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+}
+
+func httpClient(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportArchive отдаёт ZIP пространства ?workspace_id=N: workspace.json,
+// members.json и аватары участников в avatars/ - вложений у задач нет, и
+// файлы архива только они. Большие пространства и ?async=true собираются в
+// фоне: 202 и задание, чей статус по Location.
+func (s *Server) exportArchive(c *fiber.Ctx) error {
+	id := c.QueryInt("workspace_id")
+	if id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	write, job, err := s.workspaces.Archive(c.UserContext(), id, c.QueryBool("async"))
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to export workspace")
+	}
+	if job != nil {
+		c.Location(version(c).prefix + "/export/archive/jobs/" + job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="workspace-%d.zip"`, id))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// заголовки уже ушли - ошибку остаётся только записать в лог
+		if err := write(w); err != nil {
+			s.log.Error().Err(err).Int("workspace_id", id).Msg("Failed to stream workspace archive")
+			return
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+func (s *Server) getArchiveJob(c *fiber.Ctx) error {
+	job, err := s.workspaces.ArchiveJob(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return s.notFoundError(c, err, "Archive not found", "Failed to fetch archive")
+	}
+	return c.JSON(job)
+}
+
+func (s *Server) downloadArchive(c *fiber.Ctx) error {
+	f, job, err := s.workspaces.OpenArchive(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return s.notFoundError(c, err, "Archive not found", "Failed to fetch archive")
+	}
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="workspace-%d.zip"`, job.WorkspaceID))
+	// fasthttp закроет файл, когда допишет тело
+	return c.SendStream(f, int(job.Size))
+}
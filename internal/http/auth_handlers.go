@@ -0,0 +1,265 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+func (s *Server) register(c *fiber.Ctx) error {
+	var cred service.Credentials
+	if err := parseBody(c, &cred); err != nil {
+		return err
+	}
+
+	user, err := s.users.Register(c.UserContext(), cred)
+	if errors.Is(err, storage.ErrEmailTaken) {
+		return fiber.NewError(fiber.StatusConflict, "Email already registered")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to register user")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(user)
+}
+
+func (s *Server) login(c *fiber.Ctx) error {
+	var cred service.Credentials
+	if err := parseBody(c, &cred); err != nil {
+		return err
+	}
+
+	login, err := s.users.Login(c.UserContext(), cred, c.Get(fiber.HeaderUserAgent))
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to log in")
+	}
+
+	if !login.MFARequired {
+		s.setSessionCookie(c, login.SessionToken, login.SessionExpiresAt)
+	}
+	return c.JSON(login)
+}
+
+func (s *Server) loginSecondFactor(c *fiber.Ctx) error {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	login, err := s.users.CompleteLogin(c.UserContext(), req.MFAToken, req.Code, c.Get(fiber.HeaderUserAgent))
+	if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrInvalidCode) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid verification code")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to log in")
+	}
+
+	s.setSessionCookie(c, login.SessionToken, login.SessionExpiresAt)
+	return c.JSON(login)
+}
+
+func (s *Server) requestMagicLink(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if err := s.users.RequestMagicLink(c.UserContext(), req.Email); err != nil {
+		return s.serviceError(c, err, "Failed to send magic link")
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (s *Server) verifyMagicLink(c *fiber.Ctx) error {
+	login, err := s.users.VerifyMagicLink(c.UserContext(), c.Query("token"), c.Get(fiber.HeaderUserAgent))
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Link is invalid or expired")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to log in")
+	}
+
+	if !login.MFARequired {
+		s.setSessionCookie(c, login.SessionToken, login.SessionExpiresAt)
+	}
+	return c.JSON(login)
+}
+
+func (s *Server) refresh(c *fiber.Ctx) error {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	login, err := s.users.Refresh(c.UserContext(), req.RefreshToken)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid refresh token")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to refresh token")
+	}
+
+	return c.JSON(login)
+}
+
+func (s *Server) logout(c *fiber.Ctx) error {
+	if err := s.users.Logout(c.UserContext(), auth.Current(c)); err != nil {
+		return s.serviceError(c, err, "Failed to log out")
+	}
+
+	s.setSessionCookie(c, "", time.Unix(0, 0))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) listSessions(c *fiber.Ctx) error {
+	sessions, err := s.users.ListSessions(c.UserContext(), auth.Current(c))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch sessions")
+	}
+
+	return c.JSON(sessions)
+}
+
+func (s *Server) revokeSession(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid session id")
+	}
+
+	err = s.users.RevokeSession(c.UserContext(), auth.Current(c).UserID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "Session not found")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to revoke session")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) setSessionCookie(c *fiber.Ctx, token string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     auth.SessionCookieName,
+		Value:    token,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   s.cfg.SecureCookies,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func (s *Server) createAPIKey(c *fiber.Ctx) error {
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	key, secret, err := s.users.CreateAPIKey(c.UserContext(), auth.Current(c).UserID, req.Name, req.Scopes)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to create API key")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"api_key": key, "key": secret})
+}
+
+func (s *Server) listAPIKeys(c *fiber.Ctx) error {
+	keys, err := s.users.ListAPIKeys(c.UserContext(), auth.Current(c).UserID)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch API keys")
+	}
+
+	return c.JSON(keys)
+}
+
+func (s *Server) deleteAPIKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key id")
+	}
+
+	err = s.users.DeleteAPIKey(c.UserContext(), auth.Current(c).UserID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "API key not found")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to delete API key")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type codeRequest struct {
+	Code string `json:"code"`
+}
+
+func (s *Server) enrollTOTP(c *fiber.Ctx) error {
+	enrollment, err := s.users.EnrollTOTP(c.UserContext(), auth.Current(c).UserID)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to enroll two-factor authentication")
+	}
+
+	return c.JSON(enrollment)
+}
+
+func (s *Server) enableTOTP(c *fiber.Ctx) error {
+	var req codeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	codes, err := s.users.EnableTOTP(c.UserContext(), auth.Current(c).UserID, req.Code)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to enable two-factor authentication")
+	}
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+func (s *Server) disableTOTP(c *fiber.Ctx) error {
+	var req codeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if err := s.users.DisableTOTP(c.UserContext(), auth.Current(c).UserID, req.Code); err != nil {
+		return s.serviceError(c, err, "Failed to disable two-factor authentication")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) resetTOTP(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	err = s.users.ResetTOTP(c.UserContext(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to reset two-factor authentication")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
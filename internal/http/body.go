@@ -0,0 +1,207 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/markdown"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+// parseBody разбирает тело запроса в out. JSON разбирается строго: неизвестное
+// поле, значение не того типа или лишние данные после объекта - 400 с точным
+// указанием, что не так, чтобы опечатка вроде "tittle" не терялась молча.
+// MessagePack перекодируется в JSON и разбирается так же; JSON:API и
+// Protocol Buffers годятся только для задач. Формы и XML, как и раньше, разбирает BodyParser.
+func parseBody(c *fiber.Ctx, out any) error {
+	body := c.Body()
+	switch mediaType(c.Get(fiber.HeaderContentType)) {
+	case fiber.MIMEApplicationJSON:
+	case wire.MIMEMsgpack, "application/x-msgpack":
+		data, err := wire.MsgpackToJSON(body)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Malformed MessagePack body")
+		}
+		body = data
+	case MIMEJSONAPI:
+		if _, ok := out.(*model.Task); !ok {
+			return fiber.NewError(fiber.StatusUnsupportedMediaType, "JSON:API body is not supported here")
+		}
+		data, err := jsonAPIToTask(body)
+		if err != nil {
+			return err
+		}
+		body = data
+	case wire.MIMEProtobuf, "application/x-protobuf":
+		task, ok := out.(*model.Task)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnsupportedMediaType, "Protocol Buffers body is not supported here")
+		}
+		if err := wire.UnmarshalTask(body, task); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Malformed Protocol Buffers body")
+		}
+		return nil
+	default:
+		if err := c.BodyParser(out); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(dropResponseFields(body)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, bodyError(err))
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return fiber.NewError(fiber.StatusBadRequest, "Request body must contain a single JSON value")
+	}
+	return nil
+}
+
+// responseFields - поля, которые сервер дописывает к объекту в ответе: ссылки
+// задачи и предупреждения об устаревшем. Клиент вправе отправить полученный
+// объект обратно как есть, поэтому на входе они не ошибка: их просто отбрасываем.
+var responseFields = []string{"_links", "_deprecations"}
+
+// dropResponseFields убирает responseFields из JSON-объекта верхнего уровня.
+// Всё, что не разбирается как объект, возвращает как было: ошибку покажет
+// строгий разбор.
+func dropResponseFields(body []byte) []byte {
+	if !bytes.Contains(body, []byte(`"_`)) {
+		return body
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(body, &obj) != nil {
+		return body
+	}
+	n := len(obj)
+	for _, name := range responseFields {
+		delete(obj, name)
+	}
+	if len(obj) == n {
+		return body
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func bodyError(err error) string {
+	var (
+		syntax   *json.SyntaxError
+		mismatch *json.UnmarshalTypeError
+		parse    *time.ParseError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return "Request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "Malformed JSON: unexpected end of body"
+	case errors.As(err, &syntax):
+		return fmt.Sprintf("Malformed JSON at offset %d", syntax.Offset)
+	case errors.As(err, &mismatch):
+		if mismatch.Field == "" {
+			return fmt.Sprintf("Request body must be %s, got %s", jsonType(mismatch.Type), mismatch.Value)
+		}
+		return fmt.Sprintf("Field %q must be %s, got %s", mismatch.Field, jsonType(mismatch.Type), mismatch.Value)
+	case errors.As(err, &parse):
+		return fmt.Sprintf("Invalid time %q, expected RFC 3339", parse.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return "Unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	}
+	return "Invalid request body"
+}
+
+// jsonType - тип значения, которого ждёт поле, в терминах JSON
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC 3339 time string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// renderDescriptions заполняет DescriptionHTML задач при ?render=html и
+// очищает его в остальных ответах, чтобы присланное клиентом не вернулось
+func renderDescriptions(c *fiber.Ctx, v any) any {
+	render := func(t model.Task) model.Task {
+		t.DescriptionHTML = ""
+		if c.Query("render") == "html" {
+			t.DescriptionHTML = markdown.Render(t.Description)
+		}
+		return t
+	}
+	switch v := v.(type) {
+	case model.Task:
+		return render(v)
+	case []model.Task:
+		if v == nil {
+			return v
+		}
+		out := make([]model.Task, len(v))
+		for i, t := range v {
+			out[i] = render(t)
+		}
+		return out
+	}
+	return v
+}
+
+// mediaType - тип без параметров вроде charset
+func mediaType(contentType string) string {
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// respond отдаёт v в представлении из Accept: JSON, JSON:API, MessagePack или
+// Protocol Buffers. Без Accept или с неизвестным типом - JSON, как раньше.
+func (s *Server) respond(c *fiber.Ctx, v any) error {
+	c.Vary(fiber.HeaderAccept)
+	v = renderDescriptions(c, v)
+	switch c.Accepts(fiber.MIMEApplicationJSON, MIMEJSONAPI, wire.MIMEMsgpack, wire.MIMEProtobuf) {
+	case MIMEJSONAPI:
+		return s.respondJSONAPI(c, v)
+	case wire.MIMEMsgpack:
+		data, err := wire.MarshalMsgpack(s.present(c, v))
+		if err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, wire.MIMEMsgpack)
+		return c.Send(data)
+	case wire.MIMEProtobuf:
+		data, ok := wire.MarshalProto(v)
+		if !ok {
+			return fiber.NewError(fiber.StatusNotAcceptable, "Protocol Buffers are not available for this resource")
+		}
+		c.Set(fiber.HeaderContentType, wire.MIMEProtobuf)
+		return c.Send(data)
+	}
+	return c.JSON(s.present(c, v))
+}
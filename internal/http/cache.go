@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// setTaskCaching разрешает браузеру хранить ответ с задачами, но каждый раз
+// сверяться с сервером; общим кешам он не достаётся - данные зависят от
+// пользователя. Нулевой modified - пустой список, Last-Modified не ставится.
+func setTaskCaching(c *fiber.Ctx, modified time.Time) {
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Vary(fiber.HeaderAuthorization, fiber.HeaderCookie, "X-API-Key", fiber.HeaderAccept)
+	if !modified.IsZero() {
+		c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified ставит заголовки кеширования и сообщает, что у клиента уже
+// актуальная копия (If-Modified-Since): тогда отвечаем 304 без тела.
+// Last-Modified - с точностью до секунды, поэтому сравниваем по секундам.
+func notModified(c *fiber.Ctx, modified time.Time) bool {
+	setTaskCaching(c, modified)
+	ims := c.Get(fiber.HeaderIfModifiedSince)
+	if ims == "" || modified.IsZero() || c.Get(fiber.HeaderIfNoneMatch) != "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// lastModified - самое позднее updated_at в списке
+func lastModified(tasks []model.Task) time.Time {
+	var latest time.Time
+	for _, t := range tasks {
+		if t.UpdatedAt.After(latest) {
+			latest = t.UpdatedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) getCalendar(c *fiber.Ctx) error {
+	link, err := s.calendar.Status(c.UserContext())
+	if err != nil {
+		return s.notFoundError(c, err, "Google Calendar is not connected", "Failed to fetch calendar")
+	}
+	return c.JSON(link)
+}
+
+// connectCalendar отдаёт адрес согласия Google; клиент открывает его сам
+func (s *Server) connectCalendar(c *fiber.Ctx) error {
+	url, err := s.calendar.ConnectURL(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to start Google authorization")
+	}
+	return c.JSON(fiber.Map{"url": url})
+}
+
+func (s *Server) updateCalendar(c *fiber.Ctx) error {
+	var req struct {
+		PullEvents bool `json:"pull_events"`
+	}
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	link, err := s.calendar.SetPullEvents(c.UserContext(), req.PullEvents)
+	if err != nil {
+		return s.notFoundError(c, err, "Google Calendar is not connected", "Failed to update calendar")
+	}
+	return c.JSON(link)
+}
+
+func (s *Server) disconnectCalendar(c *fiber.Ctx) error {
+	if err := s.calendar.Disconnect(c.UserContext()); err != nil {
+		return s.notFoundError(c, err, "Google Calendar is not connected", "Failed to disconnect calendar")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// calendarCallback - редирект от Google после согласия пользователя
+func (s *Server) calendarCallback(c *fiber.Ctx) error {
+	if reason := c.Query("error"); reason != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Google authorization failed: "+reason)
+	}
+	if err := s.calendar.Callback(c.UserContext(), c.Query("state"), c.Query("code")); err != nil {
+		return s.serviceError(c, err, "Failed to connect Google Calendar")
+	}
+	return c.SendString("Google Calendar connected. You can close this tab.")
+}
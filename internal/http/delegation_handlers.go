@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+type rejectRequest struct {
+	Comment string `json:"comment"`
+}
+
+// delegateTask - POST /tasks/:id/delegation: поручить задачу редактору проекта
+func (s *Server) delegateTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+	var req service.DelegateRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	d, err := s.tasks.Delegate(c.UserContext(), id, req)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to delegate task")
+	}
+	return c.Status(fiber.StatusCreated).JSON(d)
+}
+
+func (s *Server) getTaskDelegation(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	d, err := s.tasks.Delegation(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Task is not delegated", "Failed to fetch delegation")
+	}
+	return c.JSON(d)
+}
+
+// cancelDelegation - DELETE /tasks/:id/delegation: забрать задачу обратно
+func (s *Server) cancelDelegation(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tasks.CancelDelegation(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "Task is not delegated", "Failed to cancel delegation")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// submitDelegation - POST /tasks/:id/delegation/submit: сдать работу
+func (s *Server) submitDelegation(c *fiber.Ctx) error {
+	return s.delegationStep(c, "Failed to submit task for review", s.tasks.SubmitForReview)
+}
+
+func (s *Server) approveDelegation(c *fiber.Ctx) error {
+	return s.delegationStep(c, "Failed to approve task", s.tasks.Approve)
+}
+
+func (s *Server) rejectDelegation(c *fiber.Ctx) error {
+	var req rejectRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	return s.delegationStep(c, "Failed to reject task", func(ctx context.Context, id int) (model.Delegation, error) {
+		return s.tasks.Reject(ctx, id, req.Comment)
+	})
+}
+
+func (s *Server) delegationStep(c *fiber.Ctx, msg string, step func(context.Context, int) (model.Delegation, error)) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	d, err := step(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Task is not delegated", msg)
+	}
+	return c.JSON(d)
+}
+
+// listDelegations - GET /delegations: поручения от пользователя и ему
+func (s *Server) listDelegations(c *fiber.Ctx) error {
+	list, err := s.tasks.Delegations(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch delegations")
+	}
+	return c.JSON(list)
+}
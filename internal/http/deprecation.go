@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// deprecation - устаревшая часть API: эндпоинт или поле
+type deprecation struct {
+	// Since - с какого момента устарело, заголовок Deprecation (RFC 9745)
+	Since time.Time
+	// Sunset - когда перестанет работать (RFC 8594); нулевое - срок не назначен
+	Sunset time.Time
+	// Successor - адрес замены, уходит в Link с rel="successor-version"
+	Successor string
+	Message   string
+}
+
+// deprecatedUsage - сколько раз клиенты обращались к устаревшему, чтобы
+// убирать его по данным, а не наугад. Счёт у каждого экземпляра свой.
+type deprecatedUsage struct {
+	Name       string     `json:"name"`
+	Since      time.Time  `json:"since"`
+	Sunset     *time.Time `json:"sunset"`
+	Count      int64      `json:"count"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+}
+
+type deprecations struct {
+	mu    sync.Mutex
+	usage map[string]*deprecatedUsage
+}
+
+func (d *deprecations) count(name string, dep deprecation, at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.usage == nil {
+		d.usage = map[string]*deprecatedUsage{}
+	}
+	u := d.usage[name]
+	if u == nil {
+		u = &deprecatedUsage{Name: name, Since: dep.Since}
+		if !dep.Sunset.IsZero() {
+			u.Sunset = &dep.Sunset
+		}
+		d.usage[name] = u
+	}
+	u.Count++
+	u.LastUsedAt = at
+}
+
+// report - использование устаревшего, самое востребованное первым
+func (d *deprecations) report() []deprecatedUsage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]deprecatedUsage, 0, len(d.usage))
+	for _, u := range d.usage {
+		out = append(out, *u)
+	}
+	slices.SortFunc(out, func(a, b deprecatedUsage) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Name, b.Name))
+	})
+	return out
+}
+
+const deprecationWarningsKey = "deprecation_warnings"
+
+// deprecate отмечает, что запрос задел устаревшее name: ставит заголовки,
+// считает обращение и добавляет предупреждение в JSON-объект ответа. Годится
+// и для полей: обработчик вызывает его, если в теле пришло устаревшее поле.
+func (s *Server) deprecate(c *fiber.Ctx, name string, dep deprecation) {
+	c.Set("Deprecation", "@"+strconv.FormatInt(dep.Since.Unix(), 10))
+	if !dep.Sunset.IsZero() {
+		c.Set("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if dep.Successor != "" {
+		c.Append(fiber.HeaderLink, "<"+dep.Successor+`>; rel="successor-version"`)
+	}
+	s.deprecated.count(name, dep, time.Now())
+
+	warnings, _ := c.Locals(deprecationWarningsKey).([]string)
+	c.Locals(deprecationWarningsKey, append(warnings, dep.Message))
+}
+
+// deprecationWarnings дописывает в JSON-объект ответа поле _deprecations с
+// предупреждениями запроса. Массивы и другие форматы не трогает: там
+// остаются только заголовки. Ставится первым, чтобы видеть готовый ответ.
+func deprecationWarnings(c *fiber.Ctx) error {
+	err := c.Next()
+	warnings, _ := c.Locals(deprecationWarningsKey).([]string)
+	if len(warnings) == 0 || !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return err
+	}
+	body := bytes.TrimSpace(c.Response().Body())
+	if len(body) < 2 || body[0] != '{' {
+		return err
+	}
+	field, merr := json.Marshal(warnings)
+	if merr != nil {
+		return err
+	}
+	var out bytes.Buffer
+	out.WriteString(`{"_deprecations":`)
+	out.Write(field)
+	if rest := bytes.TrimSpace(body[1:]); rest[0] != '}' {
+		out.WriteByte(',')
+	}
+	out.Write(body[1:])
+	c.Response().SetBody(out.Bytes())
+	return err
+}
+
+// legacyRoutes помечает устаревшими адреса без версии. Ставится на корень
+// после групп версий: запрос по адресу версии сюда уже не доходит, кроме
+// несуществующих адресов под её префиксом, их пропускаем.
+func (s *Server) legacyRoutes(c *fiber.Ctx) error {
+	for _, v := range apiVersions {
+		if strings.HasPrefix(c.Path(), v.prefix+"/") {
+			return c.Next()
+		}
+	}
+	dep := deprecation{
+		Since:     unversionedSince,
+		Sunset:    s.cfg.UnversionedSunset,
+		Successor: apiV1.prefix + c.OriginalURL(),
+		Message:   "Unversioned routes are deprecated, use " + apiV1.prefix + " instead",
+	}
+	// счёт по шаблону маршрута, который известен только после обработки
+	err := c.Next()
+	s.deprecate(c, "unversioned "+c.Method()+" "+c.Route().Path, dep)
+	return err
+}
+
+// unversionedSince - когда появился /api/v1 и адреса без версии устарели
+var unversionedSince = time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+func (s *Server) getDeprecations(c *fiber.Ctx) error {
+	return c.JSON(s.deprecated.report())
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	GUID        rssGUID `xml:"guid"`
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value     string `xml:",chardata"`
+	PermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// getTaskFeed - лента задач, доступных владельцу ключа
+func (s *Server) getTaskFeed(c *fiber.Ctx) error {
+	tasks, err := s.tasks.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch feed")
+	}
+	return s.writeFeed(c, "My tasks", "tasks", tasks)
+}
+
+// getProjectFeed - лента одного проекта
+func (s *Server) getProjectFeed(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	project, tasks, err := s.workspaces.ProjectTasks(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to fetch feed")
+	}
+	return s.writeFeed(c, project.Name, fmt.Sprintf("projects/%d", id), tasks)
+}
+
+// writeFeed отдаёт Atom, а с ?format=rss - RSS 2.0
+func (s *Server) writeFeed(c *fiber.Ctx, title, path string, tasks []model.Task) error {
+	base := strings.TrimRight(s.cfg.PublicURL, "/")
+	items := service.Activity(tasks)
+
+	updated := time.Unix(0, 0).UTC()
+	if len(items) > 0 {
+		updated = items[0].At
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	var body any
+	if c.Query("format") == "rss" {
+		feed := rssFeed{Version: "2.0", Channel: rssChannel{
+			Title:         title,
+			Link:          base + "/feeds/" + path,
+			Description:   "Recently created and completed tasks",
+			LastBuildDate: updated.Format(time.RFC1123Z),
+		}}
+		for _, it := range items {
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				GUID:        rssGUID{Value: feedEntryID(base, it)},
+				Title:       entryTitle(it),
+				Link:        fmt.Sprintf("%s/tasks/%d", base, it.Task.ID),
+				Description: it.Task.Description,
+				PubDate:     it.At.Format(time.RFC1123Z),
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/rss+xml; charset=utf-8")
+		body = feed
+	} else {
+		feed := atomFeed{
+			ID:      base + "/feeds/" + path,
+			Title:   title,
+			Updated: updated.Format(time.RFC3339),
+			Link:    atomLink{Href: base + "/feeds/" + path, Rel: "self"},
+		}
+		for _, it := range items {
+			feed.Entries = append(feed.Entries, atomEntry{
+				ID:      feedEntryID(base, it),
+				Title:   entryTitle(it),
+				Updated: it.At.Format(time.RFC3339),
+				Link:    atomLink{Href: fmt.Sprintf("%s/tasks/%d", base, it.Task.ID)},
+				Summary: it.Task.Description,
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/atom+xml; charset=utf-8")
+		body = feed
+	}
+
+	w := c.Response().BodyWriter()
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(body)
+}
+
+func entryTitle(it service.ActivityItem) string {
+	if it.Kind == service.ActivityCompleted {
+		return "Completed: " + it.Task.Title
+	}
+	return "Created: " + it.Task.Title
+}
+
+// feedEntryID - постоянный tag URI (RFC 4151) события, чтобы читалки не дублировали записи
+func feedEntryID(base string, it service.ActivityItem) string {
+	host := "localhost"
+	if u, err := url.Parse(base); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("tag:%s,%s:task-%d-%s", host, it.Task.CreatedAt.Format(time.DateOnly), it.Task.ID, it.Kind)
+}
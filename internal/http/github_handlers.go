@@ -0,0 +1,64 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+func (s *Server) linkGitHub(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+	var req service.GitHubLinkRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	link, err := s.github.Link(c.UserContext(), id, req)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to link GitHub repository")
+	}
+	return c.Status(fiber.StatusCreated).JSON(link)
+}
+
+func (s *Server) getGitHubLink(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	link, err := s.github.GetLink(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "GitHub link not found", "Failed to fetch GitHub link")
+	}
+	return c.JSON(link)
+}
+
+func (s *Server) unlinkGitHub(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	if err := s.github.Unlink(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "GitHub link not found", "Failed to unlink GitHub repository")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// receiveGitHubWebhook - вебхук репозитория; подлинность проверяется подписью
+func (s *Server) receiveGitHubWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	err = s.github.HandleWebhook(c.UserContext(), id,
+		c.Get("X-GitHub-Event"), c.Get("X-Hub-Signature-256"), c.Body())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to process GitHub webhook")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,314 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+func (s *Server) createTask(c *fiber.Ctx) error {
+	var task model.Task
+	if err := parseBody(c, &task); err != nil {
+		return err
+	}
+
+	// ?force=true - создать, даже если похожая открытая задача уже есть
+	var opts []service.CreateOption
+	if !c.QueryBool("force") {
+		opts = append(opts, service.RejectDuplicates())
+	}
+	if err := s.tasks.Create(c.UserContext(), &task, opts...); err != nil {
+		return s.serviceError(c, err, "Failed to create task")
+	}
+
+	return s.respond(c.Status(fiber.StatusCreated), task)
+}
+
+func (s *Server) getTasks(c *fiber.Ctx) error {
+	if ids := c.Query("ids"); ids != "" {
+		return s.getTasksByIDs(c, ids)
+	}
+	tasks, err := s.tasks.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch tasks")
+	}
+	// удаление не меняет updated_at оставшихся задач, но обновляет счётчики
+	stats, err := s.tasks.Stats(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch tasks")
+	}
+	modified := lastModified(tasks)
+	if stats.UpdatedAt != nil && stats.UpdatedAt.After(modified) {
+		modified = *stats.UpdatedAt
+	}
+	// закрепление меняет порядок, не трогая updated_at задач
+	pinned, err := s.tasks.PinsUpdatedAt(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch tasks")
+	}
+	if pinned.After(modified) {
+		modified = pinned
+	}
+
+	if notModified(c, modified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	// отложенные задачи - только с ?snoozed=true
+	if !c.QueryBool("snoozed") {
+		tasks = s.tasks.Awake(tasks)
+	}
+	setTotalCount(c, len(tasks))
+	return s.respond(c, tasks)
+}
+
+// HeaderTotalCount - сколько всего элементов в списке, чтобы показать
+// счётчик или страницы, не считая строки на клиенте
+const HeaderTotalCount = "X-Total-Count"
+
+func setTotalCount(c *fiber.Ctx, n int) {
+	c.Set(HeaderTotalCount, strconv.Itoa(n))
+}
+
+// countTasks - GET /tasks/count?status=todo: {"count": N} без самих задач
+func (s *Server) countTasks(c *fiber.Ctx) error {
+	n, err := s.tasks.Count(c.UserContext(), c.Query("status"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to count tasks")
+	}
+	setTotalCount(c, n)
+	return c.JSON(fiber.Map{"count": n})
+}
+
+// taskID - задача из :id, где можно указать и числовой id, и uid
+func (s *Server) taskID(c *fiber.Ctx) (int, error) {
+	id, err := s.tasks.ResolveID(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return 0, s.serviceError(c, err, "Failed to fetch task")
+	}
+	return id, nil
+}
+
+// taskBatch - ответ GET /tasks?ids=: задачи в порядке запроса и id, которых
+// нет или которые не видны
+type taskBatch struct {
+	Tasks   any   `json:"tasks"`
+	Missing []int `json:"missing"`
+}
+
+// getTasksByIDs - ?ids=1,2,3 одним запросом вместо запроса на каждую задачу
+func (s *Server) getTasksByIDs(c *fiber.Ctx, query string) error {
+	var ids []int
+	for _, part := range strings.Split(query, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid ids, expected comma-separated task ids")
+		}
+		ids = append(ids, id)
+	}
+	tasks, missing, err := s.tasks.GetMany(c.UserContext(), ids)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch tasks")
+	}
+	setTotalCount(c, len(tasks))
+	return s.respond(c, taskBatch{Tasks: s.present(c, renderDescriptions(c, tasks)), Missing: missing})
+}
+
+func (s *Server) getTaskByID(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	// ?as_of=RFC 3339 - задача на прошлый момент из журнала событий
+	var task model.Task
+	if asOf := c.Query("as_of"); asOf != "" {
+		at, perr := time.Parse(time.RFC3339, asOf)
+		if perr != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid as_of, expected RFC 3339 time")
+		}
+		task, err = s.tasks.GetAt(c.UserContext(), id, at)
+	} else {
+		task, err = s.tasks.Get(c.UserContext(), id)
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch task")
+	}
+
+	modified := task.UpdatedAt
+	pinned, err := s.tasks.PinUpdatedAt(c.UserContext(), id)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch task")
+	}
+	if pinned.After(modified) {
+		modified = pinned
+	}
+
+	if notModified(c, modified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return s.respond(c, task)
+}
+
+// getTaskStats - число видимых задач по статусам
+func (s *Server) getTaskStats(c *fiber.Ctx) error {
+	stats, err := s.tasks.Stats(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch stats")
+	}
+	return c.JSON(stats)
+}
+
+// searchTasks - полнотекстовый поиск: ?q=запрос&limit=N
+func (s *Server) searchTasks(c *fiber.Ctx) error {
+	tasks, err := s.tasks.Search(c.UserContext(), c.Query("q"), c.QueryInt("limit"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to search tasks")
+	}
+	return c.JSON(tasks)
+}
+
+// getPinnedTasks - задачи, закреплённые пользователем
+func (s *Server) getPinnedTasks(c *fiber.Ctx) error {
+	tasks, err := s.tasks.Pinned(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch pinned tasks")
+	}
+	setTotalCount(c, len(tasks))
+	return s.respond(c, tasks)
+}
+
+// getNearbyTasks - задачи рядом с клиентом: ?lat=&lon= обязательны, radius в
+// метрах
+func (s *Server) getNearbyTasks(c *fiber.Ctx) error {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+	if errLat != nil || errLon != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "lat and lon query parameters are required")
+	}
+	radius := 0.0
+	if v := c.Query("radius"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "radius must be a positive number of meters")
+		}
+		radius = r
+	}
+
+	tasks, err := s.tasks.Nearby(c.UserContext(), lat, lon, radius)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to find nearby tasks")
+	}
+	return c.JSON(tasks)
+}
+
+// getTaskHistory - журнал изменений задачи: кто, что и когда
+func (s *Server) getTaskHistory(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	history, err := s.tasks.History(c.UserContext(), id)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch task history")
+	}
+	return c.JSON(history)
+}
+
+func (s *Server) updateTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+	var task model.Task
+
+	if err := parseBody(c, &task); err != nil {
+		return err
+	}
+
+	task.ID = id
+	if err := s.tasks.Update(c.UserContext(), &task); err != nil {
+		return s.serviceError(c, err, "Failed to update task")
+	}
+
+	return s.respond(c, task)
+}
+
+// snoozeTask - POST /tasks/:id/snooze: {"until": "2026-01-01T09:00:00Z"}
+// или {"for": "3h"}
+func (s *Server) snoozeTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+	var req service.SnoozeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	task, err := s.tasks.Snooze(c.UserContext(), id, req)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to snooze task")
+	}
+	return s.respond(c, task)
+}
+
+// unsnoozeTask - DELETE /tasks/:id/snooze: вернуть задачу раньше срока
+func (s *Server) unsnoozeTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	task, err := s.tasks.Unsnooze(c.UserContext(), id)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to unsnooze task")
+	}
+	return s.respond(c, task)
+}
+
+// pinTask - POST /tasks/:id/pin: закрепить задачу только для себя
+func (s *Server) pinTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	task, err := s.tasks.Pin(c.UserContext(), id)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to pin task")
+	}
+	return s.respond(c, task)
+}
+
+// unpinTask - DELETE /tasks/:id/pin
+func (s *Server) unpinTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	task, err := s.tasks.Unpin(c.UserContext(), id)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to unpin task")
+	}
+	return s.respond(c, task)
+}
+
+func (s *Server) deleteTask(c *fiber.Ctx) error {
+	id, err := s.taskID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tasks.Delete(c.UserContext(), id); err != nil {
+		return s.serviceError(c, err, "Failed to delete task")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,851 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/config"
+	apihttp "github.com/Upiter5/todo-app/internal/http"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+	"github.com/Upiter5/todo-app/internal/testutil"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+func TestHandlers(t *testing.T) {
+	t.Parallel()
+	errDB := errors.New("connection reset by peer")
+	unavailable := &storage.UnavailableError{RetryAfter: 2500 * time.Millisecond}
+
+	tests := []struct {
+		name       string
+		store      *testutil.MockTaskStore
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:       "create: invalid JSON",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       `{"title":`,
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "create: missing title",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       map[string]string{"status": "todo"},
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "create: title too short",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       map[string]string{"title": "ab", "status": "todo"},
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "create: unknown status",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       map[string]string{"title": "Buy milk", "status": "later"},
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name: "create: db error",
+			store: &testutil.MockTaskStore{CreateTaskFunc: func(context.Context, *model.Task) error {
+				return &pgconn.PgError{Code: "23505"}
+			}},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       map[string]string{"title": "Buy milk", "status": "todo"},
+			wantStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name: "create: ok",
+			store: &testutil.MockTaskStore{CreateTaskFunc: func(_ context.Context, task *model.Task) error {
+				task.ID = 7
+				return nil
+			}},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       map[string]string{"title": "Buy milk", "status": "todo"},
+			wantStatus: fiber.StatusCreated,
+			wantBody:   `{"id":7,"title":"Buy milk","status":"todo"}`,
+		},
+		{
+			name: "list: db error",
+			store: &testutil.MockTaskStore{ListTasksFunc: func(context.Context) ([]model.Task, error) {
+				return nil, errDB
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks",
+			wantStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name: "list: storage unavailable",
+			store: &testutil.MockTaskStore{ListTasksFunc: func(context.Context) ([]model.Task, error) {
+				return nil, unavailable
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks",
+			wantStatus: fiber.StatusServiceUnavailable,
+			wantHeader: map[string]string{"Retry-After": "3"},
+		},
+		{
+			name: "list: total count header",
+			store: &testutil.MockTaskStore{ListTasksFunc: func(context.Context) ([]model.Task, error) {
+				return []model.Task{{ID: 1, Status: model.StatusTodo}, {ID: 2, Status: model.StatusDone}}, nil
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks",
+			wantStatus: fiber.StatusOK,
+			wantHeader: map[string]string{apihttp.HeaderTotalCount: "2"},
+		},
+		{
+			name: "count: by status",
+			store: &testutil.MockTaskStore{ListTasksFunc: func(context.Context) ([]model.Task, error) {
+				return []model.Task{{ID: 1, Status: model.StatusTodo}, {ID: 2, Status: model.StatusDone},
+					{ID: 3, Status: model.StatusTodo}}, nil
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks/count?status=todo",
+			wantStatus: fiber.StatusOK,
+			wantBody:   `{"count":2}`,
+			wantHeader: map[string]string{apihttp.HeaderTotalCount: "2"},
+		},
+		{
+			name:       "count: unknown status",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodGet,
+			path:       "/tasks/count?status=later",
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name: "list by ids: order kept, missing reported",
+			store: &testutil.MockTaskStore{GetTasksFunc: func(_ context.Context, ids []int) ([]model.Task, error) {
+				return []model.Task{{ID: 3, Title: "Three"}, {ID: 1, Title: "One"}}, nil
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks?ids=1,2,3,1",
+			wantStatus: fiber.StatusOK,
+			wantBody:   `{"tasks":[{"id":1,"title":"One"},{"id":3,"title":"Three"}],"missing":[2]}`,
+		},
+		{
+			name:       "list by ids: invalid id",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodGet,
+			path:       "/tasks?ids=1,abc",
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "get: invalid id",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodGet,
+			path:       "/tasks/abc",
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "get: not found",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodGet,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusNotFound,
+		},
+		{
+			name: "get: db error",
+			store: &testutil.MockTaskStore{GetTaskFunc: func(context.Context, int) (model.Task, error) {
+				return model.Task{}, errDB
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name: "get: links",
+			store: &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+				parent := 5
+				return model.Task{ID: id, Title: "Buy milk", Status: model.StatusDone, ParentID: &parent}, nil
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusOK,
+			wantBody: `{"_links": {"self": {"href": "/tasks/42"}, "delete": {"href": "/tasks/42", "method": "DELETE"},
+				"parent": {"href": "/tasks/5"}, "transitions": [
+					{"href": "/tasks/42", "method": "PUT", "status": "todo"},
+					{"href": "/tasks/42", "method": "PUT", "status": "in_progress"}]}}`,
+		},
+		{
+			name: "get: last modified",
+			store: &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+				return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo,
+					UpdatedAt: time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("MSK", 3*3600))}, nil
+			}},
+			method:     fiber.MethodGet,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusOK,
+			wantHeader: map[string]string{
+				"Last-Modified": "Sun, 01 Mar 2026 06:30:00 GMT",
+				"Cache-Control": "private, no-cache",
+			},
+		},
+		{
+			name:       "update: not found",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPut,
+			path:       "/tasks/42",
+			body:       map[string]string{"title": "Buy milk", "status": "done"},
+			wantStatus: fiber.StatusNotFound,
+		},
+		{
+			name:       "update: validation error",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPut,
+			path:       "/tasks/42",
+			body:       map[string]string{"title": "Buy milk", "status": "finished"},
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name: "delete: open subtasks",
+			store: &testutil.MockTaskStore{CountOpenSubtasksFunc: func(context.Context, int) (int, error) {
+				return 2, nil
+			}},
+			method:     fiber.MethodDelete,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name:       "create: parent not found",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodPost,
+			path:       "/tasks",
+			body:       map[string]any{"title": "Buy milk", "status": "todo", "parent_id": 9},
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "get: invalid as_of",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodGet,
+			path:       "/tasks/42?as_of=yesterday",
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "history: not enabled",
+			store:      &testutil.MockTaskStore{},
+			method:     fiber.MethodGet,
+			path:       "/tasks/42/history",
+			wantStatus: fiber.StatusNotImplemented,
+		},
+		{
+			name: "delete: db error",
+			store: &testutil.MockTaskStore{DeleteTaskFunc: func(context.Context, int) error {
+				return errDB
+			}},
+			method:     fiber.MethodDelete,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name: "delete: storage unavailable",
+			store: &testutil.MockTaskStore{DeleteTaskFunc: func(context.Context, int) error {
+				return unavailable
+			}},
+			method:     fiber.MethodDelete,
+			path:       "/tasks/42",
+			wantStatus: fiber.StatusServiceUnavailable,
+			wantHeader: map[string]string{"Retry-After": "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := testutil.NewServer(t, tt.store).AsUser(1).Do(tt.method, tt.path, tt.body)
+			resp.AssertStatus(tt.wantStatus)
+			if tt.wantBody != "" {
+				resp.AssertJSON(tt.wantBody)
+			}
+			for k, v := range tt.wantHeader {
+				resp.AssertHeader(k, v)
+			}
+		})
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	t.Parallel()
+	updated := time.Date(2026, 3, 1, 9, 30, 0, 500, time.UTC)
+	store := &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+		return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo, UpdatedAt: updated}, nil
+	}}
+
+	tests := []struct {
+		name  string
+		since string
+		want  int
+	}{
+		{"same second", "Sun, 01 Mar 2026 09:30:00 GMT", fiber.StatusNotModified},
+		{"later", "Sun, 01 Mar 2026 10:00:00 GMT", fiber.StatusNotModified},
+		{"earlier", "Sun, 01 Mar 2026 09:29:59 GMT", fiber.StatusOK},
+		{"malformed", "yesterday", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			testutil.NewServer(t, store).AsUser(1).WithHeader("If-Modified-Since", tt.since).
+				Get("/tasks/42").AssertStatus(tt.want)
+		})
+	}
+}
+
+// Завершение подзадачи меняет progress родителя, поэтому и его Last-Modified
+func TestConditionalGetParentProgress(t *testing.T) {
+	t.Parallel()
+	h := testutil.NewServer(t, nil).AsUser(1)
+	h.Post("/api/v1/tasks", map[string]string{"title": "Move out", "status": "todo"}).AssertStatus(fiber.StatusCreated)
+	h.Post("/api/v1/tasks", map[string]any{"title": "Pack books", "status": "todo", "parent_id": 1}).
+		AssertStatus(fiber.StatusCreated)
+	modified := h.Get("/api/v1/tasks/1").AssertStatus(fiber.StatusOK).AssertJSON(`{"progress": 0}`).
+		Header.Get(fiber.HeaderLastModified)
+
+	// Last-Modified с точностью до секунды
+	time.Sleep(time.Second)
+	h.Put("/api/v1/tasks/2", map[string]any{"title": "Pack books", "status": "done", "parent_id": 1}).
+		AssertStatus(fiber.StatusOK)
+	h.WithHeader("If-Modified-Since", modified).Get("/api/v1/tasks/1").
+		AssertStatus(fiber.StatusOK).AssertJSON(`{"progress": 100}`)
+}
+
+// pinnedLater - задача 42 закреплена позже своего последнего изменения
+type pinnedLater time.Time
+
+func (pinnedLater) SetTaskPinned(context.Context, int, bool) error { return nil }
+
+func (p pinnedLater) TaskFlags(context.Context) ([]model.TaskFlag, error) {
+	return []model.TaskFlag{{TaskID: 42, Pinned: true, UpdatedAt: time.Time(p)}}, nil
+}
+
+// Закрепление не меняет updated_at задачи, но меняет её представление
+func TestConditionalGetPinnedTask(t *testing.T) {
+	t.Parallel()
+	updated := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	store := &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+		return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo, UpdatedAt: updated}, nil
+	}}
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	tasks := service.NewTaskService(store, service.WithFlags(pinnedLater(updated.Add(time.Hour))))
+	token, _, err := jwt.Issue(model.User{ID: 1, Email: "user1@example.com", Role: model.RoleUser}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testutil.New(t, apihttp.NewServer(cfg, tasks, apihttp.WithAuthenticators(jwt)).App()).WithToken(token)
+
+	h.WithHeader("If-Modified-Since", "Sun, 01 Mar 2026 09:30:00 GMT").Get("/tasks/42").
+		AssertStatus(fiber.StatusOK).AssertHeader(fiber.HeaderLastModified, "Sun, 01 Mar 2026 10:30:00 GMT").
+		AssertJSON(`{"pinned": true}`)
+	h.WithHeader("If-Modified-Since", "Sun, 01 Mar 2026 10:30:00 GMT").Get("/tasks/42").
+		AssertStatus(fiber.StatusNotModified)
+}
+
+func TestStrictBody(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unknown field", `{"tittle":"Buy milk","status":"todo"}`, `Unknown field "tittle"`},
+		{"type mismatch", `{"title":"Buy milk","status":1}`, `Field "status" must be a string, got number`},
+		{"bad time", `{"title":"Buy milk","status":"todo","due_at":"tomorrow"}`, `Invalid time "tomorrow", expected RFC 3339`},
+		{"trailing data", `{"title":"Buy milk","status":"todo"} {}`, "Request body must contain a single JSON value"},
+		{"empty", ``, "Request body is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := testutil.NewServer(t, &testutil.MockTaskStore{}).AsUser(1).
+				WithHeader(fiber.HeaderContentType, fiber.MIMEApplicationJSON).
+				Do(fiber.MethodPost, "/tasks", tt.body)
+			resp.AssertStatus(fiber.StatusBadRequest)
+			if string(resp.Body) != tt.want {
+				t.Errorf("body = %q, want %q", resp.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	t.Parallel()
+	store := &testutil.MockTaskStore{
+		GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+			return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo}, nil
+		},
+		CreateTaskFunc: func(_ context.Context, task *model.Task) error {
+			task.ID = 7
+			return nil
+		},
+	}
+	h := testutil.NewServer(t, store).AsUser(1)
+
+	resp := h.WithHeader(fiber.HeaderAccept, wire.MIMEMsgpack).Get("/tasks/42").
+		AssertStatus(fiber.StatusOK).AssertHeader(fiber.HeaderContentType, wire.MIMEMsgpack)
+	js, err := wire.MsgpackToJSON(resp.Body)
+	if err != nil || !strings.Contains(string(js), `"title":"Buy milk"`) {
+		t.Errorf("msgpack body = %s, %v", js, err)
+	}
+
+	body, _ := wire.MarshalProto(model.Task{Title: "Buy bread", Status: model.StatusTodo})
+	resp = h.WithHeader(fiber.HeaderContentType, wire.MIMEProtobuf).WithHeader(fiber.HeaderAccept, wire.MIMEProtobuf).
+		Do(fiber.MethodPost, "/tasks", body).AssertStatus(fiber.StatusCreated)
+	var created model.Task
+	if err := wire.UnmarshalTask(resp.Body, &created); err != nil || created.ID != 7 || created.Title != "Buy bread" {
+		t.Errorf("created = %+v, %v", created, err)
+	}
+
+	// без Accept - по-прежнему JSON
+	testutil.NewServer(t, store).AsUser(1).Get("/tasks/42").
+		AssertHeader(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+}
+
+func TestJSONAPI(t *testing.T) {
+	t.Parallel()
+	parent := 5
+	store := &testutil.MockTaskStore{
+		GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+			if id == parent {
+				return model.Task{ID: id, Title: "Plan trip", Status: model.StatusTodo}, nil
+			}
+			return model.Task{ID: id, Title: "Buy tickets", Status: model.StatusTodo, ParentID: &parent}, nil
+		},
+		CreateTaskFunc: func(_ context.Context, task *model.Task) error {
+			task.ID = 7
+			return nil
+		},
+	}
+	h := testutil.NewServer(t, store).AsUser(1).WithHeader(fiber.HeaderAccept, apihttp.MIMEJSONAPI)
+
+	h.Get("/tasks/42?include=parent&fields[tasks]=title,parent").
+		AssertStatus(fiber.StatusOK).
+		AssertHeader(fiber.HeaderContentType, apihttp.MIMEJSONAPI).
+		AssertJSON(`{"data": {"type": "tasks", "id": "42", "attributes": {"title": "Buy tickets"},
+			"relationships": {"parent": {"data": {"type": "tasks", "id": "5"}}}},
+			"included": [{"type": "tasks", "id": "5", "attributes": {"title": "Plan trip"}}]}`)
+	if resp := h.Get("/tasks/42?fields=title"); strings.Contains(string(resp.Body), "status") {
+		t.Errorf("sparse fieldset leaked status: %s", resp.Body)
+	}
+	h.Get("/tasks/42?include=owner").AssertStatus(fiber.StatusBadRequest)
+
+	h.WithHeader(fiber.HeaderContentType, apihttp.MIMEJSONAPI).
+		Do(fiber.MethodPost, "/tasks", `{"data": {"type": "tasks", "attributes": {"title": "Pack bags", "status": "todo"},
+			"relationships": {"parent": {"data": {"type": "tasks", "id": "5"}}}}}`).
+		AssertStatus(fiber.StatusCreated).
+		AssertJSON(`{"data": {"id": "7", "relationships": {"parent": {"data": {"id": "5"}}}}}`)
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	t.Parallel()
+	store := &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+		return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo}, nil
+	}}
+	h := testutil.NewServer(t, store).AsUser(1)
+
+	h.Get("/api/v1/tasks/42").AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"id": 42, "_links": {"self": {"href": "/api/v1/tasks/42"}}}`)
+	h.Get("/tasks/42").AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"id": 42, "_links": {"self": {"href": "/tasks/42"}}}`)
+	testutil.NewServer(t, store).Get("/api/v1/tasks/42").AssertStatus(fiber.StatusUnauthorized)
+}
+
+// задача из GET уходит обратно в PUT как есть, вместе с полями-надстройками ответа
+func TestTaskRoundTrip(t *testing.T) {
+	t.Parallel()
+	h := testutil.NewServer(t, nil).AsUser(1)
+
+	var created model.Task
+	h.Post("/api/v1/tasks", map[string]string{"title": "Buy milk", "status": "todo"}).
+		AssertStatus(fiber.StatusCreated).DecodeJSON(&created)
+
+	path := fmt.Sprintf("/api/v1/tasks/%d", created.ID)
+	fetched := h.Get(path).AssertStatus(fiber.StatusOK).AssertJSON(`{"_links": {"self": {"href": "` + path + `"}}}`)
+	h.Put(path, fetched.Body).
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(fmt.Sprintf(`{"id": %d, "title": "Buy milk", "status": "todo"}`, created.ID))
+
+	// поле-надстройка ответа не прячет опечатки рядом с собой
+	h.Put(path, `{"_links": {}, "tittle": "Buy milk", "status": "todo"}`).AssertStatus(fiber.StatusBadRequest)
+
+	// без версии в ответе ещё и _deprecations
+	path = fmt.Sprintf("/tasks/%d", created.ID)
+	fetched = h.Get(path).AssertStatus(fiber.StatusOK).AssertJSON(`{"_deprecations": ["Unversioned routes are deprecated, use /api/v1 instead"]}`)
+	h.Put(path, fetched.Body).
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(fmt.Sprintf(`{"id": %d, "title": "Buy milk", "status": "todo"}`, created.ID))
+}
+
+func TestUnversionedDeprecated(t *testing.T) {
+	t.Parallel()
+	store := &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+		return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo}, nil
+	}}
+	h := testutil.NewServer(t, store).AsUser(1)
+
+	h.Get("/tasks/42").AssertStatus(fiber.StatusOK).
+		AssertHeader("Deprecation", "@1791936000").
+		AssertHeader(fiber.HeaderLink, `</api/v1/tasks/42>; rel="successor-version"`).
+		AssertJSON(`{"id": 42, "_deprecations": ["Unversioned routes are deprecated, use /api/v1 instead"]}`)
+
+	r := h.Get("/api/v1/tasks/42").AssertStatus(fiber.StatusOK).AssertHeader("Deprecation", "")
+	var body map[string]any
+	r.DecodeJSON(&body)
+	if _, ok := body["_deprecations"]; ok {
+		t.Fatalf("versioned response has _deprecations: %s", r.Body)
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	reloaded := config.Default()
+	reloaded.CORSOrigins = []string{"https://app.example.com"}
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	srv := apihttp.NewServer(cfg, service.NewTaskService(storage.NewMemory()),
+		apihttp.WithAuthenticators(jwt),
+		apihttp.WithConfigReload(func() (config.Config, error) { return reloaded, nil }))
+	admin, _, err := jwt.Issue(model.User{ID: 1, Email: "admin@example.com", Role: model.RoleAdmin}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testutil.New(t, srv.App()).WithToken(admin)
+	fromApp := h.WithHeader(fiber.HeaderOrigin, "https://app.example.com")
+
+	fromApp.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertHeader(fiber.HeaderAccessControlAllowOrigin, "")
+	h.Post("/api/v1/admin/config/reload", nil).AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"log_level": "info", "cors_origins": ["https://app.example.com"]}`)
+	fromApp.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).
+		AssertHeader(fiber.HeaderAccessControlAllowOrigin, "https://app.example.com")
+	h.WithHeader(fiber.HeaderOrigin, "https://evil.example.com").Get("/api/v1/tasks").
+		AssertHeader(fiber.HeaderAccessControlAllowOrigin, "")
+
+	testutil.NewServer(t, nil).AsUser(2).Post("/api/v1/admin/config/reload", nil).AssertStatus(fiber.StatusForbidden)
+}
+
+func TestHeadAndOptions(t *testing.T) {
+	t.Parallel()
+	store := &testutil.MockTaskStore{GetTaskFunc: func(_ context.Context, id int) (model.Task, error) {
+		return model.Task{ID: id, Title: "Buy milk", Status: model.StatusTodo}, nil
+	}}
+	h := testutil.NewServer(t, store)
+
+	get := h.AsUser(1).Get("/api/v1/tasks/42").AssertStatus(fiber.StatusOK)
+	tag := get.Header.Get(fiber.HeaderETag)
+	if tag == "" {
+		t.Fatal("GET response has no ETag")
+	}
+	head := h.AsUser(1).Do(fiber.MethodHead, "/api/v1/tasks/42", nil).AssertStatus(fiber.StatusOK).
+		AssertHeader(fiber.HeaderETag, tag)
+	if len(head.Body) != 0 {
+		t.Fatalf("HEAD response has a body: %s", head.Body)
+	}
+	h.AsUser(1).WithHeader(fiber.HeaderIfNoneMatch, tag).Get("/api/v1/tasks/42").AssertStatus(fiber.StatusNotModified)
+
+	// OPTIONS отвечает без аутентификации
+	h.Do(fiber.MethodOptions, "/api/v1/tasks/42", nil).AssertStatus(fiber.StatusNoContent).
+		AssertHeader(fiber.HeaderAllow, "GET, HEAD, PUT, DELETE, OPTIONS")
+	h.Do(fiber.MethodOptions, "/api/v1/tasks", nil).AssertStatus(fiber.StatusNoContent).
+		AssertHeader(fiber.HeaderAllow, "GET, HEAD, POST, OPTIONS")
+	h.Do(fiber.MethodOptions, "/api/v1/nope", nil).AssertStatus(fiber.StatusNotFound)
+}
+
+func TestTaskUID(t *testing.T) {
+	t.Parallel()
+	srv := testutil.NewServer(t, nil)
+	h := srv.AsUser(1)
+
+	var created model.Task
+	h.Post("/api/v1/tasks", map[string]string{"title": "Buy milk", "status": "todo"}).
+		AssertStatus(fiber.StatusCreated).DecodeJSON(&created)
+	if len(created.UID) != 36 || created.UID[14] != '7' {
+		t.Fatalf("uid = %q, want a UUIDv7", created.UID)
+	}
+	h.Get("/api/v1/tasks/" + created.UID).AssertStatus(fiber.StatusOK).AssertJSON(`{"title": "Buy milk"}`)
+
+	// uid от офлайн-клиента сохраняется, повтор создания - 409
+	offline := map[string]string{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "title": "Call mom", "status": "todo"}
+	h.Post("/api/v1/tasks", offline).AssertStatus(fiber.StatusCreated).AssertJSON(`{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b"}`)
+	h.Post("/api/v1/tasks", offline).AssertStatus(fiber.StatusConflict)
+	h.Put("/api/v1/tasks/01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", map[string]string{"title": "Call mom", "status": "done"}).
+		AssertStatus(fiber.StatusOK).AssertJSON(`{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "status": "done"}`)
+
+	h.Post("/api/v1/tasks", map[string]string{"uid": "not-a-uuid", "title": "Call dad", "status": "todo"}).
+		AssertStatus(fiber.StatusBadRequest)
+	srv.AsUser(2).Get("/api/v1/tasks/" + created.UID).AssertStatus(fiber.StatusNotFound)
+}
+
+// занятый uid - ответ базы, а не её сбой: 409, и предохранитель не размыкается
+func TestTaskUIDTakenBehindBreaker(t *testing.T) {
+	t.Parallel()
+	h := testutil.NewServer(t, storage.NewBreakerStore(storage.NewMemory(), 1, time.Minute)).AsUser(1)
+
+	offline := map[string]string{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "title": "Call mom", "status": "todo"}
+	h.Post("/api/v1/tasks", offline).AssertStatus(fiber.StatusCreated)
+	for range 3 {
+		h.Post("/api/v1/tasks", offline).AssertStatus(fiber.StatusConflict).AssertHeader(fiber.HeaderRetryAfter, "")
+	}
+	h.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertJSON(`[{"title": "Call mom"}]`)
+}
+
+func TestRenderDescriptionHTML(t *testing.T) {
+	t.Parallel()
+	srv := testutil.NewServer(t, nil)
+	h := srv.AsUser(1)
+
+	// клиент не может подсунуть свой description_html
+	src := map[string]string{"title": "Release notes", "status": "todo",
+		"description": "**Важно**: <script>alert(1)</script>", "description_html": "<script>alert(2)</script>"}
+	var created model.Task
+	h.Post("/api/v1/tasks", src).AssertStatus(fiber.StatusCreated).DecodeJSON(&created)
+	if created.DescriptionHTML != "" {
+		t.Fatalf("description_html = %q without ?render=html", created.DescriptionHTML)
+	}
+	h.Get("/api/v1/tasks/" + created.UID + "?render=html").AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"description": "**Важно**: <script>alert(1)</script>",
+			"description_html": "<p><strong>Важно</strong>: &lt;script&gt;alert(1)&lt;/script&gt;</p>\n"}`)
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	mem := storage.NewMemory()
+	srv := apihttp.NewServer(cfg, service.NewTaskService(mem, service.WithSync(mem)), apihttp.WithAuthenticators(jwt))
+	token, _, err := jwt.Issue(model.User{ID: 1, Email: "user1@example.com", Role: model.RoleUser}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testutil.New(t, srv.App()).WithToken(token)
+
+	var first struct {
+		Token string `json:"token"`
+	}
+	h.Post("/api/v1/sync", map[string]any{"changes": []map[string]any{
+		{"op": "upsert", "uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "task": map[string]string{"title": "Buy milk", "status": "todo"}},
+		{"op": "upsert", "uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6c", "task": map[string]string{"title": "Bad", "status": "later"}},
+		{"op": "delete", "uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b"},
+	}}).AssertStatus(fiber.StatusOK).AssertJSON(`{"tasks": [], "deleted": [], "results": [
+		{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "status": 201},
+		{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6c", "status": 400},
+		{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "status": 200}]}`).DecodeJSON(&first)
+
+	h.Post("/api/v1/tasks", map[string]string{"title": "Call mom", "status": "todo"}).AssertStatus(fiber.StatusCreated)
+	h.Post("/api/v1/sync", map[string]string{"token": first.Token}).AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"tasks": [{"title": "Call mom"}], "deleted": [], "results": []}`)
+	h.Post("/api/v1/sync", map[string]string{"token": "garbage"}).AssertStatus(fiber.StatusBadRequest)
+	h.Get("/api/v1/sync/conflicts").AssertStatus(fiber.StatusOK).AssertJSON(`[]`)
+	h.Delete("/api/v1/sync/conflicts/1").AssertStatus(fiber.StatusNotFound)
+	testutil.NewServer(t, nil).AsUser(1).Post("/api/v1/sync", nil).AssertStatus(fiber.StatusNotImplemented)
+}
+
+// чужой uid в синхронизации - конфликт этого изменения: задача не создаётся,
+// не меняется, и предохранитель перед базой не размыкается
+func TestSyncForeignUID(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	mem := storage.NewMemory()
+	tasks := service.NewTaskService(storage.NewBreakerStore(mem, 1, time.Minute), service.WithSync(mem))
+	h := testutil.New(t, apihttp.NewServer(cfg, tasks, apihttp.WithAuthenticators(jwt)).App())
+	as := func(id int) *testutil.Harness {
+		token, _, err := jwt.Issue(model.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Role: model.RoleUser}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h.WithToken(token)
+	}
+	alice, mallory := as(1), as(2)
+
+	alice.Post("/api/v1/tasks", map[string]string{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "title": "Call mom", "status": "todo"}).
+		AssertStatus(fiber.StatusCreated)
+	for range 3 {
+		mallory.Post("/api/v1/sync", map[string]any{"changes": []map[string]any{
+			{"op": "upsert", "uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "task": map[string]string{"title": "Mine now", "status": "done"}},
+			{"op": "delete", "uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b"},
+		}}).AssertStatus(fiber.StatusOK).AssertJSON(`{"tasks": [], "results": [
+			{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "status": 409, "error": "Task with this uid already exists"},
+			{"uid": "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", "status": 200}]}`)
+	}
+	mallory.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertJSON(`null`)
+	alice.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertJSON(`[{"title": "Call mom", "status": "todo"}]`)
+}
+
+func TestNearbyTasks(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	mem := storage.NewMemory()
+	srv := apihttp.NewServer(cfg, service.NewTaskService(mem, service.WithLocations(mem)), apihttp.WithAuthenticators(jwt))
+	token, _, err := jwt.Issue(model.User{ID: 1, Email: "user1@example.com", Role: model.RoleUser}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testutil.New(t, srv.App()).WithToken(token)
+
+	for _, task := range []map[string]any{
+		{"title": "Buy stamps", "status": "todo", "location": map[string]any{"lat": 55.7520, "lon": 37.6175, "label": "Post office"}},
+		{"title": "Pick up parcel", "status": "todo", "location": map[string]any{"lat": 55.7540, "lon": 37.6210}},
+		{"title": "Return book", "status": "done", "location": map[string]any{"lat": 55.7541, "lon": 37.6209}},
+		{"title": "Visit museum", "status": "todo", "location": map[string]any{"lat": 59.9398, "lon": 30.3146}},
+		{"title": "Call mom", "status": "todo"},
+	} {
+		h.Post("/api/v1/tasks", task).AssertStatus(fiber.StatusCreated)
+	}
+	h.Post("/api/v1/tasks", map[string]any{"title": "Nowhere", "status": "todo",
+		"location": map[string]any{"lat": 91, "lon": 0}}).AssertStatus(fiber.StatusBadRequest)
+
+	// ближайшие первыми, завершённые и далёкие не попадают
+	h.Get("/api/v1/tasks/nearby?lat=55.7539&lon=37.6208").AssertStatus(fiber.StatusOK).
+		AssertJSON(`[{"title": "Pick up parcel"}, {"title": "Buy stamps", "location": {"label": "Post office"}}]`)
+	h.Get("/api/v1/tasks/nearby?lat=55.7539&lon=37.6208&radius=50").AssertStatus(fiber.StatusOK).
+		AssertJSON(`[{"title": "Pick up parcel"}]`)
+	h.Get("/api/v1/tasks/nearby?lat=55.7539").AssertStatus(fiber.StatusBadRequest)
+	h.Get("/api/v1/tasks/nearby?lat=55.7539&lon=37.6208&radius=-1").AssertStatus(fiber.StatusBadRequest)
+	h.Get("/api/v1/tasks/nearby?lat=120&lon=0").AssertStatus(fiber.StatusBadRequest)
+	testutil.NewServer(t, nil).AsUser(1).Get("/api/v1/tasks/nearby?lat=0&lon=0").AssertStatus(fiber.StatusNotImplemented)
+}
+
+func TestSnoozeTasks(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	mem := storage.NewMemory()
+	now := time.Now().UTC()
+	tasks := service.NewTaskService(mem, service.WithSnooze(mem), service.WithClock(func() time.Time { return now }))
+	srv := apihttp.NewServer(cfg, tasks, apihttp.WithAuthenticators(jwt))
+	token, _, err := jwt.Issue(model.User{ID: 1, Email: "user1@example.com", Role: model.RoleUser}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := testutil.New(t, srv.App()).WithToken(token)
+
+	h.Post("/api/v1/tasks", map[string]string{"title": "Water plants", "status": "todo"}).AssertStatus(fiber.StatusCreated)
+	h.Post("/api/v1/tasks", map[string]string{"title": "Call mom", "status": "todo"}).AssertStatus(fiber.StatusCreated)
+	h.Post("/api/v1/tasks", map[string]string{"title": "Pay rent", "status": "done"}).AssertStatus(fiber.StatusCreated)
+
+	h.Post("/api/v1/tasks/1/snooze", map[string]string{"for": "2h"}).AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"id": 1, "snoozed_until": "` + now.Add(2*time.Hour).Format(time.RFC3339Nano) + `"}`)
+	h.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertHeader(apihttp.HeaderTotalCount, "2").
+		AssertJSON(`[{"title": "Call mom"}, {"title": "Pay rent"}]`)
+	h.Get("/api/v1/tasks?snoozed=true").AssertStatus(fiber.StatusOK).AssertHeader(apihttp.HeaderTotalCount, "3")
+
+	// правка задачи отсрочку не снимает
+	h.Put("/api/v1/tasks/1", map[string]string{"title": "Water all plants", "status": "todo"}).AssertStatus(fiber.StatusOK)
+	h.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertHeader(apihttp.HeaderTotalCount, "2")
+
+	h.Post("/api/v1/tasks/2/snooze", map[string]any{"until": now.Add(-time.Minute)}).AssertStatus(fiber.StatusBadRequest)
+	h.Post("/api/v1/tasks/2/snooze", map[string]any{"for": "1h", "until": now.Add(time.Hour)}).AssertStatus(fiber.StatusBadRequest)
+	h.Post("/api/v1/tasks/2/snooze", map[string]string{"for": "soon"}).AssertStatus(fiber.StatusBadRequest)
+	h.Post("/api/v1/tasks/3/snooze", map[string]string{"for": "1h"}).AssertStatus(fiber.StatusBadRequest)
+	h.Post("/api/v1/tasks/9/snooze", map[string]string{"for": "1h"}).AssertStatus(fiber.StatusNotFound)
+
+	h.Post("/api/v1/tasks/2/snooze", map[string]string{"for": "30m"}).AssertStatus(fiber.StatusOK)
+	h.Delete("/api/v1/tasks/2/snooze").AssertStatus(fiber.StatusOK).AssertJSON(`{"id": 2, "snoozed_until": null}`)
+
+	// по истечении отсрочки задача возвращается в список ещё до фоновой задачи,
+	// а та снимает отсрочку совсем
+	now = now.Add(3 * time.Hour)
+	h.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).AssertHeader(apihttp.HeaderTotalCount, "3")
+	n, err := tasks.WakeSnoozed(auth.WithPrincipal(context.Background(), auth.System))
+	if err != nil || n != 1 {
+		t.Fatalf("WakeSnoozed = %d, %v, want 1 task", n, err)
+	}
+	h.Get("/api/v1/tasks/1").AssertStatus(fiber.StatusOK).AssertJSON(`{"snoozed_until": null}`)
+
+	testutil.NewServer(t, nil).AsUser(1).Post("/api/v1/tasks/1/snooze", map[string]string{"for": "1h"}).
+		AssertStatus(fiber.StatusNotImplemented)
+}
+
+func TestPinTasks(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	mem := storage.NewMemory()
+	srv := apihttp.NewServer(cfg, service.NewTaskService(mem, service.WithFlags(mem)), apihttp.WithAuthenticators(jwt))
+	harness := func(id int) *testutil.Harness {
+		token, _, err := jwt.Issue(model.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Role: model.RoleUser}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return testutil.New(t, srv.App()).WithToken(token)
+	}
+	h, other := harness(1), harness(2)
+
+	for _, title := range []string{"Water plants", "Call mom", "Pay rent"} {
+		h.Post("/api/v1/tasks", map[string]string{"title": title, "status": "todo"}).AssertStatus(fiber.StatusCreated)
+	}
+	h.Get("/api/v1/tasks/pinned").AssertStatus(fiber.StatusOK).AssertJSON(`[]`)
+
+	h.Post("/api/v1/tasks/3/pin", nil).AssertStatus(fiber.StatusOK).AssertJSON(`{"id": 3, "pinned": true}`)
+	h.Post("/api/v1/tasks/2/pin", nil).AssertStatus(fiber.StatusOK)
+	h.Get("/api/v1/tasks").AssertStatus(fiber.StatusOK).
+		AssertJSON(`[{"id": 2, "pinned": true}, {"id": 3, "pinned": true}, {"id": 1, "pinned": false}]`)
+	h.Get("/api/v1/tasks/pinned").AssertStatus(fiber.StatusOK).AssertHeader(apihttp.HeaderTotalCount, "2").
+		AssertJSON(`[{"id": 2}, {"id": 3}]`)
+
+	// закрепление переживает правку задачи и видно только самому пользователю
+	h.Put("/api/v1/tasks/3", map[string]string{"title": "Pay the rent", "status": "todo"}).AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"pinned": true}`)
+	other.Post("/api/v1/tasks", map[string]string{"title": "Other task", "status": "todo"}).AssertStatus(fiber.StatusCreated).
+		AssertJSON(`{"pinned": false}`)
+	other.Get("/api/v1/tasks/pinned").AssertStatus(fiber.StatusOK).AssertJSON(`[]`)
+	other.Post("/api/v1/tasks/3/pin", nil).AssertStatus(fiber.StatusNotFound)
+
+	h.Delete("/api/v1/tasks/2/pin").AssertStatus(fiber.StatusOK).AssertJSON(`{"id": 2, "pinned": false}`)
+	h.Get("/api/v1/tasks/2").AssertStatus(fiber.StatusOK).AssertJSON(`{"pinned": false}`)
+	h.Get("/api/v1/tasks/pinned").AssertStatus(fiber.StatusOK).AssertJSON(`[{"id": 3}]`)
+	h.Post("/api/v1/tasks/9/pin", nil).AssertStatus(fiber.StatusNotFound)
+
+	testutil.NewServer(t, nil).AsUser(1).Get("/api/v1/tasks/pinned").AssertStatus(fiber.StatusNotImplemented)
+}
+
+func TestPrintTasks(t *testing.T) {
+	t.Parallel()
+	h := testutil.NewServer(t, storage.NewMemory()).AsUser(1)
+	h.Post("/api/v1/tasks", map[string]string{"title": "Book <room>", "status": "todo"}).AssertStatus(fiber.StatusCreated)
+	h.Post("/api/v1/tasks", map[string]string{"title": "Send agenda", "status": "done"}).AssertStatus(fiber.StatusCreated)
+
+	resp := h.Get("/api/v1/tasks/print?tz=Europe/Berlin").AssertStatus(fiber.StatusOK).
+		AssertHeader(fiber.HeaderContentType, "text/html; charset=utf-8")
+	body := string(resp.Body)
+	for _, want := range []string{"<title>My tasks</title>", "<h2>To do (1)</h2>", "<h2>Done (1)</h2>", "Book &lt;room&gt;", "&#9745;"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("print page has no %q:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "To do (1)") > strings.Index(body, "Done (1)") {
+		t.Errorf("done tasks are printed before open ones:\n%s", body)
+	}
+
+	resp = h.Get("/api/v1/tasks/print?group=due&status=done").AssertStatus(fiber.StatusOK)
+	if body := string(resp.Body); !strings.Contains(body, "<h2>No due date (1)</h2>") || strings.Contains(body, "Book") {
+		t.Errorf("done tasks by due date:\n%s", body)
+	}
+	h.Get("/api/v1/tasks/print?group=owner").AssertStatus(fiber.StatusBadRequest)
+	h.Get("/api/v1/tasks/print?tz=Mars/Olympus").AssertStatus(fiber.StatusBadRequest)
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+// importMicrosoft: {"access_token": "...", "workspace_id": 3}
+func (s *Server) importMicrosoft(c *fiber.Ctx) error {
+	var req service.MicrosoftImportRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	res, err := s.microsoft.Import(c.UserContext(), req)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to import from Microsoft To Do")
+	}
+	return c.JSON(res)
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+func (s *Server) linkJira(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+	var req service.JiraLinkRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	link, err := s.jira.Link(c.UserContext(), id, req)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to link Jira")
+	}
+	return c.Status(fiber.StatusCreated).JSON(link)
+}
+
+func (s *Server) getJiraLink(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	link, err := s.jira.GetLink(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Jira link not found", "Failed to fetch Jira link")
+	}
+	return c.JSON(link)
+}
+
+func (s *Server) unlinkJira(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	if err := s.jira.Unlink(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "Jira link not found", "Failed to unlink Jira")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// receiveJiraWebhook - вебхук Jira с секретом; подлинность проверяется подписью
+func (s *Server) receiveJiraWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	err = s.jira.HandleWebhook(c.UserContext(), id, c.Get("X-Hub-Signature"), c.Body())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to process Jira webhook")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,238 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// MIMEJSONAPI - представление JSON:API (jsonapi.org), выбирается заголовком Accept
+const MIMEJSONAPI = "application/vnd.api+json"
+
+const (
+	typeTasks    = "tasks"
+	typeUsers    = "users"
+	typeProjects = "projects"
+)
+
+type jsonAPIDocument struct {
+	Data     any               `json:"data"`
+	Included []jsonAPIResource `json:"included,omitempty"`
+}
+
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]any                 `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+type jsonAPIRelationship struct {
+	// nil - связи нет, в JSON "data": null
+	Data *jsonAPIIdentifier `json:"data"`
+}
+
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonAPIIncludes - связи задачи, которые можно запросить через ?include=
+var jsonAPIIncludes = []string{"parent", "project"}
+
+// respondJSONAPI отдаёт задачу или список задач документом JSON:API с
+// ?include=parent,project и ?fields[tasks]=title,status (или просто ?fields=)
+func (s *Server) respondJSONAPI(c *fiber.Ctx, v any) error {
+	var tasks []model.Task
+	switch v := v.(type) {
+	case model.Task:
+		tasks = []model.Task{v}
+	case []model.Task:
+		tasks = v
+	default:
+		return fiber.NewError(fiber.StatusNotAcceptable, "JSON:API is not available for this resource")
+	}
+
+	fields := sparseFields(c)
+	var include []string
+	if q := c.Query("include"); q != "" {
+		include = strings.Split(q, ",")
+		for _, name := range include {
+			if !slices.Contains(jsonAPIIncludes, name) {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unknown include %q", name))
+			}
+		}
+	}
+
+	resources := make([]jsonAPIResource, len(tasks))
+	for i, t := range tasks {
+		resources[i] = taskResource(version(c).prefix, t, fields[typeTasks])
+	}
+	doc := jsonAPIDocument{Data: resources}
+	if _, single := v.(model.Task); single {
+		doc.Data = resources[0]
+	}
+
+	included, err := s.jsonAPIIncluded(c, tasks, include, fields)
+	if err != nil {
+		return err
+	}
+	doc.Included = included
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, MIMEJSONAPI)
+	return c.Send(data)
+}
+
+// jsonAPIIncluded - родители и проекты задач без повторов. Недоступные
+// пользователю пропускаются: ссылка на них в relationships остаётся.
+func (s *Server) jsonAPIIncluded(c *fiber.Ctx, tasks []model.Task, include []string, fields map[string][]string) ([]jsonAPIResource, error) {
+	var included []jsonAPIResource
+	seen := map[jsonAPIIdentifier]bool{}
+	for _, t := range tasks {
+		if t.ParentID != nil && slices.Contains(include, "parent") {
+			key := jsonAPIIdentifier{typeTasks, strconv.Itoa(*t.ParentID)}
+			if !seen[key] {
+				seen[key] = true
+				parent, err := s.tasks.Get(c.UserContext(), *t.ParentID)
+				if err == nil {
+					included = append(included, taskResource(version(c).prefix, parent, fields[typeTasks]))
+				} else if !hidden(err) {
+					return nil, s.serviceError(c, err, "Failed to fetch included task")
+				}
+			}
+		}
+		if t.ProjectID != nil && s.workspaces != nil && slices.Contains(include, "project") {
+			key := jsonAPIIdentifier{typeProjects, strconv.Itoa(*t.ProjectID)}
+			if !seen[key] {
+				seen[key] = true
+				p, err := s.workspaces.Project(c.UserContext(), *t.ProjectID)
+				if err == nil {
+					included = append(included, jsonAPIResource{Type: typeProjects, ID: key.ID,
+						Attributes: attributes(p, fields[typeProjects], "id")})
+				} else if !hidden(err) {
+					return nil, s.serviceError(c, err, "Failed to fetch included project")
+				}
+			}
+		}
+	}
+	return included, nil
+}
+
+// hidden - ресурса нет или он не виден пользователю
+func hidden(err error) bool {
+	return errors.Is(err, storage.ErrNotFound) || errors.Is(err, service.ErrForbidden)
+}
+
+func taskResource(base string, t model.Task, fields []string) jsonAPIResource {
+	id := strconv.Itoa(t.ID)
+	rels := map[string]jsonAPIRelationship{
+		"owner":   relationship(typeUsers, t.OwnerID),
+		"parent":  relationship(typeTasks, t.ParentID),
+		"project": relationship(typeProjects, t.ProjectID),
+	}
+	if fields != nil {
+		for name := range rels {
+			if !slices.Contains(fields, name) {
+				delete(rels, name)
+			}
+		}
+	}
+	return jsonAPIResource{
+		Type:          typeTasks,
+		ID:            id,
+		Attributes:    attributes(t, fields, "id", "owner_id", "parent_id", "project_id"),
+		Relationships: rels,
+		Links:         map[string]string{"self": base + "/tasks/" + id},
+	}
+}
+
+func relationship(typ string, id *int) jsonAPIRelationship {
+	if id == nil {
+		return jsonAPIRelationship{}
+	}
+	return jsonAPIRelationship{Data: &jsonAPIIdentifier{typ, strconv.Itoa(*id)}}
+}
+
+// attributes - поля v в JSON без omit; fields, если задан, оставляет только перечисленные
+func attributes(v any, fields []string, omit ...string) map[string]any {
+	data, _ := json.Marshal(v)
+	var attrs map[string]any
+	_ = json.Unmarshal(data, &attrs)
+	for name := range attrs {
+		if slices.Contains(omit, name) || fields != nil && !slices.Contains(fields, name) {
+			delete(attrs, name)
+		}
+	}
+	return attrs
+}
+
+// sparseFields разбирает fields[тип]=a,b; ?fields=a,b относится к задачам
+func sparseFields(c *fiber.Ctx) map[string][]string {
+	fields := map[string][]string{}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		k := string(key)
+		typ := typeTasks
+		if k != "fields" {
+			inner, ok := strings.CutPrefix(k, "fields[")
+			if !ok || !strings.HasSuffix(inner, "]") {
+				return
+			}
+			typ = strings.TrimSuffix(inner, "]")
+		}
+		fields[typ] = append(fields[typ], strings.Split(string(value), ",")...)
+	})
+	return fields
+}
+
+// jsonAPIToTask переводит ресурс задачи из тела JSON:API в JSON модели,
+// чтобы дальше он разбирался так же строго, как обычное тело; ошибки - 400
+func jsonAPIToTask(body []byte) ([]byte, error) {
+	var doc struct {
+		Data *struct {
+			Type          string                         `json:"type"`
+			ID            string                         `json:"id"`
+			Attributes    map[string]any                 `json:"attributes"`
+			Relationships map[string]jsonAPIRelationship `json:"relationships"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, bodyError(err))
+	}
+	if doc.Data == nil || doc.Data.Type != typeTasks {
+		return nil, fiber.NewError(fiber.StatusBadRequest, `JSON:API body must contain data of type "tasks"`)
+	}
+
+	task := doc.Data.Attributes
+	if task == nil {
+		task = map[string]any{}
+	}
+	for name, rel := range doc.Data.Relationships {
+		if name != "parent" && name != "project" {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unknown relationship %q", name))
+		}
+		var id *int
+		if rel.Data != nil {
+			n, err := strconv.Atoi(rel.Data.ID)
+			if err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s id %q", name, rel.Data.ID))
+			}
+			id = &n
+		}
+		task[name+"_id"] = id
+	}
+	return json.Marshal(task)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+// link - переход по API: адрес и метод; у переходов статуса ещё и статус,
+// который надо передать в теле
+type link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+type taskLinks struct {
+	Self        link   `json:"self"`
+	Update      link   `json:"update"`
+	Delete      link   `json:"delete"`
+	History     *link  `json:"history,omitempty"`
+	Parent      *link  `json:"parent,omitempty"`
+	Project     *link  `json:"project,omitempty"`
+	Transitions []link `json:"transitions"`
+}
+
+// linkedTask - задача в ответе вместе с _links. Права в проекте не
+// проверяются: update у задачи из проекта только для чтения ответит 403.
+type linkedTask struct {
+	model.Task
+	Links taskLinks `json:"_links"`
+}
+
+// linkTask добавляет к задаче _links; base - префикс версии API
+func (s *Server) linkTask(base string, t model.Task) linkedTask {
+	self := base + "/tasks/" + strconv.Itoa(t.ID)
+	links := taskLinks{
+		Self:   link{Href: self},
+		Update: link{Href: self, Method: "PUT"},
+		Delete: link{Href: self, Method: "DELETE"},
+	}
+	if s.tasks.HistoryEnabled() {
+		links.History = &link{Href: self + "/history"}
+	}
+	if t.ParentID != nil {
+		links.Parent = &link{Href: base + "/tasks/" + strconv.Itoa(*t.ParentID)}
+	}
+	if t.ProjectID != nil && s.workspaces != nil {
+		links.Project = &link{Href: base + "/projects/" + strconv.Itoa(*t.ProjectID)}
+	}
+	for _, status := range model.Transitions(t.Status) {
+		links.Transitions = append(links.Transitions, link{Href: self, Method: "PUT", Status: status})
+	}
+	return linkedTask{Task: t, Links: links}
+}
+
+// AppendJSON - быстрый путь wire.MarshalJSON; вывод как у json.Marshal
+func (t linkedTask) AppendJSON(b []byte) []byte {
+	b = wire.AppendTaskJSON(b, t.Task)
+	b = append(b[:len(b)-1], `,"_links":{"self":`...)
+	b = t.Links.Self.appendJSON(b)
+	b = append(b, `,"update":`...)
+	b = t.Links.Update.appendJSON(b)
+	b = append(b, `,"delete":`...)
+	b = t.Links.Delete.appendJSON(b)
+	for _, l := range []struct {
+		name string
+		link *link
+	}{{"history", t.Links.History}, {"parent", t.Links.Parent}, {"project", t.Links.Project}} {
+		if l.link != nil {
+			b = append(b, `,"`+l.name+`":`...)
+			b = l.link.appendJSON(b)
+		}
+	}
+	b = append(b, `,"transitions":`...)
+	if t.Links.Transitions == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, l := range t.Links.Transitions {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = l.appendJSON(b)
+		}
+		b = append(b, ']')
+	}
+	return append(b, "}}"...)
+}
+
+func (l link) appendJSON(b []byte) []byte {
+	b = append(b, `{"href":`...)
+	b = wire.AppendJSONString(b, l.Href)
+	if l.Method != "" {
+		b = append(b, `,"method":`...)
+		b = wire.AppendJSONString(b, l.Method)
+	}
+	if l.Status != "" {
+		b = append(b, `,"status":`...)
+		b = wire.AppendJSONString(b, l.Status)
+	}
+	return append(b, '}')
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/config"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+func linkedTasks(n int) []any {
+	s := NewServer(config.Default(), service.NewTaskService(storage.NewMemory()))
+	parent, project := 1, 2
+	created := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	out := make([]any, n)
+	for i := range out {
+		t := model.Task{ID: i + 1, UID: "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", ProjectID: &project,
+			Title: "Buy <milk> & bread", Description: "По дороге домой", Status: model.Statuses[i%3],
+			CreatedAt: created, UpdatedAt: created}
+		if i%2 == 0 {
+			t.ParentID = &parent
+		}
+		out[i] = v1Task(s, apiV1.prefix, t)
+	}
+	return out
+}
+
+func TestLinkedTaskJSON(t *testing.T) {
+	tasks := linkedTasks(3)
+	want, err := json.Marshal(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := wire.MarshalJSON(tasks); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("MarshalJSON = %s, %v\nwant %s", got, err, want)
+	}
+}
+
+func BenchmarkMarshalLinkedTasks(b *testing.B) {
+	tasks := linkedTasks(1000)
+	for _, bc := range []struct {
+		name    string
+		marshal func(any) ([]byte, error)
+	}{{"encoding/json", json.Marshal}, {"wire", wire.MarshalJSON}} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var size int
+			for range b.N {
+				data, err := bc.marshal(tasks)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.SetBytes(int64(size))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+)
+
+// etags ставит ETag на ответы GET и HEAD (HEAD Fiber обслуживает
+// обработчиком GET и отбрасывает тело) и отвечает 304 на If-None-Match.
+// Стоит снаружи deprecationWarnings, чтобы хэш считался по итоговому телу.
+// ZIP-архивы пропускает: ради хэша потоковое тело пришлось бы держать в памяти.
+func etags() fiber.Handler {
+	return etag.New(etag.Config{Next: func(c *fiber.Ctx) bool {
+		return c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead ||
+			strings.Contains(c.Path(), "/export/archive")
+	}})
+}
+
+// allowOptions отвечает на OPTIONS списком методов адреса в Allow, не
+// требуя аутентификации. Preflight CORS сюда не доходит: его раньше
+// обрабатывает corsMiddleware.
+func (s *Server) allowOptions(c *fiber.Ctx) error {
+	if c.Method() != fiber.MethodOptions {
+		return c.Next()
+	}
+	methods := s.allowedMethods(c.Path())
+	if len(methods) == 0 {
+		return c.Next()
+	}
+	c.Set(fiber.HeaderAllow, strings.Join(methods, ", "))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// allowedMethods - методы всех маршрутов, под которые подходит path, в
+// порядке fiber.DefaultMethods; пусто, если адреса нет
+func (s *Server) allowedMethods(path string) []string {
+	var methods []string
+	for _, r := range s.app.GetRoutes(true) {
+		if r.Method != fiber.MethodOptions && matchRoute(r.Path, path) && !slices.Contains(methods, r.Method) {
+			methods = append(methods, r.Method)
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	methods = append(methods, fiber.MethodOptions)
+	slices.SortFunc(methods, func(a, b string) int {
+		return slices.Index(fiber.DefaultMethods, a) - slices.Index(fiber.DefaultMethods, b)
+	})
+	return methods
+}
+
+// matchRoute сравнивает path с шаблоном маршрута Fiber по сегментам:
+// :param - любой непустой сегмент, регистр и конечный / не важны, как в
+// роутере с настройками по умолчанию
+func matchRoute(pattern, path string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i, seg := range want {
+		if strings.HasPrefix(seg, ":") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if !strings.EqualFold(seg, got[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"html/template"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+// printPage - чек-лист для печати: браузер сам режет его на страницы A4,
+// повторяя шапку таблицы и не разрывая строки задач
+var printPage = template.Must(template.New("print").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+@page { size: A4; margin: 15mm; @bottom-right { content: counter(page) " / " counter(pages); font-size: 9pt; } }
+body { font-family: system-ui, sans-serif; font-size: 11pt; color: #000; max-width: 50rem; margin: 1rem auto; padding: 0 1rem; }
+h1 { font-size: 16pt; margin: 0 0 .2rem; }
+.meta { color: #555; font-size: 9pt; margin: 0 0 1rem; }
+h2 { font-size: 12pt; margin: 1.2rem 0 .3rem; border-bottom: 1px solid #000; break-after: avoid-page; }
+table { width: 100%; border-collapse: collapse; }
+thead { display: table-header-group; }
+th { text-align: left; font-size: 9pt; color: #555; font-weight: normal; }
+td, th { padding: .25rem .3rem; vertical-align: top; border-bottom: 1px solid #ccc; }
+tr { break-inside: avoid-page; }
+.box { width: 1.2rem; font-size: 13pt; line-height: 1; }
+.due, .status { white-space: nowrap; width: 1%; }
+.done .title { text-decoration: line-through; color: #555; }
+.description { white-space: pre-wrap; color: #555; font-size: 9pt; margin: .1rem 0 0; }
+@media print { body { margin: 0; max-width: none; padding: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Count}} tasks &middot; printed {{.PrintedAt.Format "2 January 2006 15:04 MST"}}</p>
+{{range .Groups}}<section>
+<h2>{{.Title}} ({{len .Tasks}})</h2>
+<table>
+<thead><tr><th class="box"></th><th>Task</th><th class="due">Due</th><th class="status">Status</th></tr></thead>
+<tbody>
+{{range .Tasks}}<tr class="{{.Status}}"><td class="box">{{if eq .Status "done"}}&#9745;{{else}}&#9744;{{end}}</td>
+<td><span class="title">{{.Title}}</span>{{if .Description}}<p class="description">{{.Description}}</p>{{end}}</td>
+<td class="due">{{with .DueAt}}{{($.Local .).Format "2 Jan 15:04"}}{{end}}</td><td class="status">{{.Status}}</td></tr>
+{{end}}</tbody>
+</table>
+</section>
+{{else}}<p>Nothing to print.</p>
+{{end}}</body>
+</html>
+`))
+
+// printView - данные printPage
+type printView struct {
+	Title     string
+	Count     int
+	PrintedAt time.Time
+	Groups    []service.PrintGroup
+	loc       *time.Location
+}
+
+// Local переводит срок в часовой пояс листа
+func (v printView) Local(t *time.Time) time.Time { return t.In(v.loc) }
+
+// printTasks - GET /tasks/print: задачи пользователя для печати,
+// ?group=status|due, ?status=, ?tz=Europe/Moscow
+func (s *Server) printTasks(c *fiber.Ctx) error {
+	tasks, err := s.tasks.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch tasks")
+	}
+	if !c.QueryBool("snoozed") {
+		tasks = s.tasks.Awake(tasks)
+	}
+	return s.writePrint(c, "My tasks", tasks)
+}
+
+// printProject - GET /projects/:id/print с теми же параметрами
+func (s *Server) printProject(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	project, tasks, err := s.workspaces.ProjectTasks(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to fetch project tasks")
+	}
+	return s.writePrint(c, project.Name, tasks)
+}
+
+func (s *Server) writePrint(c *fiber.Ctx, title string, tasks []model.Task) error {
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid tz, expected an IANA time zone like Europe/Berlin")
+		}
+		loc = l
+	}
+	now := time.Now()
+	groups, err := service.PrintGroups(tasks,
+		service.PrintOptions{GroupBy: c.Query("group"), Status: c.Query("status"), Location: loc}, now)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to print tasks")
+	}
+	count := 0
+	for _, g := range groups {
+		count += len(g.Tasks)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'")
+	c.Type("html", "utf-8")
+	return printPage.Execute(c.Response().BodyWriter(),
+		printView{Title: title, Count: count, PrintedAt: now.In(loc), Groups: groups, loc: loc})
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// Предел размера загружаемого аватара
+const maxAvatarBytes = 2 << 20
+
+// profileResponse дополняет пользователя вычисляемым адресом аватара
+type profileResponse struct {
+	model.User
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+func newProfileResponse(u model.User) profileResponse {
+	return profileResponse{User: u, AvatarURL: u.AvatarURL()}
+}
+
+func (s *Server) getMe(c *fiber.Ctx) error {
+	u, err := s.profiles.Me(c.UserContext())
+	if err != nil {
+		return s.profileError(c, err, "Failed to load profile")
+	}
+	return c.JSON(newProfileResponse(u))
+}
+
+func (s *Server) updateMe(c *fiber.Ctx) error {
+	var in service.ProfileUpdate
+	if err := parseBody(c, &in); err != nil {
+		return err
+	}
+
+	u, err := s.profiles.Update(c.UserContext(), in)
+	if err != nil {
+		return s.profileError(c, err, "Failed to update profile")
+	}
+	return c.JSON(newProfileResponse(u))
+}
+
+// uploadAvatar принимает multipart-поле "avatar" с картинкой JPEG, PNG или GIF
+func (s *Server) uploadAvatar(c *fiber.Ctx) error {
+	fh, err := c.FormFile("avatar")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, `Missing "avatar" file`)
+	}
+	if fh.Size > maxAvatarBytes {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "Avatar is too large")
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid avatar file")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxAvatarBytes))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid avatar file")
+	}
+
+	u, err := s.profiles.SetAvatar(c.UserContext(), data)
+	if err != nil {
+		return s.profileError(c, err, "Failed to save avatar")
+	}
+	return c.JSON(newProfileResponse(u))
+}
+
+func (s *Server) deleteAvatar(c *fiber.Ctx) error {
+	if err := s.profiles.DeleteAvatar(c.UserContext()); err != nil {
+		return s.profileError(c, err, "Failed to delete avatar")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) getAvatar(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	data, contentType, err := s.profiles.Avatar(c.UserContext(), id)
+	if errors.Is(err, service.ErrNoAvatar) {
+		return fiber.NewError(fiber.StatusNotFound, "Avatar not found")
+	}
+	if err != nil {
+		return s.profileError(c, err, "Failed to load avatar")
+	}
+
+	// URL меняется вместе с картинкой, так что кешировать можно надолго
+	if c.Query("v") != "" {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(data)
+}
+
+func (s *Server) profileError(c *fiber.Ctx, err error, msg string) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	return s.serviceError(c, err, msg)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type tierRequest struct {
+	// Tier - имя тарифа; для ключа пусто - тариф владельца
+	Tier string `json:"tier"`
+}
+
+func (s *Server) listRateLimitTiers(c *fiber.Ctx) error {
+	tiers, err := s.rateLimits.Tiers(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch rate limit tiers")
+	}
+	return c.JSON(tiers)
+}
+
+func (s *Server) saveRateLimitTier(c *fiber.Ctx) error {
+	var tier model.RateLimitTier
+	if err := parseBody(c, &tier); err != nil {
+		return err
+	}
+	tier.Name = c.Params("name")
+
+	if err := s.rateLimits.SaveTier(c.UserContext(), tier); err != nil {
+		return s.serviceError(c, err, "Failed to save rate limit tier")
+	}
+	return c.JSON(tier)
+}
+
+func (s *Server) setUserRateLimitTier(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+	var req tierRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	err = s.rateLimits.SetUserTier(c.UserContext(), id, req.Tier)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to set rate limit tier")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) setAPIKeyRateLimitTier(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key id")
+	}
+	var req tierRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	err = s.rateLimits.SetAPIKeyTier(c.UserContext(), id, req.Tier)
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "API key not found")
+	}
+	if err != nil {
+		return s.serviceError(c, err, "Failed to set rate limit tier")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getRateLimitUsage - запросы и отказы по пользователям и ключам: ?days=N
+func (s *Server) getRateLimitUsage(c *fiber.Ctx) error {
+	usage, err := s.rateLimits.Usage(c.UserContext(), c.QueryInt("days"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch rate limit usage")
+	}
+	return c.JSON(usage)
+}
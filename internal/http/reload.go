@@ -0,0 +1,91 @@
+package http
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/config"
+)
+
+var errReloadDisabled = errors.New("configuration reload is not configured")
+
+// WithConfigReload - откуда Reload берёт свежие настройки, обычно config.Load
+func WithConfigReload(load func() (config.Config, error)) Option {
+	return func(s *Server) { s.reload = load }
+}
+
+// Reload перечитывает настройки и применяет config.Reloadable на ходу:
+// соединения и запросы в работе не прерываются
+func (s *Server) Reload() (config.Reloadable, error) {
+	if s.reload == nil {
+		return config.Reloadable{}, errReloadDisabled
+	}
+	cfg, err := s.reload()
+	if err != nil {
+		return config.Reloadable{}, err
+	}
+	if level, err := zerolog.ParseLevel(cfg.LogLevel); err == nil && cfg.LogLevel != "" {
+		zerolog.SetGlobalLevel(level)
+	}
+	s.applyReloadable(cfg.Reloadable)
+	// тарифы тоже перечитываем, чтобы их правка в базе не ждала кэша
+	if s.limiter != nil {
+		s.limiter.ReloadTiers()
+	}
+	s.log.Info().Str("log_level", cfg.LogLevel).Bool("rate_limits_disabled", cfg.RateLimitsDisabled).
+		Strs("cors_origins", cfg.CORSOrigins).Msg("Configuration reloaded")
+	return cfg.Reloadable, nil
+}
+
+func (s *Server) applyReloadable(r config.Reloadable) {
+	s.live.Store(&r)
+	if s.limiter != nil {
+		s.limiter.SetDisabled(r.RateLimitsDisabled)
+	}
+}
+
+// corsMiddleware пускает в API страницы из CORSOrigins, список читается на
+// каждый запрос. У виджета свой CORS для всех страниц, его не трогаем.
+func (s *Server) corsMiddleware() fiber.Handler {
+	return cors.New(cors.Config{
+		Next: func(c *fiber.Ctx) bool {
+			path := c.Path()
+			for _, v := range apiVersions {
+				if v.prefix != "" {
+					path = strings.TrimPrefix(path, v.prefix)
+				}
+			}
+			return strings.HasPrefix(path, "/widget/")
+		},
+		AllowOriginsFunc: func(origin string) bool {
+			return slices.Contains(s.live.Load().CORSOrigins, origin)
+		},
+		AllowCredentials: true,
+		AllowHeaders: strings.Join([]string{fiber.HeaderAuthorization, fiber.HeaderContentType,
+			fiber.HeaderIfNoneMatch, fiber.HeaderIfModifiedSince, auth.APIKeyHeader}, ","),
+		ExposeHeaders: strings.Join([]string{fiber.HeaderETag, fiber.HeaderLink, fiber.HeaderRetryAfter,
+			"Deprecation", "Sunset", HeaderTotalCount, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}, ","),
+	})
+}
+
+func (s *Server) getConfig(c *fiber.Ctx) error {
+	return c.JSON(s.live.Load())
+}
+
+func (s *Server) reloadConfig(c *fiber.Ctx) error {
+	r, err := s.Reload()
+	if errors.Is(err, errReloadDisabled) {
+		return fiber.NewError(fiber.StatusNotImplemented, "Configuration reload is not configured")
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to reload configuration")
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Failed to reload configuration: "+err.Error())
+	}
+	return c.JSON(r)
+}
@@ -0,0 +1,474 @@
+// Package http - REST API поверх Fiber
+package http
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/config"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/ratelimit"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+// Server держит все зависимости HTTP-обработчиков
+type Server struct {
+	cfg        config.Config
+	tasks      *service.TaskService
+	users      *service.AuthService
+	profiles   *service.ProfileService
+	workspaces *service.WorkspaceService
+	shares     *service.ShareService
+	webhooks   *service.WebhookService
+	outbound   *service.OutboundWebhookService
+	github     *service.GitHubSync
+	jira       *service.JiraSync
+	calendar   *service.CalendarSync
+	microsoft  *service.MicrosoftImport
+	apiKeys    auth.TokenLookup
+	authn      []auth.Authenticator
+	limiter    *ratelimit.Limiter
+	rateLimits *service.RateLimitService
+	deprecated deprecations
+	// live - настройки, которые Reload меняет без перезапуска
+	live   atomic.Pointer[config.Reloadable]
+	reload func() (config.Config, error)
+	log    zerolog.Logger
+	app    *fiber.App
+}
+
+type Option func(*Server)
+
+func WithLogger(logger zerolog.Logger) Option {
+	return func(s *Server) { s.log = logger }
+}
+
+// WithAuthService включает регистрацию, вход и управление API-ключами
+func WithAuthService(users *service.AuthService) Option {
+	return func(s *Server) { s.users = users }
+}
+
+// WithProfiles включает /me и аватары пользователей
+func WithProfiles(profiles *service.ProfileService) Option {
+	return func(s *Server) { s.profiles = profiles }
+}
+
+// WithWorkspaces включает рабочие пространства, команды и проекты
+func WithWorkspaces(workspaces *service.WorkspaceService) Option {
+	return func(s *Server) { s.workspaces = workspaces }
+}
+
+// WithShares включает публичные ссылки на задачи и проекты
+func WithShares(shares *service.ShareService) Option {
+	return func(s *Server) { s.shares = shares }
+}
+
+// WithWebhooks включает входящие вебхуки для создания задач
+func WithWebhooks(webhooks *service.WebhookService) Option {
+	return func(s *Server) { s.webhooks = webhooks }
+}
+
+// WithOutboundWebhooks включает исходящие вебхуки с журналом доставок
+func WithOutboundWebhooks(outbound *service.OutboundWebhookService) Option {
+	return func(s *Server) { s.outbound = outbound }
+}
+
+// WithGitHub включает связь проектов с репозиториями GitHub
+func WithGitHub(github *service.GitHubSync) Option {
+	return func(s *Server) { s.github = github }
+}
+
+// WithJira включает связь проектов с Jira
+func WithJira(jira *service.JiraSync) Option {
+	return func(s *Server) { s.jira = jira }
+}
+
+// WithCalendar включает синхронизацию задач со сроком с Google Calendar
+func WithCalendar(calendar *service.CalendarSync) Option {
+	return func(s *Server) { s.calendar = calendar }
+}
+
+// WithMicrosoftImport включает перенос задач из Microsoft To Do
+func WithMicrosoftImport(m *service.MicrosoftImport) Option {
+	return func(s *Server) { s.microsoft = m }
+}
+
+// WithAPIKeyLookup включает эндпоинты для встраивания (виджет, ленты), где ключ
+// передаётся параметром ?token=, раз заголовок из iframe не послать
+func WithAPIKeyLookup(lookup auth.TokenLookup) Option {
+	return func(s *Server) { s.apiKeys = lookup }
+}
+
+// WithAuthenticators задаёт цепочку схем аутентификации в порядке проверки
+func WithAuthenticators(authenticators ...auth.Authenticator) Option {
+	return func(s *Server) { s.authn = authenticators }
+}
+
+// WithRateLimits включает ограничение частоты по тарифам для всех схем
+// аутентификации и /admin для управления тарифами и отчёта
+func WithRateLimits(limiter *ratelimit.Limiter, admin *service.RateLimitService) Option {
+	return func(s *Server) { s.limiter, s.rateLimits = limiter, admin }
+}
+
+func NewServer(cfg config.Config, tasks *service.TaskService, opts ...Option) *Server {
+	s := &Server{
+		cfg:   cfg,
+		tasks: tasks,
+		log:   zerolog.Nop(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.applyReloadable(cfg.Reloadable)
+
+	s.app = fiber.New(fiber.Config{
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		BodyLimit:    cfg.MaxBodyBytes,
+		JSONEncoder:  wire.MarshalJSON,
+	})
+	// проверка живости для watchdog systemd и балансировщиков: до всех
+	// middleware, без аутентификации, лимитов и версий
+	s.app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	s.app.Use(s.corsMiddleware())
+	s.app.Use(s.allowOptions)
+	s.app.Use(etags())
+	s.app.Use(deprecationWarnings)
+	for _, v := range apiVersions {
+		s.routes(s.app.Group(v.prefix, v.use))
+	}
+	// прежние адреса без версии, чтобы не сломать существующих клиентов
+	s.app.Use(s.legacyRoutes)
+	s.routes(s.app)
+	return s
+}
+
+// routes регистрирует маршруты на r: по разу для каждой версии и для
+// адресов без версии
+func (s *Server) routes(r fiber.Router) {
+	authenticators := s.authn
+	if s.limiter != nil {
+		authenticators = s.limiter.Wrap(authenticators...)
+	}
+	authn := auth.Middleware(authenticators...)
+	// одна группа на все /admin: middleware второй группы с тем же префиксом
+	// выполнился бы повторно
+	admin := r.Group("/admin", authn, auth.RequireRole(model.RoleAdmin))
+
+	if s.users != nil {
+		r.Post("/auth/register", s.register)
+		r.Post("/auth/login", s.login)
+		r.Post("/auth/login/2fa", s.loginSecondFactor)
+		r.Post("/auth/magic-link", s.requestMagicLink)
+		r.Get("/auth/magic-link/verify", s.verifyMagicLink)
+		r.Post("/auth/refresh", s.refresh)
+		r.Post("/auth/logout", authn, s.logout)
+
+		sessions := r.Group("/auth/sessions", authn)
+		sessions.Get("", s.listSessions)
+		sessions.Delete("/:id", s.revokeSession)
+
+		totp := r.Group("/auth/2fa", authn)
+		totp.Post("/enroll", s.enrollTOTP)
+		totp.Post("/enable", s.enableTOTP)
+		totp.Post("/disable", s.disableTOTP)
+
+		admin.Delete("/users/:id/2fa", s.resetTOTP)
+
+		keys := r.Group("/auth/api-keys", authn)
+		keys.Post("", s.createAPIKey)
+		keys.Get("", s.listAPIKeys)
+		keys.Delete("/:id", s.deleteAPIKey)
+	}
+
+	if s.profiles != nil {
+		me := r.Group("/me", authn)
+		me.Get("", s.getMe)
+		me.Patch("", s.updateMe)
+		me.Post("/avatar", s.uploadAvatar)
+		me.Delete("/avatar", s.deleteAvatar)
+
+		r.Get("/users/:id/avatar", authn, s.getAvatar)
+	}
+
+	if s.workspaces != nil {
+		ws := r.Group("/workspaces", authn)
+		ws.Post("", s.createWorkspace)
+		ws.Get("", s.listWorkspaces)
+		ws.Get("/:id", s.getWorkspace)
+		ws.Get("/:id/members", s.listWorkspaceMembers)
+		ws.Put("/:id/members", s.setWorkspaceMember)
+		ws.Delete("/:id/members/:userID", s.removeWorkspaceMember)
+		ws.Post("/:id/teams", s.createTeam)
+		ws.Get("/:id/teams", s.listTeams)
+		ws.Post("/:id/projects", s.createProject)
+		ws.Get("/:id/projects", s.listProjects)
+		ws.Post("/:id/invites", s.createInvite)
+		ws.Get("/:id/invites", s.listInvites)
+		ws.Delete("/:id/invites/:inviteID", s.revokeInvite)
+		ws.Post("/:id/snapshots", s.createSnapshot)
+		ws.Get("/:id/snapshots", s.listSnapshots)
+		ws.Post("/:id/restore", s.restoreWorkspace)
+
+		export := r.Group("/export/archive", authn)
+		export.Get("", s.exportArchive)
+		export.Get("/jobs/:id", s.getArchiveJob)
+		export.Get("/jobs/:id/download", s.downloadArchive)
+
+		invites := r.Group("/invites", authn)
+		invites.Get("/:token", s.getInvite)
+		invites.Post("/:token/accept", s.acceptInvite)
+
+		teams := r.Group("/teams", authn)
+		teams.Get("/:id", s.getTeam)
+		teams.Get("/:id/members", s.listTeamMembers)
+		teams.Put("/:id/members", s.setTeamMember)
+		teams.Delete("/:id/members/:userID", s.removeTeamMember)
+		teams.Get("/:id/tasks", s.listTeamTasks)
+		teams.Get("/:id/board", s.getTeamBoard)
+
+		projects := r.Group("/projects", authn)
+		projects.Get("/:id", s.getProject)
+		projects.Patch("/:id", s.updateProject)
+		projects.Get("/:id/board", s.getProjectBoard)
+		projects.Get("/:id/stats", s.getProjectStats)
+		projects.Get("/:id/print", s.printProject)
+		projects.Get("/:id/members", s.listProjectMembers)
+		projects.Put("/:id/members", s.setProjectMember)
+		projects.Delete("/:id/members/:userID", s.removeProjectMember)
+
+		if s.github != nil {
+			projects.Post("/:id/github", s.linkGitHub)
+			projects.Get("/:id/github", s.getGitHubLink)
+			projects.Delete("/:id/github", s.unlinkGitHub)
+
+			r.Post("/integrations/github/:id", s.receiveGitHubWebhook)
+		}
+		if s.jira != nil {
+			projects.Post("/:id/jira", s.linkJira)
+			projects.Get("/:id/jira", s.getJiraLink)
+			projects.Delete("/:id/jira", s.unlinkJira)
+
+			r.Post("/integrations/jira/:id", s.receiveJiraWebhook)
+		}
+	}
+
+	admin.Get("/deprecations", s.getDeprecations)
+	admin.Get("/config", s.getConfig)
+	admin.Post("/config/reload", s.reloadConfig)
+
+	if s.rateLimits != nil {
+		admin.Get("/rate-limits/tiers", s.listRateLimitTiers)
+		admin.Put("/rate-limits/tiers/:name", s.saveRateLimitTier)
+		admin.Get("/rate-limits/usage", s.getRateLimitUsage)
+		admin.Put("/users/:id/rate-limit", s.setUserRateLimitTier)
+		admin.Put("/api-keys/:id/rate-limit", s.setAPIKeyRateLimitTier)
+	}
+
+	if s.shares != nil {
+		shares := r.Group("/shares", authn)
+		shares.Post("", s.createShare)
+		shares.Get("", s.listShares)
+		shares.Delete("/:id", s.revokeShare)
+
+		r.Get("/public/:token", s.openShare)
+	}
+
+	if s.webhooks != nil {
+		hooks := r.Group("/webhooks/inbound", authn)
+		hooks.Post("", s.createInboundWebhook)
+		hooks.Get("", s.listInboundWebhooks)
+		hooks.Delete("/:id", s.deleteInboundWebhook)
+
+		r.Post("/hooks/:token", s.receiveWebhook)
+	}
+
+	if s.outbound != nil {
+		hooks := r.Group("/webhooks/outbound", authn)
+		hooks.Post("", s.createOutboundWebhook)
+		hooks.Get("", s.listOutboundWebhooks)
+		hooks.Delete("/:id", s.deleteOutboundWebhook)
+		hooks.Get("/:id/deliveries", s.listWebhookDeliveries)
+		hooks.Get("/:id/deliveries/:deliveryID", s.getWebhookDelivery)
+		hooks.Post("/:id/deliveries/:deliveryID/redeliver", s.redeliverWebhook)
+	}
+
+	if s.calendar != nil {
+		calendar := r.Group("/calendar/google", authn)
+		calendar.Get("", s.getCalendar)
+		calendar.Post("/connect", s.connectCalendar)
+		calendar.Put("", s.updateCalendar)
+		calendar.Delete("", s.disconnectCalendar)
+
+		r.Get("/integrations/google/callback", s.calendarCallback)
+	}
+
+	if s.microsoft != nil {
+		r.Post("/import/microsoft", authn, s.importMicrosoft)
+	}
+
+	if s.apiKeys != nil {
+		// Виджет читают с чужих страниц: CORS для всех, но без cookie
+		query := []auth.Authenticator{auth.NewAPIKeyQuery(s.apiKeys, "token")}
+		if s.limiter != nil {
+			query = s.limiter.Wrap(query...)
+		}
+		authenticators := append(query, authenticators...)
+		widget := r.Group("/widget",
+			cors.New(cors.Config{AllowOrigins: "*", AllowMethods: "GET", AllowHeaders: auth.APIKeyHeader}),
+			auth.ScopedMiddleware(auth.ScopeWidget, authenticators...))
+		widget.Get("/tasks", s.getWidget)
+		widget.Get("/embed", s.getWidgetEmbed)
+
+		// Ленты забирают читалки, которые умеют только GET по URL
+		feeds := r.Group("/feeds", auth.ScopedMiddleware(auth.ScopeFeed, authenticators...))
+		feeds.Get("/tasks", s.getTaskFeed)
+		if s.workspaces != nil {
+			feeds.Get("/projects/:id", s.getProjectFeed)
+		}
+	}
+
+	tasks := r.Group("/tasks", authn)
+	tasks.Post("", s.createTask)
+	tasks.Get("", s.getTasks)
+	tasks.Get("/stats", s.getTaskStats)
+	tasks.Get("/count", s.countTasks)
+	tasks.Get("/search", s.searchTasks)
+	tasks.Get("/nearby", s.getNearbyTasks)
+	tasks.Get("/pinned", s.getPinnedTasks)
+	tasks.Get("/print", s.printTasks)
+	tasks.Get("/:id", s.getTaskByID)
+	tasks.Get("/:id/history", s.getTaskHistory)
+	tasks.Put("/:id", s.updateTask)
+	tasks.Post("/:id/snooze", s.snoozeTask)
+	tasks.Delete("/:id/snooze", s.unsnoozeTask)
+	tasks.Post("/:id/pin", s.pinTask)
+	tasks.Delete("/:id/pin", s.unpinTask)
+	tasks.Post("/:id/delegation", s.delegateTask)
+	tasks.Get("/:id/delegation", s.getTaskDelegation)
+	tasks.Delete("/:id/delegation", s.cancelDelegation)
+	tasks.Post("/:id/delegation/submit", s.submitDelegation)
+	tasks.Post("/:id/delegation/approve", s.approveDelegation)
+	tasks.Post("/:id/delegation/reject", s.rejectDelegation)
+	tasks.Delete("/:id", s.deleteTask)
+
+	r.Get("/delegations", authn, s.listDelegations)
+
+	sync := r.Group("/sync", authn)
+	sync.Post("", s.syncTasks)
+	sync.Get("/conflicts", s.listConflicts)
+	sync.Delete("/conflicts/:id", s.resolveConflict)
+}
+
+// App отдаёт Fiber-приложение, например для app.Test или встраивания
+func (s *Server) App() *fiber.App { return s.app }
+
+func (s *Server) Listen() error { return s.app.Listen(s.cfg.Addr) }
+
+func (s *Server) Shutdown() error { return s.app.Shutdown() }
+
+// serviceError переводит ошибку сервиса или хранилища в HTTP-ответ
+func (s *Server) serviceError(c *fiber.Ctx, err error, msg string) error {
+	var (
+		unavailable *storage.UnavailableError
+		invalid     *service.ValidationError
+		quota       *service.QuotaError
+		duplicate   *service.DuplicateError
+	)
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	case errors.As(err, &invalid):
+		return fiber.NewError(fiber.StatusBadRequest, invalid.Error())
+	case errors.As(err, &quota):
+		// клиенту нужна не только фраза, но и сама квота, чтобы показать её
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Quota exceeded", "quota": quota.Quota, "limit": quota.Limit, "used": quota.Used})
+	case errors.As(err, &duplicate):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":      "Possible duplicate, repeat with ?force=true to create anyway",
+			"candidates": s.present(c, renderDescriptions(c, duplicate.Candidates))})
+	case errors.Is(err, service.ErrInvalidParent):
+		return fiber.NewError(fiber.StatusBadRequest, "Parent task not found")
+	case errors.Is(err, service.ErrHistoryDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Task history is not enabled")
+	case errors.Is(err, service.ErrLocationsDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Nearby search is not enabled")
+	case errors.Is(err, service.ErrSnoozeDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Snooze is not enabled")
+	case errors.Is(err, service.ErrPinsDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Pinning is not enabled")
+	case errors.Is(err, service.ErrDelegationsDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Delegation is not enabled")
+	case errors.Is(err, service.ErrReviewRequired):
+		return fiber.NewError(fiber.StatusConflict, "Task is delegated, submit it for the delegator's review instead")
+	case errors.Is(err, service.ErrDelegationState):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, storage.ErrInvalidDelegate):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrSyncDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Delta sync is not enabled")
+	case errors.Is(err, storage.ErrInvalidSyncToken):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid sync token, sync again without one")
+	case errors.Is(err, service.ErrSnapshotsDisabled), errors.Is(err, service.ErrPointInTimeDisabled),
+		errors.Is(err, service.ErrArchivesDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, err.Error())
+	case errors.Is(err, service.ErrArchiveNotReady):
+		return fiber.NewError(fiber.StatusConflict, "Archive is not ready yet")
+	case errors.Is(err, service.ErrMagicLinkDisabled):
+		return fiber.NewError(fiber.StatusNotImplemented, "Magic-link login is not configured")
+	case errors.Is(err, service.ErrInvalidCode):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid verification code")
+	case errors.Is(err, service.ErrTooManyAttempts):
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed attempts, try again later")
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled), errors.Is(err, service.ErrTOTPNotEnabled):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, storage.ErrUnknownTier):
+		return fiber.NewError(fiber.StatusBadRequest, "Unknown rate limit tier")
+	case errors.Is(err, storage.ErrTaskUIDTaken):
+		return fiber.NewError(fiber.StatusConflict, "Task with this uid already exists")
+	case errors.Is(err, storage.ErrInvalidProject):
+		return fiber.NewError(fiber.StatusBadRequest, "Project not found")
+	case errors.Is(err, storage.ErrReadOnly):
+		return fiber.NewError(fiber.StatusForbidden, "Project is read-only for you")
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
+	case errors.Is(err, service.ErrNotWorkspaceMember):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrInvalidOAuthState):
+		return fiber.NewError(fiber.StatusBadRequest, "Authorization link expired, please try again")
+	case errors.Is(err, service.ErrInvalidSignature):
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid webhook signature")
+	case errors.Is(err, service.ErrInvalidWebhook):
+		return fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+	case errors.Is(err, service.ErrInvalidShareLink):
+		return fiber.NewError(fiber.StatusNotFound, "Link not found or expired")
+	case errors.Is(err, service.ErrInvalidInvite):
+		return fiber.NewError(fiber.StatusNotFound, "Invite not found or expired")
+	case errors.Is(err, service.ErrInviteEmailMismatch):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrOwnerImmutable):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrOpenSubtasks):
+		return fiber.NewError(fiber.StatusConflict, "Task has open subtasks")
+	case errors.Is(err, storage.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Task not found")
+	case errors.As(err, &unavailable):
+		s.log.Warn().Err(err).Msg(msg)
+		retry := int(math.Ceil(unavailable.RetryAfter.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(retry, 1)))
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Service temporarily unavailable")
+	default:
+		s.log.Error().Err(err).Msg(msg)
+		return fiber.NewError(fiber.StatusInternalServerError, msg)
+	}
+}
@@ -0,0 +1,59 @@
+package http_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/testutil"
+)
+
+func TestTaskCRUD(t *testing.T) {
+	t.Parallel()
+	h := testutil.NewServer(t, nil).AsUser(1)
+
+	var created model.Task
+	h.Post("/tasks", map[string]string{"title": "Buy milk", "status": "todo"}).
+		AssertStatus(fiber.StatusCreated).
+		AssertJSON(`{"title":"Buy milk","status":"todo"}`).
+		DecodeJSON(&created)
+
+	path := fmt.Sprintf("/tasks/%d", created.ID)
+	h.Get(path).
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"title":"Buy milk","status":"todo"}`)
+
+	h.Put(path, map[string]string{"title": "Buy oat milk", "status": "done"}).
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(fmt.Sprintf(`{"id":%d,"title":"Buy oat milk","status":"done"}`, created.ID))
+
+	h.Get("/tasks").
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(`[{"title":"Buy oat milk"}]`)
+
+	h.Delete(path).AssertStatus(fiber.StatusNoContent)
+	h.Get(path).AssertStatus(fiber.StatusNotFound)
+}
+
+func TestTasksScopedToOwner(t *testing.T) {
+	t.Parallel()
+	srv := testutil.NewServer(t, nil)
+	alice, bob := srv.AsUser(1), srv.AsUser(2)
+
+	var task model.Task
+	alice.Post("/tasks", map[string]string{"title": "Alice's task", "status": "todo"}).
+		AssertStatus(fiber.StatusCreated).
+		AssertJSON(`{"owner_id":1}`).
+		DecodeJSON(&task)
+
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+	bob.Get(path).AssertStatus(fiber.StatusNotFound)
+	bob.Put(path, map[string]string{"title": "Hijacked", "status": "done"}).AssertStatus(fiber.StatusNotFound)
+	bob.Get("/tasks").AssertStatus(fiber.StatusOK).AssertJSON(`null`)
+	alice.Get("/tasks").AssertJSON(`[{"title":"Alice's task"}]`)
+
+	srv.Get("/tasks").AssertStatus(fiber.StatusUnauthorized)
+	srv.WithToken("not-a-jwt").Get("/tasks").AssertStatus(fiber.StatusUnauthorized)
+}
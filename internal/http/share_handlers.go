@@ -0,0 +1,89 @@
+package http
+
+import (
+	"html/template"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+var sharedPage = template.Must(template.New("shared").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+li { margin: .5rem 0; list-style: none; }
+.status { font-size: .8rem; padding: .1rem .4rem; border-radius: .3rem; background: #eee; }
+.done .title { text-decoration: line-through; color: #888; }
+.description { white-space: pre-wrap; color: #555; margin: .2rem 0 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{define "task"}}<li class="{{.Status}}"><span class="status">{{.Status}}</span> <span class="title">{{.Title}}</span>
+{{if .Description}}<p class="description">{{.Description}}</p>{{end}}</li>{{end}}
+<ul>
+{{with .Task}}{{template "task" .}}{{end}}
+{{range .Tasks}}{{template "task" .}}{{end}}
+</ul>
+{{with .ExpiresAt}}<p><small>This link expires on {{.Format "2006-01-02 15:04 MST"}}.</small></p>{{end}}
+</body>
+</html>
+`))
+
+func (s *Server) createShare(c *fiber.Ctx) error {
+	var req service.ShareRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	link, err := s.shares.Create(c.UserContext(), req)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to create share link")
+	}
+	return c.Status(fiber.StatusCreated).JSON(link)
+}
+
+func (s *Server) listShares(c *fiber.Ctx) error {
+	links, err := s.shares.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch share links")
+	}
+	return c.JSON(links)
+}
+
+func (s *Server) revokeShare(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid share link id")
+	}
+
+	if err := s.shares.Revoke(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "Share link not found", "Failed to revoke share link")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// openShare отдаёт содержимое публичной ссылки: HTML для браузера
+// (Accept: text/html или ?format=html), иначе JSON
+func (s *Server) openShare(c *fiber.Ctx) error {
+	view, err := s.shares.Open(c.UserContext(), c.Params("token"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to open share link")
+	}
+
+	c.Set("X-Robots-Tag", "noindex")
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	if c.Query("format") != "html" && c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) != fiber.MIMETextHTML {
+		return c.JSON(view)
+	}
+
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'")
+	c.Type("html", "utf-8")
+	return sharedPage.Execute(c.Response().BodyWriter(), view)
+}
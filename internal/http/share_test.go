@@ -0,0 +1,96 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	apihttp "github.com/Upiter5/todo-app/internal/http"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+	"github.com/Upiter5/todo-app/internal/testutil"
+)
+
+// memoryShares - ShareStore в памяти без сроков действия
+type memoryShares struct {
+	links map[string]model.ShareLink
+}
+
+func (m *memoryShares) CreateShareLink(_ context.Context, l *model.ShareLink, hash string) error {
+	l.ID = len(m.links) + 1
+	m.links[hash] = *l
+	return nil
+}
+
+func (m *memoryShares) ListShareLinks(_ context.Context, userID int) ([]model.ShareLink, error) {
+	var out []model.ShareLink
+	for _, l := range m.links {
+		if l.UserID == userID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryShares) RevokeShareLink(_ context.Context, userID, id int) error {
+	for hash, l := range m.links {
+		if l.UserID == userID && l.ID == id {
+			delete(m.links, hash)
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func (m *memoryShares) ShareLinkByToken(_ context.Context, hash string) (model.ShareLink, error) {
+	l, ok := m.links[hash]
+	if !ok {
+		return model.ShareLink{}, storage.ErrNotFound
+	}
+	return l, nil
+}
+
+func TestShareLink(t *testing.T) {
+	t.Parallel()
+	tasks := storage.NewMemory()
+	shares := service.NewShareService(&memoryShares{links: map[string]model.ShareLink{}}, tasks, nil, "")
+	srv := testutil.NewServer(t, tasks, apihttp.WithShares(shares))
+	alice := srv.AsUser(1)
+
+	var task model.Task
+	alice.Post("/tasks", map[string]string{"title": "Plan <trip>", "status": "todo"}).
+		AssertStatus(fiber.StatusCreated).
+		DecodeJSON(&task)
+
+	srv.AsUser(2).Post("/shares", map[string]int{"task_id": task.ID}).AssertStatus(fiber.StatusNotFound)
+
+	var link model.ShareLink
+	alice.Post("/shares", map[string]int{"task_id": task.ID}).
+		AssertStatus(fiber.StatusCreated).
+		DecodeJSON(&link)
+	u, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Get(u.Path).
+		AssertStatus(fiber.StatusOK).
+		AssertJSON(`{"title":"Plan <trip>","task":{"title":"Plan <trip>","status":"todo"}}`)
+	if body := srv.Get(u.Path).Body; strings.Contains(string(body), "owner_id") {
+		t.Errorf("public view leaks owner: %s", body)
+	}
+
+	html := srv.WithHeader(fiber.HeaderAccept, fiber.MIMETextHTML).Get(u.Path).
+		AssertStatus(fiber.StatusOK)
+	if !strings.Contains(string(html.Body), "Plan &lt;trip&gt;") {
+		t.Errorf("HTML view does not contain escaped title: %s", html.Body)
+	}
+
+	alice.Delete(fmt.Sprintf("/shares/%d", link.ID)).AssertStatus(fiber.StatusNoContent)
+	srv.Get(u.Path).AssertStatus(fiber.StatusNotFound)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+func (s *Server) createSnapshot(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	var in struct {
+		Label string `json:"label"`
+	}
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &in); err != nil {
+			return err
+		}
+	}
+
+	snap, err := s.workspaces.CreateSnapshot(c.UserContext(), id, in.Label)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to create snapshot")
+	}
+	return c.Status(fiber.StatusCreated).JSON(snap)
+}
+
+func (s *Server) listSnapshots(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	snaps, err := s.workspaces.Snapshots(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to fetch snapshots")
+	}
+	return c.JSON(snaps)
+}
+
+// restoreWorkspace: {"snapshot_id": 3} или {"at": "2026-01-01T12:00:00Z"}
+func (s *Server) restoreWorkspace(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	var req service.RestoreRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	res, err := s.workspaces.Restore(c.UserContext(), id, req)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace or snapshot not found", "Failed to restore workspace")
+	}
+	return c.JSON(res)
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+)
+
+type syncRequest struct {
+	// Token - из прошлого ответа; пустой - первая синхронизация
+	Token   string             `json:"token"`
+	Changes []model.SyncChange `json:"changes"`
+}
+
+// syncResult - итог изменения клиента с тем же кодом и ошибкой, что ответил
+// бы отдельный запрос
+type syncResult struct {
+	UID       string               `json:"uid"`
+	Status    int                  `json:"status"`
+	Error     string               `json:"error,omitempty"`
+	Conflicts []model.TaskConflict `json:"conflicts,omitempty"`
+}
+
+type syncResponse struct {
+	Token   string            `json:"token"`
+	Tasks   any               `json:"tasks"`
+	Deleted []model.Tombstone `json:"deleted"`
+	Results []syncResult      `json:"results"`
+}
+
+// syncTasks: {"token": "...", "changes": [{"op": "upsert", "uid": "...", "task": {...}}]}
+func (s *Server) syncTasks(c *fiber.Ctx) error {
+	var req syncRequest
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return err
+		}
+	}
+
+	changes, results, err := s.tasks.Sync(c.UserContext(), req.Token, req.Changes)
+	if err != nil {
+		return s.serviceError(c, err, "Failed to sync tasks")
+	}
+	resp := syncResponse{
+		Token:   changes.Token,
+		Tasks:   s.present(c, renderDescriptions(c, changes.Tasks)),
+		Deleted: changes.Deleted,
+		Results: make([]syncResult, len(results)),
+	}
+	for i, r := range results {
+		resp.Results[i] = s.syncResult(c, r)
+	}
+	return c.JSON(resp)
+}
+
+func (s *Server) syncResult(c *fiber.Ctx, r service.SyncResult) syncResult {
+	res := syncResult{UID: r.UID, Status: fiber.StatusOK, Conflicts: r.Conflicts}
+	var (
+		quota *service.QuotaError
+		fe    *fiber.Error
+	)
+	switch {
+	case r.Err == nil && r.Created:
+		res.Status = fiber.StatusCreated
+	case r.Err == nil:
+	case errors.As(r.Err, &quota):
+		// serviceError ответил бы телом с квотой, а здесь нужен только код
+		res.Status, res.Error = fiber.StatusForbidden, "Quota exceeded"
+	default:
+		res.Status, res.Error = fiber.StatusInternalServerError, "Failed to apply change"
+		if errors.As(s.serviceError(c, r.Err, "Failed to apply sync change"), &fe) {
+			res.Status, res.Error = fe.Code, fe.Message
+		}
+	}
+	return res
+}
+
+func (s *Server) listConflicts(c *fiber.Ctx) error {
+	conflicts, err := s.tasks.Conflicts(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch conflicts")
+	}
+	return c.JSON(conflicts)
+}
+
+func (s *Server) resolveConflict(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid conflict id")
+	}
+	if err := s.tasks.ResolveConflict(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "Conflict not found", "Failed to resolve conflict")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
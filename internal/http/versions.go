@@ -0,0 +1,64 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// apiVersion - версия API: префикс маршрутов и представление моделей в
+// ответах. Сервисы и обработчики у версий общие; несовместимая смена ответов -
+// новая версия со своим taskDTO рядом с прежней, а не правка старой.
+type apiVersion struct {
+	name   string
+	prefix string
+	// taskDTO - задача в ответе этой версии; base - префикс для ссылок
+	taskDTO func(s *Server, base string, t model.Task) any
+}
+
+var (
+	apiV1 = apiVersion{name: "v1", prefix: "/api/v1", taskDTO: v1Task}
+	// unversioned - прежние адреса без префикса; отвечают как v1
+	unversioned = apiVersion{name: "v1", taskDTO: v1Task}
+
+	// apiVersions - версии, которые обслуживает сервер
+	apiVersions = []apiVersion{apiV1}
+)
+
+const versionKey = "api_version"
+
+func v1Task(s *Server, base string, t model.Task) any { return s.linkTask(base, t) }
+
+// use запоминает версию в запросе для respond и ссылок
+func (v apiVersion) use(c *fiber.Ctx) error {
+	c.Locals(versionKey, v)
+	return c.Next()
+}
+
+// version - версия, по адресу которой пришёл запрос
+func version(c *fiber.Ctx) apiVersion {
+	if v, ok := c.Locals(versionKey).(apiVersion); ok {
+		return v
+	}
+	return unversioned
+}
+
+// present переводит задачу или список задач в DTO версии запроса;
+// остальное возвращает как есть
+func (s *Server) present(c *fiber.Ctx, body any) any {
+	v := version(c)
+	switch body := body.(type) {
+	case model.Task:
+		return v.taskDTO(s, v.prefix, body)
+	case []model.Task:
+		if body == nil {
+			return body
+		}
+		out := make([]any, len(body))
+		for i, t := range body {
+			out[i] = v.taskDTO(s, v.prefix, t)
+		}
+		return out
+	}
+	return body
+}
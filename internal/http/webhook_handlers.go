@@ -0,0 +1,134 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+func (s *Server) createInboundWebhook(c *fiber.Ctx) error {
+	var hook model.InboundWebhook
+	if err := parseBody(c, &hook); err != nil {
+		return err
+	}
+
+	if err := s.webhooks.Create(c.UserContext(), &hook); err != nil {
+		return s.serviceError(c, err, "Failed to create webhook")
+	}
+	return c.Status(fiber.StatusCreated).JSON(hook)
+}
+
+func (s *Server) listInboundWebhooks(c *fiber.Ctx) error {
+	hooks, err := s.webhooks.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch webhooks")
+	}
+	return c.JSON(hooks)
+}
+
+func (s *Server) deleteInboundWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook id")
+	}
+
+	if err := s.webhooks.Delete(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "Webhook not found", "Failed to delete webhook")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// receiveWebhook принимает любой JSON: Content-Type у Zapier и IFTTT бывает разным
+func (s *Server) receiveWebhook(c *fiber.Ctx) error {
+	task, err := s.webhooks.Receive(c.UserContext(), c.Params("token"), c.Body())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to create task from webhook")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": task.ID})
+}
+
+func (s *Server) createOutboundWebhook(c *fiber.Ctx) error {
+	var hook model.OutboundWebhook
+	if err := parseBody(c, &hook); err != nil {
+		return err
+	}
+
+	if err := s.outbound.Create(c.UserContext(), &hook); err != nil {
+		return s.serviceError(c, err, "Failed to create webhook")
+	}
+	return c.Status(fiber.StatusCreated).JSON(hook)
+}
+
+func (s *Server) listOutboundWebhooks(c *fiber.Ctx) error {
+	hooks, err := s.outbound.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch webhooks")
+	}
+	return c.JSON(hooks)
+}
+
+func (s *Server) deleteOutboundWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook id")
+	}
+
+	if err := s.outbound.Delete(c.UserContext(), id); err != nil {
+		return s.notFoundError(c, err, "Webhook not found", "Failed to delete webhook")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// listWebhookDeliveries - последние доставки: ?status=failed&limit=N
+func (s *Server) listWebhookDeliveries(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook id")
+	}
+
+	deliveries, err := s.outbound.Deliveries(c.UserContext(), id, c.Query("status"), c.QueryInt("limit"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch deliveries")
+	}
+	return c.JSON(deliveries)
+}
+
+func (s *Server) getWebhookDelivery(c *fiber.Ctx) error {
+	id, deliveryID, err := deliveryParams(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := s.outbound.Delivery(c.UserContext(), id, deliveryID)
+	if err != nil {
+		return s.notFoundError(c, err, "Delivery not found", "Failed to fetch delivery")
+	}
+	return c.JSON(delivery)
+}
+
+// redeliverWebhook ставит доставку в очередь заново; обычно - провалившуюся
+func (s *Server) redeliverWebhook(c *fiber.Ctx) error {
+	id, deliveryID, err := deliveryParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.outbound.Redeliver(c.UserContext(), id, deliveryID); err != nil {
+		return s.notFoundError(c, err, "Delivery not found", "Failed to redeliver webhook")
+	}
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func deliveryParams(c *fiber.Ctx) (int, int64, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid webhook id")
+	}
+	deliveryID, err := strconv.ParseInt(c.Params("deliveryID"), 10, 64)
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid delivery id")
+	}
+	return id, deliveryID, nil
+}
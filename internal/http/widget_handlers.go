@@ -0,0 +1,59 @@
+package http
+
+import (
+	"html/template"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var widgetPage = template.Must(template.New("widget").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>My tasks</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; padding: .5rem; font-size: 14px; color: #222; background: transparent; }
+ul { margin: 0; padding: 0; }
+li { list-style: none; padding: .2rem 0; }
+.in_progress::before { content: "\25B6\FE0E "; color: #c80; }
+.todo::before { content: "\25CB "; color: #888; }
+.more { color: #888; font-size: 12px; }
+</style>
+</head>
+<body>
+<ul>
+{{range .Tasks}}<li class="{{.Status}}">{{.Title}}</li>
+{{else}}<li>Nothing to do</li>
+{{end}}</ul>
+{{if gt .OpenCount (len .Tasks)}}<p class="more">and {{.More}} more</p>{{end}}
+</body>
+</html>
+`))
+
+// getWidget - компактный JSON для дашбордов
+func (s *Server) getWidget(c *fiber.Ctx) error {
+	w, err := s.tasks.Widget(c.UserContext(), c.QueryInt("limit"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch widget data")
+	}
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+	return c.JSON(w)
+}
+
+// getWidgetEmbed - HTML для iframe (Notion, Grafana text panel)
+func (s *Server) getWidgetEmbed(c *fiber.Ctx) error {
+	w, err := s.tasks.Widget(c.UserContext(), c.QueryInt("limit"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch widget data")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors *")
+	c.Type("html", "utf-8")
+	return widgetPage.Execute(c.Response().BodyWriter(), struct {
+		Tasks     any
+		OpenCount int
+		More      int
+	}{w.Tasks, w.OpenCount, w.OpenCount - len(w.Tasks)})
+}
@@ -0,0 +1,431 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type memberRequest struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+func (s *Server) createWorkspace(c *fiber.Ctx) error {
+	var ws model.Workspace
+	if err := parseBody(c, &ws); err != nil {
+		return err
+	}
+
+	if err := s.workspaces.Create(c.UserContext(), &ws); err != nil {
+		return s.serviceError(c, err, "Failed to create workspace")
+	}
+	return c.Status(fiber.StatusCreated).JSON(ws)
+}
+
+func (s *Server) listWorkspaces(c *fiber.Ctx) error {
+	list, err := s.workspaces.List(c.UserContext())
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch workspaces")
+	}
+	return c.JSON(list)
+}
+
+func (s *Server) getWorkspace(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	ws, err := s.workspaces.Get(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to fetch workspace")
+	}
+	return c.JSON(ws)
+}
+
+func (s *Server) listWorkspaceMembers(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	members, err := s.workspaces.Members(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to fetch members")
+	}
+	return c.JSON(members)
+}
+
+func (s *Server) setWorkspaceMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	var req memberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Role == "" {
+		req.Role = model.WorkspaceMember
+	}
+
+	if err := s.workspaces.SetMember(c.UserContext(), id, req.UserID, req.Role); err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to add member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) removeWorkspaceMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	userID, err := c.ParamsInt("userID")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	if err := s.workspaces.RemoveMember(c.UserContext(), id, userID); err != nil {
+		return s.notFoundError(c, err, "Member not found", "Failed to remove member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) createTeam(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	var team model.Team
+	if err := parseBody(c, &team); err != nil {
+		return err
+	}
+
+	team.WorkspaceID = id
+	err = s.workspaces.CreateTeam(c.UserContext(), &team)
+	if errors.Is(err, storage.ErrTeamExists) {
+		return fiber.NewError(fiber.StatusConflict, "Team with this name already exists")
+	}
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to create team")
+	}
+	return c.Status(fiber.StatusCreated).JSON(team)
+}
+
+func (s *Server) listTeams(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	teams, err := s.workspaces.Teams(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to fetch teams")
+	}
+	return c.JSON(teams)
+}
+
+func (s *Server) getTeam(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid team id")
+	}
+
+	team, err := s.workspaces.Team(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Team not found", "Failed to fetch team")
+	}
+	return c.JSON(team)
+}
+
+func (s *Server) listTeamMembers(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid team id")
+	}
+
+	members, err := s.workspaces.TeamMembers(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Team not found", "Failed to fetch team members")
+	}
+	return c.JSON(members)
+}
+
+func (s *Server) setTeamMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid team id")
+	}
+	var req memberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Role == "" {
+		req.Role = model.TeamMember
+	}
+
+	if err := s.workspaces.SetTeamMember(c.UserContext(), id, req.UserID, req.Role); err != nil {
+		return s.notFoundError(c, err, "Team not found", "Failed to add team member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) removeTeamMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid team id")
+	}
+	userID, err := c.ParamsInt("userID")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	if err := s.workspaces.RemoveTeamMember(c.UserContext(), id, userID); err != nil {
+		return s.notFoundError(c, err, "Team member not found", "Failed to remove team member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) listTeamTasks(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid team id")
+	}
+
+	tasks, err := s.workspaces.TeamTasks(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Team not found", "Failed to fetch team tasks")
+	}
+	// If-Modified-Since не поддержан: по updated_at не видно удалённых задач
+	setTaskCaching(c, lastModified(tasks))
+	return c.JSON(tasks)
+}
+
+func (s *Server) getTeamBoard(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid team id")
+	}
+
+	// ?limit=N - не больше N задач в колонке, Count - полное число
+	board, err := s.workspaces.TeamBoard(c.UserContext(), id, c.QueryInt("limit"))
+	if err != nil {
+		return s.notFoundError(c, err, "Team not found", "Failed to fetch board")
+	}
+	if notModified(c, board.UpdatedAt) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(board)
+}
+
+func (s *Server) createProject(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	var p model.Project
+	if err := parseBody(c, &p); err != nil {
+		return err
+	}
+
+	p.WorkspaceID = id
+	if err := s.workspaces.CreateProject(c.UserContext(), &p); err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to create project")
+	}
+	return c.Status(fiber.StatusCreated).JSON(p)
+}
+
+func (s *Server) listProjects(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	projects, err := s.workspaces.Projects(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to fetch projects")
+	}
+	return c.JSON(projects)
+}
+
+func (s *Server) getProjectBoard(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	board, err := s.workspaces.ProjectBoard(c.UserContext(), id, c.QueryInt("limit"))
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to fetch board")
+	}
+	if notModified(c, board.UpdatedAt) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(board)
+}
+
+func (s *Server) getProjectStats(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	stats, err := s.workspaces.ProjectStats(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to fetch stats")
+	}
+	return c.JSON(stats)
+}
+
+// notFoundError - serviceError с сообщением 404 для конкретной сущности
+func (s *Server) notFoundError(c *fiber.Ctx, err error, notFound, msg string) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, notFound)
+	}
+	return s.serviceError(c, err, msg)
+}
+
+func (s *Server) createInvite(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	var req service.InviteRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	inv, err := s.workspaces.Invite(c.UserContext(), id, req)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to create invite")
+	}
+	return c.Status(fiber.StatusCreated).JSON(inv)
+}
+
+func (s *Server) listInvites(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+
+	invites, err := s.workspaces.Invites(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Workspace not found", "Failed to fetch invites")
+	}
+	return c.JSON(invites)
+}
+
+func (s *Server) revokeInvite(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid workspace id")
+	}
+	inviteID, err := c.ParamsInt("inviteID")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid invite id")
+	}
+
+	if err := s.workspaces.RevokeInvite(c.UserContext(), id, inviteID); err != nil {
+		return s.notFoundError(c, err, "Invite not found", "Failed to revoke invite")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) getInvite(c *fiber.Ctx) error {
+	inv, err := s.workspaces.InviteInfo(c.UserContext(), c.Params("token"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to fetch invite")
+	}
+	return c.JSON(inv)
+}
+
+func (s *Server) acceptInvite(c *fiber.Ctx) error {
+	ws, err := s.workspaces.AcceptInvite(c.UserContext(), c.Params("token"))
+	if err != nil {
+		return s.serviceError(c, err, "Failed to accept invite")
+	}
+	return c.JSON(ws)
+}
+
+func (s *Server) getProject(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	p, err := s.workspaces.Project(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to fetch project")
+	}
+	return c.JSON(p)
+}
+
+func (s *Server) updateProject(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+	var in service.ProjectUpdate
+	if err := parseBody(c, &in); err != nil {
+		return err
+	}
+
+	p, err := s.workspaces.UpdateProject(c.UserContext(), id, in)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to update project")
+	}
+	return c.JSON(p)
+}
+
+func (s *Server) listProjectMembers(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+
+	members, err := s.workspaces.ProjectMembers(c.UserContext(), id)
+	if err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to fetch project members")
+	}
+	return c.JSON(members)
+}
+
+func (s *Server) setProjectMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+	var req memberRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Role == "" {
+		req.Role = model.ProjectEditor
+	}
+
+	if err := s.workspaces.SetProjectMember(c.UserContext(), id, req.UserID, req.Role); err != nil {
+		return s.notFoundError(c, err, "Project not found", "Failed to add project member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (s *Server) removeProjectMember(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid project id")
+	}
+	userID, err := c.ParamsInt("userID")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user id")
+	}
+
+	if err := s.workspaces.RemoveProjectMember(c.UserContext(), id, userID); err != nil {
+		return s.notFoundError(c, err, "Project member not found", "Failed to remove project member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,213 @@
+// Package jira - минимальный клиент Jira REST API v2 (Cloud и Data Center)
+package jira
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Категории статусов Jira: по ним сопоставляются статусы без явной настройки
+const (
+	CategoryNew        = "new"
+	CategoryInProgress = "indeterminate"
+	CategoryDone       = "done"
+)
+
+// Client: при заданном Email - Basic с API-токеном (Cloud), иначе Bearer с PAT
+type Client struct {
+	BaseURL string
+	Email   string
+	Token   string
+	HTTP    *http.Client
+}
+
+// Time - формат дат Jira: 2025-03-01T10:00:00.000+0000
+type Time struct{ time.Time }
+
+const timeLayout = "2006-01-02T15:04:05.000-0700"
+
+func (t *Time) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	v, err := time.Parse(timeLayout, s)
+	if err != nil {
+		v, err = time.Parse(time.RFC3339, s)
+	}
+	t.Time = v
+	return err
+}
+
+type Status struct {
+	Name     string `json:"name"`
+	Category struct {
+		Key string `json:"key"`
+	} `json:"statusCategory"`
+}
+
+type Issue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      Status `json:"status"`
+		Updated     Time   `json:"updated"`
+	} `json:"fields"`
+}
+
+type Transition struct {
+	ID string `json:"id"`
+	To Status `json:"to"`
+}
+
+type User struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// StatusError - ответ API с кодом не 2xx
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("jira: %d %s", e.Code, e.Body)
+}
+
+var issueFields = []string{"summary", "description", "status", "updated"}
+
+// Myself - владелец учётных данных; заодно проверяет их
+func (c *Client) Myself(ctx context.Context) (User, error) {
+	var u User
+	err := c.do(ctx, http.MethodGet, "/rest/api/2/myself", nil, &u)
+	return u, err
+}
+
+// Search - все issues по JQL, постранично
+func (c *Client) Search(ctx context.Context, jql string) ([]Issue, error) {
+	var all []Issue
+	for {
+		var page struct {
+			Total  int     `json:"total"`
+			Issues []Issue `json:"issues"`
+		}
+		err := c.do(ctx, http.MethodPost, "/rest/api/2/search", map[string]any{
+			"jql": jql, "startAt": len(all), "maxResults": 100, "fields": issueFields,
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Issues...)
+		if len(page.Issues) == 0 || len(all) >= page.Total {
+			return all, nil
+		}
+	}
+}
+
+func (c *Client) Issue(ctx context.Context, key string) (Issue, error) {
+	var i Issue
+	q := url.Values{"fields": {strings.Join(issueFields, ",")}}
+	err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?"+q.Encode(), nil, &i)
+	return i, err
+}
+
+// Transitions - переходы, доступные из текущего статуса issue
+func (c *Client) Transitions(ctx context.Context, key string) ([]Transition, error) {
+	var resp struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"/transitions", nil, &resp)
+	return resp.Transitions, err
+}
+
+func (c *Client) Transition(ctx context.Context, key, transitionID string) error {
+	return c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/transitions",
+		map[string]any{"transition": map[string]string{"id": transitionID}}, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Email != "" {
+		req.SetBasicAuth(c.Email, c.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// VerifySignature проверяет заголовок X-Hub-Signature вебхука с секретом
+func VerifySignature(secret string, body []byte, signature string) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Event - тело вебхука jira:issue_*
+type Event struct {
+	WebhookEvent string `json:"webhookEvent"`
+	Issue        Issue  `json:"issue"`
+}
+
+// Типы событий вебхука
+const (
+	EventIssueCreated = "jira:issue_created"
+	EventIssueUpdated = "jira:issue_updated"
+	EventIssueDeleted = "jira:issue_deleted"
+)
@@ -0,0 +1,89 @@
+// Package mail - отправка писем пользователям
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Log пишет письма в лог вместо отправки - для разработки без SMTP
+type Log struct {
+	Logger zerolog.Logger
+}
+
+func (l Log) Send(_ context.Context, m Message) error {
+	l.Logger.Info().Str("to", m.To).Str("subject", m.Subject).Msg(m.Body)
+	return nil
+}
+
+// SMTP отправляет письма через SMTP-сервер с PLAIN-аутентификацией
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// ErrInvalidRecipient - в адресе получателя перевод строки: заголовки письма
+// из него не собрать
+var ErrInvalidRecipient = errors.New("mail recipient contains a line break")
+
+func (s SMTP) Send(_ context.Context, m Message) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	var a smtp.Auth
+	if s.Username != "" {
+		a = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg, err := s.message(m)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, a, s.From, []string{m.To}, msg)
+}
+
+// message собирает письмо. Тема часто содержит то, что ввели пользователи, -
+// заголовок задачи, название пространства, - поэтому переводы строк в ней
+// схлопываются в пробел, иначе через тему можно дописать свои заголовки или
+// подменить текст письма. Не-ASCII тема кодируется по RFC 2047.
+func (s SMTP) message(m Message) ([]byte, error) {
+	if strings.ContainsAny(m.To, "\r\n") {
+		return nil, ErrInvalidRecipient
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", HeaderText(m.Subject)))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(m.Body)
+	return []byte(b.String()), nil
+}
+
+// HeaderText - s в одну строку: переводы строк и пробелы вокруг них
+// становятся одним пробелом
+func HeaderText(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSMTPMessageHeaders(t *testing.T) {
+	s := SMTP{From: "todo@example.com"}
+
+	msg, err := s.message(Message{To: "user@example.com", Subject: "Task delegated to you: Report\r\nX-Injected: 1", Body: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, body, _ := strings.Cut(string(msg), "\r\n\r\n")
+	if strings.Contains(headers, "\r\nX-Injected") || strings.Count(headers, "\r\n") != 4 {
+		t.Errorf("headers = %q, want the subject on one line", headers)
+	}
+	if !strings.Contains(headers, "\r\nSubject: Task delegated to you: Report X-Injected: 1\r\n") || body != "Hi" {
+		t.Errorf("message = %q", msg)
+	}
+
+	msg, err = s.message(Message{To: "user@example.com", Subject: "Приглашение в Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(msg), "\r\nSubject: =?utf-8?q?") {
+		t.Errorf("non-ASCII subject is not RFC 2047 encoded: %q", msg)
+	}
+
+	if _, err := s.message(Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"}); !errors.Is(err, ErrInvalidRecipient) {
+		t.Errorf("recipient with a line break: err = %v, want ErrInvalidRecipient", err)
+	}
+}
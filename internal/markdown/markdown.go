@@ -0,0 +1,219 @@
+// Package markdown - подмножество Markdown для описаний задач в HTML.
+//
+// Безопасность держится не на чистке готового HTML, а на том, что сырой HTML
+// сюда не проходит вовсе: весь текст экранируется, а теги появляются только
+// из разметки Markdown и только из белого списка. Ссылки - лишь http, https,
+// mailto и относительные.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletRe  = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedRe = regexp.MustCompile(`^\s{0,3}\d{1,9}[.)]\s+(.*)$`)
+	ruleRe    = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	fenceRe   = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	quoteRe   = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+)
+
+// Render переводит Markdown в HTML: заголовки, абзацы, списки, цитаты, блоки
+// и фрагменты кода, **жирный**, *курсив*, ~~зачёркнутый~~ и [ссылки](url)
+func Render(src string) string {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\r", "\n")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return b.String()
+}
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>")
+			for i, line := range para {
+				if i > 0 {
+					b.WriteString("<br>\n")
+				}
+				b.WriteString(inline(strings.TrimSpace(line)))
+			}
+			b.WriteString("</p>\n")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case fenceRe.MatchString(line):
+			flush()
+			fence := fenceRe.FindStringSubmatch(line)[1]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+		case headingRe.MatchString(line):
+			flush()
+			m := headingRe.FindStringSubmatch(line)
+			tag := "h" + string(rune('0'+len(m[1])))
+			b.WriteString("<" + tag + ">" + inline(m[2]) + "</" + tag + ">\n")
+		case ruleRe.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+		case quoteRe.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && quoteRe.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quoteRe.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+		case bulletRe.MatchString(line), orderedRe.MatchString(line):
+			flush()
+			re, tag := bulletRe, "ul"
+			if !bulletRe.MatchString(line) {
+				re, tag = orderedRe, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && re.MatchString(lines[i]); i++ {
+				item := re.FindStringSubmatch(lines[i])[1]
+				// строки с отступом продолжают пункт
+				for i+1 < len(lines) && strings.HasPrefix(lines[i+1], "  ") && strings.TrimSpace(lines[i+1]) != "" &&
+					!bulletRe.MatchString(lines[i+1]) && !orderedRe.MatchString(lines[i+1]) {
+					i++
+					item += " " + strings.TrimSpace(lines[i])
+				}
+				b.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			i--
+			b.WriteString("</" + tag + ">\n")
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+}
+
+// emphasis - парные разделители и их теги; длинные раньше коротких
+var emphasis = []struct{ delim, tag string }{
+	{"**", "strong"}, {"__", "strong"}, {"~~", "del"}, {"*", "em"}, {"_", "em"},
+}
+
+// inline размечает строку внутри блока; всё, что не разметка, экранируется
+func inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()#+-.!>", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case c == '[':
+			if text, href, n, ok := parseLink(s[i:]); ok {
+				if url, safe := safeURL(href); safe {
+					b.WriteString(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener noreferrer">` +
+						inline(text) + "</a>")
+				} else {
+					b.WriteString(inline(text))
+				}
+				i += n
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			if tag, inner, n, ok := parseEmphasis(s, i); ok {
+				b.WriteString("<" + tag + ">" + inline(inner) + "</" + tag + ">")
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// parseLink разбирает [text](url) в начале s; n - длина разметки
+func parseLink(s string) (text, href string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if i+1 >= len(s) || s[i+1] != '(' {
+					return "", "", 0, false
+				}
+				end := strings.IndexByte(s[i+2:], ')')
+				if end < 0 {
+					return "", "", 0, false
+				}
+				return s[1:i], strings.TrimSpace(s[i+2 : i+2+end]), i + 3 + end, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// parseEmphasis ищет закрывающий разделитель для открывающего в s[i:].
+// _ внутри слова (snake_case) разметкой не считается.
+func parseEmphasis(s string, i int) (tag, inner string, n int, ok bool) {
+	for _, e := range emphasis {
+		if !strings.HasPrefix(s[i:], e.delim) {
+			continue
+		}
+		if e.delim[0] == '_' && i > 0 && isWordByte(s[i-1]) {
+			return "", "", 0, false
+		}
+		rest := s[i+len(e.delim):]
+		end := strings.Index(rest, e.delim)
+		if end <= 0 || rest[0] == ' ' || rest[end-1] == ' ' {
+			continue
+		}
+		return e.tag, rest[:end], len(e.delim)*2 + end, true
+	}
+	return "", "", 0, false
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// safeURL пропускает только http, https, mailto и относительные адреса;
+// javascript:, data: и прочие схемы отбрасываются
+func safeURL(raw string) (string, bool) {
+	for _, r := range raw {
+		if r < 0x20 || r == 0x7f || r == ' ' || r == '<' || r == '>' || r == '"' {
+			return "", false
+		}
+	}
+	lower := strings.ToLower(raw)
+	scheme, _, hasScheme := strings.Cut(lower, ":")
+	switch {
+	case !hasScheme || strings.ContainsAny(scheme, "/?#"):
+		// относительный адрес: двоеточие только после пути
+		return raw, raw != ""
+	case scheme == "http", scheme == "https", scheme == "mailto":
+		return raw, true
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package markdown_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Upiter5/todo-app/internal/markdown"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"paragraph with line break", "first\nsecond", "<p>first<br>\nsecond</p>\n"},
+		{"heading", "## Plan ##", "<h2>Plan</h2>\n"},
+		{"emphasis", "**bold**, *it* and ~~gone~~", "<p><strong>bold</strong>, <em>it</em> and <del>gone</del></p>\n"},
+		{"snake_case stays", "use snake_case_names", "<p>use snake_case_names</p>\n"},
+		{"code span", "run `rm -rf <dir>`", "<p>run <code>rm -rf &lt;dir&gt;</code></p>\n"},
+		{"fenced code", "```go\nif a < b {}\n```", "<pre><code>if a &lt; b {}</code></pre>\n"},
+		{"lists", "- milk\n- eggs\n\n1. first\n2. second",
+			"<ul>\n<li>milk</li>\n<li>eggs</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"},
+		{"quote", "> note", "<blockquote>\n<p>note</p>\n</blockquote>\n"},
+		{"link", "[docs](https://example.com/a?b=1&c=2)",
+			`<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">docs</a></p>` + "\n"},
+		{"relative link", "[task](/tasks/7)", `<p><a href="/tasks/7" rel="nofollow noopener noreferrer">task</a></p>` + "\n"},
+		{"escape", `\*not em\*`, "<p>*not em*</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdown.Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderStripsDangerousContent(t *testing.T) {
+	for _, src := range []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`[click](javascript:alert(1))`,
+		`[click](JavaScript:alert(1))`,
+		"[click](java\tscript:alert(1))",
+		`[click](data:text/html;base64,PHNjcmlwdD4=)`,
+		`[x](https://example.com/"onmouseover="alert(1))`,
+		"# <iframe src=//evil>",
+		"- <a href=javascript:alert(1)>x</a>",
+		"**<svg onload=alert(1)>**",
+	} {
+		// экранированный текст безвреден, опасны только живые теги и адреса
+		got := strings.ToLower(markdown.Render(src))
+		for _, bad := range []string{"<script", "<img", "<iframe", "<svg", `href="javascript`, `href="java`, `href="data:`, `" onmouseover`, "<a href=javascript"} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Render(%q) = %q, contains %q", src, got, bad)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package microsoft - чтение Microsoft To Do через Microsoft Graph без
+// клиентских библиотек Microsoft
+package microsoft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const graphURL = "https://graph.microsoft.com/v1.0"
+
+// TasksReadScope - право читать задачи To Do пользователя
+const TasksReadScope = "Tasks.Read"
+
+// Client - клиент Graph с access-токеном пользователя (делегированный доступ)
+type Client struct {
+	// BaseURL переопределяется в тестах
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+type List struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	// WellknownListName: defaultList - "Задачи", flaggedEmails - помеченные письма
+	WellknownListName string `json:"wellknownListName"`
+}
+
+// Списки, которые To Do создаёт сам
+const (
+	ListDefault       = "defaultList"
+	ListFlaggedEmails = "flaggedEmails"
+)
+
+// Статусы и важность задачи To Do (и Outlook Tasks, который на них же)
+const (
+	StatusNotStarted      = "notStarted"
+	StatusInProgress      = "inProgress"
+	StatusCompleted       = "completed"
+	StatusWaitingOnOthers = "waitingOnOthers"
+	StatusDeferred        = "deferred"
+
+	ImportanceLow    = "low"
+	ImportanceNormal = "normal"
+	ImportanceHigh   = "high"
+)
+
+type Task struct {
+	ID                string          `json:"id"`
+	Title             string          `json:"title"`
+	Status            string          `json:"status"`
+	Importance        string          `json:"importance"`
+	Body              Body            `json:"body"`
+	DueDateTime       *DateTime       `json:"dueDateTime"`
+	CompletedDateTime *DateTime       `json:"completedDateTime"`
+	ChecklistItems    []ChecklistItem `json:"checklistItems"`
+}
+
+type Body struct {
+	Content string `json:"content"`
+	// ContentType - text или html
+	ContentType string `json:"contentType"`
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Text - заметки задачи простым текстом: у HTML (так хранит Outlook)
+// остаются только строки
+func (b Body) Text() string {
+	s := b.Content
+	if strings.EqualFold(b.ContentType, "html") {
+		s = htmlBreaks.ReplaceAllString(s, "\n")
+		s = html.UnescapeString(htmlTags.ReplaceAllString(s, ""))
+	}
+	return strings.TrimSpace(s)
+}
+
+// ChecklistItem - шаг задачи
+type ChecklistItem struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	IsChecked   bool   `json:"isChecked"`
+}
+
+// DateTime - dateTimeTimeZone Graph: время без смещения и имя зоны, IANA
+// или Windows ("Pacific Standard Time")
+type DateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+// Time переводит значение в time.Time. Зоны Windows Go не знает - время в
+// них читается как UTC; To Do отдаёт сроки в UTC, если не просить иного.
+func (d DateTime) Time() (time.Time, error) {
+	loc, err := time.LoadLocation(d.TimeZone)
+	if err != nil || d.TimeZone == "" {
+		loc = time.UTC
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05.9999999", d.DateTime, loc)
+}
+
+// StatusError - ответ Graph с кодом не 2xx
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("microsoft graph: %d %s", e.Code, e.Body)
+}
+
+// Lists - все списки задач пользователя
+func (c *Client) Lists(ctx context.Context) ([]List, error) {
+	return collect[List](ctx, c, "/me/todo/lists")
+}
+
+// Tasks - все задачи списка вместе с шагами
+func (c *Client) Tasks(ctx context.Context, listID string) ([]Task, error) {
+	q := url.Values{"$expand": {"checklistItems"}, "$top": {"100"}}
+	return collect[Task](ctx, c, "/me/todo/lists/"+url.PathEscape(listID)+"/tasks?"+q.Encode())
+}
+
+// collect проходит страницы по @odata.nextLink - это полный адрес
+func collect[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = graphURL
+	}
+	next := strings.TrimRight(base, "/") + path
+	var all []T
+	for next != "" {
+		var page struct {
+			Value    []T    `json:"value"`
+			NextLink string `json:"@odata.nextLink"`
+		}
+		if err := c.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Value...)
+		next = page.NextLink
+	}
+	return all, nil
+}
+
+func (c *Client) get(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// CalendarLink - подключённый Google Calendar пользователя
+type CalendarLink struct {
+	UserID       int       `json:"-"`
+	CalendarID   string    `json:"calendar_id"`
+	AccessToken  string    `json:"-"`
+	RefreshToken string    `json:"-"`
+	TokenExpiry  time.Time `json:"-"`
+	SyncToken    string    `json:"-"`
+	// PullEvents - создавать задачи из событий, добавленных в календарь
+	PullEvents bool      `json:"pull_events"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CalendarEvent - событие календаря, связанное с задачей
+type CalendarEvent struct {
+	UserID  int
+	EventID string
+	TaskID  int
+	Pulled  bool
+}
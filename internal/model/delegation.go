@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Состояния поручения
+const (
+	// DelegationActive - исполнитель работает над задачей
+	DelegationActive = "delegated"
+	// DelegationPendingReview - исполнитель сдал работу, ждёт решения
+	DelegationPendingReview = "pending_review"
+	// DelegationApproved - поручивший принял работу, задача завершена
+	DelegationApproved = "approved"
+	// DelegationRejected - работу вернули с комментарием, её можно сдать снова
+	DelegationRejected = "rejected"
+)
+
+// Delegation - поручение задачи другому участнику проекта. Завершить такую
+// задачу может только поручивший: сам или приняв работу исполнителя.
+type Delegation struct {
+	TaskID      int    `json:"task_id"`
+	TaskTitle   string `json:"task_title"`
+	DelegatorID int    `json:"delegator_id"`
+	DelegateID  int    `json:"delegate_id"`
+	State       string `json:"state"`
+	// Comment - почему работу вернули; пуст, пока её не отклоняли
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
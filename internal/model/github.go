@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// GitHubLink - связь проекта с репозиторием GitHub
+type GitHubLink struct {
+	ProjectID int    `json:"project_id"`
+	UserID    int    `json:"user_id"`
+	Repo      string `json:"repo"`
+	// Login - владелец токена: синхронизируются назначенные ему issues
+	Login string `json:"login"`
+	Token string `json:"-"`
+	// WebhookSecret и WebhookURL отдаются только при создании связи
+	WebhookSecret string     `json:"webhook_secret,omitempty"`
+	WebhookURL    string     `json:"webhook_url,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSyncedAt  *time.Time `json:"last_synced_at"`
+}
+
+// GitHubIssue - задача, созданная из issue, и последнее синхронизированное
+// состояние: Status задачи и время изменения issue на GitHub
+type GitHubIssue struct {
+	ProjectID int
+	Number    int
+	TaskID    int
+	Status    string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package model
+
+// ImportResult - итог переноса задач из другого сервиса
+type ImportResult struct {
+	Lists []ImportedList `json:"lists"`
+	// Created - новые задачи вместе с подзадачами
+	Created int `json:"created"`
+	// Skipped - задачи, перенесённые прошлым импортом
+	Skipped int `json:"skipped"`
+}
+
+// ImportedList - список задач сервиса и проект, в который он лёг; без
+// пространства ProjectID - nil, задачи становятся личными
+type ImportedList struct {
+	Name      string `json:"name"`
+	ProjectID *int   `json:"project_id"`
+	Created   int    `json:"created"`
+	Skipped   int    `json:"skipped"`
+}
+
+// Add учитывает перенесённый список в итоге
+func (r *ImportResult) Add(l ImportedList) {
+	r.Lists = append(r.Lists, l)
+	r.Created += l.Created
+	r.Skipped += l.Skipped
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// JiraLink - связь проекта с Jira
+type JiraLink struct {
+	ProjectID int    `json:"project_id"`
+	UserID    int    `json:"user_id"`
+	BaseURL   string `json:"base_url"`
+	// Email задаётся для Jira Cloud (API-токен), пустой - персональный токен Data Center
+	Email string `json:"email,omitempty"`
+	Token string `json:"-"`
+	JQL   string `json:"jql"`
+	// StatusMap - статус Jira -> статус задачи; не указанные сопоставляются по категории
+	StatusMap map[string]string `json:"status_map"`
+	// WebhookSecret и WebhookURL отдаются только при создании связи
+	WebhookSecret string     `json:"webhook_secret,omitempty"`
+	WebhookURL    string     `json:"webhook_url,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSyncedAt  *time.Time `json:"last_synced_at"`
+}
+
+// JiraIssue - задача, созданная из issue, и последнее согласованное состояние
+type JiraIssue struct {
+	ProjectID int
+	Key       string
+	TaskID    int
+	Status    string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// RateLimitTier - ограничение частоты запросов: ровный поток
+// RequestsPerMinute и всплеск до Burst запросов подряд
+type RateLimitTier struct {
+	Name              string `json:"name" validate:"required,max=50"`
+	RequestsPerMinute int    `json:"requests_per_minute" validate:"min=1"`
+	Burst             int    `json:"burst" validate:"min=1"`
+}
+
+// RateLimitUsage - запросы пользователя или его API-ключа за сутки (UTC)
+type RateLimitUsage struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+	// APIKeyID - 0 для запросов по JWT и cookie сессии
+	APIKeyID int `json:"api_key_id,omitempty"`
+	// Tier - текущий тариф, а не тот, что действовал в тот день
+	Tier     string    `json:"tier,omitempty"`
+	Day      time.Time `json:"day"`
+	Requests int64     `json:"requests"`
+	// Limited - из них отклонено с 429
+	Limited int64 `json:"limited"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// ShareLink - публичная ссылка только на чтение на задачу или проект
+type ShareLink struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"-"`
+	TaskID    *int       `json:"task_id,omitempty"`
+	ProjectID *int       `json:"project_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// URL со секретным токеном отдаётся только при создании
+	URL string `json:"url,omitempty"`
+}
+
+// PublicTask - задача в публичном представлении, без владельца и проекта
+type PublicTask struct {
+	ID          int        `json:"id"`
+	ParentID    *int       `json:"parent_id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func NewPublicTask(t Task) PublicTask {
+	return PublicTask{
+		ID:          t.ID,
+		ParentID:    t.ParentID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		CompletedAt: t.CompletedAt,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+// SharedView - то, что видит получатель ссылки
+type SharedView struct {
+	// Title - название задачи или проекта
+	Title     string       `json:"title"`
+	Task      *PublicTask  `json:"task,omitempty"`
+	Tasks     []PublicTask `json:"tasks,omitempty"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+}
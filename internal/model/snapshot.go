@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// WorkspaceState - данные пространства для снимка: проекты и их задачи
+type WorkspaceState struct {
+	Projects []Project `json:"projects"`
+	Tasks    []Task    `json:"tasks"`
+}
+
+type Snapshot struct {
+	ID           int       `json:"id"`
+	WorkspaceID  int       `json:"workspace_id"`
+	Label        string    `json:"label" validate:"max=100"`
+	CreatedBy    *int      `json:"created_by"`
+	ProjectCount int       `json:"project_count"`
+	TaskCount    int       `json:"task_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	// State есть только у снимка, загруженного для восстановления
+	State *WorkspaceState `json:"-"`
+}
+
+// RestoreResult - итог восстановления пространства
+type RestoreResult struct {
+	// BackupID - снимок, сделанный перед восстановлением, чтобы его можно было отменить
+	BackupID int `json:"backup_id"`
+	Restored int `json:"restored"`
+	Deleted  int `json:"deleted"`
+	// Skipped - задачи, которые нельзя вернуть: автор удалён или задачу
+	// перенесли в другое пространство
+	Skipped int `json:"skipped"`
+}
+
+// Статусы сборки архива
+const (
+	ArchiveRunning = "running"
+	ArchiveDone    = "done"
+	ArchiveFailed  = "failed"
+)
+
+// ArchiveJob - фоновая сборка ZIP-архива пространства. Готовый архив
+// хранится до ExpiresAt.
+type ArchiveJob struct {
+	ID          string `json:"id"`
+	WorkspaceID int    `json:"workspace_id"`
+	Status      string `json:"status"`
+	// Progress - доля записанных файлов архива в процентах
+	Progress   int        `json:"progress"`
+	FilesDone  int        `json:"files_done"`
+	FilesTotal int        `json:"files_total"`
+	Size       int64      `json:"size"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
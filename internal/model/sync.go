@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Операции в локальных изменениях клиента синхронизации
+const (
+	SyncUpsert = "upsert"
+	SyncDelete = "delete"
+)
+
+// SyncChange - изменение, сделанное клиентом офлайн. Задача определяется по
+// uid: upsert создаёт её или заменяет целиком, delete удаляет. С Base -
+// задачей, какой клиент видел её до правки, - upsert сливается с правками
+// на сервере по полям, а не затирает их.
+type SyncChange struct {
+	Op   string `json:"op" validate:"oneof=upsert delete"`
+	UID  string `json:"uid" validate:"required,uuid"`
+	Task *Task  `json:"task,omitempty" validate:"required_if=Op upsert"`
+	Base *Task  `json:"base,omitempty" validate:"-"`
+	// ChangedAt - когда правку сделали на клиенте; без него - в момент синхронизации
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+}
+
+// TaskConflict - поле, которое изменили и клиент, и сервер. Побеждает более
+// поздняя правка; Kept - значение, оставшееся в задаче, Discarded - копия
+// проигравшего, например расходящегося описания.
+type TaskConflict struct {
+	ID         int             `json:"id"`
+	TaskID     int             `json:"task_id"`
+	Field      string          `json:"field"`
+	Kept       json.RawMessage `json:"kept"`
+	Discarded  json.RawMessage `json:"discarded"`
+	ActorID    *int            `json:"actor_id"`
+	DetectedAt time.Time       `json:"detected_at"`
+}
+
+// Tombstone - след удалённой задачи, чтобы клиент убрал её у себя
+type Tombstone struct {
+	ID        int       `json:"id"`
+	UID       string    `json:"uid"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TaskChanges - изменения задач после токена синхронизации и токен для
+// следующего запроса. Задачи могут повторяться между ответами: клиент
+// применяет их по uid, поэтому повтор безвреден.
+type TaskChanges struct {
+	Tasks   []Task
+	Deleted []Tombstone
+	Token   string
+}
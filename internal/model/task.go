@@ -0,0 +1,145 @@
+// Package model - доменные сущности приложения
+package model
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+type Task struct {
+	ID int `json:"id" validate:"-"`
+	// UID - глобально уникальный UUID; клиент может задать его при создании
+	UID         string     `json:"uid" validate:"omitempty,uuid"`
+	OwnerID     *int       `json:"owner_id" validate:"-"`
+	ParentID    *int       `json:"parent_id" validate:"omitempty,gt=0"`
+	ProjectID   *int       `json:"project_id" validate:"omitempty,gt=0"`
+	Title       string     `json:"title" validate:"required,min=3,max=100"`
+	Description string     `json:"description" validate:"max=500"`
+	Status      string     `json:"status" validate:"oneof=todo in_progress done"`
+	DueAt       *time.Time `json:"due_at"`
+	// Location - место, где задачу удобно сделать; nil - не привязана
+	Location *Location `json:"location"`
+	// SnoozedUntil - до какого момента задача отложена: её нет в списке по
+	// умолчанию и в напоминаниях. Меняется только через /tasks/:id/snooze.
+	SnoozedUntil *time.Time `json:"snoozed_until" validate:"-"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// Progress - доля завершённых подзадач в процентах; nil, если их нет.
+	// Считает сервер, из тела запроса не берётся.
+	Progress *int `json:"progress" validate:"-"`
+	// Pinned - закрепил ли задачу текущий пользователь; у каждого свои
+	// закрепления, меняются только через /tasks/:id/pin
+	Pinned bool `json:"pinned" validate:"-"`
+	// DescriptionHTML - Description, отрендеренный из Markdown без опасного
+	// HTML; заполняется только в ответе на ?render=html
+	DescriptionHTML string `json:"description_html,omitempty" validate:"-"`
+}
+
+// Location - точка на карте в градусах WGS 84 и её подпись для людей
+type Location struct {
+	Lat   float64 `json:"lat" validate:"gte=-90,lte=90"`
+	Lon   float64 `json:"lon" validate:"gte=-180,lte=180"`
+	Label string  `json:"label,omitempty" validate:"max=100"`
+}
+
+// earthRadius - радиус Земли в метрах, как earth() в earthdistance
+const earthRadius = 6378168
+
+// Distance - расстояние по большому кругу между точками в метрах
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dlat, dlon := (lat2-lat1)*rad/2, (lon2-lon1)*rad/2
+	h := math.Sin(dlat)*math.Sin(dlat) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dlon)*math.Sin(dlon)
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// Snoozed - отложена ли задача в момент now; завершённая - уже нет
+func (t Task) Snoozed(now time.Time) bool {
+	return t.SnoozedUntil != nil && t.SnoozedUntil.After(now) && t.Status != StatusDone
+}
+
+// TaskFlag - личные отметки пользователя на задаче. Снятая отметка остаётся
+// строкой с новым UpdatedAt, чтобы список задач знал, что он изменился.
+type TaskFlag struct {
+	TaskID    int       `json:"task_id"`
+	Pinned    bool      `json:"pinned"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetProgress считает Progress по числу подзадач и завершённых из них
+func (t *Task) SetProgress(total, done int) {
+	t.Progress = nil
+	if total > 0 {
+		p := done * 100 / total
+		t.Progress = &p
+	}
+}
+
+// NormalizeTitle приводит заголовок к виду для сравнения дублей: нижний
+// регистр, только буквы и цифры, слова через один пробел. То же делает
+// функция normalize_title в базе.
+func NormalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Statuses - все статусы задачи в порядке работы над ней
+var Statuses = []string{StatusTodo, StatusInProgress, StatusDone}
+
+// Transitions - статусы, в которые можно перевести задачу из status;
+// пока переходы не ограничены - из любого статуса в любой другой
+func Transitions(status string) []string {
+	var next []string
+	for _, s := range Statuses {
+		if s != status {
+			next = append(next, s)
+		}
+	}
+	return next
+}
+
+// TaskStats - число задач по статусам
+type TaskStats struct {
+	Todo       int `json:"todo"`
+	InProgress int `json:"in_progress"`
+	Done       int `json:"done"`
+	Total      int `json:"total"`
+	// UpdatedAt - последнее изменение счётчиков; nil, если задач не было
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// Of - число задач в статусе status; пустой status - всего
+func (s TaskStats) Of(status string) int {
+	switch status {
+	case StatusTodo:
+		return s.Todo
+	case StatusInProgress:
+		return s.InProgress
+	case StatusDone:
+		return s.Done
+	}
+	return s.Total
+}
+
+// Add учитывает n задач в статусе status
+func (s *TaskStats) Add(status string, n int) {
+	switch status {
+	case StatusTodo:
+		s.Todo += n
+	case StatusInProgress:
+		s.InProgress += n
+	case StatusDone:
+		s.Done += n
+	}
+	s.Total += n
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Типы событий журнала задач
+const (
+	TaskEventCreated       = "task.created"
+	TaskEventEdited        = "task.edited"
+	TaskEventStatusChanged = "task.status_changed"
+	TaskEventDeleted       = "task.deleted"
+	// TaskEventRestored - задача возвращена к снимку; Data как у created
+	TaskEventRestored = "task.restored"
+)
+
+// TaskEvent - запись журнала задачи. Data содержит только поля, которые
+// задаёт событие этого типа: created - все, edited - title, description,
+// parent_id, project_id и due_at, status_changed - status и completed_at.
+type TaskEvent struct {
+	Seq        int64     `json:"seq"`
+	TaskID     int       `json:"task_id"`
+	Type       string    `json:"type"`
+	Data       Task      `json:"data"`
+	ActorID    *int      `json:"actor_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Apply - состояние задачи после события; exists=false после удаления
+func (e TaskEvent) Apply(t Task) (next Task, exists bool) {
+	switch e.Type {
+	case TaskEventCreated, TaskEventRestored:
+		t = e.Data
+		t.ID = e.TaskID
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = e.OccurredAt
+		}
+	case TaskEventEdited:
+		t.Title, t.Description = e.Data.Title, e.Data.Description
+		t.ParentID, t.ProjectID, t.DueAt = e.Data.ParentID, e.Data.ProjectID, e.Data.DueAt
+		t.Location = e.Data.Location
+	case TaskEventStatusChanged:
+		t.Status, t.CompletedAt = e.Data.Status, e.Data.CompletedAt
+	case TaskEventDeleted:
+		return Task{}, false
+	}
+	t.UpdatedAt = e.OccurredAt
+	return t, true
+}
@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+func TestTaskEventApplyReplaysHistory(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	done := t0.Add(2 * time.Hour)
+	project := 7
+	evs := []model.TaskEvent{
+		{TaskID: 5, Type: model.TaskEventCreated, OccurredAt: t0,
+			Data: model.Task{Title: "Draft", Status: model.StatusTodo}},
+		{TaskID: 5, Type: model.TaskEventEdited, OccurredAt: t0.Add(time.Hour),
+			Data: model.Task{Title: "Final", ProjectID: &project, Status: "ignored"}},
+		{TaskID: 5, Type: model.TaskEventStatusChanged, OccurredAt: done,
+			Data: model.Task{Status: model.StatusDone, CompletedAt: &done, Title: "ignored"}},
+	}
+
+	var (
+		task   model.Task
+		exists bool
+	)
+	for _, e := range evs {
+		task, exists = e.Apply(task)
+	}
+	if !exists || task.ID != 5 || task.Title != "Final" || task.Status != model.StatusDone {
+		t.Fatalf("replayed = %+v, exists=%v", task, exists)
+	}
+	if task.ProjectID == nil || *task.ProjectID != project {
+		t.Errorf("project_id = %v, want %d", task.ProjectID, project)
+	}
+	if !task.CreatedAt.Equal(t0) || !task.UpdatedAt.Equal(done) {
+		t.Errorf("created_at=%v updated_at=%v", task.CreatedAt, task.UpdatedAt)
+	}
+
+	if _, exists := (model.TaskEvent{Type: model.TaskEventDeleted}).Apply(task); exists {
+		t.Error("task exists after delete event")
+	}
+}
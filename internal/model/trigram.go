@@ -0,0 +1,45 @@
+package model
+
+import "strings"
+
+// trigrams - множество триграмм строки, как у pg_trgm: слова в нижнем
+// регистре, дополненные двумя пробелами в начале и одним в конце
+func trigrams(s string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, w := range strings.Fields(NormalizeTitle(s)) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// similarity - доля общих триграмм, как similarity() в pg_trgm
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for g := range a {
+		if _, ok := b[g]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// WordSimilarity - насколько query похож на лучший отрезок text из стольких
+// же слов. Приближение word_similarity() из pg_trgm для хранилища в памяти:
+// опечатка в слове даёт около 0.4-0.6, чужое слово - почти 0.
+func WordSimilarity(query, text string) float64 {
+	q := trigrams(query)
+	n := len(strings.Fields(NormalizeTitle(query)))
+	words := strings.Fields(NormalizeTitle(text))
+	best := 0.0
+	for i := 0; i < len(words); i++ {
+		window := strings.Join(words[i:min(i+n, len(words))], " ")
+		best = max(best, similarity(q, trigrams(window)))
+	}
+	return best
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+type User struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	DisplayName  string    `json:"display_name"`
+	Timezone     string    `json:"timezone"`
+	Locale       string    `json:"locale"`
+	AvatarKey    string    `json:"-"`
+	TOTPSecret   string    `json:"-"`
+	TOTPEnabled  bool      `json:"totp_enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AvatarURL - адрес аватара; ключ объекта входит в URL, чтобы новая
+// картинка не подменялась закешированной старой
+func (u User) AvatarURL() string {
+	if u.AvatarKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("/users/%d/avatar?v=%s", u.ID, path.Base(u.AvatarKey))
+}
+
+// Profile - краткие сведения об авторе для встраивания в другие ответы
+type Profile struct {
+	ID          int    `json:"id"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+func (u User) Profile() Profile {
+	return Profile{ID: u.ID, DisplayName: u.DisplayName, AvatarURL: u.AvatarURL()}
+}
+
+// APIKey - ключ для машинного доступа; сам ключ хранится только как хеш
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Session - вход с конкретного устройства
+type Session struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// InboundWebhook - секретный URL, по которому внешние сервисы создают задачи.
+// Шаблоны подставляют поля JSON по JSONPath: "{{ $.issue.title }}".
+type InboundWebhook struct {
+	ID                  int        `json:"id"`
+	UserID              int        `json:"-"`
+	Name                string     `json:"name" validate:"required,max=100"`
+	ProjectID           *int       `json:"project_id" validate:"omitempty,gt=0"`
+	TitleTemplate       string     `json:"title_template" validate:"required,max=500"`
+	DescriptionTemplate string     `json:"description_template" validate:"max=2000"`
+	CreatedAt           time.Time  `json:"created_at"`
+	LastUsedAt          *time.Time `json:"last_used_at"`
+	// URL со секретным токеном отдаётся только при создании
+	URL string `json:"url,omitempty"`
+}
+
+// OutboundWebhook - адрес, на который уходят события задач пользователя
+type OutboundWebhook struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"-"`
+	URL    string `json:"url" validate:"required,url,max=2000"`
+	// Events - типы событий вида task.created; пусто - все
+	Events    []string  `json:"events" validate:"dive,oneof=task.created task.updated task.completed task.deleted"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret для проверки подписи X-Webhook-Signature отдаётся только при создании
+	Secret string `json:"secret,omitempty"`
+}
+
+// Статусы доставки; failed - попытки кончились, повторить можно вручную
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery - событие, отправляемое на исходящий вебхук
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	// History - попытки доставки, только в ответе по одной доставке
+	History []WebhookAttempt `json:"history,omitempty"`
+}
+
+type WebhookAttempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	// StatusCode - nil, если ответа не было: таймаут, отказ соединения
+	StatusCode *int   `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int    `json:"duration_ms"`
+}
@@ -0,0 +1,131 @@
+package model
+
+import "time"
+
+// Роли в рабочем пространстве
+const (
+	WorkspaceOwner  = "owner"
+	WorkspaceAdmin  = "admin"
+	WorkspaceMember = "member"
+)
+
+// Роли в команде
+const (
+	TeamLead   = "lead"
+	TeamMember = "member"
+)
+
+// WorkspaceRoleRank упорядочивает роли пространства: больше - больше прав
+func WorkspaceRoleRank(role string) int {
+	switch role {
+	case WorkspaceOwner:
+		return 3
+	case WorkspaceAdmin:
+		return 2
+	case WorkspaceMember:
+		return 1
+	}
+	return 0
+}
+
+type Workspace struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" validate:"required,min=1,max=100"`
+	Role      string    `json:"role,omitempty" validate:"-"` // роль текущего пользователя
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Member - участник пространства или команды
+type Member struct {
+	UserID      int       `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Team struct {
+	ID          int    `json:"id"`
+	WorkspaceID int    `json:"workspace_id"`
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	// DefaultProjectID - проект, задачи которого образуют доску команды
+	DefaultProjectID int       `json:"default_project_id"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Видимость проекта
+const (
+	// VisibilityWorkspace - доступ по ролям пространства и команды
+	VisibilityWorkspace = "workspace"
+	// VisibilityPrivate - доступ только явным участникам проекта
+	VisibilityPrivate = "private"
+)
+
+// Роли в проекте
+const (
+	ProjectViewer = "viewer"
+	ProjectEditor = "editor"
+	ProjectAdmin  = "admin"
+)
+
+type Project struct {
+	ID          int    `json:"id"`
+	WorkspaceID int    `json:"workspace_id"`
+	TeamID      *int   `json:"team_id" validate:"omitempty,gt=0"`
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Visibility  string `json:"visibility" validate:"omitempty,oneof=workspace private"`
+	IsDefault   bool   `json:"is_default"`
+	// Role - действующая роль текущего пользователя
+	Role      string    `json:"role,omitempty" validate:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Board - задачи проекта по колонкам статусов
+type Board struct {
+	ProjectID int           `json:"project_id"`
+	Columns   []BoardColumn `json:"columns"`
+	// UpdatedAt - последнее изменение задач доски
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type BoardColumn struct {
+	Status string `json:"status"`
+	// Count - задач в колонке всего, даже если Tasks обрезаны лимитом
+	Count int    `json:"count"`
+	Tasks []Task `json:"tasks"`
+}
+
+// NewBoard раскладывает задачи по колонкам в порядке todo, in_progress, done
+func NewBoard(projectID int, tasks []Task) Board {
+	b := Board{ProjectID: projectID}
+	for _, status := range []string{StatusTodo, StatusInProgress, StatusDone} {
+		col := BoardColumn{Status: status, Tasks: []Task{}}
+		for _, t := range tasks {
+			if t.Status == status {
+				col.Tasks = append(col.Tasks, t)
+			}
+		}
+		col.Count = len(col.Tasks)
+		b.Columns = append(b.Columns, col)
+	}
+	for _, t := range tasks {
+		if t.UpdatedAt.After(b.UpdatedAt) {
+			b.UpdatedAt = t.UpdatedAt
+		}
+	}
+	return b
+}
+
+// Invite - приглашение в пространство по email или по ссылке
+type Invite struct {
+	ID            int       `json:"id"`
+	WorkspaceID   int       `json:"workspace_id"`
+	WorkspaceName string    `json:"workspace_name,omitempty"`
+	Email         string    `json:"email,omitempty"`
+	Role          string    `json:"role"`
+	InvitedBy     *int      `json:"invited_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	// URL со секретным токеном отдаётся только при создании
+	URL string `json:"url,omitempty"`
+}
@@ -0,0 +1,217 @@
+// Package mqtt - минимальный клиент MQTT 3.1.1, только публикация с QoS 1
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetPublish    = 0x30
+	packetPuback     = 0x40
+	packetDisconnect = 0xE0
+
+	ackTimeout = 10 * time.Second
+)
+
+// Client держит одно соединение и переподключается при ошибке.
+// Keep alive выключен: обрыв обнаруживается по неполученному PUBACK.
+type Client struct {
+	Addr     string
+	ClientID string
+	Username string
+	Password string
+	// TLS - для mqtts; nil - обычный TCP
+	TLS *tls.Config
+
+	mu     sync.Mutex
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID uint16
+}
+
+// Publish отправляет сообщение с QoS 1 и ждёт подтверждения брокера;
+// при разорванном соединении делает одну повторную попытку
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.publish(ctx, topic, payload, retain)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		c.close()
+		err = c.publish(ctx, topic, payload, retain)
+	}
+	if err != nil {
+		c.close()
+	}
+	return err
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		_, _ = c.conn.Write([]byte{packetDisconnect, 0})
+	}
+	c.close()
+	return nil
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn, c.r = nil, nil
+	}
+}
+
+func (c *Client) publish(ctx context.Context, topic string, payload []byte, retain bool) error {
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return err
+		}
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+
+	flags := byte(packetPublish | 1<<1) // QoS 1
+	if retain {
+		flags |= 1
+	}
+	body := appendString(nil, topic)
+	body = binary.BigEndian.AppendUint16(body, id)
+	body = append(body, payload...)
+
+	deadline := time.Now().Add(ackTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+	if _, err := c.conn.Write(packet(flags, body)); err != nil {
+		return err
+	}
+	for {
+		typ, data, err := c.read()
+		if err != nil {
+			return err
+		}
+		if typ&0xF0 == packetPuback && len(data) >= 2 && binary.BigEndian.Uint16(data) == id {
+			return nil
+		}
+	}
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: ackTimeout}
+	var conn net.Conn
+	var err error
+	if c.TLS != nil {
+		conn, err = (&tls.Dialer{NetDialer: &d, Config: c.TLS}).DialContext(ctx, "tcp", c.Addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+
+	// clean session, keep alive 0
+	flags := byte(0x02)
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, 0, 0, 0)
+	body = appendString(body, c.ClientID)
+	if c.Username != "" {
+		flags |= 0x80
+		body = appendString(body, c.Username)
+		if c.Password != "" {
+			flags |= 0x40
+			body = appendString(body, c.Password)
+		}
+	}
+	body[7] = flags
+
+	_ = conn.SetDeadline(time.Now().Add(ackTimeout))
+	if _, err := conn.Write(packet(packetConnect, body)); err != nil {
+		c.close()
+		return err
+	}
+	typ, data, err := c.read()
+	if err != nil {
+		c.close()
+		return err
+	}
+	if typ != packetConnack || len(data) < 2 {
+		c.close()
+		return fmt.Errorf("mqtt: unexpected packet 0x%02x instead of CONNACK", typ)
+	}
+	if code := data[1]; code != 0 {
+		c.close()
+		return fmt.Errorf("mqtt: connection refused, code %d", code)
+	}
+	return nil
+}
+
+func (c *Client) read() (byte, []byte, error) {
+	typ, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := readLength(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	data := make([]byte, n)
+	_, err = io.ReadFull(c.r, data)
+	return typ, data, err
+}
+
+// packet - фиксированный заголовок с длиной переменной части
+func packet(header byte, body []byte) []byte {
+	out := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func readLength(r io.ByteReader) (int, error) {
+	n, mult := 0, 1
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(b&0x7F) * mult
+		if b&0x80 == 0 {
+			return n, nil
+		}
+		mult *= 128
+	}
+	return 0, errors.New("mqtt: malformed remaining length")
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
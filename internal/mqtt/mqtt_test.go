@@ -0,0 +1,86 @@
+package mqtt_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Upiter5/todo-app/internal/mqtt"
+)
+
+// broker принимает одно соединение, подтверждает CONNECT и каждый PUBLISH
+// и отдаёт в канал полученные сообщения
+func broker(t *testing.T, got chan<- [3]string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			n, mult := 0, 1
+			for {
+				b, _ := r.ReadByte()
+				n += int(b&0x7F) * mult
+				mult *= 128
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			body := make([]byte, n)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			switch header & 0xF0 {
+			case 0x10:
+				conn.Write([]byte{0x20, 2, 0, 0})
+			case 0x30:
+				tl := int(binary.BigEndian.Uint16(body))
+				topic, id := body[2:2+tl], body[2+tl:4+tl]
+				retain := "false"
+				if header&1 == 1 {
+					retain = "true"
+				}
+				got <- [3]string{string(topic), string(body[4+tl:]), retain}
+				conn.Write([]byte{0x40, 2, id[0], id[1]})
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestPublish(t *testing.T) {
+	got := make(chan [3]string, 2)
+	c := &mqtt.Client{Addr: broker(t, got), ClientID: "test", Username: "u", Password: "p"}
+	defer c.Close()
+
+	if err := c.Publish(context.Background(), "todo/users/1/tasks/completed", []byte(`{"id":1}`), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Publish(context.Background(), "todo/users/1/due_today", []byte(`{"count":0}`), true); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range [][3]string{
+		{"todo/users/1/tasks/completed", `{"id":1}`, "false"},
+		{"todo/users/1/due_today", `{"count":0}`, "true"},
+	} {
+		if m := <-got; m != want {
+			t.Errorf("message = %v, want %v", m, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package nats - минимальный клиент NATS для публикации в JetStream с подтверждением
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ackTimeout = 10 * time.Second
+
+// JetStream публикует сообщения и ждёт PubAck от потока. Поток, в который
+// попадают темы, создаётся заранее (nats stream add).
+type JetStream struct {
+	Addr  string
+	User  string
+	Pass  string
+	Token string
+	TLS   *tls.Config
+
+	mu    sync.Mutex
+	conn  net.Conn
+	r     *bufio.Reader
+	inbox string
+	seq   int
+}
+
+// ErrNoStream - ни один поток JetStream не принимает эту тему
+var ErrNoStream = errors.New("nats: no JetStream stream for subject")
+
+// Send публикует payload с Nats-Msg-Id = id: повтор после обрыва поток отбросит
+func (j *JetStream) Send(ctx context.Context, subject, _, id string, payload []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := j.send(ctx, subject, id, payload)
+	if err != nil {
+		j.close()
+	}
+	return err
+}
+
+func (j *JetStream) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.close()
+	return nil
+}
+
+func (j *JetStream) close() {
+	if j.conn != nil {
+		_ = j.conn.Close()
+		j.conn, j.r = nil, nil
+	}
+}
+
+func (j *JetStream) send(ctx context.Context, subject, id string, payload []byte) error {
+	if j.conn == nil {
+		if err := j.connect(ctx); err != nil {
+			return err
+		}
+	}
+	deadline := time.Now().Add(ackTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = j.conn.SetDeadline(deadline)
+
+	j.seq++
+	reply := j.inbox + "." + strconv.Itoa(j.seq)
+	headers := "NATS/1.0\r\nNats-Msg-Id: " + id + "\r\n\r\n"
+	msg := fmt.Sprintf("HPUB %s %s %d %d\r\n%s%s\r\n", subject, reply, len(headers), len(headers)+len(payload), headers, payload)
+	if _, err := io.WriteString(j.conn, msg); err != nil {
+		return err
+	}
+
+	for {
+		line, err := j.line()
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0, fields[0] == "+OK", fields[0] == "PONG", fields[0] == "INFO":
+		case fields[0] == "PING":
+			if _, err := io.WriteString(j.conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case fields[0] == "-ERR":
+			return fmt.Errorf("nats: %s", strings.TrimPrefix(line, "-ERR "))
+		case fields[0] == "MSG" || fields[0] == "HMSG":
+			hdr, body, err := j.readMsg(fields)
+			if err != nil {
+				return err
+			}
+			if fields[1] != reply {
+				continue // запоздалый ответ на прошлую публикацию
+			}
+			// без потока сервер отвечает статусом 503 в заголовках
+			if strings.HasPrefix(hdr, "NATS/1.0 503") {
+				return ErrNoStream
+			}
+			var ack struct {
+				Error *struct {
+					Description string `json:"description"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(body, &ack); err != nil {
+				return fmt.Errorf("nats: bad PubAck: %w", err)
+			}
+			if ack.Error != nil {
+				return fmt.Errorf("nats: jetstream: %s", ack.Error.Description)
+			}
+			return nil
+		}
+	}
+}
+
+// readMsg читает тело MSG/HMSG; заголовок строки уже разобран в fields
+func (j *JetStream) readMsg(fields []string) (string, []byte, error) {
+	hdrLen, total := 0, 0
+	var err error
+	if fields[0] == "HMSG" {
+		if len(fields) < 5 {
+			return "", nil, errors.New("nats: malformed HMSG")
+		}
+		if hdrLen, err = strconv.Atoi(fields[len(fields)-2]); err != nil {
+			return "", nil, err
+		}
+	} else if len(fields) < 4 {
+		return "", nil, errors.New("nats: malformed MSG")
+	}
+	if total, err = strconv.Atoi(fields[len(fields)-1]); err != nil {
+		return "", nil, err
+	}
+	buf := make([]byte, total+2)
+	if _, err := io.ReadFull(j.r, buf); err != nil {
+		return "", nil, err
+	}
+	return string(buf[:hdrLen]), buf[hdrLen:total], nil
+}
+
+func (j *JetStream) line() (string, error) {
+	s, err := j.r.ReadString('\n')
+	return strings.TrimRight(s, "\r\n"), err
+}
+
+func (j *JetStream) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: ackTimeout}
+	conn, err := d.DialContext(ctx, "tcp", j.Addr)
+	if err != nil {
+		return err
+	}
+	j.conn, j.r = conn, bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(ackTimeout))
+
+	info, err := j.line()
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		j.close()
+		return fmt.Errorf("nats: expected INFO, got %q: %v", info, err)
+	}
+	var server struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	_ = json.Unmarshal([]byte(strings.TrimPrefix(info, "INFO ")), &server)
+	if j.TLS != nil || server.TLSRequired {
+		cfg := j.TLS
+		if cfg == nil {
+			cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName, _, _ = net.SplitHostPort(j.Addr)
+		}
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			j.close()
+			return err
+		}
+		j.conn, j.r = tc, bufio.NewReader(tc)
+	}
+
+	opts, _ := json.Marshal(map[string]any{
+		"verbose": false, "pedantic": false, "name": "todo-app", "lang": "go", "version": "1",
+		"protocol": 1, "headers": true, "no_responders": true,
+		"user": j.User, "pass": j.Pass, "auth_token": j.Token,
+	})
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	j.inbox, j.seq = "_INBOX."+hex.EncodeToString(b), 0
+	if _, err := fmt.Fprintf(j.conn, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", opts, j.inbox); err != nil {
+		j.close()
+		return err
+	}
+	for {
+		line, err := j.line()
+		if err != nil {
+			j.close()
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			j.close()
+			return fmt.Errorf("nats: %s", strings.TrimPrefix(line, "-ERR "))
+		}
+	}
+}
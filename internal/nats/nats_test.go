@@ -0,0 +1,83 @@
+package nats_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Upiter5/todo-app/internal/nats"
+)
+
+// server - поддельный JetStream: подтверждает HPUB в тему "todo.>" и отвечает
+// 503 без потока на остальные; полученные заголовки и тела уходят в канал
+func server(t *testing.T, got chan<- [2]string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"headers\":true}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			f := strings.Fields(line)
+			switch f[0] {
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case "HPUB":
+				hdr, _ := strconv.Atoi(f[3])
+				total, _ := strconv.Atoi(f[4])
+				buf := make([]byte, total+2)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return
+				}
+				if !strings.HasPrefix(f[1], "todo.") {
+					fmt.Fprintf(conn, "HMSG %s 1 16 16\r\nNATS/1.0 503\r\n\r\n\r\n", f[2])
+					continue
+				}
+				got <- [2]string{string(buf[:hdr]), string(buf[hdr:total])}
+				ack := `{"stream":"TODO","seq":1}`
+				fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", f[2], len(ack), ack)
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestSendWaitsForAck(t *testing.T) {
+	got := make(chan [2]string, 1)
+	js := &nats.JetStream{Addr: server(t, got)}
+	defer js.Close()
+
+	if err := js.Send(context.Background(), "todo.task.created", "1", "abc", []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	msg := <-got
+	if !strings.Contains(msg[0], "Nats-Msg-Id: abc") {
+		t.Errorf("headers = %q, want Nats-Msg-Id", msg[0])
+	}
+	if msg[1] != `{"id":1}` {
+		t.Errorf("payload = %q", msg[1])
+	}
+
+	if err := js.Send(context.Background(), "other.task", "1", "def", []byte(`{}`)); !errors.Is(err, nats.ErrNoStream) {
+		t.Fatalf("Send without stream: err = %v, want ErrNoStream", err)
+	}
+}
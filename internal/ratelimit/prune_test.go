@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// fastStore - тариф, при котором корзина наполняется за доли микросекунды:
+// prune выбрасывает её почти сразу после каждого запроса
+type fastStore struct{}
+
+func (fastStore) RateLimitTier(context.Context, int, int) (model.RateLimitTier, error) {
+	return model.RateLimitTier{Name: "fast", RequestsPerMinute: 600_000_000, Burst: 1000}, nil
+}
+
+func (fastStore) AddRateLimitUsage(context.Context, []model.RateLimitUsage) error { return nil }
+
+func TestAllowWhilePruning(t *testing.T) {
+	l := New(fastStore{}, zerolog.Nop())
+	ctx := context.Background()
+	done := make(chan struct{})
+
+	var pruners sync.WaitGroup
+	for range 4 {
+		pruners.Add(1)
+		go func() {
+			defer pruners.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					l.prune()
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for userID := 1; userID <= 8; userID++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20000 {
+				if d := l.Allow(ctx, userID, 0); !d.Allowed || d.Tier.Name != "fast" {
+					t.Errorf("user %d: decision = %+v, want allowed on the fast tier", userID, d)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	pruners.Wait()
+}
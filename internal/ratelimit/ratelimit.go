@@ -0,0 +1,288 @@
+// Package ratelimit - ограничение частоты запросов по тарифам пользователей
+// и API-ключей. Корзины токенов живут в памяти процесса: у каждого экземпляра
+// сервера свой счёт, общие только тарифы и отчёт об использовании.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderReset     = "X-RateLimit-Reset"
+)
+
+// tierTTL - как долго помним тариф; столько же ждёт смена тарифа админом
+const tierTTL = time.Minute
+
+// Default - тариф на случай, когда узнать настоящий не вышло: сбой базы
+// не должен отключать ни API, ни ограничение
+var Default = model.RateLimitTier{Name: "standard", RequestsPerMinute: 120, Burst: 30}
+
+// Store - тарифы и учёт запросов
+type Store interface {
+	// RateLimitTier - тариф ключа apiKeyID, а без него или ключа без тарифа -
+	// пользователя userID
+	RateLimitTier(ctx context.Context, userID, apiKeyID int) (model.RateLimitTier, error)
+	// AddRateLimitUsage прибавляет счётчики к уже записанным за те же сутки
+	AddRateLimitUsage(ctx context.Context, usage []model.RateLimitUsage) error
+}
+
+// subject - чей счёт: у каждого API-ключа свой, входы по JWT и cookie
+// сессии делят счёт пользователя
+type subject struct {
+	userID, apiKeyID int
+}
+
+type bucket struct {
+	tier     model.RateLimitTier
+	loadedAt time.Time
+	tokens   float64
+	at       time.Time
+}
+
+type usageKey struct {
+	subject
+	day time.Time
+}
+
+// Decision - итог проверки одного запроса
+type Decision struct {
+	Tier      model.RateLimitTier
+	Allowed   bool
+	Remaining int
+	// Reset - через сколько корзина наполнится целиком
+	Reset time.Duration
+	// RetryAfter - через сколько пропустим следующий запрос; 0, если этот пропущен
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	store    Store
+	log      zerolog.Logger
+	now      func() time.Time
+	disabled atomic.Bool
+	mu       sync.Mutex
+	buckets  map[subject]*bucket
+	usage    map[usageKey]*model.RateLimitUsage
+}
+
+type Option func(*Limiter)
+
+// WithClock подменяет часы, например в тестах
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) { l.now = now }
+}
+
+func New(store Store, logger zerolog.Logger, opts ...Option) *Limiter {
+	l := &Limiter{
+		store:   store,
+		log:     logger,
+		now:     time.Now,
+		buckets: map[subject]*bucket{},
+		usage:   map[usageKey]*model.RateLimitUsage{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// SetDisabled выключает и снова включает ограничение без перезапуска;
+// корзины и несохранённые счётчики остаются
+func (l *Limiter) SetDisabled(disabled bool) { l.disabled.Store(disabled) }
+
+// ReloadTiers забывает тарифы: каждый следующий запрос перечитает свой
+// из базы, не дожидаясь tierTTL
+func (l *Limiter) ReloadTiers() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, b := range l.buckets {
+		b.loadedAt = time.Time{}
+	}
+}
+
+// Allow списывает запрос со счёта пользователя или ключа
+func (l *Limiter) Allow(ctx context.Context, userID, apiKeyID int) Decision {
+	s := subject{userID, apiKeyID}
+	now := l.now()
+
+	// тариф читаем без блокировки, чтобы медленная база не держала всех
+	var (
+		tier     model.RateLimitTier
+		loadedAt time.Time
+		tierErr  error
+	)
+	l.mu.Lock()
+	b := l.buckets[s]
+	stale := b == nil || now.Sub(b.loadedAt) >= tierTTL
+	if !stale {
+		tier, loadedAt = b.tier, b.loadedAt
+	}
+	l.mu.Unlock()
+	if stale {
+		tier, tierErr = l.store.RateLimitTier(ctx, userID, apiKeyID)
+		if tierErr != nil {
+			l.log.Warn().Err(tierErr).Int("user_id", userID).Int("api_key_id", apiKeyID).
+				Msg("Failed to load rate limit tier")
+			tier = Default
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// пока читали тариф, prune мог выбросить полную корзину: новая получает
+	// тот же тариф, что был у неё
+	b = l.buckets[s]
+	if b == nil {
+		b = &bucket{tier: tier, loadedAt: loadedAt, tokens: float64(tier.Burst), at: now}
+		l.buckets[s] = b
+	}
+	if stale {
+		// при сбое оставляем прежний тариф, но базу до конца tierTTL не дёргаем
+		if tierErr == nil || b.tier.Name == "" {
+			b.tier = tier
+		}
+		b.loadedAt = now
+	}
+
+	rate := float64(b.tier.RequestsPerMinute) / 60
+	burst := float64(b.tier.Burst)
+	b.tokens = min(burst, b.tokens+now.Sub(b.at).Seconds()*rate)
+	b.at = now
+
+	d := Decision{Tier: b.tier}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((burst - b.tokens) / rate)
+	l.count(s, now, d.Allowed)
+	return d
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func (l *Limiter) count(s subject, now time.Time, allowed bool) {
+	key := usageKey{s, now.UTC().Truncate(24 * time.Hour)}
+	u := l.usage[key]
+	if u == nil {
+		u = &model.RateLimitUsage{UserID: s.userID, APIKeyID: s.apiKeyID, Day: key.day}
+		l.usage[key] = u
+	}
+	u.Requests++
+	if !allowed {
+		u.Limited++
+	}
+}
+
+// Run раз в every сбрасывает счётчики в базу и забывает полные корзины:
+// полная корзина ничем не отличается от новой. Остаток после остановки
+// сервера записывается отдельным вызовом Flush.
+func (l *Limiter) Run(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		l.Flush(ctx)
+		l.prune()
+	}
+}
+
+// Flush записывает накопленные счётчики; при ошибке возвращает их в очередь
+func (l *Limiter) Flush(ctx context.Context) {
+	l.mu.Lock()
+	pending := l.usage
+	l.usage = map[usageKey]*model.RateLimitUsage{}
+	l.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	usage := make([]model.RateLimitUsage, 0, len(pending))
+	for _, u := range pending {
+		usage = append(usage, *u)
+	}
+	if err := l.store.AddRateLimitUsage(ctx, usage); err != nil {
+		l.log.Error().Err(err).Int("subjects", len(usage)).Msg("Failed to save rate limit usage")
+		l.mu.Lock()
+		for key, u := range pending {
+			if cur := l.usage[key]; cur != nil {
+				cur.Requests += u.Requests
+				cur.Limited += u.Limited
+			} else {
+				l.usage[key] = u
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (l *Limiter) prune() {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for s, b := range l.buckets {
+		rate := float64(b.tier.RequestsPerMinute) / 60
+		if b.tokens+now.Sub(b.at).Seconds()*rate >= float64(b.tier.Burst) {
+			delete(l.buckets, s)
+		}
+	}
+}
+
+// Wrap ставит ограничение за каждой схемой: запрос считается после
+// успешной аутентификации, от имени узнанного пользователя или ключа
+func (l *Limiter) Wrap(authenticators ...auth.Authenticator) []auth.Authenticator {
+	wrapped := make([]auth.Authenticator, len(authenticators))
+	for i, a := range authenticators {
+		wrapped[i] = &limited{limiter: l, next: a}
+	}
+	return wrapped
+}
+
+type limited struct {
+	limiter *Limiter
+	next    auth.Authenticator
+}
+
+func (a *limited) Authenticate(c *fiber.Ctx) (*auth.Principal, error) {
+	p, err := a.next.Authenticate(c)
+	if err != nil || p.UserID == 0 || a.limiter.disabled.Load() {
+		return p, err
+	}
+
+	d := a.limiter.Allow(c.UserContext(), p.UserID, p.APIKeyID)
+	c.Set(HeaderLimit, strconv.Itoa(d.Tier.RequestsPerMinute))
+	c.Set(HeaderRemaining, strconv.Itoa(d.Remaining))
+	c.Set(HeaderReset, strconv.Itoa(ceilSeconds(d.Reset)))
+	if !d.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(ceilSeconds(d.RetryAfter), 1)))
+		return nil, fiber.NewError(fiber.StatusTooManyRequests, "Rate limit exceeded")
+	}
+	return p, nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
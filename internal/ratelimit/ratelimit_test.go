@@ -0,0 +1,99 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/ratelimit"
+)
+
+type fakeStore struct {
+	usage []model.RateLimitUsage
+}
+
+// у ключа 7 свой тариф, остальные живут по тарифу пользователя
+func (f *fakeStore) RateLimitTier(_ context.Context, _, apiKeyID int) (model.RateLimitTier, error) {
+	if apiKeyID == 7 {
+		return model.RateLimitTier{Name: "pro", RequestsPerMinute: 600, Burst: 10}, nil
+	}
+	return model.RateLimitTier{Name: "standard", RequestsPerMinute: 60, Burst: 2}, nil
+}
+
+func (f *fakeStore) AddRateLimitUsage(_ context.Context, usage []model.RateLimitUsage) error {
+	f.usage = append(f.usage, usage...)
+	return nil
+}
+
+type staticAuth struct{ p auth.Principal }
+
+func (a staticAuth) Authenticate(*fiber.Ctx) (*auth.Principal, error) {
+	p := a.p
+	return &p, nil
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{}
+	l := ratelimit.New(store, zerolog.Nop(), ratelimit.WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		if d := l.Allow(ctx, 1, 0); d.Allowed != want {
+			t.Fatalf("request %d: allowed = %v, want %v", i+1, d.Allowed, want)
+		}
+	}
+	if d := l.Allow(ctx, 1, 7); !d.Allowed || d.Tier.Name != "pro" || d.Remaining != 9 {
+		t.Errorf("key decision = %+v, want its own pro bucket", d)
+	}
+
+	// 60 в минуту - токен в секунду
+	now = now.Add(time.Second)
+	if d := l.Allow(ctx, 1, 0); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("after refill: %+v, want allowed with 0 remaining", d)
+	}
+	if d := l.Allow(ctx, 1, 0); d.Allowed || d.RetryAfter != time.Second {
+		t.Errorf("retry after = %v, want 1s", d.RetryAfter)
+	}
+
+	l.Flush(ctx)
+	got := map[int]model.RateLimitUsage{}
+	for _, u := range store.usage {
+		got[u.APIKeyID] = u
+	}
+	if u := got[0]; u.Requests != 5 || u.Limited != 2 || !u.Day.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("user usage = %+v, want 5 requests, 2 limited on 2026-03-01", u)
+	}
+	if u := got[7]; u.Requests != 1 || u.Limited != 0 {
+		t.Errorf("key usage = %+v, want 1 request", u)
+	}
+}
+
+func TestWrapRespondsTooManyRequests(t *testing.T) {
+	l := ratelimit.New(&fakeStore{}, zerolog.Nop())
+	app := fiber.New()
+	app.Get("/", auth.Middleware(l.Wrap(staticAuth{auth.Principal{UserID: 1}})...),
+		func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	for i, want := range []int{fiber.StatusNoContent, fiber.StatusNoContent, fiber.StatusTooManyRequests} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("request %d: status = %d, want %d", i+1, resp.StatusCode, want)
+		}
+		if got := resp.Header.Get(ratelimit.HeaderLimit); got != "60" {
+			t.Errorf("%s = %q, want 60", ratelimit.HeaderLimit, got)
+		}
+		if want == fiber.StatusTooManyRequests && resp.Header.Get(fiber.HeaderRetryAfter) != "1" {
+			t.Errorf("Retry-After = %q, want 1", resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Elasticsearch - Elasticsearch или OpenSearch: API документов, _bulk и
+// _search у них общий. Индекс создаётся при первой записи с динамической
+// схемой; опечатки прощает fuzziness AUTO, заголовок весит втрое больше.
+type Elasticsearch struct {
+	BaseURL   string
+	IndexName string
+	Username  string
+	Password  string
+	HTTP      *http.Client
+}
+
+func (e *Elasticsearch) url(path string) string {
+	return strings.TrimRight(e.BaseURL, "/") + path
+}
+
+func (e *Elasticsearch) auth(req *http.Request) {
+	if e.Username != "" {
+		req.SetBasicAuth(e.Username, e.Password)
+	}
+}
+
+func (e *Elasticsearch) Index(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, d := range docs {
+		action := map[string]any{"index": map[string]string{"_index": e.IndexName, "_id": strconv.Itoa(d.ID)}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	// _bulk отвечает 200, даже если отдельный документ не записан
+	var out struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := doJSON(ctx, e.HTTP, e.auth, http.MethodPost, e.url("/_bulk"), "application/x-ndjson",
+		body.Bytes(), false, &out); err != nil {
+		return err
+	}
+	if out.Errors {
+		for _, item := range out.Items {
+			for _, r := range item {
+				if r.Status/100 != 2 {
+					return fmt.Errorf("search: document rejected: %d %s", r.Status, r.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Elasticsearch) Delete(ctx context.Context, id int) error {
+	return doJSON(ctx, e.HTTP, e.auth, http.MethodDelete,
+		e.url("/"+url.PathEscape(e.IndexName)+"/_doc/"+strconv.Itoa(id)), "", nil, true, nil)
+}
+
+func (e *Elasticsearch) Search(ctx context.Context, q Query) ([]int, error) {
+	query := map[string]any{
+		"must": map[string]any{"multi_match": map[string]any{
+			"query": q.Text, "fields": []string{"title^3", "description"}, "fuzziness": "AUTO"}},
+	}
+	if q.OwnerID != nil {
+		query["filter"] = map[string]any{"bool": map[string]any{
+			"should": []any{
+				map[string]any{"exists": map[string]string{"field": "project_id"}},
+				map[string]any{"term": map[string]int{"owner_id": *q.OwnerID}},
+			},
+			"minimum_should_match": 1,
+		}}
+	}
+	body, err := json.Marshal(map[string]any{"size": q.Limit, "_source": false, "query": map[string]any{"bool": query}})
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	// индекса ещё нет, пока не проиндексирована ни одна задача
+	if err := doJSON(ctx, e.HTTP, e.auth, http.MethodPost, e.url("/"+url.PathEscape(e.IndexName)+"/_search"),
+		"application/json", body, true, &out); err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(out.Hits.Hits))
+	for _, h := range out.Hits.Hits {
+		if id, err := strconv.Atoi(h.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
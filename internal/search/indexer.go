@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+const (
+	indexerQueueSize = 1024
+	// reindexBatch - документов в одном запросе Reindex
+	reindexBatch = 500
+)
+
+// Indexer переносит события задач в Engine в порядке возникновения, так что
+// поздняя правка не перезапишется ранней. Подзадачи, удалённые вместе с
+// родителем, остаются в индексе до Reindex; в выдачу они не попадают -
+// найденное проверяет хранилище.
+type Indexer struct {
+	engine Engine
+	log    zerolog.Logger
+	queue  chan events.Event
+}
+
+func NewIndexer(engine Engine, logger zerolog.Logger) *Indexer {
+	return &Indexer{engine: engine, log: logger, queue: make(chan events.Event, indexerQueueSize)}
+}
+
+// Publish не ждёт движка: при переполненной очереди событие теряется, и
+// задача догонит индекс со следующей правкой или после Reindex
+func (x *Indexer) Publish(_ context.Context, e events.Event) {
+	select {
+	case x.queue <- e:
+	default:
+		x.log.Warn().Str("type", e.Type).Int("task_id", e.TaskID).Msg("Search index queue is full, event dropped")
+	}
+}
+
+// Run применяет события к индексу, повторяя неудачные с паузой, пока ctx не отменён
+func (x *Indexer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-x.queue:
+			x.apply(ctx, e)
+		}
+	}
+}
+
+func (x *Indexer) apply(ctx context.Context, e events.Event) {
+	for delay := time.Second; ; delay = min(2*delay, time.Minute) {
+		var err error
+		switch {
+		case e.Type == events.TaskDeleted:
+			err = x.engine.Delete(ctx, e.TaskID)
+		case e.Task != nil:
+			err = x.engine.Index(ctx, NewDocument(*e.Task))
+		}
+		if err == nil {
+			return
+		}
+		x.log.Error().Err(err).Str("type", e.Type).Int("task_id", e.TaskID).Msg("Failed to update search index, retrying")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Reindex записывает задачи в индекс пачками: для первого наполнения и после
+// потерянных событий
+func (x *Indexer) Reindex(ctx context.Context, tasks []model.Task) error {
+	for start := 0; start < len(tasks); start += reindexBatch {
+		batch := tasks[start:min(start+reindexBatch, len(tasks))]
+		docs := make([]Document, len(batch))
+		for i, t := range batch {
+			docs[i] = NewDocument(t)
+		}
+		if err := x.engine.Index(ctx, docs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Meilisearch - индекс в Meilisearch. Опечатки он прощает сам; фильтру по
+// владельцу нужны filterableAttributes, их выставляет первая запись или поиск.
+// Запись в Meilisearch асинхронна: документ находится через долю секунды.
+type Meilisearch struct {
+	BaseURL   string
+	IndexName string
+	APIKey    string
+	HTTP      *http.Client
+
+	mu         sync.Mutex
+	configured bool
+}
+
+func (m *Meilisearch) url(path string) string {
+	return strings.TrimRight(m.BaseURL, "/") + "/indexes/" + url.PathEscape(m.IndexName) + path
+}
+
+func (m *Meilisearch) auth(req *http.Request) {
+	if m.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	}
+}
+
+// configure один раз задаёт поля поиска и фильтров; неудача повторится при
+// следующем обращении
+func (m *Meilisearch) configure(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.configured {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{
+		"searchableAttributes": []string{"title", "description"},
+		"filterableAttributes": []string{"owner_id", "project_id", "status"},
+	})
+	if err != nil {
+		return err
+	}
+	if err := doJSON(ctx, m.HTTP, m.auth, http.MethodPatch, m.url("/settings"), "application/json",
+		body, false, nil); err != nil {
+		return err
+	}
+	m.configured = true
+	return nil
+}
+
+func (m *Meilisearch) Index(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := m.configure(ctx); err != nil {
+		return err
+	}
+	body, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	return doJSON(ctx, m.HTTP, m.auth, http.MethodPost, m.url("/documents?primaryKey=id"), "application/json",
+		body, false, nil)
+}
+
+func (m *Meilisearch) Delete(ctx context.Context, id int) error {
+	return doJSON(ctx, m.HTTP, m.auth, http.MethodDelete, m.url("/documents/"+strconv.Itoa(id)), "", nil, true, nil)
+}
+
+func (m *Meilisearch) Search(ctx context.Context, q Query) ([]int, error) {
+	if err := m.configure(ctx); err != nil {
+		return nil, err
+	}
+	req := map[string]any{"q": q.Text, "limit": q.Limit, "attributesToRetrieve": []string{"id"}}
+	if q.OwnerID != nil {
+		req["filter"] = "project_id EXISTS OR owner_id = " + strconv.Itoa(*q.OwnerID)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Hits []struct {
+			ID int `json:"id"`
+		} `json:"hits"`
+	}
+	if err := doJSON(ctx, m.HTTP, m.auth, http.MethodPost, m.url("/search"), "application/json",
+		body, false, &out); err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(out.Hits))
+	for i, h := range out.Hits {
+		ids[i] = h.ID
+	}
+	return ids, nil
+}
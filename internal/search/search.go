@@ -0,0 +1,86 @@
+// Package search - внешний поисковый движок для задач: Elasticsearch,
+// OpenSearch или Meilisearch. Индекс пополняется событиями задач, а доступ
+// к найденному по-прежнему проверяет хранилище.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Document - задача в индексе. Пустые owner_id и project_id не пишутся:
+// фильтр «есть проект» в обоих движках проверяет наличие поля.
+type Document struct {
+	ID          int       `json:"id"`
+	OwnerID     *int      `json:"owner_id,omitempty"`
+	ProjectID   *int      `json:"project_id,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewDocument(t model.Task) Document {
+	return Document{ID: t.ID, OwnerID: t.OwnerID, ProjectID: t.ProjectID, Title: t.Title,
+		Description: t.Description, Status: t.Status, UpdatedAt: t.UpdatedAt}
+}
+
+// Query - поисковый запрос; OwnerID nil - без фильтра по владельцу (auth.System)
+type Query struct {
+	Text    string
+	OwnerID *int
+	Limit   int
+}
+
+// Engine - поисковый движок
+type Engine interface {
+	Index(ctx context.Context, docs ...Document) error
+	Delete(ctx context.Context, id int) error
+	// Search - id задач, самые релевантные первыми. Чужие личные задачи
+	// отсекает сам движок, а задачи проектов - нет: кто в каком проекте,
+	// знает только база, поэтому результат надо проверить через хранилище.
+	Search(ctx context.Context, q Query) ([]int, error)
+}
+
+// doJSON отправляет body в JSON и разбирает ответ в out; 404 с okNotFound -
+// не ошибка, например удаление уже удалённого документа
+func doJSON(ctx context.Context, client *http.Client, auth func(*http.Request), method, url, contentType string,
+	body []byte, okNotFound bool, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	auth(req)
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && okNotFound {
+		return nil
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("search: %s %s: %d %s", method, url, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
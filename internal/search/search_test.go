@@ -0,0 +1,196 @@
+package search_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/search"
+)
+
+// recorded - запрос, который получил поддельный движок
+type recorded struct {
+	method, path, auth string
+	body               string
+}
+
+func fakeEngine(t *testing.T, reply func(r *http.Request) (int, string)) (*httptest.Server, func() []recorded) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []recorded
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, recorded{r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), string(body)})
+		mu.Unlock()
+		status, out := reply(r)
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, out)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []recorded {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(reqs)
+	}
+}
+
+func TestElasticsearch(t *testing.T) {
+	srv, requests := fakeEngine(t, func(r *http.Request) (int, string) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			return http.StatusOK, `{"hits": {"hits": [{"_id": "7"}, {"_id": "3"}]}}`
+		case r.URL.Path == "/_bulk":
+			return http.StatusOK, `{"errors": false, "items": [{"index": {"status": 201}}]}`
+		}
+		return http.StatusNotFound, `{"result": "not_found"}`
+	})
+	es := &search.Elasticsearch{BaseURL: srv.URL, IndexName: "tasks", Username: "elastic", Password: "secret"}
+	ctx := context.Background()
+
+	owner := 5
+	ids, err := es.Search(ctx, search.Query{Text: "grocerys", OwnerID: &owner, Limit: 10})
+	if err != nil || !slices.Equal(ids, []int{7, 3}) {
+		t.Fatalf("Search = %v, %v; want [7 3]", ids, err)
+	}
+	if err := es.Index(ctx, search.Document{ID: 7, Title: "Buy groceries", OwnerID: &owner}); err != nil {
+		t.Fatal(err)
+	}
+	// удаление того, чего в индексе нет, - не ошибка
+	if err := es.Delete(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	var query map[string]any
+	if err := json.Unmarshal([]byte(reqs[0].body), &query); err != nil {
+		t.Fatal(err)
+	}
+	if reqs[0].path != "/tasks/_search" || !strings.Contains(reqs[0].body, `"fuzziness":"AUTO"`) ||
+		!strings.Contains(reqs[0].body, `"owner_id":5`) || !strings.HasPrefix(reqs[0].auth, "Basic ") {
+		t.Errorf("search request = %+v", reqs[0])
+	}
+	if lines := strings.Split(strings.TrimSpace(reqs[1].body), "\n"); len(lines) != 2 ||
+		lines[0] != `{"index":{"_id":"7","_index":"tasks"}}` {
+		t.Errorf("bulk body = %q", reqs[1].body)
+	}
+	if reqs[2].method != http.MethodDelete || reqs[2].path != "/tasks/_doc/7" {
+		t.Errorf("delete request = %+v", reqs[2])
+	}
+}
+
+func TestElasticsearchBulkRejection(t *testing.T) {
+	srv, _ := fakeEngine(t, func(*http.Request) (int, string) {
+		return http.StatusOK, `{"errors": true, "items": [{"index": {"status": 400, "error": {"type": "mapper_parsing_exception"}}}]}`
+	})
+	es := &search.Elasticsearch{BaseURL: srv.URL, IndexName: "tasks"}
+	if err := es.Index(context.Background(), search.Document{ID: 1}); err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Fatalf("err = %v, want the rejected document error", err)
+	}
+}
+
+func TestMeilisearch(t *testing.T) {
+	srv, requests := fakeEngine(t, func(r *http.Request) (int, string) {
+		if strings.HasSuffix(r.URL.Path, "/search") {
+			return http.StatusOK, `{"hits": [{"id": 4}]}`
+		}
+		return http.StatusAccepted, `{"taskUid": 1}`
+	})
+	meili := &search.Meilisearch{BaseURL: srv.URL, IndexName: "tasks", APIKey: "key"}
+	ctx := context.Background()
+
+	owner := 2
+	if ids, err := meili.Search(ctx, search.Query{Text: "milk", OwnerID: &owner, Limit: 5}); err != nil || !slices.Equal(ids, []int{4}) {
+		t.Fatalf("Search = %v, %v; want [4]", ids, err)
+	}
+	if err := meili.Index(ctx, search.Document{ID: 4, Title: "Buy milk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// настройки индекса выставляются один раз
+	var paths []string
+	for _, r := range requests() {
+		paths = append(paths, r.method+" "+r.path)
+		if r.auth != "Bearer key" {
+			t.Errorf("%s %s: Authorization = %q", r.method, r.path, r.auth)
+		}
+	}
+	want := []string{"PATCH /indexes/tasks/settings", "POST /indexes/tasks/search", "POST /indexes/tasks/documents?primaryKey=id"}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("requests = %v, want %v", paths, want)
+	}
+	if body := requests()[1].body; !strings.Contains(body, `"filter":"project_id EXISTS OR owner_id = 2"`) {
+		t.Errorf("search body = %s", body)
+	}
+}
+
+// memEngine - движок в памяти для проверки Indexer
+type memEngine struct {
+	mu   sync.Mutex
+	docs map[int]search.Document
+}
+
+func (m *memEngine) Index(_ context.Context, docs ...search.Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range docs {
+		m.docs[d.ID] = d
+	}
+	return nil
+}
+
+func (m *memEngine) Delete(_ context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.docs, id)
+	return nil
+}
+
+func (m *memEngine) Search(context.Context, search.Query) ([]int, error) { return nil, nil }
+
+func (m *memEngine) titles() map[int]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := map[int]string{}
+	for id, d := range m.docs {
+		out[id] = d.Title
+	}
+	return out
+}
+
+func TestIndexer(t *testing.T) {
+	engine := &memEngine{docs: map[int]search.Document{}}
+	x := search.NewIndexer(engine, zerolog.Nop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := x.Reindex(ctx, []model.Task{{ID: 1, Title: "Buy milk"}, {ID: 2, Title: "Call mom"}}); err != nil {
+		t.Fatal(err)
+	}
+	x.Publish(ctx, events.Event{Type: events.TaskUpdated, TaskID: 1, Task: &model.Task{ID: 1, Title: "Buy oat milk"}})
+	x.Publish(ctx, events.Event{Type: events.TaskDeleted, TaskID: 2})
+	go x.Run(ctx)
+
+	want := map[int]string{1: "Buy oat milk"}
+	deadline := time.Now().Add(2 * time.Second)
+	for !maps.Equal(engine.titles(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("index = %v, want %v", engine.titles(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
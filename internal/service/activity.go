@@ -0,0 +1,37 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Виды записей ленты активности
+const (
+	ActivityCreated   = "created"
+	ActivityCompleted = "completed"
+)
+
+const maxActivityItems = 50
+
+// ActivityItem - событие ленты: задача создана или завершена
+type ActivityItem struct {
+	Kind string
+	At   time.Time
+	Task model.Task
+}
+
+// Activity восстанавливает последние события по датам задач, новые первыми.
+// Истории изменений нет, поэтому у задачи не больше двух событий.
+func Activity(tasks []model.Task) []ActivityItem {
+	items := make([]ActivityItem, 0, len(tasks))
+	for _, t := range tasks {
+		items = append(items, ActivityItem{Kind: ActivityCreated, At: t.CreatedAt, Task: t})
+		if t.CompletedAt != nil {
+			items = append(items, ActivityItem{Kind: ActivityCompleted, At: *t.CompletedAt, Task: t})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].At.After(items[j].At) })
+	return items[:min(len(items), maxActivityItems)]
+}
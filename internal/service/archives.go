@@ -0,0 +1,289 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Upiter5/todo-app/internal/blob"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var (
+	ErrArchivesDisabled = errors.New("workspace archives are not enabled")
+	// ErrArchiveNotReady - архив ещё собирается или сборка не удалась
+	ErrArchiveNotReady = errors.New("archive is not ready")
+)
+
+const (
+	// maxInlineArchiveTasks - до скольких задач архив отдаётся сразу в ответе;
+	// больше - собирается в фоне
+	maxInlineArchiveTasks = 1000
+	// archiveTTL - сколько хранится готовый архив
+	archiveTTL = time.Hour
+)
+
+// WithArchives включает ZIP-архивы пространств: дамп проектов и задач, как в
+// снимке, и файлы из blobs - аватары участников. Нужны и снимки (WithSnapshots).
+func WithArchives(blobs blob.Store, users storage.UserStore) WorkspaceOption {
+	return func(s *WorkspaceService) {
+		s.archives = &archiveJobs{blobs: blobs, users: users, jobs: map[string]*archiveJob{}}
+	}
+}
+
+// archiveJobs - фоновые сборки этого процесса. Архив лежит во временном
+// файле, поэтому статус и скачивание работают только на том экземпляре,
+// который начал сборку.
+type archiveJobs struct {
+	blobs blob.Store
+	users storage.UserStore
+
+	mu   sync.Mutex
+	jobs map[string]*archiveJob
+}
+
+type archiveJob struct {
+	model.ArchiveJob
+	createdBy int
+	path      string
+}
+
+// Archive - ZIP-архив пространства workspaceID; только admin. Небольшой
+// архив write пишет сразу в ответ. Большой, или при async, собирается в фоне:
+// тогда write - nil, а job - задание, за которым следят через ArchiveJob.
+// При async состояние пространства читается уже в фоне, и запрос его не ждёт.
+func (s *WorkspaceService) Archive(ctx context.Context, workspaceID int, async bool) (
+	write func(io.Writer) error, job *model.ArchiveJob, err error) {
+	if s.archives == nil || s.snapshots == nil {
+		return nil, nil, ErrArchivesDisabled
+	}
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return nil, nil, err
+	}
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if async {
+		return nil, s.startArchive(ctx, workspaceID, uid, nil), nil
+	}
+
+	state, err := s.snapshots.WorkspaceState(ctx, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(state.Tasks) > maxInlineArchiveTasks {
+		return nil, s.startArchive(ctx, workspaceID, uid, &state), nil
+	}
+	members, err := s.store.ListWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(w io.Writer) error {
+		return s.archives.write(ctx, w, state, members, func(int, int) {})
+	}, nil, nil
+}
+
+// startArchive запускает сборку в фоне; state - уже прочитанное состояние
+// пространства, nil - прочитать в фоне
+func (s *WorkspaceService) startArchive(ctx context.Context, workspaceID, uid int, state *model.WorkspaceState) *model.ArchiveJob {
+	// сборка переживает запрос, который её начал
+	ctx = context.WithoutCancel(ctx)
+	started := s.archives.start(workspaceID, uid, s.now(), func(w io.Writer, progress func(int, int)) error {
+		if state == nil {
+			loaded, err := s.snapshots.WorkspaceState(ctx, workspaceID)
+			if err != nil {
+				return err
+			}
+			state = &loaded
+		}
+		members, err := s.store.ListWorkspaceMembers(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		return s.archives.write(ctx, w, *state, members, progress)
+	}, s.now)
+	s.log.Info().Int("workspace_id", workspaceID).Str("archive_id", started.ID).Msg("Workspace archive started")
+	return &started
+}
+
+// ArchiveJob - состояние сборки; видно только тому, кто её начал
+func (s *WorkspaceService) ArchiveJob(ctx context.Context, id string) (model.ArchiveJob, error) {
+	job, err := s.archiveJob(ctx, id)
+	if err != nil {
+		return model.ArchiveJob{}, err
+	}
+	return job.ArchiveJob, nil
+}
+
+// OpenArchive открывает готовый архив для скачивания
+func (s *WorkspaceService) OpenArchive(ctx context.Context, id string) (io.ReadCloser, model.ArchiveJob, error) {
+	job, err := s.archiveJob(ctx, id)
+	if err != nil {
+		return nil, model.ArchiveJob{}, err
+	}
+	if job.Status != model.ArchiveDone {
+		return nil, job.ArchiveJob, ErrArchiveNotReady
+	}
+	f, err := os.Open(job.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, model.ArchiveJob{}, storage.ErrNotFound
+	}
+	return f, job.ArchiveJob, err
+}
+
+func (s *WorkspaceService) archiveJob(ctx context.Context, id string) (archiveJob, error) {
+	if s.archives == nil {
+		return archiveJob{}, ErrArchivesDisabled
+	}
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return archiveJob{}, err
+	}
+	job, ok := s.archives.get(id, s.now())
+	if !ok || job.createdBy != uid {
+		return archiveJob{}, storage.ErrNotFound
+	}
+	return job, nil
+}
+
+// start регистрирует сборку и запускает её в фоне
+func (a *archiveJobs) start(workspaceID, createdBy int, now time.Time,
+	build func(io.Writer, func(done, total int)) error, clock func() time.Time) model.ArchiveJob {
+	job := &archiveJob{ArchiveJob: model.ArchiveJob{ID: uuid.NewString(), WorkspaceID: workspaceID,
+		Status: model.ArchiveRunning, CreatedAt: now}, createdBy: createdBy}
+	a.mu.Lock()
+	a.sweep(now)
+	a.jobs[job.ID] = job
+	snapshot := job.ArchiveJob
+	a.mu.Unlock()
+
+	go func() {
+		size, path, err := a.build(job.ID, func(w io.Writer) error {
+			return build(w, func(done, total int) {
+				a.mu.Lock()
+				job.FilesDone, job.FilesTotal, job.Progress = done, total, done*100/max(total, 1)
+				a.mu.Unlock()
+			})
+		})
+		finished := clock()
+		expires := finished.Add(archiveTTL)
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		job.FinishedAt, job.ExpiresAt = &finished, &expires
+		if err != nil {
+			job.Status, job.Error = model.ArchiveFailed, err.Error()
+			return
+		}
+		job.Status, job.Size, job.path, job.Progress = model.ArchiveDone, size, path, 100
+	}()
+	return snapshot
+}
+
+// build пишет архив во временный файл; при ошибке файл удаляется
+func (a *archiveJobs) build(id string, write func(io.Writer) error) (int64, string, error) {
+	f, err := os.CreateTemp("", "archive-"+id+"-*.zip")
+	if err != nil {
+		return 0, "", err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return 0, "", err
+	}
+	info, err := os.Stat(f.Name())
+	if err != nil {
+		return 0, "", err
+	}
+	return info.Size(), f.Name(), nil
+}
+
+func (a *archiveJobs) get(id string, now time.Time) (archiveJob, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sweep(now)
+	job, ok := a.jobs[id]
+	if !ok {
+		return archiveJob{}, false
+	}
+	return *job, true
+}
+
+// sweep удаляет истёкшие архивы; вызывается под a.mu
+func (a *archiveJobs) sweep(now time.Time) {
+	for id, job := range a.jobs {
+		if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+			if job.path != "" {
+				os.Remove(job.path)
+			}
+			delete(a.jobs, id)
+		}
+	}
+}
+
+// write собирает архив: workspace.json - проекты и задачи в формате снимка,
+// members.json - участники, avatars/<user_id>.png - их аватары
+func (a *archiveJobs) write(ctx context.Context, w io.Writer, state model.WorkspaceState, members []model.Member,
+	progress func(done, total int)) error {
+	var avatars []model.User
+	for _, m := range members {
+		u, err := a.users.GetUser(ctx, m.UserID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if u.AvatarKey != "" {
+			avatars = append(avatars, u)
+		}
+	}
+	total, done := 2+len(avatars), 0
+	progress(done, total)
+
+	zw := zip.NewWriter(w)
+	for name, v := range map[string]any{"workspace.json": state, "members.json": members} {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		done++
+		progress(done, total)
+	}
+	for _, u := range avatars {
+		data, _, err := a.blobs.Get(ctx, u.AvatarKey)
+		if err != nil && !errors.Is(err, blob.ErrNotFound) {
+			return fmt.Errorf("avatar of user %d: %w", u.ID, err)
+		}
+		if err == nil {
+			// PNG уже сжат - сохраняем как есть
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("avatars/%d.png", u.ID), Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(data); err != nil {
+				return err
+			}
+		}
+		done++
+		progress(done, total)
+	}
+	return zw.Close()
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// Хеш для сравнения, когда пользователь не найден: время ответа не выдаёт,
+// зарегистрирован ли email
+var dummyPasswordHash, _ = auth.HashPassword("dummy-password")
+
+type Credentials struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// Login - результат входа: короткий access-токен, refresh-токен для его
+// обновления и cookie-сессия для браузера
+//
+// Если включена 2FA, вместо токенов возвращается MFAToken для CompleteLogin.
+type Login struct {
+	AccessToken      string      `json:"access_token,omitempty"`
+	TokenType        string      `json:"token_type,omitempty"`
+	ExpiresAt        *time.Time  `json:"expires_at,omitempty"`
+	RefreshToken     string      `json:"refresh_token,omitempty"`
+	User             *model.User `json:"user,omitempty"`
+	MFARequired      bool        `json:"mfa_required"`
+	MFAToken         string      `json:"mfa_token,omitempty"`
+	SessionToken     string      `json:"-"`
+	SessionExpiresAt time.Time   `json:"-"`
+}
+
+type AuthService struct {
+	users      storage.UserStore
+	sessions   storage.SessionStore
+	jwt        *auth.JWT
+	sessionTTL time.Duration
+	now        func() time.Time
+	validate   *validator.Validate
+	codes      *codeLimiter
+	mailer     mail.Mailer
+	publicURL  string
+	log        zerolog.Logger
+}
+
+type AuthOption func(*AuthService)
+
+// WithMailer включает вход по ссылке; publicURL - внешний адрес сервера для ссылок
+func WithMailer(m mail.Mailer, publicURL string) AuthOption {
+	return func(s *AuthService) {
+		s.mailer = m
+		s.publicURL = strings.TrimRight(publicURL, "/")
+	}
+}
+
+func WithAuthLogger(logger zerolog.Logger) AuthOption {
+	return func(s *AuthService) { s.log = logger }
+}
+
+func NewAuthService(users storage.UserStore, sessions storage.SessionStore, jwt *auth.JWT, sessionTTL time.Duration, opts ...AuthOption) *AuthService {
+	s := &AuthService{
+		users:      users,
+		sessions:   sessions,
+		jwt:        jwt,
+		sessionTTL: sessionTTL,
+		now:        time.Now,
+		validate:   validator.New(),
+		codes:      &codeLimiter{failures: make(map[int][]time.Time)},
+		log:        zerolog.Nop(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *AuthService) Register(ctx context.Context, cred Credentials) (model.User, error) {
+	if err := s.validate.Struct(cred); err != nil {
+		return model.User{}, &ValidationError{Err: err}
+	}
+	hash, err := auth.HashPassword(cred.Password)
+	if err != nil {
+		return model.User{}, err
+	}
+	u := model.User{Email: strings.TrimSpace(cred.Email), PasswordHash: hash, Role: model.RoleUser}
+	if err := s.users.CreateUser(ctx, &u); err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+func (s *AuthService) Login(ctx context.Context, cred Credentials, userAgent string) (Login, error) {
+	u, err := s.users.GetUserByEmail(ctx, strings.TrimSpace(cred.Email))
+	if errors.Is(err, storage.ErrNotFound) {
+		auth.CheckPassword(dummyPasswordHash, cred.Password)
+		return Login{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Login{}, err
+	}
+	if !auth.CheckPassword(u.PasswordHash, cred.Password) {
+		return Login{}, ErrInvalidCredentials
+	}
+	if u.TOTPEnabled {
+		challenge, err := s.jwt.IssueChallenge(u.ID, mfaChallengeTTL)
+		if err != nil {
+			return Login{}, err
+		}
+		return Login{MFARequired: true, MFAToken: challenge}, nil
+	}
+	return s.issue(ctx, u, userAgent)
+}
+
+// issue заводит сессию устройства и выдаёт для неё токены
+func (s *AuthService) issue(ctx context.Context, u model.User, userAgent string) (Login, error) {
+	session, err := auth.NewToken("tds_")
+	if err != nil {
+		return Login{}, err
+	}
+	refresh, err := auth.NewToken("tdr_")
+	if err != nil {
+		return Login{}, err
+	}
+	sess := model.Session{UserID: u.ID, UserAgent: userAgent, ExpiresAt: s.now().Add(s.sessionTTL)}
+	if err := s.sessions.CreateSession(ctx, &sess, auth.HashToken(session), auth.HashToken(refresh)); err != nil {
+		return Login{}, err
+	}
+
+	access, expiresAt, err := s.jwt.Issue(u, sess.ID)
+	if err != nil {
+		return Login{}, err
+	}
+	return Login{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresAt:        &expiresAt,
+		RefreshToken:     refresh,
+		User:             &u,
+		SessionToken:     session,
+		SessionExpiresAt: sess.ExpiresAt,
+	}, nil
+}
+
+// Refresh меняет refresh-токен на новую пару токенов. Каждый refresh-токен
+// одноразовый: повторное предъявление отзывает сессию.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (Login, error) {
+	if refreshToken == "" {
+		return Login{}, ErrInvalidCredentials
+	}
+	next, err := auth.NewToken("tdr_")
+	if err != nil {
+		return Login{}, err
+	}
+	sess, err := s.sessions.RotateRefreshToken(ctx,
+		auth.HashToken(refreshToken), auth.HashToken(next), s.now().Add(s.sessionTTL))
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrRefreshTokenReused) {
+		return Login{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Login{}, err
+	}
+
+	u, err := s.users.GetUser(ctx, sess.UserID)
+	if err != nil {
+		return Login{}, err
+	}
+	access, expiresAt, err := s.jwt.Issue(u, sess.ID)
+	if err != nil {
+		return Login{}, err
+	}
+	return Login{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresAt:    &expiresAt,
+		RefreshToken: next,
+		User:         &u,
+	}, nil
+}
+
+// Logout отзывает сессию, из которой пришёл запрос. Уже выданные JWT
+// остаются действительными до истечения своего короткого срока.
+func (s *AuthService) Logout(ctx context.Context, p *auth.Principal) error {
+	if p.SessionID == 0 {
+		return nil
+	}
+	err := s.sessions.RevokeSession(ctx, p.UserID, p.SessionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ListSessions - активные сессии пользователя, текущая помечена Current
+func (s *AuthService) ListSessions(ctx context.Context, p *auth.Principal) ([]model.Session, error) {
+	sessions, err := s.sessions.ListSessions(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == p.SessionID
+	}
+	return sessions, nil
+}
+
+func (s *AuthService) RevokeSession(ctx context.Context, userID, id int) error {
+	return s.sessions.RevokeSession(ctx, userID, id)
+}
+
+// CreateAPIKey возвращает ключ в открытом виде - показать его можно только сейчас.
+// Без scopes ключ даёт полный доступ.
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string) (model.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return model.APIKey{}, "", &ValidationError{Err: errors.New("name must be 1-100 characters")}
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(auth.KnownScopes, scope) {
+			return model.APIKey{}, "", &ValidationError{Err: fmt.Errorf("unknown scope %q", scope)}
+		}
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	secret, err := auth.NewToken("tdk_")
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	key := model.APIKey{UserID: userID, Name: name, Prefix: secret[:12], Scopes: scopes}
+	if err := s.users.CreateAPIKey(ctx, &key, auth.HashToken(secret)); err != nil {
+		return model.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID int) ([]model.APIKey, error) {
+	return s.users.ListAPIKeys(ctx, userID)
+}
+
+func (s *AuthService) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	return s.users.DeleteAPIKey(ctx, userID, id)
+}
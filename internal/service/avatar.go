@@ -0,0 +1,52 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// AvatarSize - сторона квадратного аватара после обработки
+const AvatarSize = 256
+
+// Ограничение на размер исходника в пикселях: декодирование огромной
+// картинки съедает память раньше, чем сработает лимит на размер файла
+const maxAvatarPixels = 40_000_000
+
+var ErrInvalidImage = errors.New("unsupported or corrupted image")
+
+// resizeAvatar обрезает картинку до квадрата по центру, уменьшает до
+// AvatarSize и перекодирует в PNG (заодно отбрасывая EXIF и прочие метаданные)
+func resizeAvatar(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxAvatarPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	size := min(side, AvatarSize)
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestResizeAvatar(t *testing.T) {
+	// широкая картинка: красные поля по краям, синий квадрат в центре
+	src := image.NewRGBA(image.Rect(0, 0, 900, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 900; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 150 && x < 750 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var in bytes.Buffer
+	if err := jpeg.Encode(&in, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := resizeAvatar(in.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("result is not PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != AvatarSize || b.Dy() != AvatarSize {
+		t.Fatalf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), AvatarSize, AvatarSize)
+	}
+	// обрезка по центру оставляет только синий квадрат
+	if r, _, b, _ := img.At(0, AvatarSize/2).RGBA(); r > 0x2000 || b < 0xd000 {
+		t.Errorf("left edge is not cropped: r=%#x b=%#x", r, b)
+	}
+}
+
+func TestResizeAvatarRejectsGarbage(t *testing.T) {
+	if _, err := resizeAvatar([]byte("not an image")); !errors.Is(err, ErrInvalidImage) {
+		t.Fatalf("err = %v, want ErrInvalidImage", err)
+	}
+}
@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/google"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var ErrInvalidOAuthState = errors.New("oauth state is invalid or expired")
+
+const (
+	calendarName = "Tasks"
+	// taskEventLength - длительность события для задачи со сроком
+	taskEventLength = 30 * time.Minute
+	oauthStateTTL   = 10 * time.Minute
+	taskIDProperty  = "todo_task_id"
+)
+
+// CalendarSync выгружает задачи со сроком в отдельный календарь Google и,
+// если включено, создаёт задачи из событий, добавленных в этот календарь.
+// Удаление события снимает срок у задачи (или удаляет задачу из события).
+type CalendarSync struct {
+	store    storage.CalendarStore
+	tasks    *TaskService
+	taskOpts []Option
+	oauth    *google.OAuth
+	calendar func(token string) *google.Calendar
+	secret   []byte
+	log      zerolog.Logger
+	now      func() time.Time
+}
+
+type CalendarOption func(*CalendarSync)
+
+// WithCalendarTasks - публикаторы и настройки сервиса задач, через который
+// применяются изменения из календаря; сама синхронизация из publishers
+// исключается
+func WithCalendarTasks(publishers *events.Fanout, opts ...Option) CalendarOption {
+	return func(c *CalendarSync) {
+		c.taskOpts = append(slices.Clip(opts), WithPublisher(publishers.Except(c)))
+	}
+}
+
+// WithCalendarAPI подменяет адрес Calendar API (тесты)
+func WithCalendarAPI(baseURL string) CalendarOption {
+	return func(c *CalendarSync) {
+		c.calendar = func(token string) *google.Calendar {
+			return &google.Calendar{BaseURL: baseURL, Token: token, HTTP: c.oauth.HTTP}
+		}
+	}
+}
+
+// NewCalendarSync: secret подписывает параметр state при OAuth-редиректе
+func NewCalendarSync(store storage.CalendarStore, tasks storage.TaskStore, oauth *google.OAuth, secret string,
+	logger zerolog.Logger, opts ...CalendarOption) *CalendarSync {
+	c := &CalendarSync{
+		store:  store,
+		oauth:  oauth,
+		secret: []byte(secret),
+		log:    logger,
+		now:    time.Now,
+	}
+	c.calendar = func(token string) *google.Calendar {
+		return &google.Calendar{Token: token, HTTP: oauth.HTTP}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.tasks = NewTaskService(tasks, c.taskOpts...)
+	return c
+}
+
+// ConnectURL - адрес согласия Google для текущего пользователя
+func (c *CalendarSync) ConnectURL(ctx context.Context) (string, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.oauth.AuthCodeURL(c.state(uid, c.now().Add(oauthStateTTL))), nil
+}
+
+// state - "uid.expiry.hmac": редирект от Google приходит без нашей аутентификации
+func (c *CalendarSync) state(uid int, exp time.Time) string {
+	payload := fmt.Sprintf("%d.%d", uid, exp.Unix())
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *CalendarSync) parseState(state string) (int, error) {
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidOAuthState
+	}
+	uid, err1 := strconv.Atoi(parts[0])
+	exp, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || c.now().Unix() > exp ||
+		!hmac.Equal([]byte(c.state(uid, time.Unix(exp, 0))), []byte(state)) {
+		return 0, ErrInvalidOAuthState
+	}
+	return uid, nil
+}
+
+// Callback завершает OAuth: сохраняет токены, создаёт календарь "Tasks"
+// и выгружает в него текущие задачи со сроком
+func (c *CalendarSync) Callback(ctx context.Context, state, code string) error {
+	uid, err := c.parseState(state)
+	if err != nil {
+		return err
+	}
+	token, err := c.oauth.Exchange(ctx, code)
+	if err != nil {
+		return fmt.Errorf("google: exchange code: %w", err)
+	}
+
+	link, err := c.store.CalendarLink(ctx, uid)
+	if errors.Is(err, storage.ErrNotFound) {
+		link = model.CalendarLink{UserID: uid}
+	} else if err != nil {
+		return err
+	}
+	link.AccessToken, link.TokenExpiry = token.AccessToken, token.Expiry
+	if token.RefreshToken != "" {
+		link.RefreshToken = token.RefreshToken
+	}
+	if link.CalendarID == "" {
+		if link.CalendarID, err = c.calendar(link.AccessToken).CreateCalendar(ctx, calendarName); err != nil {
+			return fmt.Errorf("google: create calendar: %w", err)
+		}
+	}
+	if err := c.store.SaveCalendarLink(ctx, &link); err != nil {
+		return err
+	}
+
+	go c.pushAll(context.WithoutCancel(ctx), uid)
+	return nil
+}
+
+func (c *CalendarSync) Status(ctx context.Context) (model.CalendarLink, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.CalendarLink{}, err
+	}
+	return c.store.CalendarLink(ctx, uid)
+}
+
+// SetPullEvents включает или выключает создание задач из событий
+func (c *CalendarSync) SetPullEvents(ctx context.Context, pull bool) (model.CalendarLink, error) {
+	link, err := c.Status(ctx)
+	if err != nil {
+		return model.CalendarLink{}, err
+	}
+	link.PullEvents = pull
+	return link, c.store.SaveCalendarLink(ctx, &link)
+}
+
+// Disconnect забывает подключение; календарь в Google остаётся
+func (c *CalendarSync) Disconnect(ctx context.Context) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return c.store.DeleteCalendarLink(ctx, uid)
+}
+
+// client - Calendar API с действующим access-токеном
+func (c *CalendarSync) client(ctx context.Context, link *model.CalendarLink) (*google.Calendar, error) {
+	if c.now().Add(time.Minute).After(link.TokenExpiry) {
+		token, err := c.oauth.Refresh(ctx, link.RefreshToken)
+		if err != nil {
+			return nil, fmt.Errorf("google: refresh token: %w", err)
+		}
+		link.AccessToken, link.RefreshToken, link.TokenExpiry = token.AccessToken, token.RefreshToken, token.Expiry
+		if err := c.store.SaveCalendarLink(ctx, link); err != nil {
+			return nil, err
+		}
+	}
+	return c.calendar(link.AccessToken), nil
+}
+
+func userPrincipal(ctx context.Context, uid int) context.Context {
+	return auth.WithPrincipal(ctx, &auth.Principal{UserID: uid, Role: model.RoleUser, Scheme: auth.SchemeIntegration})
+}
+
+// Publish отражает создание, изменение и удаление задач в календаре владельца
+func (c *CalendarSync) Publish(ctx context.Context, e events.Event) {
+	ctx = context.WithoutCancel(ctx)
+	switch {
+	case e.Type == events.TaskDeleted:
+		go func() {
+			if err := c.remove(ctx, e.TaskID); err != nil {
+				c.log.Error().Err(err).Int("task_id", e.TaskID).Msg("Failed to delete calendar event")
+			}
+		}()
+	case (e.Type == events.TaskCreated || e.Type == events.TaskUpdated) && e.Task != nil && e.Task.OwnerID != nil:
+		task := *e.Task
+		go func() {
+			if err := c.push(ctx, task); err != nil {
+				c.log.Error().Err(err).Int("task_id", task.ID).Msg("Failed to push task to calendar")
+			}
+		}()
+	}
+}
+
+func (c *CalendarSync) pushAll(ctx context.Context, uid int) {
+	tasks, err := c.tasks.List(userPrincipal(ctx, uid))
+	if err != nil {
+		c.log.Error().Err(err).Int("user_id", uid).Msg("Failed to list tasks for calendar")
+		return
+	}
+	for _, t := range tasks {
+		if t.DueAt == nil || t.OwnerID == nil || *t.OwnerID != uid {
+			continue
+		}
+		if err := c.push(ctx, t); err != nil {
+			c.log.Error().Err(err).Int("task_id", t.ID).Msg("Failed to push task to calendar")
+		}
+	}
+}
+
+func taskEvent(t model.Task) google.Event {
+	summary := t.Title
+	if t.Status == model.StatusDone {
+		summary = "✓ " + summary
+	}
+	return google.Event{
+		Summary:     summary,
+		Description: t.Description,
+		ExtendedProperties: &google.ExtendedProperties{
+			Private: map[string]string{taskIDProperty: strconv.Itoa(t.ID)},
+		},
+	}
+}
+
+func (c *CalendarSync) push(ctx context.Context, task model.Task) error {
+	link, err := c.store.CalendarLink(ctx, *task.OwnerID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	mapped, err := c.store.CalendarEventByTask(ctx, task.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	found := err == nil
+	if !found && task.DueAt == nil {
+		return nil
+	}
+	cal, err := c.client(ctx, &link)
+	if err != nil {
+		return err
+	}
+
+	if task.DueAt == nil {
+		if err := cal.DeleteEvent(ctx, link.CalendarID, mapped.EventID); err != nil {
+			return err
+		}
+		return c.store.DeleteCalendarEvent(ctx, task.ID)
+	}
+
+	ev := taskEvent(task)
+	length := taskEventLength
+	if found {
+		// у задачи из события сохраняем длительность слота
+		old, err := cal.GetEvent(ctx, link.CalendarID, mapped.EventID)
+		switch {
+		case google.IsGone(err) || (err == nil && old.Status == google.StatusCancelled):
+			found = false
+		case err != nil:
+			return err
+		case old.Start != nil && old.End != nil:
+			start, err1 := old.Start.Time()
+			end, err2 := old.End.Time()
+			if err1 == nil && err2 == nil && end.After(start) {
+				length = end.Sub(start)
+			}
+		}
+	}
+	start, end := google.NewEventTime(*task.DueAt), google.NewEventTime(task.DueAt.Add(length))
+	ev.Start, ev.End = &start, &end
+
+	if found {
+		ev.ID = mapped.EventID
+		_, err = cal.UpdateEvent(ctx, link.CalendarID, ev)
+		return err
+	}
+	created, err := cal.InsertEvent(ctx, link.CalendarID, ev)
+	if err != nil {
+		return err
+	}
+	return c.store.SaveCalendarEvent(ctx, model.CalendarEvent{UserID: link.UserID, EventID: created.ID, TaskID: task.ID})
+}
+
+func (c *CalendarSync) remove(ctx context.Context, taskID int) error {
+	mapped, err := c.store.CalendarEventByTask(ctx, taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link, err := c.store.CalendarLink(ctx, mapped.UserID)
+	if err != nil {
+		return err
+	}
+	cal, err := c.client(ctx, &link)
+	if err != nil {
+		return err
+	}
+	if err := cal.DeleteEvent(ctx, link.CalendarID, mapped.EventID); err != nil {
+		return err
+	}
+	return c.store.DeleteCalendarEvent(ctx, taskID)
+}
+
+// Run раз в every забирает изменения календарей по sync token
+func (c *CalendarSync) Run(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		links, err := c.store.ListCalendarLinks(ctx)
+		if err != nil {
+			c.log.Error().Err(err).Msg("Failed to list calendar links")
+			continue
+		}
+		for _, link := range links {
+			if err := c.pull(ctx, link); err != nil {
+				c.log.Error().Err(err).Int("user_id", link.UserID).Msg("Calendar sync failed")
+			}
+		}
+	}
+}
+
+// pull применяет изменения календаря. При полной синхронизации (нет или
+// протух sync token) удаления не видны, поэтому события только добавляются.
+func (c *CalendarSync) pull(ctx context.Context, link model.CalendarLink) error {
+	cal, err := c.client(ctx, &link)
+	if err != nil {
+		return err
+	}
+	evs, next, err := cal.ListEvents(ctx, link.CalendarID, link.SyncToken)
+	if errors.Is(err, google.ErrSyncTokenExpired) {
+		evs, next, err = cal.ListEvents(ctx, link.CalendarID, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx = userPrincipal(ctx, link.UserID)
+	for _, ev := range evs {
+		if err := c.applyEvent(ctx, link, ev); err != nil {
+			return fmt.Errorf("event %s: %w", ev.ID, err)
+		}
+	}
+	link.SyncToken = next
+	return c.store.SaveCalendarLink(ctx, &link)
+}
+
+func (c *CalendarSync) applyEvent(ctx context.Context, link model.CalendarLink, ev google.Event) error {
+	mapped, err := c.store.CalendarEvent(ctx, link.UserID, ev.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	found := err == nil
+
+	if ev.Status == google.StatusCancelled {
+		if !found {
+			return nil
+		}
+		if err := c.store.DeleteCalendarEvent(ctx, mapped.TaskID); err != nil {
+			return err
+		}
+		if mapped.Pulled {
+			err = c.tasks.Delete(ctx, mapped.TaskID)
+		} else {
+			err = c.updateTask(ctx, mapped.TaskID, func(t *model.Task) { t.DueAt = nil })
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if ev.Start == nil {
+		return nil
+	}
+	start, err := ev.Start.Time()
+	if err != nil {
+		return nil
+	}
+
+	if found {
+		// перенос события в календаре переносит срок задачи
+		err := c.updateTask(ctx, mapped.TaskID, func(t *model.Task) {
+			t.DueAt = &start
+			if mapped.Pulled && ev.Summary != "" {
+				t.Title = truncate(ev.Summary, maxTitleLen)
+			}
+		})
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.store.DeleteCalendarEvent(ctx, mapped.TaskID)
+		}
+		return err
+	}
+	// свои события узнаём по свойству, даже если связь потерялась
+	if ev.ExtendedProperties != nil && ev.ExtendedProperties.Private[taskIDProperty] != "" {
+		return nil
+	}
+	if !link.PullEvents {
+		return nil
+	}
+
+	title := strings.TrimSpace(ev.Summary)
+	if utf8.RuneCountInString(title) < 3 {
+		title = "Time block: " + title
+	}
+	task := model.Task{
+		Title:       truncate(title, maxTitleLen),
+		Description: truncate(ev.Description, maxDescriptionLen),
+		Status:      model.StatusTodo,
+		DueAt:       &start,
+	}
+	if err := c.tasks.Create(ctx, &task); err != nil {
+		return err
+	}
+	return c.store.SaveCalendarEvent(ctx, model.CalendarEvent{UserID: link.UserID, EventID: ev.ID, TaskID: task.ID, Pulled: true})
+}
+
+// updateTask меняет задачу, только если change действительно что-то изменил
+func (c *CalendarSync) updateTask(ctx context.Context, id int, change func(*model.Task)) error {
+	task, err := c.tasks.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	before := task
+	change(&task)
+	if task.Title == before.Title && sameTime(task.DueAt, before.DueAt) {
+		return nil
+	}
+	return c.tasks.Update(ctx, &task)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,130 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/google"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type fakeCalendars struct {
+	mu     sync.Mutex
+	links  map[int]model.CalendarLink
+	events map[int]model.CalendarEvent // task -> event
+}
+
+func (f *fakeCalendars) SaveCalendarLink(_ context.Context, l *model.CalendarLink) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.links[l.UserID] = *l
+	return nil
+}
+
+func (f *fakeCalendars) CalendarLink(_ context.Context, userID int) (model.CalendarLink, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.links[userID]
+	if !ok {
+		return model.CalendarLink{}, storage.ErrNotFound
+	}
+	return l, nil
+}
+
+func (f *fakeCalendars) ListCalendarLinks(context.Context) ([]model.CalendarLink, error) {
+	return nil, nil
+}
+func (f *fakeCalendars) DeleteCalendarLink(context.Context, int) error { return nil }
+
+func (f *fakeCalendars) CalendarEventByTask(_ context.Context, taskID int) (model.CalendarEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.events[taskID]
+	if !ok {
+		return model.CalendarEvent{}, storage.ErrNotFound
+	}
+	return e, nil
+}
+
+func (f *fakeCalendars) CalendarEvent(context.Context, int, string) (model.CalendarEvent, error) {
+	return model.CalendarEvent{}, storage.ErrNotFound
+}
+
+func (f *fakeCalendars) SaveCalendarEvent(_ context.Context, e model.CalendarEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events[e.TaskID] = e
+	return nil
+}
+
+func (f *fakeCalendars) DeleteCalendarEvent(context.Context, int) error { return nil }
+
+func TestCalendarConnectAndPush(t *testing.T) {
+	inserted := make(chan google.Event, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /token":
+			fmt.Fprint(w, `{"access_token": "at", "refresh_token": "rt", "expires_in": 3600}`)
+		case "POST /calendars":
+			fmt.Fprint(w, `{"id": "cal-1"}`)
+		case "POST /calendars/cal-1/events":
+			var ev google.Event
+			_ = json.NewDecoder(r.Body).Decode(&ev)
+			ev.ID = "ev-1"
+			inserted <- ev
+			_ = json.NewEncoder(w).Encode(ev)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	store := &fakeCalendars{links: map[int]model.CalendarLink{}, events: map[int]model.CalendarEvent{}}
+	oauth := &google.OAuth{ClientID: "id", TokenURL: api.URL + "/token", HTTP: api.Client()}
+	cal := service.NewCalendarSync(store, storage.NewMemory(), oauth, "secret", zerolog.Nop(),
+		service.WithCalendarAPI(api.URL))
+
+	consent, err := cal.ConnectURL(userContext(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(consent)
+	state := u.Query().Get("state")
+
+	if err := cal.Callback(context.Background(), "8"+state[1:], "code"); !errors.Is(err, service.ErrInvalidOAuthState) {
+		t.Errorf("tampered state: err = %v, want ErrInvalidOAuthState", err)
+	}
+	if err := cal.Callback(context.Background(), state, "code"); err != nil {
+		t.Fatal(err)
+	}
+	if link, err := store.CalendarLink(context.Background(), 7); err != nil || link.CalendarID != "cal-1" {
+		t.Fatalf("link = %+v, %v; want calendar cal-1", link, err)
+	}
+
+	owner := 7
+	due := time.Date(2025, 3, 1, 15, 0, 0, 0, time.UTC)
+	task := model.Task{ID: 42, OwnerID: &owner, Title: "Pay rent", Status: model.StatusTodo, DueAt: &due}
+	cal.Publish(context.Background(), events.Event{Type: events.TaskCreated, TaskID: 42, Task: &task})
+
+	select {
+	case ev := <-inserted:
+		if ev.Summary != "Pay rent" || ev.Start.DateTime != "2025-03-01T15:00:00Z" || ev.End.DateTime != "2025-03-01T15:30:00Z" {
+			t.Errorf("event = %q %s..%s", ev.Summary, ev.Start.DateTime, ev.End.DateTime)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event was not created")
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// mergeField - поле задачи, которое сливается при синхронизации отдельно
+type mergeField struct {
+	name  string
+	value func(t *model.Task) any
+	copy  func(dst, src *model.Task)
+}
+
+var mergeFields = []mergeField{
+	{"title", func(t *model.Task) any { return t.Title }, func(d, s *model.Task) { d.Title = s.Title }},
+	{"description", func(t *model.Task) any { return t.Description }, func(d, s *model.Task) { d.Description = s.Description }},
+	{"status", func(t *model.Task) any { return t.Status }, func(d, s *model.Task) { d.Status = s.Status }},
+	{"parent_id", func(t *model.Task) any { return t.ParentID }, func(d, s *model.Task) { d.ParentID = s.ParentID }},
+	{"project_id", func(t *model.Task) any { return t.ProjectID }, func(d, s *model.Task) { d.ProjectID = s.ProjectID }},
+	{"due_at", func(t *model.Task) any { return utc(t.DueAt) }, func(d, s *model.Task) { d.DueAt = s.DueAt }},
+	{"location", func(t *model.Task) any { return t.Location }, func(d, s *model.Task) { d.Location = s.Location }},
+}
+
+// utc - чтобы один и тот же момент в разных поясах не считался правкой
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// merge сводит правку клиента local, сделанную поверх base, с текущей
+// задачей current. Поле, изменённое только на одной стороне, берётся оттуда;
+// изменённое на обеих по-разному - у более поздней правки (localWins), а
+// проигравшее значение уходит в конфликт. Время правок по отдельным полям
+// сервер не хранит, поэтому серверная сторона датируется updated_at задачи.
+func merge(base, local, current model.Task, localWins bool) (model.Task, []model.TaskConflict) {
+	merged := current
+	var conflicts []model.TaskConflict
+	for _, f := range mergeFields {
+		b, l, c := fieldJSON(f, &base), fieldJSON(f, &local), fieldJSON(f, &current)
+		switch {
+		case bytes.Equal(l, b), bytes.Equal(l, c):
+			// клиент поле не трогал или пришёл к тому же
+		case bytes.Equal(c, b):
+			f.copy(&merged, &local)
+		case localWins:
+			f.copy(&merged, &local)
+			conflicts = append(conflicts, model.TaskConflict{TaskID: current.ID, Field: f.name, Kept: l, Discarded: c})
+		default:
+			conflicts = append(conflicts, model.TaskConflict{TaskID: current.ID, Field: f.name, Kept: c, Discarded: l})
+		}
+	}
+	return merged, conflicts
+}
+
+func fieldJSON(f mergeField, t *model.Task) json.RawMessage {
+	// значения полей - строки, числа и время, их кодирование не падает
+	data, _ := json.Marshal(f.value(t))
+	return data
+}
+
+// Conflicts - конфликты синхронизации по видимым задачам, новые первыми
+func (s *TaskService) Conflicts(ctx context.Context) ([]model.TaskConflict, error) {
+	if s.sync == nil {
+		return nil, ErrSyncDisabled
+	}
+	return s.sync.TaskConflicts(ctx)
+}
+
+// ResolveConflict убирает конфликт, который пользователь разобрал; значение
+// из него, если нужно, клиент возвращает в задачу обычной правкой
+func (s *TaskService) ResolveConflict(ctx context.Context, id int) error {
+	if s.sync == nil {
+		return ErrSyncDisabled
+	}
+	return s.sync.DeleteConflict(ctx, id)
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var (
+	// ErrDelegationsDisabled - хранилище не ведёт поручения
+	ErrDelegationsDisabled = errors.New("delegation is not enabled")
+	// ErrReviewRequired - поручённую задачу завершает только поручивший
+	ErrReviewRequired = errors.New("delegated task must be approved by the delegator")
+	// ErrDelegationState - поручение не в том состоянии, например работу
+	// принимают, а её ещё не сдали
+	ErrDelegationState = errors.New("delegation is not in a state that allows this")
+)
+
+const maxDelegationComment = 1000
+
+// delegations - поручения и письма о них сторонам
+type delegations struct {
+	store     storage.DelegationStore
+	users     storage.UserStore
+	mailer    mail.Mailer
+	publicURL string
+	log       zerolog.Logger
+}
+
+// WithDelegations включает поручения задач: исполнитель сдаёт работу, а
+// завершает задачу поручивший. Письма о каждом шаге уходят другой стороне
+// через mailer; nil - без писем.
+func WithDelegations(store storage.DelegationStore, users storage.UserStore, mailer mail.Mailer, publicURL string,
+	logger zerolog.Logger) Option {
+	return func(s *TaskService) {
+		s.delegation = &delegations{store: store, users: users, mailer: mailer,
+			publicURL: strings.TrimRight(publicURL, "/"), log: logger}
+	}
+}
+
+// DelegateRequest - кому поручить задачу
+type DelegateRequest struct {
+	UserID int `json:"user_id" validate:"gt=0"`
+}
+
+// Delegate поручает задачу редактору её проекта. Прежнее поручение задачи
+// заменяет только тот, кто его дал; остальным, в том числе исполнителю, -
+// ErrForbidden.
+func (s *TaskService) Delegate(ctx context.Context, id int, req DelegateRequest) (model.Delegation, error) {
+	if s.delegation == nil {
+		return model.Delegation{}, ErrDelegationsDisabled
+	}
+	if err := s.validate.Struct(req); err != nil {
+		return model.Delegation{}, &ValidationError{Err: err}
+	}
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	if req.UserID == uid {
+		return model.Delegation{}, &ValidationError{Err: errors.New("cannot delegate a task to yourself")}
+	}
+	d, err := s.delegation.store.Delegate(ctx, id, req.UserID)
+	if errors.Is(err, storage.ErrDelegatedByOther) {
+		return model.Delegation{}, ErrForbidden
+	}
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	s.delegation.notify(d, d.DelegateID, "Task delegated to you: %s",
+		"%s asked you to do this task. When it is done, submit it for review instead of completing it.")
+	return d, nil
+}
+
+// Delegation - поручение задачи
+func (s *TaskService) Delegation(ctx context.Context, id int) (model.Delegation, error) {
+	if s.delegation == nil {
+		return model.Delegation{}, ErrDelegationsDisabled
+	}
+	return s.delegation.store.TaskDelegation(ctx, id)
+}
+
+// Delegations - поручения, где текущий пользователь поручивший или исполнитель
+func (s *TaskService) Delegations(ctx context.Context) ([]model.Delegation, error) {
+	if s.delegation == nil {
+		return nil, ErrDelegationsDisabled
+	}
+	list, err := s.delegation.store.Delegations(ctx)
+	if list == nil && err == nil {
+		list = []model.Delegation{}
+	}
+	return list, err
+}
+
+// SubmitForReview - исполнитель сдаёт работу; в том числе повторно, после
+// отказа
+func (s *TaskService) SubmitForReview(ctx context.Context, id int) (model.Delegation, error) {
+	d, err := s.delegationAs(ctx, id, func(d model.Delegation) int { return d.DelegateID })
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	d, err = s.setDelegationState(ctx, id, model.DelegationPendingReview, "",
+		model.DelegationActive, model.DelegationRejected)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	s.delegation.notify(d, d.DelegatorID, "Ready for review: %s",
+		"%s has finished this task and is waiting for your approval.")
+	return d, nil
+}
+
+// Approve - поручивший принимает сданную работу, и задача завершается
+func (s *TaskService) Approve(ctx context.Context, id int) (model.Delegation, error) {
+	if _, err := s.delegationAs(ctx, id, func(d model.Delegation) int { return d.DelegatorID }); err != nil {
+		return model.Delegation{}, err
+	}
+	task, err := s.store.GetTask(ctx, id)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	// состояние меняется до правки задачи, иначе Update её не завершит; если
+	// правка не удалась, поручивший может завершить задачу сам
+	d, err := s.setDelegationState(ctx, id, model.DelegationApproved, "", model.DelegationPendingReview)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	if task.Status != model.StatusDone {
+		task.Status = model.StatusDone
+		if err := s.Update(ctx, &task); err != nil {
+			return model.Delegation{}, err
+		}
+	}
+	s.delegation.notify(d, d.DelegateID, "Approved: %s", "%s approved your work, the task is done.")
+	return d, nil
+}
+
+// Reject - поручивший возвращает работу с комментарием
+func (s *TaskService) Reject(ctx context.Context, id int, comment string) (model.Delegation, error) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" || len(comment) > maxDelegationComment {
+		return model.Delegation{}, &ValidationError{Err: fmt.Errorf("comment is required, at most %d bytes", maxDelegationComment)}
+	}
+	if _, err := s.delegationAs(ctx, id, func(d model.Delegation) int { return d.DelegatorID }); err != nil {
+		return model.Delegation{}, err
+	}
+	d, err := s.setDelegationState(ctx, id, model.DelegationRejected, comment, model.DelegationPendingReview)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	s.delegation.notify(d, d.DelegateID, "Changes requested: %s",
+		"%s sent this task back:\n\n%s\n\nSubmit it for review again when it is ready.", d.Comment)
+	return d, nil
+}
+
+// CancelDelegation - поручивший забирает задачу обратно
+func (s *TaskService) CancelDelegation(ctx context.Context, id int) error {
+	d, err := s.delegationAs(ctx, id, func(d model.Delegation) int { return d.DelegatorID })
+	if err != nil {
+		return err
+	}
+	if err := s.delegation.store.DeleteDelegation(ctx, id); err != nil {
+		return err
+	}
+	if d.State != model.DelegationApproved {
+		s.delegation.notify(d, d.DelegateID, "Delegation cancelled: %s", "%s took this task back, you no longer need to do it.")
+	}
+	return nil
+}
+
+// delegationAs - поручение задачи, если текущий пользователь - нужная
+// сторона: party выбирает её id из поручения
+func (s *TaskService) delegationAs(ctx context.Context, id int, party func(model.Delegation) int) (model.Delegation, error) {
+	if s.delegation == nil {
+		return model.Delegation{}, ErrDelegationsDisabled
+	}
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	d, err := s.delegation.store.TaskDelegation(ctx, id)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	if party(d) != uid {
+		return model.Delegation{}, ErrForbidden
+	}
+	return d, nil
+}
+
+func (s *TaskService) setDelegationState(ctx context.Context, id int, state, comment string, from ...string) (model.Delegation, error) {
+	d, err := s.delegation.store.SetDelegationState(ctx, id, from, state, comment)
+	if errors.Is(err, storage.ErrNotFound) {
+		// поручение есть - его только что прочитали, значит, состояние уже другое
+		return model.Delegation{}, ErrDelegationState
+	}
+	return d, err
+}
+
+// checkReview не даёт завершить поручённую задачу никому, кроме
+// поручившего, пока работа не принята. Поручивший может завершить её и
+// сам - это тоже приёмка.
+func (s *TaskService) checkReview(ctx context.Context, id int) error {
+	if s.delegation == nil {
+		return nil
+	}
+	d, err := s.delegation.store.TaskDelegation(ctx, id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case d.State == model.DelegationApproved:
+		return nil
+	}
+	if uid, err := currentUserID(ctx); err != nil || uid != d.DelegatorID {
+		return ErrReviewRequired
+	}
+	_, err = s.delegation.store.SetDelegationState(ctx, id, []string{d.State}, model.DelegationApproved, "")
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrDelegationState
+	}
+	return err
+}
+
+// notify пишет стороне поручения to от имени другой стороны. Тема и текст -
+// форматы с заголовком задачи и именем автора шага соответственно; args -
+// остальные значения текста, всё написанное пользователем передаётся через них.
+func (d *delegations) notify(del model.Delegation, to int, subject, body string, args ...any) {
+	if d.mailer == nil {
+		return
+	}
+	from := del.DelegatorID
+	if to == del.DelegatorID {
+		from = del.DelegateID
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		recipient, err := d.users.GetUser(ctx, to)
+		if err != nil {
+			d.log.Error().Err(err).Int("task_id", del.TaskID).Msg("Failed to notify about delegation")
+			return
+		}
+		name := "Someone"
+		if author, err := d.users.GetUser(ctx, from); err == nil {
+			name = author.Email
+			if author.DisplayName != "" {
+				name = author.DisplayName
+			}
+		}
+		// заголовок задачи пишет любой редактор проекта, а тема - заголовок
+		// письма: в ней он в одну строку
+		msg := mail.Message{
+			To:      recipient.Email,
+			Subject: fmt.Sprintf(subject, mail.HeaderText(del.TaskTitle)),
+			Body:    fmt.Sprintf(body, append([]any{name}, args...)...) + fmt.Sprintf("\n\n%s\n%s/tasks/%d\n", del.TaskTitle, d.publicURL, del.TaskID),
+		}
+		if err := d.mailer.Send(ctx, msg); err != nil {
+			d.log.Error().Err(err).Int("task_id", del.TaskID).Msg("Failed to notify about delegation")
+		}
+	}()
+}
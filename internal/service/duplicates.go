@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// maxDuplicateCandidates - сколько похожих задач показать в DuplicateError
+const maxDuplicateCandidates = 5
+
+// DuplicateError - среди открытых задач уже есть такая же
+type DuplicateError struct {
+	Candidates []model.Task
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("possible duplicate of %d open task(s)", len(e.Candidates))
+}
+
+// WithDuplicateCheck включает проверку на дубли для Create с RejectDuplicates
+func WithDuplicateCheck(store storage.DuplicateStore) Option {
+	return func(s *TaskService) { s.duplicates = store }
+}
+
+// CreateOption - настройки одного вызова Create
+type CreateOption func(*createOptions)
+
+type createOptions struct {
+	rejectDuplicates bool
+	keepCompletedAt  bool
+}
+
+// RejectDuplicates - не создавать задачу, если открытая задача с тем же
+// заголовком уже есть в том же проекте (или среди личных), а вернуть
+// DuplicateError с ними. Нужна там, где задачи вводит человек: синхронизация
+// с внешними системами сама следит за соответствием.
+func RejectDuplicates() CreateOption {
+	return func(o *createOptions) { o.rejectDuplicates = true }
+}
+
+func (s *TaskService) checkDuplicates(ctx context.Context, task *model.Task) error {
+	if s.duplicates == nil || task.Status == model.StatusDone {
+		return nil
+	}
+	similar, err := s.duplicates.SimilarOpenTasks(ctx, task.Title, task.ProjectID, maxDuplicateCandidates)
+	if err != nil {
+		return err
+	}
+	if len(similar) > 0 {
+		return &DuplicateError{Candidates: similar}
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/github"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// InProgressLabel - метка issue, соответствующая статусу in_progress
+const InProgressLabel = "in progress"
+
+var repoName = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+type GitHubLinkRequest struct {
+	Repo  string `json:"repo"`
+	Token string `json:"token"`
+}
+
+// GitHubSync связывает проекты с репозиториями: назначенные владельцу связи
+// issues становятся задачами, а статус меняется в обе стороны. Изменения из
+// GitHub публикуются всем, кроме самой связи, поэтому не отражаются обратно.
+type GitHubSync struct {
+	store     storage.GitHubStore
+	tasks     *TaskService
+	taskOpts  []Option
+	projects  storage.WorkspaceStore
+	baseURL   string
+	http      *http.Client
+	publicURL string
+	log       zerolog.Logger
+	now       func() time.Time
+}
+
+type GitHubOption func(*GitHubSync)
+
+// WithGitHubTasks - публикаторы и настройки сервиса задач, через который
+// применяются изменения из GitHub
+func WithGitHubTasks(publishers *events.Fanout, opts ...Option) GitHubOption {
+	return func(g *GitHubSync) {
+		g.taskOpts = append(slices.Clip(opts), WithPublisher(publishers.Except(g)))
+	}
+}
+
+// WithGitHubAPI задаёт адрес API (GitHub Enterprise, тесты) и HTTP-клиент
+func WithGitHubAPI(baseURL string, client *http.Client) GitHubOption {
+	return func(g *GitHubSync) { g.baseURL, g.http = baseURL, client }
+}
+
+func NewGitHubSync(store storage.GitHubStore, tasks storage.TaskStore, projects storage.WorkspaceStore,
+	publicURL string, logger zerolog.Logger, opts ...GitHubOption) *GitHubSync {
+	g := &GitHubSync{
+		store:     store,
+		projects:  projects,
+		baseURL:   github.DefaultBaseURL,
+		http:      &http.Client{Timeout: 15 * time.Second},
+		publicURL: strings.TrimRight(publicURL, "/"),
+		log:       logger,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.tasks = NewTaskService(tasks, g.taskOpts...)
+	return g
+}
+
+func (g *GitHubSync) client(link model.GitHubLink) *github.Client {
+	return &github.Client{BaseURL: g.baseURL, Token: link.Token, HTTP: g.http}
+}
+
+// adminProject - связью управляет только администратор проекта
+func (g *GitHubSync) adminProject(ctx context.Context, projectID int) error {
+	p, err := g.projects.GetProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if p.Role != model.ProjectAdmin {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Link проверяет токен, сохраняет связь и запускает первую синхронизацию.
+// Вебхук в репозитории настраивается вручную по возвращённым URL и секрету.
+func (g *GitHubSync) Link(ctx context.Context, projectID int, req GitHubLinkRequest) (model.GitHubLink, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.GitHubLink{}, err
+	}
+	if !repoName.MatchString(req.Repo) {
+		return model.GitHubLink{}, &ValidationError{Err: errors.New("repo must be in owner/name form")}
+	}
+	if req.Token == "" {
+		return model.GitHubLink{}, &ValidationError{Err: errors.New("token is required")}
+	}
+	if err := g.adminProject(ctx, projectID); err != nil {
+		return model.GitHubLink{}, err
+	}
+
+	link := model.GitHubLink{ProjectID: projectID, UserID: uid, Repo: req.Repo, Token: req.Token}
+	user, err := g.client(link).CurrentUser(ctx)
+	var se *github.StatusError
+	if errors.As(err, &se) && se.Code == http.StatusUnauthorized {
+		return model.GitHubLink{}, &ValidationError{Err: errors.New("github rejected the token")}
+	}
+	if err != nil {
+		return model.GitHubLink{}, fmt.Errorf("github: check token: %w", err)
+	}
+	link.Login = user.Login
+
+	if link.WebhookSecret, err = auth.NewToken(""); err != nil {
+		return model.GitHubLink{}, err
+	}
+	if err := g.store.SaveGitHubLink(ctx, &link); err != nil {
+		return model.GitHubLink{}, err
+	}
+
+	go g.syncLink(context.WithoutCancel(ctx), link)
+
+	link.WebhookURL = fmt.Sprintf("%s/integrations/github/%d", g.publicURL, projectID)
+	return link, nil
+}
+
+func (g *GitHubSync) GetLink(ctx context.Context, projectID int) (model.GitHubLink, error) {
+	if _, err := g.projects.GetProject(ctx, projectID); err != nil {
+		return model.GitHubLink{}, err
+	}
+	link, err := g.store.GitHubLink(ctx, projectID)
+	link.WebhookSecret = ""
+	return link, err
+}
+
+func (g *GitHubSync) Unlink(ctx context.Context, projectID int) error {
+	if err := g.adminProject(ctx, projectID); err != nil {
+		return err
+	}
+	return g.store.DeleteGitHubLink(ctx, projectID)
+}
+
+// HandleWebhook применяет событие "issues"; остальные события игнорируются
+func (g *GitHubSync) HandleWebhook(ctx context.Context, projectID int, event, signature string, body []byte) error {
+	link, err := g.store.GitHubLink(ctx, projectID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrInvalidWebhook
+	}
+	if err != nil {
+		return err
+	}
+	if !github.VerifySignature(link.WebhookSecret, body, signature) {
+		return ErrInvalidSignature
+	}
+	if event != "issues" {
+		return nil
+	}
+
+	var e github.IssuesEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return &ValidationError{Err: errors.New("payload must be a GitHub issues event")}
+	}
+	if !strings.EqualFold(e.Repository.FullName, link.Repo) {
+		return nil
+	}
+	return g.apply(ctx, link, e.Issue)
+}
+
+// issueStatus - статус задачи, которому соответствует issue
+func issueStatus(i github.Issue) string {
+	if i.State == github.StateClosed {
+		return model.StatusDone
+	}
+	for _, l := range i.Labels {
+		if strings.EqualFold(l.Name, InProgressLabel) || strings.EqualFold(l.Name, "in-progress") {
+			return model.StatusInProgress
+		}
+	}
+	return model.StatusTodo
+}
+
+// apply переносит issue в задачу от имени владельца связи. Устаревшие
+// события пропускаются; статус задачи меняется, только если он изменился
+// на стороне GitHub, чтобы не затирать локальные правки.
+func (g *GitHubSync) apply(ctx context.Context, link model.GitHubLink, issue github.Issue) error {
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserID: link.UserID, Role: model.RoleUser, Scheme: auth.SchemeIntegration})
+	title := truncate(fmt.Sprintf("#%d %s", issue.Number, issue.Title), maxTitleLen)
+	status := issueStatus(issue)
+
+	known, err := g.store.GitHubIssue(ctx, link.ProjectID, issue.Number)
+	if errors.Is(err, storage.ErrNotFound) {
+		if !issue.AssignedTo(link.Login) || issue.State == github.StateClosed {
+			return nil
+		}
+		task := model.Task{
+			ProjectID:   &link.ProjectID,
+			Title:       title,
+			Description: truncate(strings.TrimSpace(issue.HTMLURL+"\n\n"+issue.Body), maxDescriptionLen),
+			Status:      status,
+		}
+		if err := g.tasks.Create(ctx, &task); err != nil {
+			return err
+		}
+		return g.store.SaveGitHubIssue(ctx, model.GitHubIssue{
+			ProjectID: link.ProjectID, Number: issue.Number, TaskID: task.ID, Status: status, UpdatedAt: issue.UpdatedAt,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if !issue.UpdatedAt.After(known.UpdatedAt) {
+		return nil
+	}
+
+	task, err := g.tasks.Get(ctx, known.TaskID)
+	if err != nil {
+		return err
+	}
+	prev := task
+	task.Title = title
+	if status != known.Status {
+		task.Status = status
+	}
+	if task.Title != prev.Title || task.Status != prev.Status {
+		err := g.tasks.Update(ctx, &task)
+		if errors.Is(err, ErrReviewRequired) {
+			// поручённую задачу завершает только поручивший, закрытие issue
+			// её не завершает; заголовок всё равно переносим
+			g.log.Info().Int("task_id", task.ID).Msg("Delegated task is awaiting review, issue status not applied")
+			task.Status, err = prev.Status, nil
+			if task.Title != prev.Title {
+				err = g.tasks.Update(ctx, &task)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	known.Status, known.UpdatedAt = status, issue.UpdatedAt
+	return g.store.SaveGitHubIssue(ctx, known)
+}
+
+// Publish отправляет в GitHub смену статуса связанной задачи
+func (g *GitHubSync) Publish(ctx context.Context, e events.Event) {
+	if e.Type != events.TaskUpdated || e.Task == nil || e.Task.ProjectID == nil {
+		return
+	}
+	task := *e.Task
+	go func() {
+		if err := g.push(context.WithoutCancel(ctx), task); err != nil {
+			g.log.Error().Err(err).Int("task_id", task.ID).Msg("Failed to push task status to GitHub")
+		}
+	}()
+}
+
+func (g *GitHubSync) push(ctx context.Context, task model.Task) error {
+	known, err := g.store.GitHubIssueByTask(ctx, task.ID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && known.Status == task.Status) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link, err := g.store.GitHubLink(ctx, known.ProjectID)
+	if err != nil {
+		return err
+	}
+	c := g.client(link)
+
+	wasDone, isDone := known.Status == model.StatusDone, task.Status == model.StatusDone
+	if wasDone != isDone {
+		state := github.StateOpen
+		if isDone {
+			state = github.StateClosed
+		}
+		if err := c.SetIssueState(ctx, link.Repo, known.Number, state); err != nil {
+			return err
+		}
+	}
+	hadLabel, hasLabel := known.Status == model.StatusInProgress, task.Status == model.StatusInProgress
+	switch {
+	case hasLabel && !hadLabel:
+		err = c.AddLabel(ctx, link.Repo, known.Number, InProgressLabel)
+	case hadLabel && !hasLabel:
+		err = c.RemoveLabel(ctx, link.Repo, known.Number, InProgressLabel)
+	}
+	if err != nil {
+		return err
+	}
+
+	// время изменения issue после наших правок: эхо-вебхук окажется устаревшим
+	issue, err := c.Issue(ctx, link.Repo, known.Number)
+	if err != nil {
+		return err
+	}
+	known.Status, known.UpdatedAt = task.Status, issue.UpdatedAt
+	return g.store.SaveGitHubIssue(ctx, known)
+}
+
+// Run сверяет все связи раз в every, добирая пропущенные вебхуки
+func (g *GitHubSync) Run(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		links, err := g.store.ListGitHubLinks(ctx)
+		if err != nil {
+			g.log.Error().Err(err).Msg("Failed to list GitHub links")
+			continue
+		}
+		for _, link := range links {
+			g.syncLink(ctx, link)
+		}
+	}
+}
+
+func (g *GitHubSync) syncLink(ctx context.Context, link model.GitHubLink) {
+	started := g.now()
+	var since time.Time
+	if link.LastSyncedAt != nil {
+		// запас на расхождение часов с GitHub
+		since = link.LastSyncedAt.Add(-time.Minute)
+	}
+
+	log := g.log.With().Int("project_id", link.ProjectID).Str("repo", link.Repo).Logger()
+	issues, err := g.client(link).AssignedIssues(ctx, link.Repo, link.Login, since)
+	if err != nil {
+		log.Error().Err(err).Msg("GitHub reconciliation failed")
+		return
+	}
+	for _, issue := range issues {
+		if err := g.apply(ctx, link, issue); err != nil {
+			log.Error().Err(err).Int("issue", issue.Number).Msg("Failed to sync GitHub issue")
+			return
+		}
+	}
+	if err := g.store.SetGitHubSynced(ctx, link.ProjectID, started); err != nil {
+		log.Error().Err(err).Msg("Failed to record GitHub sync time")
+	}
+}
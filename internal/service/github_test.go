@@ -0,0 +1,235 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type fakeGitHub struct {
+	links  map[int]model.GitHubLink
+	issues map[int]model.GitHubIssue // task -> issue
+}
+
+func (f *fakeGitHub) SaveGitHubLink(_ context.Context, l *model.GitHubLink) error {
+	f.links[l.ProjectID] = *l
+	return nil
+}
+
+func (f *fakeGitHub) GitHubLink(_ context.Context, projectID int) (model.GitHubLink, error) {
+	l, ok := f.links[projectID]
+	if !ok {
+		return model.GitHubLink{}, storage.ErrNotFound
+	}
+	return l, nil
+}
+
+func (f *fakeGitHub) ListGitHubLinks(context.Context) ([]model.GitHubLink, error) { return nil, nil }
+func (f *fakeGitHub) DeleteGitHubLink(context.Context, int) error                 { return nil }
+func (f *fakeGitHub) SetGitHubSynced(context.Context, int, time.Time) error       { return nil }
+
+func (f *fakeGitHub) GitHubIssue(_ context.Context, projectID, number int) (model.GitHubIssue, error) {
+	for _, i := range f.issues {
+		if i.ProjectID == projectID && i.Number == number {
+			return i, nil
+		}
+	}
+	return model.GitHubIssue{}, storage.ErrNotFound
+}
+
+func (f *fakeGitHub) GitHubIssueByTask(_ context.Context, taskID int) (model.GitHubIssue, error) {
+	i, ok := f.issues[taskID]
+	if !ok {
+		return model.GitHubIssue{}, storage.ErrNotFound
+	}
+	return i, nil
+}
+
+func (f *fakeGitHub) SaveGitHubIssue(_ context.Context, i model.GitHubIssue) error {
+	f.issues[i.TaskID] = i
+	return nil
+}
+
+func signedEvent(t *testing.T, secret, state string, updated time.Time) ([]byte, string) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"action":     "edited",
+		"repository": map[string]string{"full_name": "acme/app"},
+		"issue": map[string]any{
+			"number": 7, "title": "Fix login", "state": state,
+			"assignees": []map[string]string{{"login": "octo"}}, "updated_at": updated,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return body, "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubSyncBothWays(t *testing.T) {
+	patched := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "PATCH /repos/acme/app/issues/7":
+			var body struct{ State string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			patched <- body.State
+			fmt.Fprint(w, `{}`)
+		case "GET /repos/acme/app/issues/7":
+			fmt.Fprint(w, `{"number": 7, "state": "open", "updated_at": "2025-03-01T12:00:00Z"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	gh := &fakeGitHub{links: map[int]model.GitHubLink{}, issues: map[int]model.GitHubIssue{}}
+	gh.links[1] = model.GitHubLink{ProjectID: 1, UserID: 1, Repo: "acme/app", Login: "octo", WebhookSecret: "s3cret"}
+	tasks := storage.NewMemory()
+	sync := service.NewGitHubSync(gh, tasks, newFakeWorkspaces(), "", zerolog.Nop(),
+		service.WithGitHubAPI(api.URL, api.Client()))
+	ctx := context.Background()
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	body, sig := signedEvent(t, "s3cret", "open", t0)
+	if err := sync.HandleWebhook(ctx, 1, "issues", "sha256=00", body); err == nil {
+		t.Fatal("bad signature accepted")
+	}
+	if err := sync.HandleWebhook(ctx, 1, "issues", sig, body); err != nil {
+		t.Fatal(err)
+	}
+	if len(gh.issues) != 1 {
+		t.Fatalf("linked issues = %d, want 1", len(gh.issues))
+	}
+	var taskID int
+	for id := range gh.issues {
+		taskID = id
+	}
+	task, err := tasks.GetTask(userContext(1), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Title != "#7 Fix login" || task.Status != model.StatusTodo {
+		t.Errorf("created task = %q (%s), want %q (todo)", task.Title, task.Status, "#7 Fix login")
+	}
+
+	// событие не новее известного состояния не применяется
+	body, sig = signedEvent(t, "s3cret", "closed", t0)
+	if err := sync.HandleWebhook(ctx, 1, "issues", sig, body); err != nil {
+		t.Fatal(err)
+	}
+	if task, _ = tasks.GetTask(userContext(1), taskID); task.Status != model.StatusTodo {
+		t.Errorf("stale close applied: status = %s", task.Status)
+	}
+
+	// завершение задачи закрывает issue
+	task.Status = model.StatusDone
+	sync.Publish(ctx, events.Event{Type: events.TaskUpdated, TaskID: taskID, Task: &task})
+	select {
+	case state := <-patched:
+		if state != "closed" {
+			t.Errorf("issue state = %q, want closed", state)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("issue was not closed")
+	}
+}
+
+// Изменения из GitHub доходят до остальных публикаторов, но не возвращаются
+// в сам GitHub
+func TestGitHubSyncPublishesToOthers(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected GitHub request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer api.Close()
+
+	gh := &fakeGitHub{links: map[int]model.GitHubLink{}, issues: map[int]model.GitHubIssue{}}
+	gh.links[1] = model.GitHubLink{ProjectID: 1, UserID: 1, Repo: "acme/app", Login: "octo", WebhookSecret: "s3cret"}
+	rec := &recorder{}
+	publishers := events.Fanout{rec}
+	sync := service.NewGitHubSync(gh, storage.NewMemory(), newFakeWorkspaces(), "", zerolog.Nop(),
+		service.WithGitHubAPI(api.URL, api.Client()),
+		service.WithGitHubTasks(&publishers))
+	publishers = append(publishers, sync)
+
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, state := range []string{"open", "closed"} {
+		body, sig := signedEvent(t, "s3cret", state, t0.Add(time.Duration(i)*time.Minute))
+		if err := sync.HandleWebhook(context.Background(), 1, "issues", sig, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := rec.types(); !slices.Equal(got, []string{events.TaskCreated, events.TaskUpdated, events.TaskCompleted}) {
+		t.Fatalf("published = %v, want created, updated and completed", got)
+	}
+	// push в GitHub асинхронный: даём ему время проявиться
+	time.Sleep(50 * time.Millisecond)
+}
+
+// delegatedByOther - каждая задача поручена владельцу связи пользователем 2
+// и ждёт приёмки
+type delegatedByOther struct{ storage.DelegationStore }
+
+func (delegatedByOther) TaskDelegation(_ context.Context, id int) (model.Delegation, error) {
+	return model.Delegation{TaskID: id, DelegatorID: 2, DelegateID: 1, State: model.DelegationActive}, nil
+}
+
+// Закрытие issue не завершает поручённую владельцу связи задачу: её
+// принимает поручивший. Заголовок при этом переносится.
+func TestGitHubSyncKeepsDelegatedTaskInReview(t *testing.T) {
+	api := httptest.NewServer(http.NotFoundHandler())
+	defer api.Close()
+
+	gh := &fakeGitHub{links: map[int]model.GitHubLink{}, issues: map[int]model.GitHubIssue{}}
+	gh.links[1] = model.GitHubLink{ProjectID: 1, UserID: 1, Repo: "acme/app", Login: "octo", WebhookSecret: "s3cret"}
+	tasks := storage.NewMemory()
+	var publishers events.Fanout
+	sync := service.NewGitHubSync(gh, tasks, newFakeWorkspaces(), "", zerolog.Nop(),
+		service.WithGitHubAPI(api.URL, api.Client()),
+		service.WithGitHubTasks(&publishers, service.WithDelegations(delegatedByOther{}, fakeUsers{}, nil, "", zerolog.Nop())))
+
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	body, sig := signedEvent(t, "s3cret", "open", t0)
+	if err := sync.HandleWebhook(context.Background(), 1, "issues", sig, body); err != nil {
+		t.Fatal(err)
+	}
+	var taskID int
+	for id := range gh.issues {
+		taskID = id
+	}
+	body = bytes.Replace(body, []byte(`"Fix login"`), []byte(`"Fix login page"`), 1)
+	body = bytes.Replace(body, []byte(`"open"`), []byte(`"closed"`), 1)
+	body = bytes.Replace(body, []byte(t0.Format(time.RFC3339)), []byte(t0.Add(time.Minute).Format(time.RFC3339)), 1)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if err := sync.HandleWebhook(context.Background(), 1, "issues", "sha256="+hex.EncodeToString(mac.Sum(nil)), body); err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := tasks.GetTask(userContext(1), taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status == model.StatusDone || task.Title != "#7 Fix login page" {
+		t.Fatalf("task = %q (%s), want the new title and no completion without review", task.Title, task.Status)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+const (
+	defaultInviteTTL = 7 * 24 * time.Hour
+	maxInviteTTL     = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidInvite = errors.New("invite is invalid, expired or already used")
+	// ErrInviteEmailMismatch - приглашение выписано на другой email
+	ErrInviteEmailMismatch = errors.New("invite was issued for a different email")
+)
+
+type InviteRequest struct {
+	Email   string `json:"email" validate:"omitempty,email,max=254"`
+	Role    string `json:"role" validate:"omitempty,oneof=admin member"`
+	TTLDays int    `json:"ttl_days" validate:"omitempty,min=1,max=30"`
+}
+
+// Invite создаёт приглашение. С email оно действует только для владельца
+// этого адреса и отправляется письмом; без email - это ссылка для любого.
+func (s *WorkspaceService) Invite(ctx context.Context, workspaceID int, req InviteRequest) (model.Invite, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return model.Invite{}, &ValidationError{Err: err}
+	}
+	if req.Role == "" {
+		req.Role = model.WorkspaceMember
+	}
+	callerRole, err := s.role(ctx, workspaceID, model.WorkspaceAdmin)
+	if err != nil {
+		return model.Invite{}, err
+	}
+	if req.Role == model.WorkspaceAdmin && callerRole != model.WorkspaceOwner {
+		return model.Invite{}, ErrForbidden
+	}
+	ws, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return model.Invite{}, err
+	}
+
+	ttl := defaultInviteTTL
+	if req.TTLDays > 0 {
+		ttl = min(time.Duration(req.TTLDays)*24*time.Hour, maxInviteTTL)
+	}
+	token, err := auth.NewToken("inv_")
+	if err != nil {
+		return model.Invite{}, err
+	}
+	uid, _ := currentUserID(ctx)
+	inv := model.Invite{
+		WorkspaceID:   workspaceID,
+		WorkspaceName: ws.Name,
+		Email:         strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:          req.Role,
+		InvitedBy:     &uid,
+		ExpiresAt:     s.now().Add(ttl),
+	}
+	if err := s.store.CreateInvite(ctx, &inv, auth.HashToken(token)); err != nil {
+		return model.Invite{}, err
+	}
+	inv.URL = s.publicURL + "/invites/" + url.PathEscape(token)
+
+	if inv.Email != "" && s.mailer != nil {
+		s.sendInvite(inv)
+	}
+	return inv, nil
+}
+
+func (s *WorkspaceService) sendInvite(inv model.Invite) {
+	// письмо уходит на любой адрес, а название пространства вводят пользователи:
+	// в теме оно в одну строку
+	msg := mail.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You are invited to %s", mail.HeaderText(inv.WorkspaceName)),
+		Body: fmt.Sprintf("You have been invited to join the workspace %q as %s.\n\n"+
+			"Sign in or register with this email, then open:\n\n%s\n\nThe invite expires on %s.\n",
+			inv.WorkspaceName, inv.Role, inv.URL, inv.ExpiresAt.UTC().Format(time.RFC1123)),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			s.log.Error().Err(err).Int("invite_id", inv.ID).Msg("Failed to send workspace invite")
+		}
+	}()
+}
+
+func (s *WorkspaceService) Invites(ctx context.Context, workspaceID int) ([]model.Invite, error) {
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return nil, err
+	}
+	return s.store.ListInvites(ctx, workspaceID)
+}
+
+func (s *WorkspaceService) RevokeInvite(ctx context.Context, workspaceID, id int) error {
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return err
+	}
+	return s.store.RevokeInvite(ctx, workspaceID, id)
+}
+
+// InviteInfo - сведения о приглашении по токену, чтобы показать его до принятия
+func (s *WorkspaceService) InviteInfo(ctx context.Context, token string) (model.Invite, error) {
+	if _, err := currentUserID(ctx); err != nil {
+		return model.Invite{}, err
+	}
+	inv, err := s.store.InviteByToken(ctx, auth.HashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.Invite{}, ErrInvalidInvite
+	}
+	return inv, err
+}
+
+// AcceptInvite добавляет текущего пользователя в пространство приглашения
+func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string) (model.Workspace, error) {
+	inv, err := s.InviteInfo(ctx, token)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	p, _ := auth.FromContext(ctx)
+	if inv.Email != "" && !strings.EqualFold(inv.Email, p.Email) {
+		return model.Workspace{}, ErrInviteEmailMismatch
+	}
+
+	inv, err = s.store.AcceptInvite(ctx, auth.HashToken(token), p.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.Workspace{}, ErrInvalidInvite
+	}
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	return s.store.GetWorkspace(ctx, inv.WorkspaceID, p.UserID)
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/jira"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type JiraLinkRequest struct {
+	BaseURL   string            `json:"base_url"`
+	Email     string            `json:"email"`
+	Token     string            `json:"token"`
+	JQL       string            `json:"jql"`
+	StatusMap map[string]string `json:"status_map"`
+}
+
+// JiraSync - то же, что GitHubSync, для Jira: issues из JQL становятся
+// задачами проекта, статус переводится в обе стороны по StatusMap или по
+// категории статуса Jira
+type JiraSync struct {
+	store     storage.JiraStore
+	tasks     *TaskService
+	taskOpts  []Option
+	projects  storage.WorkspaceStore
+	http      *http.Client
+	publicURL string
+	log       zerolog.Logger
+	now       func() time.Time
+}
+
+type JiraOption func(*JiraSync)
+
+// WithJiraTasks - публикаторы и настройки сервиса задач, через который
+// применяются изменения из Jira; сама связь из publishers исключается
+func WithJiraTasks(publishers *events.Fanout, opts ...Option) JiraOption {
+	return func(j *JiraSync) {
+		j.taskOpts = append(slices.Clip(opts), WithPublisher(publishers.Except(j)))
+	}
+}
+
+func WithJiraHTTPClient(client *http.Client) JiraOption {
+	return func(j *JiraSync) { j.http = client }
+}
+
+func NewJiraSync(store storage.JiraStore, tasks storage.TaskStore, projects storage.WorkspaceStore,
+	publicURL string, logger zerolog.Logger, opts ...JiraOption) *JiraSync {
+	j := &JiraSync{
+		store:     store,
+		projects:  projects,
+		http:      publicHTTPClient(15 * time.Second),
+		publicURL: strings.TrimRight(publicURL, "/"),
+		log:       logger,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	j.tasks = NewTaskService(tasks, j.taskOpts...)
+	return j
+}
+
+func (j *JiraSync) client(link model.JiraLink) *jira.Client {
+	return &jira.Client{BaseURL: link.BaseURL, Email: link.Email, Token: link.Token, HTTP: j.http}
+}
+
+func (j *JiraSync) adminProject(ctx context.Context, projectID int) error {
+	p, err := j.projects.GetProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if p.Role != model.ProjectAdmin {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Link проверяет учётные данные и JQL, сохраняет связь и запускает первую
+// синхронизацию. Вебхук в Jira настраивается по возвращённым URL и секрету.
+func (j *JiraSync) Link(ctx context.Context, projectID int, req JiraLinkRequest) (model.JiraLink, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.JiraLink{}, err
+	}
+	if u, err := url.Parse(req.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return model.JiraLink{}, &ValidationError{Err: errors.New("base_url must be an http(s) URL")}
+	}
+	if req.Token == "" || strings.TrimSpace(req.JQL) == "" {
+		return model.JiraLink{}, &ValidationError{Err: errors.New("token and jql are required")}
+	}
+	for name, status := range req.StatusMap {
+		if status != model.StatusTodo && status != model.StatusInProgress && status != model.StatusDone {
+			return model.JiraLink{}, &ValidationError{Err: fmt.Errorf("status_map[%q]: unknown task status %q", name, status)}
+		}
+	}
+	if err := j.adminProject(ctx, projectID); err != nil {
+		return model.JiraLink{}, err
+	}
+
+	link := model.JiraLink{
+		ProjectID: projectID, UserID: uid, BaseURL: strings.TrimRight(req.BaseURL, "/"),
+		Email: req.Email, Token: req.Token, JQL: req.JQL, StatusMap: req.StatusMap,
+	}
+	if link.StatusMap == nil {
+		link.StatusMap = map[string]string{}
+	}
+	if _, err := j.client(link).Myself(ctx); err != nil {
+		return model.JiraLink{}, jiraCheckError("credentials", err)
+	}
+	// JQL проверяем сразу: иначе ошибка всплывёт только в фоновой синхронизации
+	if _, err := j.client(link).Search(ctx, "("+link.JQL+") AND updated >= -1m"); err != nil {
+		return model.JiraLink{}, jiraCheckError("jql", err)
+	}
+
+	if link.WebhookSecret, err = auth.NewToken(""); err != nil {
+		return model.JiraLink{}, err
+	}
+	if err := j.store.SaveJiraLink(ctx, &link); err != nil {
+		return model.JiraLink{}, err
+	}
+
+	go j.syncLink(context.WithoutCancel(ctx), link)
+
+	link.WebhookURL = fmt.Sprintf("%s/integrations/jira/%d", j.publicURL, projectID)
+	return link, nil
+}
+
+// jiraCheckError: отказ Jira (4xx) - ошибка в запросе пользователя
+func jiraCheckError(what string, err error) error {
+	if errors.Is(err, errPrivateAddress) {
+		return &ValidationError{Err: errors.New("base_url must point to a public address")}
+	}
+	var se *jira.StatusError
+	if errors.As(err, &se) && se.Code >= 400 && se.Code < 500 {
+		return &ValidationError{Err: fmt.Errorf("jira rejected the %s: %d", what, se.Code)}
+	}
+	return fmt.Errorf("jira: check %s: %w", what, err)
+}
+
+func (j *JiraSync) GetLink(ctx context.Context, projectID int) (model.JiraLink, error) {
+	if _, err := j.projects.GetProject(ctx, projectID); err != nil {
+		return model.JiraLink{}, err
+	}
+	link, err := j.store.JiraLink(ctx, projectID)
+	link.WebhookSecret = ""
+	return link, err
+}
+
+func (j *JiraSync) Unlink(ctx context.Context, projectID int) error {
+	if err := j.adminProject(ctx, projectID); err != nil {
+		return err
+	}
+	return j.store.DeleteJiraLink(ctx, projectID)
+}
+
+// HandleWebhook применяет события создания и изменения issue
+func (j *JiraSync) HandleWebhook(ctx context.Context, projectID int, signature string, body []byte) error {
+	link, err := j.store.JiraLink(ctx, projectID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrInvalidWebhook
+	}
+	if err != nil {
+		return err
+	}
+	if !jira.VerifySignature(link.WebhookSecret, body, signature) {
+		return ErrInvalidSignature
+	}
+
+	var e jira.Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return &ValidationError{Err: errors.New("payload must be a Jira issue event")}
+	}
+	if e.WebhookEvent != jira.EventIssueCreated && e.WebhookEvent != jira.EventIssueUpdated {
+		return nil
+	}
+
+	// фильтр вебхука в Jira может быть шире JQL связи: новую issue сверяем с JQL
+	if _, err := j.store.JiraIssue(ctx, projectID, e.Issue.Key); errors.Is(err, storage.ErrNotFound) {
+		found, err := j.client(link).Search(ctx, fmt.Sprintf("(%s) AND key = %q", link.JQL, e.Issue.Key))
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return nil
+		}
+	}
+	return j.apply(ctx, link, e.Issue)
+}
+
+// mapStatus - статус задачи для статуса Jira
+func mapStatus(link model.JiraLink, s jira.Status) string {
+	if status, ok := link.StatusMap[s.Name]; ok {
+		return status
+	}
+	switch s.Category.Key {
+	case jira.CategoryDone:
+		return model.StatusDone
+	case jira.CategoryInProgress:
+		return model.StatusInProgress
+	default:
+		return model.StatusTodo
+	}
+}
+
+// apply - как GitHubSync.apply: устаревшие события пропускаются, статус
+// задачи меняется, только если он изменился на стороне Jira
+func (j *JiraSync) apply(ctx context.Context, link model.JiraLink, issue jira.Issue) error {
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserID: link.UserID, Role: model.RoleUser, Scheme: auth.SchemeIntegration})
+	title := truncate(issue.Key+" "+issue.Fields.Summary, maxTitleLen)
+	status := mapStatus(link, issue.Fields.Status)
+	updated := issue.Fields.Updated.Time
+
+	known, err := j.store.JiraIssue(ctx, link.ProjectID, issue.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		if status == model.StatusDone {
+			return nil
+		}
+		task := model.Task{
+			ProjectID: &link.ProjectID,
+			Title:     title,
+			Description: truncate(strings.TrimSpace(link.BaseURL+"/browse/"+issue.Key+"\n\n"+issue.Fields.Description),
+				maxDescriptionLen),
+			Status: status,
+		}
+		if err := j.tasks.Create(ctx, &task); err != nil {
+			return err
+		}
+		return j.store.SaveJiraIssue(ctx, model.JiraIssue{
+			ProjectID: link.ProjectID, Key: issue.Key, TaskID: task.ID, Status: status, UpdatedAt: updated,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if !updated.After(known.UpdatedAt) {
+		return nil
+	}
+
+	task, err := j.tasks.Get(ctx, known.TaskID)
+	if err != nil {
+		return err
+	}
+	prev := task
+	task.Title = title
+	if status != known.Status {
+		task.Status = status
+	}
+	if task.Title != prev.Title || task.Status != prev.Status {
+		err := j.tasks.Update(ctx, &task)
+		if errors.Is(err, ErrReviewRequired) {
+			// поручённую задачу завершает только поручивший, закрытие issue
+			// её не завершает; заголовок всё равно переносим
+			j.log.Info().Int("task_id", task.ID).Msg("Delegated task is awaiting review, issue status not applied")
+			task.Status, err = prev.Status, nil
+			if task.Title != prev.Title {
+				err = j.tasks.Update(ctx, &task)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	known.Status, known.UpdatedAt = status, updated
+	return j.store.SaveJiraIssue(ctx, known)
+}
+
+// Publish переводит связанную issue в статус, соответствующий задаче
+func (j *JiraSync) Publish(ctx context.Context, e events.Event) {
+	if e.Type != events.TaskUpdated || e.Task == nil || e.Task.ProjectID == nil {
+		return
+	}
+	task := *e.Task
+	go func() {
+		if err := j.push(context.WithoutCancel(ctx), task); err != nil {
+			j.log.Error().Err(err).Int("task_id", task.ID).Msg("Failed to push task status to Jira")
+		}
+	}()
+}
+
+func (j *JiraSync) push(ctx context.Context, task model.Task) error {
+	known, err := j.store.JiraIssueByTask(ctx, task.ID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && known.Status == task.Status) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link, err := j.store.JiraLink(ctx, known.ProjectID)
+	if err != nil {
+		return err
+	}
+	c := j.client(link)
+
+	transitions, err := c.Transitions(ctx, known.Key)
+	if err != nil {
+		return err
+	}
+	var target *jira.Transition
+	for i, t := range transitions {
+		if mapStatus(link, t.To) == task.Status {
+			target = &transitions[i]
+			break
+		}
+	}
+	if target == nil {
+		// workflow не позволяет перейти напрямую - оставляем issue как есть
+		j.log.Warn().Str("issue", known.Key).Str("status", task.Status).Msg("No Jira transition to task status")
+		return nil
+	}
+	if err := c.Transition(ctx, known.Key, target.ID); err != nil {
+		return err
+	}
+
+	// время изменения после перехода: эхо-вебхук окажется устаревшим
+	issue, err := c.Issue(ctx, known.Key)
+	if err != nil {
+		return err
+	}
+	known.Status, known.UpdatedAt = task.Status, issue.Fields.Updated.Time
+	return j.store.SaveJiraIssue(ctx, known)
+}
+
+// Run сверяет все связи раз в every по их JQL
+func (j *JiraSync) Run(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		links, err := j.store.ListJiraLinks(ctx)
+		if err != nil {
+			j.log.Error().Err(err).Msg("Failed to list Jira links")
+			continue
+		}
+		for _, link := range links {
+			j.syncLink(ctx, link)
+		}
+	}
+}
+
+func (j *JiraSync) syncLink(ctx context.Context, link model.JiraLink) {
+	started := j.now()
+	jql := "(" + link.JQL + ")"
+	if link.LastSyncedAt != nil {
+		// относительное время не зависит от часового пояса пользователя Jira;
+		// минута запаса на округление
+		minutes := int(started.Sub(*link.LastSyncedAt).Minutes()) + 2
+		jql += fmt.Sprintf(" AND updated >= -%dm", minutes)
+	}
+
+	log := j.log.With().Int("project_id", link.ProjectID).Str("jira", link.BaseURL).Logger()
+	issues, err := j.client(link).Search(ctx, jql)
+	if err != nil {
+		log.Error().Err(err).Msg("Jira reconciliation failed")
+		return
+	}
+	for _, issue := range issues {
+		if err := j.apply(ctx, link, issue); err != nil {
+			log.Error().Err(err).Str("issue", issue.Key).Msg("Failed to sync Jira issue")
+			return
+		}
+	}
+	if err := j.store.SetJiraSynced(ctx, link.ProjectID, started); err != nil {
+		log.Error().Err(err).Msg("Failed to record Jira sync time")
+	}
+}
@@ -0,0 +1,150 @@
+package service_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type fakeJira struct {
+	links  map[int]model.JiraLink
+	issues map[int]model.JiraIssue // task -> issue
+}
+
+func (f *fakeJira) SaveJiraLink(_ context.Context, l *model.JiraLink) error {
+	f.links[l.ProjectID] = *l
+	return nil
+}
+
+func (f *fakeJira) JiraLink(_ context.Context, projectID int) (model.JiraLink, error) {
+	l, ok := f.links[projectID]
+	if !ok {
+		return model.JiraLink{}, storage.ErrNotFound
+	}
+	return l, nil
+}
+
+func (f *fakeJira) ListJiraLinks(context.Context) ([]model.JiraLink, error) { return nil, nil }
+func (f *fakeJira) DeleteJiraLink(context.Context, int) error               { return nil }
+func (f *fakeJira) SetJiraSynced(context.Context, int, time.Time) error     { return nil }
+
+func (f *fakeJira) JiraIssue(_ context.Context, projectID int, key string) (model.JiraIssue, error) {
+	for _, i := range f.issues {
+		if i.ProjectID == projectID && i.Key == key {
+			return i, nil
+		}
+	}
+	return model.JiraIssue{}, storage.ErrNotFound
+}
+
+func (f *fakeJira) JiraIssueByTask(_ context.Context, taskID int) (model.JiraIssue, error) {
+	i, ok := f.issues[taskID]
+	if !ok {
+		return model.JiraIssue{}, storage.ErrNotFound
+	}
+	return i, nil
+}
+
+func (f *fakeJira) SaveJiraIssue(_ context.Context, i model.JiraIssue) error {
+	f.issues[i.TaskID] = i
+	return nil
+}
+
+const jiraIssueJSON = `{"key": "OPS-3", "fields": {"summary": "Rotate keys", "updated": "2025-03-01T10:00:00.000+0000",
+	"status": {"name": "Review", "statusCategory": {"key": "indeterminate"}}}}`
+
+func TestJiraSyncMapsStatuses(t *testing.T) {
+	transitioned := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/api/2/search":
+			fmt.Fprintf(w, `{"total": 1, "issues": [%s]}`, jiraIssueJSON)
+		case "GET /rest/api/2/issue/OPS-3/transitions":
+			fmt.Fprint(w, `{"transitions": [
+				{"id": "11", "to": {"name": "In Progress", "statusCategory": {"key": "indeterminate"}}},
+				{"id": "31", "to": {"name": "Closed", "statusCategory": {"key": "done"}}}]}`)
+		case "POST /rest/api/2/issue/OPS-3/transitions":
+			var body struct{ Transition struct{ ID string } }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			transitioned <- body.Transition.ID
+		case "GET /rest/api/2/issue/OPS-3":
+			fmt.Fprint(w, jiraIssueJSON)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	store := &fakeJira{links: map[int]model.JiraLink{}, issues: map[int]model.JiraIssue{}}
+	store.links[1] = model.JiraLink{ProjectID: 1, UserID: 1, BaseURL: api.URL, Token: "pat", JQL: "project = OPS",
+		StatusMap: map[string]string{"Review": model.StatusTodo}, WebhookSecret: "s3cret"}
+	tasks := storage.NewMemory()
+	sync := service.NewJiraSync(store, tasks, newFakeWorkspaces(), "", zerolog.Nop(),
+		service.WithJiraHTTPClient(api.Client()))
+
+	body := []byte(`{"webhookEvent": "jira:issue_created", "issue": ` + jiraIssueJSON + `}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if err := sync.HandleWebhook(context.Background(), 1, "sha256="+hex.EncodeToString(mac.Sum(nil)), body); err != nil {
+		t.Fatal(err)
+	}
+
+	known, err := store.JiraIssue(context.Background(), 1, "OPS-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := tasks.GetTask(userContext(1), known.TaskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// явное сопоставление важнее категории статуса
+	if task.Title != "OPS-3 Rotate keys" || task.Status != model.StatusTodo {
+		t.Errorf("created task = %q (%s), want %q (todo)", task.Title, task.Status, "OPS-3 Rotate keys")
+	}
+
+	task.Status = model.StatusDone
+	sync.Publish(context.Background(), events.Event{Type: events.TaskUpdated, TaskID: task.ID, Task: &task})
+	select {
+	case id := <-transitioned:
+		if id != "31" {
+			t.Errorf("transition = %s, want 31 (Closed)", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("issue was not transitioned")
+	}
+}
+
+// Связь с сервером Jira во внутренней сети не сохраняется: клиент по
+// умолчанию не подключается к непубличным адресам
+func TestJiraLinkRejectsPrivateAddress(t *testing.T) {
+	var requests atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { requests.Add(1) }))
+	defer api.Close()
+
+	store := &fakeJira{links: map[int]model.JiraLink{}, issues: map[int]model.JiraIssue{}}
+	projects := newFakeWorkspaces()
+	projects.project[1] = model.ProjectAdmin
+	sync := service.NewJiraSync(store, storage.NewMemory(), projects, "", zerolog.Nop())
+
+	_, err := sync.Link(userContext(1), 1, service.JiraLinkRequest{BaseURL: api.URL, Token: "pat", JQL: "project = OPS"})
+	var verr *service.ValidationError
+	if !errors.As(err, &verr) || requests.Load() != 0 || len(store.links) != 0 {
+		t.Fatalf("Link(%s) = %v after %d requests, want ValidationError", api.URL, err, requests.Load())
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// placeholder - {{ $.path }} в шаблоне вебхука
+var placeholder = regexp.MustCompile(`\{\{\s*(\$[^}]*?)\s*\}\}`)
+
+// jsonPath - последовательность ключей объектов (string) и индексов массивов (int).
+// Поддерживается подмножество JSONPath: $.a.b, $['a b'], $.items[0].
+type jsonPath []any
+
+func parseJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", expr)
+	}
+	var path jsonPath
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("json path %q: empty key", expr)
+			}
+			path, rest = append(path, key), rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q: unclosed [", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if n, err := strconv.Atoi(inner); err == nil && n >= 0 {
+				path = append(path, n)
+			} else if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				path = append(path, inner[1:len(inner)-1])
+			} else {
+				return nil, fmt.Errorf("json path %q: bad selector [%s]", expr, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("json path %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return path, nil
+}
+
+// lookup возвращает значение по пути; отсутствующее поле - не ошибка
+func (p jsonPath) lookup(doc any) (any, bool) {
+	for _, step := range p {
+		switch key := step.(type) {
+		case string:
+			obj, ok := doc.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if doc, ok = obj[key]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := doc.([]any)
+			if !ok || key >= len(arr) {
+				return nil, false
+			}
+			doc = arr[key]
+		}
+	}
+	return doc, true
+}
+
+// checkTemplate проверяет все выражения шаблона заранее, при сохранении
+func checkTemplate(tmpl string) error {
+	for _, m := range placeholder.FindAllStringSubmatch(tmpl, -1) {
+		if _, err := parseJSONPath(m[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderTemplate подставляет значения из doc; строки - как есть,
+// объекты и массивы - в виде JSON, отсутствующие поля - пустой строкой
+func renderTemplate(tmpl string, doc any) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		path, err := parseJSONPath(placeholder.FindStringSubmatch(m)[1])
+		if err != nil {
+			return ""
+		}
+		v, ok := path.lookup(doc)
+		if !ok || v == nil {
+			return ""
+		}
+		switch v := v.(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		case bool:
+			return strconv.FormatBool(v)
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	})
+}
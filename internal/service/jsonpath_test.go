@@ -0,0 +1,37 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(`{
+		"issue": {"title": "Crash on start", "number": 42, "labels": [{"name": "bug"}]},
+		"repository": {"full name": "acme/app"}
+	}`)))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for tmpl, want := range map[string]string{
+		"{{ $.issue.title }}": "Crash on start",
+		"#{{$.issue.number}} in {{ $['repository']['full name'] }}": "#42 in acme/app",
+		"label: {{ $.issue.labels[0].name }}":                       "label: bug",
+		"missing: {{ $.issue.assignee.login }}.":                    "missing: .",
+		"{{ $.issue.labels }}":                                      `[{"name":"bug"}]`,
+	} {
+		if got := renderTemplate(tmpl, doc); got != want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+
+	for _, bad := range []string{"{{ $.a[ }}", "{{ $..a }}", "{{ $[x] }}"} {
+		if err := checkTemplate(bad); err == nil {
+			t.Errorf("checkTemplate(%q) = nil, want error", bad)
+		}
+	}
+}
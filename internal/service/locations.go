@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// ErrLocationsDisabled - хранилище не ищет задачи по месту
+var ErrLocationsDisabled = errors.New("nearby search is not enabled")
+
+const (
+	// defaultNearbyRadius - радиус в метрах, если клиент его не задал: пешком
+	// за несколько минут
+	defaultNearbyRadius = 500
+	maxNearbyRadius     = 50_000
+	maxNearbyTasks      = 100
+)
+
+// WithLocations включает поиск задач поблизости
+func WithLocations(store storage.LocationStore) Option {
+	return func(s *TaskService) { s.locations = store }
+}
+
+// Nearby - незавершённые задачи с местом в радиусе radius метров от точки,
+// ближайшие первыми. Мобильный клиент спрашивает, когда узнаёт, где он.
+func (s *TaskService) Nearby(ctx context.Context, lat, lon, radius float64) ([]model.Task, error) {
+	if s.locations == nil {
+		return nil, ErrLocationsDisabled
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, &ValidationError{Err: errors.New("lat must be within [-90, 90] and lon within [-180, 180]")}
+	}
+	if radius <= 0 {
+		radius = defaultNearbyRadius
+	}
+	tasks, err := s.locations.NearbyTasks(ctx, lat, lon, min(radius, maxNearbyRadius), maxNearbyTasks)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []model.Task{}
+	}
+	return tasks, nil
+}
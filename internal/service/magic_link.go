@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+const magicLinkTTL = 15 * time.Minute
+
+var ErrMagicLinkDisabled = errors.New("magic-link login is not configured")
+
+// RequestMagicLink отправляет ссылку для входа без пароля. Для незнакомого
+// email ничего не происходит, а ответ тот же - чтобы не раскрывать, кто
+// зарегистрирован. Письмо уходит в фоне по той же причине.
+func (s *AuthService) RequestMagicLink(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return ErrMagicLinkDisabled
+	}
+	email = strings.TrimSpace(email)
+	if err := s.validate.Var(email, "required,email,max=254"); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	u, err := s.users.GetUserByEmail(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.jwt.IssueMagicLink(u.ID, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+	link := s.publicURL + "/auth/magic-link/verify?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Open this link to sign in:\n\n%s\n\nThe link expires in %d minutes and works once. "+
+			"If you did not request it, ignore this email.\n", link, int(magicLinkTTL.Minutes())),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			s.log.Error().Err(err).Int("user_id", u.ID).Msg("Failed to send magic link")
+		}
+	}()
+	return nil
+}
+
+// VerifyMagicLink обменивает ссылку на сессию (или на challenge, если включена 2FA)
+func (s *AuthService) VerifyMagicLink(ctx context.Context, token, userAgent string) (Login, error) {
+	userID, jti, expiresAt, err := s.jwt.ParseMagicLink(token)
+	if err != nil {
+		return Login{}, ErrInvalidCredentials
+	}
+	fresh, err := s.users.UseMagicLink(ctx, jti, expiresAt)
+	if err != nil {
+		return Login{}, err
+	}
+	if !fresh {
+		return Login{}, ErrInvalidCredentials
+	}
+
+	u, err := s.users.GetUser(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Login{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Login{}, err
+	}
+	if u.TOTPEnabled {
+		challenge, err := s.jwt.IssueChallenge(u.ID, mfaChallengeTTL)
+		if err != nil {
+			return Login{}, err
+		}
+		return Login{MFARequired: true, MFAToken: challenge}, nil
+	}
+	return s.issue(ctx, u, userAgent)
+}
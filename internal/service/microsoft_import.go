@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/microsoft"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// importNamespace - пространство имён UUIDv5 для uid перенесённых задач
+var importNamespace = uuid.MustParse("5b0f9a1e-5d2c-4c47-9a43-7c6f0df3a0b1")
+
+// MicrosoftImport переносит списки и задачи Microsoft To Do (в нём же живут
+// задачи Outlook) через Graph. Токен с правом Tasks.Read клиент получает
+// сам, например через MSAL, - сервер его не хранит.
+//
+// У перенесённой задачи uid выводится из id задачи в To Do, поэтому
+// повторный импорт пропускает уже перенесённое и после обрыва продолжает
+// с того же места.
+type MicrosoftImport struct {
+	tasks      *TaskService
+	workspaces *WorkspaceService
+	graph      func(token string) *microsoft.Client
+	validate   *validator.Validate
+	log        zerolog.Logger
+}
+
+type MicrosoftImportOption func(*MicrosoftImport)
+
+// WithGraphAPI подменяет адрес Microsoft Graph (тесты)
+func WithGraphAPI(baseURL string) MicrosoftImportOption {
+	return func(m *MicrosoftImport) {
+		m.graph = func(token string) *microsoft.Client {
+			return &microsoft.Client{BaseURL: baseURL, Token: token}
+		}
+	}
+}
+
+// NewMicrosoftImport: workspaces нужен только для импорта в пространство,
+// может быть nil
+func NewMicrosoftImport(tasks *TaskService, workspaces *WorkspaceService, logger zerolog.Logger,
+	opts ...MicrosoftImportOption) *MicrosoftImport {
+	m := &MicrosoftImport{
+		tasks:      tasks,
+		workspaces: workspaces,
+		validate:   validator.New(),
+		log:        logger,
+	}
+	m.graph = func(token string) *microsoft.Client {
+		return &microsoft.Client{Token: token, HTTP: &http.Client{Timeout: 30 * time.Second}}
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// MicrosoftImportRequest: с WorkspaceID каждый список становится проектом
+// пространства (нужна роль admin), без него задачи - личные
+type MicrosoftImportRequest struct {
+	AccessToken string `json:"access_token" validate:"required"`
+	WorkspaceID *int   `json:"workspace_id" validate:"omitempty,gt=0"`
+}
+
+// Import переносит все списки, кроме помеченных писем: это письма Outlook,
+// а не задачи. Шаги задачи становятся подзадачами. Важность не переносится:
+// приоритета у задач нет.
+func (m *MicrosoftImport) Import(ctx context.Context, req MicrosoftImportRequest) (model.ImportResult, error) {
+	if err := m.validate.Struct(req); err != nil {
+		return model.ImportResult{}, &ValidationError{Err: err}
+	}
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	var projects map[string]int
+	if req.WorkspaceID != nil {
+		if m.workspaces == nil {
+			return model.ImportResult{}, &ValidationError{Err: errors.New("workspaces are not enabled")}
+		}
+		if projects, err = m.projects(ctx, *req.WorkspaceID); err != nil {
+			return model.ImportResult{}, err
+		}
+	}
+
+	graph := m.graph(req.AccessToken)
+	lists, err := graph.Lists(ctx)
+	if err != nil {
+		return model.ImportResult{}, graphError(err)
+	}
+	res := model.ImportResult{Lists: []model.ImportedList{}}
+	for _, l := range lists {
+		if l.WellknownListName == microsoft.ListFlaggedEmails {
+			continue
+		}
+		items, err := graph.Tasks(ctx, l.ID)
+		if err != nil {
+			return res, graphError(err)
+		}
+		imported := model.ImportedList{Name: l.DisplayName}
+		if req.WorkspaceID != nil {
+			if imported.ProjectID, err = m.project(ctx, *req.WorkspaceID, projects, l.DisplayName); err != nil {
+				return res, err
+			}
+		}
+		for _, item := range items {
+			created, existed, err := m.importTask(ctx, uid, imported.ProjectID, item)
+			imported.Created += created
+			if existed {
+				imported.Skipped++
+			}
+			if err != nil {
+				res.Add(imported)
+				return res, fmt.Errorf("import %q: %w", item.Title, err)
+			}
+		}
+		res.Add(imported)
+	}
+	m.log.Info().Int("user_id", uid).Int("created", res.Created).Int("skipped", res.Skipped).
+		Msg("Microsoft To Do import finished")
+	return res, nil
+}
+
+// projects - проекты пространства по имени, чтобы повторный импорт лёг в те же
+func (m *MicrosoftImport) projects(ctx context.Context, workspaceID int) (map[string]int, error) {
+	if _, err := m.workspaces.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return nil, err
+	}
+	existing, err := m.workspaces.Projects(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]int, len(existing))
+	for _, p := range existing {
+		byName[p.Name] = p.ID
+	}
+	return byName, nil
+}
+
+func (m *MicrosoftImport) project(ctx context.Context, workspaceID int, projects map[string]int, list string) (*int, error) {
+	name := truncate(strings.TrimSpace(list), 100)
+	if name == "" {
+		name = "To Do"
+	}
+	if id, ok := projects[name]; ok {
+		return &id, nil
+	}
+	p := model.Project{WorkspaceID: workspaceID, Name: name}
+	if err := m.workspaces.CreateProject(ctx, &p); err != nil {
+		return nil, err
+	}
+	projects[name] = p.ID
+	return &p.ID, nil
+}
+
+// importTask создаёт задачу и недостающие шаги. Уже перенесённая задача
+// (existed) не меняется, но шаги к ней досоздаются: прошлый импорт мог
+// оборваться между ними.
+func (m *MicrosoftImport) importTask(ctx context.Context, uid int, projectID *int, item microsoft.Task) (
+	created int, existed bool, err error) {
+	task := model.Task{
+		UID:         importUID(uid, item.ID),
+		ProjectID:   projectID,
+		Title:       importTitle(item.Title),
+		Description: truncate(item.Body.Text(), maxDescriptionLen),
+		Status:      todoStatus(item.Status),
+	}
+	if item.DueDateTime != nil {
+		if due, err := item.DueDateTime.Time(); err == nil {
+			task.DueAt = &due
+		}
+	}
+	if item.CompletedDateTime != nil && task.Status == model.StatusDone {
+		if done, err := item.CompletedDateTime.Time(); err == nil {
+			task.CompletedAt = &done
+		}
+	}
+	err = m.tasks.Create(ctx, &task, KeepCompletedAt())
+	switch {
+	case errors.Is(err, storage.ErrTaskUIDTaken):
+		existed = true
+		if task.ID, err = m.tasks.ResolveID(ctx, task.UID); err != nil {
+			return 0, true, err
+		}
+	case err != nil:
+		return 0, false, err
+	default:
+		created++
+	}
+
+	for _, step := range item.ChecklistItems {
+		sub := model.Task{
+			UID:       importUID(uid, item.ID+"/"+step.ID),
+			ParentID:  &task.ID,
+			ProjectID: projectID,
+			Title:     importTitle(step.DisplayName),
+			Status:    model.StatusTodo,
+		}
+		if step.IsChecked {
+			sub.Status = model.StatusDone
+		}
+		err := m.tasks.Create(ctx, &sub)
+		if errors.Is(err, storage.ErrTaskUIDTaken) {
+			continue
+		}
+		if err != nil {
+			return created, existed, err
+		}
+		created++
+	}
+	return created, existed, nil
+}
+
+// importUID - uid задачи из её id в To Do; владелец входит в него, чтобы
+// один аккаунт To Do могли перенести несколько пользователей
+func importUID(userID int, externalID string) string {
+	return uuid.NewSHA1(importNamespace, []byte(strconv.Itoa(userID)+":"+externalID)).String()
+}
+
+// importTitle подгоняет заголовок под ограничения задачи: 3-100 символов
+func importTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) < 3 {
+		title = "To Do: " + title
+	}
+	return truncate(title, maxTitleLen)
+}
+
+// todoStatus: "ждёт других" - задача в работе, отложенная - ещё не начата
+func todoStatus(status string) string {
+	switch status {
+	case microsoft.StatusCompleted:
+		return model.StatusDone
+	case microsoft.StatusInProgress, microsoft.StatusWaitingOnOthers:
+		return model.StatusInProgress
+	}
+	return model.StatusTodo
+}
+
+// graphError: отказ Graph в доступе - ошибка в запросе пользователя
+func graphError(err error) error {
+	var se *microsoft.StatusError
+	if errors.As(err, &se) && (se.Code == http.StatusUnauthorized || se.Code == http.StatusForbidden) {
+		return &ValidationError{Err: fmt.Errorf("microsoft graph rejected the access token: %d", se.Code)}
+	}
+	return fmt.Errorf("microsoft graph: %w", err)
+}
@@ -0,0 +1,92 @@
+package service_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+func TestMicrosoftImport(t *testing.T) {
+	var api *httptest.Server
+	api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer graph-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.RequestURI() {
+		case "/me/todo/lists":
+			fmt.Fprintf(w, `{"value": [{"id": "L1", "displayName": "Tasks", "wellknownListName": "defaultList"}],
+				"@odata.nextLink": "%s/me/todo/lists?$skip=1"}`, api.URL)
+		case "/me/todo/lists?$skip=1":
+			fmt.Fprint(w, `{"value": [{"id": "L2", "displayName": "Flagged email", "wellknownListName": "flaggedEmails"}]}`)
+		case "/me/todo/lists/L1/tasks?%24expand=checklistItems&%24top=100":
+			fmt.Fprint(w, `{"value": [
+				{"id": "T1", "title": "Pay rent", "status": "notStarted", "importance": "high",
+				 "body": {"content": "<p>Landlord &amp; co</p><p>IBAN</p>", "contentType": "html"},
+				 "dueDateTime": {"dateTime": "2026-11-01T00:00:00.0000000", "timeZone": "UTC"},
+				 "checklistItems": [{"id": "C1", "displayName": "Check", "isChecked": true}, {"id": "C2", "displayName": "OK"}]},
+				{"id": "T2", "title": "Tax", "status": "completed",
+				 "body": {"content": "", "contentType": "text"},
+				 "completedDateTime": {"dateTime": "2026-04-30T10:00:00.0000000", "timeZone": "UTC"}}]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	// как в сервере - за предохранителем: повтор импорта упирается в занятые uid
+	tasks := service.NewTaskService(storage.NewBreakerStore(storage.NewMemory(), 1, time.Minute))
+	imp := service.NewMicrosoftImport(tasks, nil, zerolog.Nop(), service.WithGraphAPI(api.URL))
+	ctx := userContext(1)
+
+	res, err := imp.Import(ctx, service.MicrosoftImportRequest{AccessToken: "graph-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 4 || len(res.Lists) != 1 || res.Lists[0].Name != "Tasks" {
+		t.Fatalf("result = %+v, want 4 tasks from the default list only", res)
+	}
+
+	byTitle := map[string]model.Task{}
+	all, _ := tasks.List(ctx)
+	for _, task := range all {
+		byTitle[task.Title] = task
+	}
+	rent := byTitle["Pay rent"]
+	if rent.Description != "Landlord & co\nIBAN" || rent.DueAt == nil ||
+		!rent.DueAt.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("rent = %+v", rent)
+	}
+	if check := byTitle["Check"]; check.ParentID == nil || *check.ParentID != rent.ID || check.Status != model.StatusDone {
+		t.Errorf("checked step = %+v, want a done subtask", check)
+	}
+	if _, ok := byTitle["To Do: OK"]; !ok {
+		t.Errorf("short step title was not padded: %v", byTitle)
+	}
+	tax := byTitle["Tax"]
+	if tax.Status != model.StatusDone || tax.CompletedAt == nil ||
+		!tax.CompletedAt.Equal(time.Date(2026, 4, 30, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("tax = %+v, want completion time from To Do", tax)
+	}
+
+	// повторный импорт ничего не дублирует
+	res, err = imp.Import(ctx, service.MicrosoftImportRequest{AccessToken: "graph-token"})
+	if err != nil || res.Created != 0 || res.Skipped != 2 {
+		t.Errorf("second import = %+v, %v; want everything skipped", res, err)
+	}
+
+	var invalid *service.ValidationError
+	if _, err := imp.Import(ctx, service.MicrosoftImportRequest{AccessToken: "expired"}); !errors.As(err, &invalid) {
+		t.Errorf("rejected token: err = %v, want ValidationError", err)
+	}
+}
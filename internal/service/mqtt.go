@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// Broker - то, что нужно MQTTPublisher от клиента MQTT
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte, retain bool) error
+}
+
+const mqttQueueSize = 256
+
+// MQTTPublisher публикует события задач в топики
+//
+//	{prefix}/workspaces/{id}/tasks/{created|updated|completed|deleted}
+//	{prefix}/users/{id}/tasks/...      - для задач вне проектов
+//
+// и раз в период - {prefix}/users/{id}/due_today с флагом retain, чтобы
+// табло получало текущий список сразу после подписки
+type MQTTPublisher struct {
+	broker   Broker
+	tasks    storage.TaskStore
+	projects storage.WorkspaceStore
+	users    storage.UserStore
+	prefix   string
+	log      zerolog.Logger
+	now      func() time.Time
+	queue    chan events.Event
+	// lastDue - последние опубликованные списки, чтобы не слать одно и то же
+	lastDue map[int]string
+}
+
+func NewMQTTPublisher(broker Broker, tasks storage.TaskStore, projects storage.WorkspaceStore, users storage.UserStore,
+	prefix string, logger zerolog.Logger) *MQTTPublisher {
+	return &MQTTPublisher{
+		broker:   broker,
+		tasks:    tasks,
+		projects: projects,
+		users:    users,
+		prefix:   strings.Trim(prefix, "/"),
+		log:      logger,
+		now:      time.Now,
+		queue:    make(chan events.Event, mqttQueueSize),
+		lastDue:  map[int]string{},
+	}
+}
+
+// Publish не ждёт брокера: при переполненной очереди событие теряется
+func (m *MQTTPublisher) Publish(_ context.Context, e events.Event) {
+	select {
+	case m.queue <- e:
+	default:
+		m.log.Warn().Str("type", e.Type).Int("task_id", e.TaskID).Msg("MQTT queue is full, event dropped")
+	}
+}
+
+// Run отправляет события по порядку и раз в dueEvery обновляет списки на сегодня
+func (m *MQTTPublisher) Run(ctx context.Context, dueEvery time.Duration) {
+	ctx = auth.WithPrincipal(ctx, auth.System)
+	t := time.NewTicker(dueEvery)
+	defer t.Stop()
+	m.publishDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-m.queue:
+			if err := m.send(ctx, e); err != nil {
+				m.log.Error().Err(err).Str("type", e.Type).Int("task_id", e.TaskID).Msg("Failed to publish MQTT event")
+			}
+		case <-t.C:
+			m.publishDue(ctx)
+		}
+	}
+}
+
+func (m *MQTTPublisher) topic(ctx context.Context, t model.Task) (string, error) {
+	if t.ProjectID != nil {
+		p, err := m.projects.GetProject(ctx, *t.ProjectID)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s/workspaces/%d/tasks", m.prefix, p.WorkspaceID), nil
+	}
+	if t.OwnerID == nil {
+		return "", fmt.Errorf("task %d has neither project nor owner", t.ID)
+	}
+	return fmt.Sprintf("%s/users/%d/tasks", m.prefix, *t.OwnerID), nil
+}
+
+func (m *MQTTPublisher) send(ctx context.Context, e events.Event) error {
+	if e.Task == nil {
+		return nil
+	}
+	base, err := m.topic(ctx, *e.Task)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// task.completed -> .../tasks/completed
+	return m.broker.Publish(ctx, base+"/"+strings.TrimPrefix(e.Type, "task."), payload, false)
+}
+
+type dueToday struct {
+	Date  string      `json:"date"`
+	Count int         `json:"count"`
+	Tasks []dueTaskMQ `json:"tasks"`
+}
+
+type dueTaskMQ struct {
+	ID    int       `json:"id"`
+	Title string    `json:"title"`
+	DueAt time.Time `json:"due_at"`
+}
+
+// publishDue - незавершённые и не отложенные задачи со сроком до конца
+// сегодняшнего дня владельца (в его часовом поясе), включая просроченные
+func (m *MQTTPublisher) publishDue(ctx context.Context) {
+	tasks, err := m.tasks.ListTasks(ctx)
+	if err != nil {
+		m.log.Error().Err(err).Msg("Failed to list tasks for MQTT due_today")
+		return
+	}
+
+	now := m.now()
+	lists := map[int]*dueToday{}
+	// у кого что-то было - получат пустой список, чтобы табло очистилось
+	for uid := range m.lastDue {
+		lists[uid] = nil
+	}
+	zones := map[int]*time.Location{}
+	for _, t := range tasks {
+		if t.DueAt == nil || t.OwnerID == nil || t.Status == model.StatusDone || t.Snoozed(now) {
+			continue
+		}
+		uid := *t.OwnerID
+		loc, ok := zones[uid]
+		if !ok {
+			loc = time.UTC
+			if u, err := m.users.GetUser(ctx, uid); err == nil && u.Timezone != "" {
+				if l, err := time.LoadLocation(u.Timezone); err == nil {
+					loc = l
+				}
+			}
+			zones[uid] = loc
+		}
+		local := now.In(loc)
+		end := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+		if !t.DueAt.Before(end) {
+			continue
+		}
+		if lists[uid] == nil {
+			lists[uid] = &dueToday{Date: local.Format(time.DateOnly), Tasks: []dueTaskMQ{}}
+		}
+		lists[uid].Tasks = append(lists[uid].Tasks, dueTaskMQ{ID: t.ID, Title: t.Title, DueAt: *t.DueAt})
+		lists[uid].Count++
+	}
+
+	for uid, list := range lists {
+		if list == nil {
+			list = &dueToday{Date: now.UTC().Format(time.DateOnly), Tasks: []dueTaskMQ{}}
+		}
+		payload, err := json.Marshal(list)
+		if err != nil {
+			continue
+		}
+		if m.lastDue[uid] == string(payload) {
+			continue
+		}
+		topic := fmt.Sprintf("%s/users/%d/due_today", m.prefix, uid)
+		if err := m.broker.Publish(ctx, topic, payload, true); err != nil {
+			m.log.Error().Err(err).Int("user_id", uid).Msg("Failed to publish MQTT due_today")
+			continue
+		}
+		if list.Count == 0 {
+			delete(m.lastDue, uid)
+		} else {
+			m.lastDue[uid] = string(payload)
+		}
+	}
+}
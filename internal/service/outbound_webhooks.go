@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// Заголовки исходящего вебхука. Подпись - HMAC-SHA256 тела с секретом
+// вебхука: "sha256=<hex>", как у GitHub.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	deliveryBatch = 50
+	// deliveryWorkers - сколько доставок пачки отправляется одновременно
+	deliveryWorkers = 10
+	// deliveryLease - на сколько забранная доставка скрыта от других
+	// экземпляров. Пачка из 50 доставок по 10 в 5 заходов укладывается в
+	// него даже при таймауте каждой, а отправки, не закончившиеся за
+	// deliveryMargin до конца срока, обрываются, чтобы успеть записать
+	// результат до того, как доставку заберёт другой экземпляр.
+	deliveryLease  = 2 * time.Minute
+	deliveryMargin = 15 * time.Second
+)
+
+// OutboundWebhookService отправляет события задач на адреса пользователей.
+// Событие сначала сохраняется в очередь доставок, поэтому переживает
+// перезапуск; неудачные доставки повторяются с удвоением паузы, а после
+// последней попытки остаются со статусом failed до ручного повтора.
+type OutboundWebhookService struct {
+	store       storage.OutboundWebhookStore
+	http        *http.Client
+	validate    *validator.Validate
+	log         zerolog.Logger
+	now         func() time.Time
+	maxAttempts int
+	backoff     time.Duration
+}
+
+type OutboundWebhookOption func(*OutboundWebhookService)
+
+func WithOutboundHTTPClient(client *http.Client) OutboundWebhookOption {
+	return func(s *OutboundWebhookService) { s.http = client }
+}
+
+// WithDeliveryRetries - число попыток доставки и пауза после первой неудачи
+func WithDeliveryRetries(maxAttempts int, backoff time.Duration) OutboundWebhookOption {
+	return func(s *OutboundWebhookService) { s.maxAttempts, s.backoff = maxAttempts, backoff }
+}
+
+func WithOutboundClock(now func() time.Time) OutboundWebhookOption {
+	return func(s *OutboundWebhookService) { s.now = now }
+}
+
+func NewOutboundWebhookService(store storage.OutboundWebhookStore, logger zerolog.Logger, opts ...OutboundWebhookOption) *OutboundWebhookService {
+	s := &OutboundWebhookService{
+		store:    store,
+		http:     publicHTTPClient(10 * time.Second),
+		validate: validator.New(),
+		log:      logger,
+		now:      time.Now,
+		// 1, 2, 4 ... 64 минуты: всего около двух часов
+		maxAttempts: 8,
+		backoff:     time.Minute,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *OutboundWebhookService) Create(ctx context.Context, hook *model.OutboundWebhook) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.validate.Struct(hook); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := checkPublicURL(ctx, hook.URL); err != nil {
+		return &ValidationError{Err: fmt.Errorf("url: %w", err)}
+	}
+
+	secret, err := auth.NewToken("whsec_")
+	if err != nil {
+		return err
+	}
+	hook.UserID, hook.Secret = uid, secret
+	return s.store.CreateOutboundWebhook(ctx, hook)
+}
+
+func (s *OutboundWebhookService) List(ctx context.Context) ([]model.OutboundWebhook, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.ListOutboundWebhooks(ctx, uid)
+}
+
+func (s *OutboundWebhookService) Delete(ctx context.Context, id int) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteOutboundWebhook(ctx, uid, id)
+}
+
+// Deliveries - последние доставки вебхука; status отбирает, например, failed
+func (s *OutboundWebhookService) Deliveries(ctx context.Context, webhookID int, status string, limit int) ([]model.WebhookDelivery, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", model.DeliveryPending, model.DeliveryDelivered, model.DeliveryFailed:
+	default:
+		return nil, &ValidationError{Err: fmt.Errorf("unknown delivery status %q", status)}
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.store.ListWebhookDeliveries(ctx, uid, webhookID, status, limit)
+}
+
+// Delivery - доставка со всеми попытками
+func (s *OutboundWebhookService) Delivery(ctx context.Context, webhookID int, id int64) (model.WebhookDelivery, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return s.store.WebhookDelivery(ctx, uid, webhookID, id)
+}
+
+// Redeliver ставит доставку в очередь заново с полным циклом попыток
+func (s *OutboundWebhookService) Redeliver(ctx context.Context, webhookID int, id int64) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return s.store.RedeliverWebhook(ctx, uid, webhookID, id)
+}
+
+// Publish сохраняет событие в очередь доставок вебхукам владельца задачи;
+// не ждёт базы, как и остальные публикаторы
+func (s *OutboundWebhookService) Publish(ctx context.Context, e events.Event) {
+	if e.Task == nil || e.Task.OwnerID == nil {
+		return
+	}
+	owner := *e.Task.OwnerID
+	go func() {
+		env := events.NewEnvelope(e)
+		payload, err := json.Marshal(env)
+		if err == nil {
+			err = s.store.EnqueueWebhookDeliveries(context.WithoutCancel(ctx), owner, env.ID, e.Type, payload)
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("type", e.Type).Int("task_id", e.TaskID).Msg("Failed to enqueue webhook deliveries")
+		}
+	}()
+}
+
+// Run раз в every отправляет доставки, срок которых наступил
+func (s *OutboundWebhookService) Run(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := s.DeliverDue(ctx); err != nil {
+				s.log.Error().Err(err).Msg("Failed to deliver webhooks")
+			}
+		}
+	}
+}
+
+// DeliverDue отправляет очередную пачку доставок
+func (s *OutboundWebhookService) DeliverDue(ctx context.Context) error {
+	now := s.now()
+	// Postgres хранит время с точностью до микросекунд, а срок потом
+	// сравнивается на равенство
+	leaseUntil := now.Add(deliveryLease).Truncate(time.Microsecond)
+	due, err := s.store.ClaimWebhookDeliveries(ctx, now, leaseUntil, deliveryBatch)
+	if err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, deliveryLease-deliveryMargin)
+	defer cancel()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, deliveryWorkers)
+	)
+	for _, d := range due {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if err := s.deliver(ctx, sendCtx, leaseUntil, d); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// deliver отправляет доставку и записывает попытку, если доставка всё ещё
+// забрана этим экземпляром
+func (s *OutboundWebhookService) deliver(ctx, sendCtx context.Context, leaseUntil time.Time, d storage.DueDelivery) error {
+	attempt := s.send(sendCtx, d)
+	status, next := model.DeliveryDelivered, (*time.Time)(nil)
+	if attempt.Error != "" {
+		status = model.DeliveryFailed
+		if d.Attempts+1 < s.maxAttempts {
+			at := s.now().Add(s.backoff << d.Attempts)
+			status, next = model.DeliveryPending, &at
+		}
+	}
+	err := s.store.RecordWebhookAttempt(ctx, d.ID, leaseUntil, attempt, status, next)
+	if errors.Is(err, storage.ErrLeaseLost) {
+		s.log.Warn().Int64("delivery_id", d.ID).Int("webhook_id", d.WebhookID).
+			Msg("Webhook delivery lease expired before the attempt was recorded")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if status == model.DeliveryFailed {
+		s.log.Warn().Int64("delivery_id", d.ID).Int("webhook_id", d.WebhookID).Str("error", attempt.Error).
+			Msg("Webhook delivery failed permanently")
+	}
+	return nil
+}
+
+func (s *OutboundWebhookService) send(ctx context.Context, d storage.DueDelivery) (attempt model.WebhookAttempt) {
+	start := s.now()
+	attempt.AttemptedAt = start
+	defer func() { attempt.DurationMS = int(s.now().Sub(start).Milliseconds()) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, d.EventID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.Secret, d.Payload))
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	attempt.StatusCode = &resp.StatusCode
+	if resp.StatusCode/100 != 2 {
+		attempt.Error = "unexpected status " + resp.Status
+	}
+	return attempt
+}
+
+// SignWebhook - значение заголовка X-Webhook-Signature для тела body
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
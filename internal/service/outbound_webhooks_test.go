@@ -0,0 +1,246 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/client"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// fakeOutbound - один вебхук пользователя 1 и очередь его доставок
+type fakeOutbound struct {
+	storage.OutboundWebhookStore
+	url, secret string
+	mu          sync.Mutex
+	deliveries  []model.WebhookDelivery
+	attempts    []model.WebhookAttempt
+	enqueued    chan struct{}
+}
+
+func (f *fakeOutbound) EnqueueWebhookDeliveries(_ context.Context, userID int, eventID, eventType string, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if userID == 1 {
+		now := time.Time{}
+		f.deliveries = append(f.deliveries, model.WebhookDelivery{ID: int64(len(f.deliveries) + 1), WebhookID: 1,
+			EventID: eventID, EventType: eventType, Payload: payload, Status: model.DeliveryPending, NextAttemptAt: &now})
+	}
+	f.enqueued <- struct{}{}
+	return nil
+}
+
+func (f *fakeOutbound) ClaimWebhookDeliveries(_ context.Context, now, leaseUntil time.Time, _ int) ([]storage.DueDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var due []storage.DueDelivery
+	for i, d := range f.deliveries {
+		if d.Status == model.DeliveryPending && !d.NextAttemptAt.After(now) {
+			f.deliveries[i].NextAttemptAt = &leaseUntil
+			due = append(due, storage.DueDelivery{WebhookDelivery: d, URL: f.url, Secret: f.secret})
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeOutbound) RecordWebhookAttempt(_ context.Context, id int64, leaseUntil time.Time, a model.WebhookAttempt,
+	status string, next *time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := &f.deliveries[id-1]
+	if d.Status != model.DeliveryPending || d.NextAttemptAt == nil || !d.NextAttemptAt.Equal(leaseUntil) {
+		return storage.ErrLeaseLost
+	}
+	d.Attempts++
+	d.Status, d.NextAttemptAt, d.LastError = status, next, a.Error
+	f.attempts = append(f.attempts, a)
+	return nil
+}
+
+func TestOutboundWebhookDelivery(t *testing.T) {
+	t.Parallel()
+	var (
+		mu       sync.Mutex
+		failures = 1
+		received [][]byte
+		headers  []http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		received, headers = append(received, body), append(headers, r.Header)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	store := &fakeOutbound{url: srv.URL, secret: "whsec_test", enqueued: make(chan struct{}, 2)}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	hooks := service.NewOutboundWebhookService(store, zerolog.Nop(),
+		service.WithDeliveryRetries(2, time.Minute),
+		service.WithOutboundHTTPClient(srv.Client()),
+		service.WithOutboundClock(func() time.Time { return now }))
+
+	owner, stranger := 1, 2
+	hooks.Publish(context.Background(), events.Event{Type: events.TaskCreated, TaskID: 7,
+		Task: &model.Task{ID: 7, OwnerID: &owner, Title: "Buy milk"}, OccurredAt: now})
+	hooks.Publish(context.Background(), events.Event{Type: events.TaskCreated, TaskID: 8,
+		Task: &model.Task{ID: 8, OwnerID: &stranger}, OccurredAt: now})
+	<-store.enqueued
+	<-store.enqueued
+	if len(store.deliveries) != 1 {
+		t.Fatalf("deliveries = %d, want 1: only the owner's webhook subscribes", len(store.deliveries))
+	}
+
+	// первая попытка - 500, повтор через минуту
+	if err := hooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	d := store.deliveries[0]
+	if d.Status != model.DeliveryPending || !d.NextAttemptAt.Equal(now.Add(time.Minute)) ||
+		d.LastError != "unexpected status 500 Internal Server Error" {
+		t.Fatalf("after failure: %+v", d)
+	}
+	if code := store.attempts[0].StatusCode; code == nil || *code != http.StatusInternalServerError {
+		t.Fatalf("attempt status code = %v, want 500", code)
+	}
+
+	if err := hooks.DeliverDue(context.Background()); err != nil || len(received) != 1 {
+		t.Fatalf("delivered before backoff: %d requests, err %v", len(received), err)
+	}
+	now = now.Add(time.Minute)
+	if err := hooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := store.deliveries[0]; d.Status != model.DeliveryDelivered || d.Attempts != 2 || d.NextAttemptAt != nil {
+		t.Fatalf("after success: %+v", d)
+	}
+
+	h := headers[1]
+	if !client.VerifyWebhookSignature("whsec_test", received[1], h.Get(service.WebhookSignatureHeader)) {
+		t.Fatalf("signature %q does not match body %s", h.Get(service.WebhookSignatureHeader), received[1])
+	}
+	if h.Get(service.WebhookEventHeader) != events.TaskCreated || h.Get(service.WebhookDeliveryHeader) != d.EventID {
+		t.Fatalf("headers = %v", h)
+	}
+}
+
+func TestOutboundWebhookDeadLetter(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	store := &fakeOutbound{url: srv.URL, secret: "whsec_test", enqueued: make(chan struct{}, 1)}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	hooks := service.NewOutboundWebhookService(store, zerolog.Nop(),
+		service.WithDeliveryRetries(3, time.Second),
+		service.WithOutboundHTTPClient(srv.Client()),
+		service.WithOutboundClock(func() time.Time { return now }))
+
+	owner := 1
+	hooks.Publish(context.Background(), events.Event{Type: events.TaskDeleted, TaskID: 7,
+		Task: &model.Task{ID: 7, OwnerID: &owner}, OccurredAt: now})
+	<-store.enqueued
+
+	// паузы 1s и 2s, третья попытка последняя
+	for _, wait := range []time.Duration{0, time.Second, 2 * time.Second} {
+		now = now.Add(wait)
+		if err := hooks.DeliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := store.deliveries[0]; d.Status != model.DeliveryFailed || d.Attempts != 3 || d.NextAttemptAt != nil {
+		t.Fatalf("after last attempt: %+v", d)
+	}
+	now = now.Add(time.Hour)
+	if err := hooks.DeliverDue(context.Background()); err != nil || len(store.attempts) != 3 {
+		t.Fatalf("failed delivery retried: %d attempts, err %v", len(store.attempts), err)
+	}
+}
+
+// Пока запрос шёл, срок истёк и доставку забрал другой экземпляр: его
+// попытка и статус не затираются
+func TestOutboundWebhookLeaseLost(t *testing.T) {
+	t.Parallel()
+	var store *fakeOutbound
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		later := time.Date(2026, 10, 14, 12, 5, 0, 0, time.UTC)
+		store.deliveries[0].NextAttemptAt = &later
+	}))
+	t.Cleanup(srv.Close)
+
+	store = &fakeOutbound{url: srv.URL, secret: "whsec_test", enqueued: make(chan struct{}, 1)}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	hooks := service.NewOutboundWebhookService(store, zerolog.Nop(),
+		service.WithOutboundHTTPClient(srv.Client()),
+		service.WithOutboundClock(func() time.Time { return now }))
+
+	owner := 1
+	hooks.Publish(context.Background(), events.Event{Type: events.TaskCreated, TaskID: 7,
+		Task: &model.Task{ID: 7, OwnerID: &owner}, OccurredAt: now})
+	<-store.enqueued
+
+	if err := hooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := store.deliveries[0]; d.Status != model.DeliveryPending || d.Attempts != 0 || len(store.attempts) != 0 {
+		t.Fatalf("attempt recorded after the lease was lost: %+v, %d attempts", d, len(store.attempts))
+	}
+}
+
+// Адреса внутренней сети отклоняются и при создании вебхука, и при отправке:
+// адрес мог начать указывать внутрь уже после проверки
+func TestOutboundWebhookPrivateAddress(t *testing.T) {
+	t.Parallel()
+	hooks := service.NewOutboundWebhookService(&fakeOutbound{}, zerolog.Nop())
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	} {
+		var verr *service.ValidationError
+		if err := hooks.Create(userContext(1), &model.OutboundWebhook{URL: url}); !errors.As(err, &verr) {
+			t.Errorf("Create(%s) = %v, want ValidationError", url, err)
+		}
+	}
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { requests.Add(1) }))
+	t.Cleanup(srv.Close)
+	store := &fakeOutbound{url: srv.URL, secret: "whsec_test", enqueued: make(chan struct{}, 1)}
+	hooks = service.NewOutboundWebhookService(store, zerolog.Nop())
+	owner := 1
+	hooks.Publish(context.Background(), events.Event{Type: events.TaskCreated, TaskID: 7,
+		Task: &model.Task{ID: 7, OwnerID: &owner}, OccurredAt: time.Now()})
+	<-store.enqueued
+
+	if err := hooks.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 0 || len(store.attempts) != 1 || !strings.Contains(store.attempts[0].Error, "not public") {
+		t.Fatalf("delivered to a loopback address: %d requests, attempts %+v", requests.Load(), store.attempts)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// ErrPinsDisabled - хранилище не ведёт личные отметки на задачах
+var ErrPinsDisabled = errors.New("pinning is not enabled")
+
+// WithFlags включает личные закрепления задач
+func WithFlags(store storage.FlagStore) Option {
+	return func(s *TaskService) { s.flags = store }
+}
+
+// Pin закрепляет задачу за текущим пользователем: в его списке она идёт
+// первой, остальные её закрепления не видят
+func (s *TaskService) Pin(ctx context.Context, id int) (model.Task, error) {
+	return s.setPinned(ctx, id, true)
+}
+
+// Unpin снимает закрепление
+func (s *TaskService) Unpin(ctx context.Context, id int) (model.Task, error) {
+	return s.setPinned(ctx, id, false)
+}
+
+func (s *TaskService) setPinned(ctx context.Context, id int, pinned bool) (model.Task, error) {
+	if s.flags == nil {
+		return model.Task{}, ErrPinsDisabled
+	}
+	if err := s.flags.SetTaskPinned(ctx, id, pinned); err != nil {
+		return model.Task{}, err
+	}
+	return s.Get(ctx, id)
+}
+
+// Pinned - закреплённые пользователем задачи, последние закреплённые первыми
+func (s *TaskService) Pinned(ctx context.Context) ([]model.Task, error) {
+	if s.flags == nil {
+		return nil, ErrPinsDisabled
+	}
+	flags, err := s.flags.TaskFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pinnedAt := map[int]time.Time{}
+	ids := []int{}
+	for _, f := range flags {
+		if f.Pinned {
+			pinnedAt[f.TaskID] = f.UpdatedAt
+			ids = append(ids, f.TaskID)
+		}
+	}
+	tasks := []model.Task{}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	found, err := s.store.GetTasks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range found {
+		t.Pinned = true
+		tasks = append(tasks, t)
+	}
+	slices.SortFunc(tasks, func(a, b model.Task) int {
+		if c := pinnedAt[b.ID].Compare(pinnedAt[a.ID]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return tasks, nil
+}
+
+// PinsUpdatedAt - последнее изменение закреплений пользователя: порядок
+// списка задач зависит и от него, а не только от updated_at задач
+func (s *TaskService) PinsUpdatedAt(ctx context.Context) (time.Time, error) {
+	_, updated, err := s.pins(ctx)
+	return updated, err
+}
+
+// PinUpdatedAt - последнее изменение закрепления задачи id текущим
+// пользователем: отметка pinned есть в ответе, но не меняет updated_at задачи
+func (s *TaskService) PinUpdatedAt(ctx context.Context, id int) (time.Time, error) {
+	var updated time.Time
+	if s.flags == nil {
+		return updated, nil
+	}
+	flags, err := s.flags.TaskFlags(ctx)
+	for _, f := range flags {
+		if f.TaskID == id {
+			updated = f.UpdatedAt
+		}
+	}
+	return updated, err
+}
+
+// markPinned отмечает закреплённые задачи пользователя
+func (s *TaskService) markPinned(ctx context.Context, tasks []model.Task) error {
+	pinned, _, err := s.pins(ctx)
+	for i := range tasks {
+		tasks[i].Pinned = pinned[tasks[i].ID]
+	}
+	return err
+}
+
+// pins - id закреплённых задач и время последнего изменения отметок;
+// без WithFlags закреплённых нет
+func (s *TaskService) pins(ctx context.Context) (map[int]bool, time.Time, error) {
+	var updated time.Time
+	if s.flags == nil {
+		return nil, updated, nil
+	}
+	flags, err := s.flags.TaskFlags(ctx)
+	if err != nil {
+		return nil, updated, err
+	}
+	pinned := make(map[int]bool, len(flags))
+	for _, f := range flags {
+		pinned[f.TaskID] = f.Pinned
+		if f.UpdatedAt.After(updated) {
+			updated = f.UpdatedAt
+		}
+	}
+	return pinned, updated, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Как разложить задачи на листе для печати
+const (
+	PrintByStatus = "status"
+	PrintByDue    = "due"
+)
+
+// PrintOptions - что и как печатать. Пустой Status - все статусы, Location
+// nil - UTC: в нём считаются дни сроков.
+type PrintOptions struct {
+	GroupBy  string
+	Status   string
+	Location *time.Location
+}
+
+// PrintGroup - раздел листа: задачи одного статуса или одного дня срока
+type PrintGroup struct {
+	Title string
+	Tasks []model.Task
+}
+
+var statusTitles = map[string]string{
+	model.StatusTodo:       "To do",
+	model.StatusInProgress: "In progress",
+	model.StatusDone:       "Done",
+}
+
+// PrintGroups раскладывает задачи по разделам для печатного чек-листа:
+// по статусам в порядке работы или по дням срока - сначала просроченные,
+// затем по дням, в конце без срока. Внутри раздела - по сроку, затем по id.
+// Пустых разделов нет.
+func PrintGroups(tasks []model.Task, opts PrintOptions, now time.Time) ([]PrintGroup, error) {
+	if opts.Status != "" && !slices.Contains(model.Statuses, opts.Status) {
+		return nil, &ValidationError{Err: fmt.Errorf("unknown status %q", opts.Status)}
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	selected := make([]model.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if opts.Status == "" || t.Status == opts.Status {
+			selected = append(selected, t)
+		}
+	}
+	slices.SortStableFunc(selected, func(a, b model.Task) int {
+		if c := compareDue(a.DueAt, b.DueAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	var groups []PrintGroup
+	add := func(title string, t model.Task) {
+		if n := len(groups); n > 0 && groups[n-1].Title == title {
+			groups[n-1].Tasks = append(groups[n-1].Tasks, t)
+			return
+		}
+		groups = append(groups, PrintGroup{Title: title, Tasks: []model.Task{t}})
+	}
+	switch opts.GroupBy {
+	case PrintByStatus, "":
+		for _, status := range model.Statuses {
+			for _, t := range selected {
+				if t.Status == status {
+					add(statusTitles[status], t)
+				}
+			}
+		}
+	case PrintByDue:
+		local := now.In(loc)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		overdue := func(t model.Task) bool {
+			return t.DueAt != nil && t.Status != model.StatusDone && t.DueAt.Before(today)
+		}
+		for _, t := range selected {
+			if overdue(t) {
+				add("Overdue", t)
+			}
+		}
+		// задачи отсортированы по сроку, поэтому дни идут подряд, а задачи
+		// без срока - последними
+		for _, t := range selected {
+			switch {
+			case overdue(t):
+			case t.DueAt == nil:
+				add("No due date", t)
+			default:
+				add(t.DueAt.In(loc).Format("Monday, 2 January 2006"), t)
+			}
+		}
+	default:
+		return nil, &ValidationError{Err: errors.New("group must be status or due")}
+	}
+	return groups, nil
+}
+
+// compareDue - задачи без срока после задач со сроком
+func compareDue(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+	"golang.org/x/text/language"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/blob"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var ErrNoAvatar = errors.New("user has no avatar")
+
+// ProfileUpdate - частичное изменение профиля: nil-поля не трогаются
+type ProfileUpdate struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=64"`
+	Timezone    *string `json:"timezone" validate:"omitempty,max=64"`
+	Locale      *string `json:"locale" validate:"omitempty,max=35"`
+}
+
+type ProfileService struct {
+	users    storage.UserStore
+	blobs    blob.Store
+	validate *validator.Validate
+	log      zerolog.Logger
+}
+
+func NewProfileService(users storage.UserStore, blobs blob.Store, logger zerolog.Logger) *ProfileService {
+	return &ProfileService{users: users, blobs: blobs, validate: validator.New(), log: logger}
+}
+
+// Me возвращает профиль текущего пользователя
+func (s *ProfileService) Me(ctx context.Context) (model.User, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.User{}, err
+	}
+	return s.users.GetUser(ctx, uid)
+}
+
+func (s *ProfileService) Update(ctx context.Context, in ProfileUpdate) (model.User, error) {
+	if err := s.validate.Struct(in); err != nil {
+		return model.User{}, &ValidationError{Err: err}
+	}
+	u, err := s.Me(ctx)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if in.DisplayName != nil {
+		u.DisplayName = *in.DisplayName
+	}
+	if in.Timezone != nil {
+		// пустая строка и "Local" дают часовой пояс сервера, а не пользователя
+		if *in.Timezone == "" || *in.Timezone == "Local" {
+			return model.User{}, &ValidationError{Err: fmt.Errorf("unknown timezone %q", *in.Timezone)}
+		}
+		if _, err := time.LoadLocation(*in.Timezone); err != nil {
+			return model.User{}, &ValidationError{Err: fmt.Errorf("unknown timezone %q", *in.Timezone)}
+		}
+		u.Timezone = *in.Timezone
+	}
+	if in.Locale != nil {
+		tag, err := language.Parse(*in.Locale)
+		if err != nil {
+			return model.User{}, &ValidationError{Err: fmt.Errorf("invalid locale %q", *in.Locale)}
+		}
+		u.Locale = tag.String()
+	}
+
+	if err := s.users.UpdateProfile(ctx, &u); err != nil {
+		return model.User{}, err
+	}
+	return u, nil
+}
+
+// SetAvatar обрабатывает загруженную картинку и заменяет ею текущий аватар
+func (s *ProfileService) SetAvatar(ctx context.Context, data []byte) (model.User, error) {
+	u, err := s.Me(ctx)
+	if err != nil {
+		return model.User{}, err
+	}
+	img, err := resizeAvatar(data)
+	if err != nil {
+		return model.User{}, &ValidationError{Err: err}
+	}
+
+	// новый ключ на каждую загрузку: старый URL остаётся валидным в кешах,
+	// пока не удалён объект
+	name, err := auth.NewToken("")
+	if err != nil {
+		return model.User{}, err
+	}
+	key := fmt.Sprintf("avatars/%d/%s.png", u.ID, name[:16])
+	if err := s.blobs.Put(ctx, key, "image/png", img); err != nil {
+		return model.User{}, err
+	}
+	old, err := s.users.SetAvatarKey(ctx, u.ID, key)
+	if err != nil {
+		s.deleteBlob(ctx, key)
+		return model.User{}, err
+	}
+	s.deleteBlob(ctx, old)
+
+	u.AvatarKey = key
+	return u, nil
+}
+
+func (s *ProfileService) DeleteAvatar(ctx context.Context) error {
+	u, err := s.Me(ctx)
+	if err != nil {
+		return err
+	}
+	old, err := s.users.SetAvatarKey(ctx, u.ID, "")
+	if err != nil {
+		return err
+	}
+	s.deleteBlob(ctx, old)
+	return nil
+}
+
+// Avatar возвращает картинку аватара пользователя userID
+func (s *ProfileService) Avatar(ctx context.Context, userID int) ([]byte, string, error) {
+	u, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.AvatarKey == "" {
+		return nil, "", ErrNoAvatar
+	}
+	data, contentType, err := s.blobs.Get(ctx, u.AvatarKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, "", ErrNoAvatar
+	}
+	return data, contentType, err
+}
+
+// deleteBlob удаляет объект без возврата ошибки: осиротевший файл
+// безвреден, а откатывать уже сохранённый профиль из-за него не стоит
+func (s *ProfileService) deleteBlob(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := s.blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+		s.log.Warn().Err(err).Str("key", key).Msg("delete avatar blob")
+	}
+}
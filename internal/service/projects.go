@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// ProjectUpdate - частичное изменение проекта
+type ProjectUpdate struct {
+	Name       *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Visibility *string `json:"visibility" validate:"omitempty,oneof=workspace private"`
+}
+
+// manageProject возвращает проект, если текущий пользователь - его администратор
+func (s *WorkspaceService) manageProject(ctx context.Context, projectID int) (model.Project, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return model.Project{}, err
+	}
+	if p.Role != model.ProjectAdmin {
+		return model.Project{}, ErrForbidden
+	}
+	return p, nil
+}
+
+func (s *WorkspaceService) Project(ctx context.Context, projectID int) (model.Project, error) {
+	if _, err := currentUserID(ctx); err != nil {
+		return model.Project{}, err
+	}
+	return s.store.GetProject(ctx, projectID)
+}
+
+// UpdateProject меняет имя или видимость. Перевод в private оставляет
+// доступ только явным участникам проекта.
+func (s *WorkspaceService) UpdateProject(ctx context.Context, projectID int, in ProjectUpdate) (model.Project, error) {
+	if err := s.validate.Struct(in); err != nil {
+		return model.Project{}, &ValidationError{Err: err}
+	}
+	p, err := s.manageProject(ctx, projectID)
+	if err != nil {
+		return model.Project{}, err
+	}
+	if in.Name != nil {
+		p.Name = *in.Name
+	}
+	if in.Visibility != nil {
+		if *in.Visibility == model.VisibilityPrivate && p.Visibility != model.VisibilityPrivate {
+			// закрепляем текущего администратора, чтобы он не потерял проект
+			uid, _ := currentUserID(ctx)
+			if err := s.store.SetProjectMember(ctx, p.ID, uid, model.ProjectAdmin); err != nil {
+				return model.Project{}, err
+			}
+		}
+		p.Visibility = *in.Visibility
+	}
+	if err := s.store.UpdateProject(ctx, &p); err != nil {
+		return model.Project{}, err
+	}
+	return p, nil
+}
+
+// ProjectTasks - задачи проекта, доступного текущему пользователю
+func (s *WorkspaceService) ProjectTasks(ctx context.Context, projectID int) (model.Project, []model.Task, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return model.Project{}, nil, err
+	}
+	tasks, err := s.store.ListProjectTasks(ctx, projectID)
+	return p, tasks, err
+}
+
+func (s *WorkspaceService) ProjectMembers(ctx context.Context, projectID int) ([]model.Member, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.store.ListProjectMembers(ctx, projectID)
+}
+
+// SetProjectMember задаёт роль участника пространства в проекте
+func (s *WorkspaceService) SetProjectMember(ctx context.Context, projectID, userID int, role string) error {
+	switch role {
+	case model.ProjectViewer, model.ProjectEditor, model.ProjectAdmin:
+	default:
+		return &ValidationError{Err: fmt.Errorf("role must be %q, %q or %q",
+			model.ProjectViewer, model.ProjectEditor, model.ProjectAdmin)}
+	}
+	p, err := s.manageProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.store.GetWorkspace(ctx, p.WorkspaceID, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotWorkspaceMember
+		}
+		return err
+	}
+	return s.store.SetProjectMember(ctx, projectID, userID, role)
+}
+
+func (s *WorkspaceService) RemoveProjectMember(ctx context.Context, projectID, userID int) error {
+	if _, err := s.manageProject(ctx, projectID); err != nil {
+		return err
+	}
+	return s.store.RemoveProjectMember(ctx, projectID, userID)
+}
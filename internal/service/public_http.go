@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress - адрес, который задал пользователь, ведёт во внутреннюю
+// сеть сервера: loopback, частные диапазоны, link-local (в том числе
+// метаданные облака 169.254.169.254)
+var errPrivateAddress = errors.New("destination address is not public")
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// dialPublicOnly проверяет уже разрешённый адрес перед подключением, поэтому
+// проверку не обойти ни DNS-ребиндингом, ни редиректом на внутренний адрес
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(ap.Addr()) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, ap.Addr())
+	}
+	return nil
+}
+
+// publicHTTPClient - клиент для адресов, которые задают пользователи: вебхуков
+// и серверов Jira. Прокси из окружения не используется, иначе проверялся бы
+// адрес прокси, а не назначения.
+func publicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// checkPublicURL проверяет адрес при сохранении, чтобы пользователь сразу
+// увидел ошибку; при отправке его всё равно проверяет dialPublicOnly
+func checkPublicURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+		return errors.New("must be an http or https URL")
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%s resolves to a non-public address", u.Hostname())
+		}
+	}
+	return nil
+}
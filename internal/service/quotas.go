@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// QuotaActiveTasks - квота на незавершённые задачи пользователя
+const QuotaActiveTasks = "active_tasks"
+
+// QuotaError - создание превысило бы квоту
+type QuotaError struct {
+	Quota string
+	Limit int
+	Used  int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota %s exceeded: %d of %d used", e.Quota, e.Used, e.Limit)
+}
+
+// WithQuotas ограничивает число незавершённых задач пользователя; 0 - без
+// ограничения. Фоновые задачи (System) квоте не подчиняются.
+func WithQuotas(store storage.QuotaStore, maxActiveTasks int) Option {
+	return func(s *TaskService) { s.quotas, s.maxActiveTasks = store, maxActiveTasks }
+}
+
+// checkQuota проверяет квоту перед созданием задачи. Одновременные запросы
+// могут превысить её на несколько задач: счёт и вставка не в одной транзакции.
+func (s *TaskService) checkQuota(ctx context.Context, task *model.Task) error {
+	if s.quotas == nil || s.maxActiveTasks <= 0 || task.Status == model.StatusDone {
+		return nil
+	}
+	if p, ok := auth.FromContext(ctx); ok && p.IsSystem() {
+		return nil
+	}
+	n, err := s.quotas.CountActiveTasks(ctx)
+	if err != nil {
+		return err
+	}
+	if n >= s.maxActiveTasks {
+		return &QuotaError{Quota: QuotaActiveTasks, Limit: s.maxActiveTasks, Used: n}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// maxUsageDays - за сколько суток можно запросить отчёт об использовании
+const maxUsageDays = 90
+
+// RateLimitService - управление тарифами ограничения частоты и отчёт;
+// права admin проверяет HTTP-слой, как и у остальных /admin
+type RateLimitService struct {
+	store    storage.RateLimitStore
+	now      func() time.Time
+	validate *validator.Validate
+}
+
+func NewRateLimitService(store storage.RateLimitStore) *RateLimitService {
+	return &RateLimitService{store: store, now: time.Now, validate: validator.New()}
+}
+
+func (s *RateLimitService) Tiers(ctx context.Context) ([]model.RateLimitTier, error) {
+	return s.store.ListRateLimitTiers(ctx)
+}
+
+func (s *RateLimitService) SaveTier(ctx context.Context, tier model.RateLimitTier) error {
+	if err := s.validate.Struct(tier); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return s.store.SaveRateLimitTier(ctx, tier)
+}
+
+func (s *RateLimitService) SetUserTier(ctx context.Context, userID int, tier string) error {
+	if tier == "" {
+		return &ValidationError{Err: errors.New("tier is required")}
+	}
+	return s.store.SetUserRateLimitTier(ctx, userID, tier)
+}
+
+// SetAPIKeyTier; пустой tier возвращает ключ к тарифу владельца
+func (s *RateLimitService) SetAPIKeyTier(ctx context.Context, keyID int, tier string) error {
+	return s.store.SetAPIKeyRateLimitTier(ctx, keyID, tier)
+}
+
+// Usage - счётчики запросов за последние days суток, включая текущие
+func (s *RateLimitService) Usage(ctx context.Context, days int) ([]model.RateLimitUsage, error) {
+	if days == 0 {
+		days = 7
+	}
+	if days < 1 || days > maxUsageDays {
+		return nil, &ValidationError{Err: errors.New("days must be between 1 and 90")}
+	}
+	today := s.now().UTC().Truncate(24 * time.Hour)
+	return s.store.RateLimitUsage(ctx, today.AddDate(0, 0, 1-days))
+}
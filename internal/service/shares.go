@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var ErrInvalidShareLink = errors.New("share link is invalid, expired or revoked")
+
+type ShareRequest struct {
+	TaskID    *int `json:"task_id"`
+	ProjectID *int `json:"project_id"`
+	// ExpiresInHours - 0 означает бессрочную ссылку
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+// ShareService выпускает публичные ссылки и открывает их без аутентификации.
+// Содержимое читается с правами автора ссылки: если он потерял доступ к
+// задаче или проекту, ссылка перестаёт работать.
+type ShareService struct {
+	shares    storage.ShareStore
+	tasks     storage.TaskStore
+	projects  storage.WorkspaceStore
+	publicURL string
+	now       func() time.Time
+}
+
+func NewShareService(shares storage.ShareStore, tasks storage.TaskStore, projects storage.WorkspaceStore, publicURL string) *ShareService {
+	return &ShareService{
+		shares:    shares,
+		tasks:     tasks,
+		projects:  projects,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		now:       time.Now,
+	}
+}
+
+func (s *ShareService) Create(ctx context.Context, req ShareRequest) (model.ShareLink, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.ShareLink{}, err
+	}
+	if (req.TaskID == nil) == (req.ProjectID == nil) {
+		return model.ShareLink{}, &ValidationError{Err: errors.New("exactly one of task_id and project_id is required")}
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > 8760 {
+		return model.ShareLink{}, &ValidationError{Err: errors.New("expires_in_hours must be between 0 and 8760")}
+	}
+
+	// делиться можно только тем, что видишь сам
+	if req.TaskID != nil {
+		if _, err := s.tasks.GetTask(ctx, *req.TaskID); err != nil {
+			return model.ShareLink{}, err
+		}
+	} else if _, err := s.projects.GetProject(ctx, *req.ProjectID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return model.ShareLink{}, storage.ErrInvalidProject
+		}
+		return model.ShareLink{}, err
+	}
+
+	token, err := auth.NewToken("shr_")
+	if err != nil {
+		return model.ShareLink{}, err
+	}
+	link := model.ShareLink{UserID: uid, TaskID: req.TaskID, ProjectID: req.ProjectID}
+	if req.ExpiresInHours > 0 {
+		exp := s.now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		link.ExpiresAt = &exp
+	}
+	if err := s.shares.CreateShareLink(ctx, &link, auth.HashToken(token)); err != nil {
+		return model.ShareLink{}, err
+	}
+	link.URL = s.publicURL + "/public/" + url.PathEscape(token)
+	return link, nil
+}
+
+func (s *ShareService) List(ctx context.Context) ([]model.ShareLink, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.shares.ListShareLinks(ctx, uid)
+}
+
+func (s *ShareService) Revoke(ctx context.Context, id int) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return s.shares.RevokeShareLink(ctx, uid, id)
+}
+
+// Open возвращает содержимое ссылки; запрос аутентификации не требует
+func (s *ShareService) Open(ctx context.Context, token string) (model.SharedView, error) {
+	link, err := s.shares.ShareLinkByToken(ctx, auth.HashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.SharedView{}, ErrInvalidShareLink
+	}
+	if err != nil {
+		return model.SharedView{}, err
+	}
+
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserID: link.UserID, Role: model.RoleUser, Scheme: auth.SchemeShare})
+	view := model.SharedView{ExpiresAt: link.ExpiresAt}
+	if link.TaskID != nil {
+		task, err := s.tasks.GetTask(ctx, *link.TaskID)
+		if err != nil {
+			return model.SharedView{}, shareError(err)
+		}
+		pt := model.NewPublicTask(task)
+		view.Title, view.Task = task.Title, &pt
+		return view, nil
+	}
+
+	project, err := s.projects.GetProject(ctx, *link.ProjectID)
+	if err != nil {
+		return model.SharedView{}, shareError(err)
+	}
+	tasks, err := s.projects.ListProjectTasks(ctx, project.ID)
+	if err != nil {
+		return model.SharedView{}, err
+	}
+	view.Title = project.Name
+	view.Tasks = make([]model.PublicTask, 0, len(tasks))
+	for _, t := range tasks {
+		view.Tasks = append(view.Tasks, model.NewPublicTask(t))
+	}
+	return view, nil
+}
+
+// shareError: пропавшая или недоступная автору цель - та же недействительная ссылка
+func shareError(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrInvalidShareLink
+	}
+	return err
+}
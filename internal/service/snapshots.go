@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var (
+	ErrSnapshotsDisabled = errors.New("workspace snapshots are not enabled")
+	// ErrPointInTimeDisabled - восстановление на момент времени требует журнала
+	// событий (STORAGE_MODE=events)
+	ErrPointInTimeDisabled = errors.New("point-in-time restore requires the event-sourced storage mode")
+)
+
+// WithSnapshots включает снимки пространств; восстановление на момент
+// времени доступно, если store ещё и PointInTimeStore
+func WithSnapshots(store storage.SnapshotStore) WorkspaceOption {
+	return func(s *WorkspaceService) { s.snapshots = store }
+}
+
+// RestoreRequest - к чему вернуть пространство: к снимку или к моменту времени
+type RestoreRequest struct {
+	SnapshotID *int       `json:"snapshot_id"`
+	At         *time.Time `json:"at"`
+}
+
+// CreateSnapshot сохраняет текущие проекты и задачи пространства; только admin
+func (s *WorkspaceService) CreateSnapshot(ctx context.Context, workspaceID int, label string) (model.Snapshot, error) {
+	if s.snapshots == nil {
+		return model.Snapshot{}, ErrSnapshotsDisabled
+	}
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return model.Snapshot{}, err
+	}
+	snap := model.Snapshot{WorkspaceID: workspaceID, Label: label}
+	if err := s.validate.Struct(snap); err != nil {
+		return model.Snapshot{}, &ValidationError{Err: err}
+	}
+	return snap, s.snapshot(ctx, &snap)
+}
+
+func (s *WorkspaceService) snapshot(ctx context.Context, snap *model.Snapshot) error {
+	state, err := s.snapshots.WorkspaceState(ctx, snap.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	uid, _ := currentUserID(ctx)
+	snap.CreatedBy, snap.State = &uid, &state
+	return s.snapshots.CreateSnapshot(ctx, snap)
+}
+
+func (s *WorkspaceService) Snapshots(ctx context.Context, workspaceID int) ([]model.Snapshot, error) {
+	if s.snapshots == nil {
+		return nil, ErrSnapshotsDisabled
+	}
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return nil, err
+	}
+	return s.snapshots.ListSnapshots(ctx, workspaceID)
+}
+
+// Restore возвращает проекты и задачи пространства к снимку или к моменту
+// времени. Перед этим делается снимок текущего состояния, так что
+// восстановление можно отменить, восстановив BackupID.
+func (s *WorkspaceService) Restore(ctx context.Context, workspaceID int, req RestoreRequest) (model.RestoreResult, error) {
+	if s.snapshots == nil {
+		return model.RestoreResult{}, ErrSnapshotsDisabled
+	}
+	if (req.SnapshotID == nil) == (req.At == nil) {
+		return model.RestoreResult{}, &ValidationError{Err: errors.New("exactly one of snapshot_id and at is required")}
+	}
+	if req.At != nil && req.At.After(s.now()) {
+		return model.RestoreResult{}, &ValidationError{Err: errors.New("at must be in the past")}
+	}
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceAdmin); err != nil {
+		return model.RestoreResult{}, err
+	}
+
+	var (
+		state model.WorkspaceState
+		label string
+	)
+	if req.SnapshotID != nil {
+		snap, err := s.snapshots.GetSnapshot(ctx, workspaceID, *req.SnapshotID)
+		if err != nil {
+			return model.RestoreResult{}, err
+		}
+		state, label = *snap.State, "Before restore to snapshot "+snap.CreatedAt.UTC().Format(time.RFC3339)
+	} else {
+		pit, ok := s.snapshots.(storage.PointInTimeStore)
+		if !ok {
+			return model.RestoreResult{}, ErrPointInTimeDisabled
+		}
+		var err error
+		if state, err = pit.WorkspaceStateAt(ctx, workspaceID, *req.At); err != nil {
+			return model.RestoreResult{}, err
+		}
+		label = "Before restore to " + req.At.UTC().Format(time.RFC3339)
+	}
+
+	backup := model.Snapshot{WorkspaceID: workspaceID, Label: label}
+	if err := s.snapshot(ctx, &backup); err != nil {
+		return model.RestoreResult{}, err
+	}
+	res, err := s.snapshots.RestoreWorkspace(ctx, workspaceID, state)
+	res.BackupID = backup.ID
+	if err == nil {
+		s.log.Info().Int("workspace_id", workspaceID).Int("backup_id", backup.ID).Int("restored", res.Restored).
+			Int("deleted", res.Deleted).Int("skipped", res.Skipped).Msg("Workspace restored")
+	}
+	return res, err
+}
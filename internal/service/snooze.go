@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// ErrSnoozeDisabled - хранилище не умеет откладывать задачи
+var ErrSnoozeDisabled = errors.New("snooze is not enabled")
+
+// maxSnooze - дальше года задачу не откладывают: это уже другой срок
+const maxSnooze = 366 * 24 * time.Hour
+
+// WithSnooze включает отсрочку задач
+func WithSnooze(store storage.SnoozeStore) Option {
+	return func(s *TaskService) { s.snooze = store }
+}
+
+// SnoozeRequest - до какого момента отложить: until или на for, например "2h30m"
+type SnoozeRequest struct {
+	Until *time.Time `json:"until"`
+	For   string     `json:"for"`
+}
+
+// Snooze откладывает незавершённую задачу: до истечения отсрочки её нет в
+// списке по умолчанию, в виджете и в напоминаниях
+func (s *TaskService) Snooze(ctx context.Context, id int, req SnoozeRequest) (model.Task, error) {
+	if s.snooze == nil {
+		return model.Task{}, ErrSnoozeDisabled
+	}
+	if (req.Until == nil) == (req.For == "") {
+		return model.Task{}, &ValidationError{Err: errors.New("exactly one of until and for is required")}
+	}
+	now := s.now()
+	until := req.Until
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil {
+			return model.Task{}, &ValidationError{Err: errors.New(`invalid for, expected a duration like "2h30m"`)}
+		}
+		t := now.Add(d)
+		until = &t
+	}
+	if !until.After(now) {
+		return model.Task{}, &ValidationError{Err: errors.New("snooze must end in the future")}
+	}
+	if until.Sub(now) > maxSnooze {
+		return model.Task{}, &ValidationError{Err: errors.New("snooze must end within a year")}
+	}
+
+	task, err := s.store.GetTask(ctx, id)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if task.Status == model.StatusDone {
+		return model.Task{}, &ValidationError{Err: errors.New("completed tasks cannot be snoozed")}
+	}
+	utc := until.UTC()
+	return s.setSnooze(ctx, id, &utc)
+}
+
+// Unsnooze возвращает задачу сразу, не дожидаясь конца отсрочки
+func (s *TaskService) Unsnooze(ctx context.Context, id int) (model.Task, error) {
+	if s.snooze == nil {
+		return model.Task{}, ErrSnoozeDisabled
+	}
+	return s.setSnooze(ctx, id, nil)
+}
+
+func (s *TaskService) setSnooze(ctx context.Context, id int, until *time.Time) (model.Task, error) {
+	task, err := s.snooze.SnoozeTask(ctx, id, until)
+	if err != nil {
+		return model.Task{}, err
+	}
+	s.publish(ctx, events.TaskUpdated, task.ID, &task)
+	if pinned, _, err := s.pins(ctx); err == nil {
+		task.Pinned = pinned[task.ID]
+	}
+	return task, nil
+}
+
+// Awake убирает из tasks отложенные сейчас задачи - для списков по умолчанию
+func (s *TaskService) Awake(tasks []model.Task) []model.Task {
+	now := s.now()
+	var awake []model.Task
+	for _, t := range tasks {
+		if !t.Snoozed(now) {
+			awake = append(awake, t)
+		}
+	}
+	return awake
+}
+
+// WakeSnoozed снимает истёкшие отсрочки и сообщает подписчикам о каждой
+// вернувшейся задаче
+func (s *TaskService) WakeSnoozed(ctx context.Context) (int, error) {
+	if s.snooze == nil {
+		return 0, ErrSnoozeDisabled
+	}
+	woken, err := s.snooze.WakeTasks(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+	for i := range woken {
+		s.publish(ctx, events.TaskUpdated, woken[i].ID, &woken[i])
+	}
+	return len(woken), nil
+}
+
+// SnoozeWaker раз в период возвращает задачи с истёкшей отсрочкой
+type SnoozeWaker struct {
+	tasks *TaskService
+	log   zerolog.Logger
+}
+
+func NewSnoozeWaker(tasks *TaskService, logger zerolog.Logger) *SnoozeWaker {
+	return &SnoozeWaker{tasks: tasks, log: logger}
+}
+
+func (w *SnoozeWaker) Run(ctx context.Context, every time.Duration) {
+	ctx = auth.WithPrincipal(ctx, auth.System)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n, err := w.tasks.WakeSnoozed(ctx)
+			if err != nil {
+				w.log.Error().Err(err).Msg("Failed to wake snoozed tasks")
+			} else if n > 0 {
+				w.log.Info().Int("tasks", n).Msg("Woke snoozed tasks")
+			}
+		}
+	}
+}
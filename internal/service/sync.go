@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// ErrSyncDisabled - хранилище не отдаёт изменения для синхронизации
+var ErrSyncDisabled = errors.New("delta sync is not enabled")
+
+// maxSyncChanges - сколько локальных изменений можно прислать в одном Sync
+const maxSyncChanges = 500
+
+// WithSync включает дельта-синхронизацию для офлайн-клиентов
+func WithSync(store storage.SyncStore) Option {
+	return func(s *TaskService) { s.sync = store }
+}
+
+// SyncResult - итог одного изменения клиента. Err - почему оно не применено;
+// остальные изменения от этого не откатываются. Conflicts - поля, которые
+// при слиянии с Base изменились и у клиента, и на сервере.
+type SyncResult struct {
+	UID       string
+	Created   bool
+	Conflicts []model.TaskConflict
+	Err       error
+}
+
+// Sync применяет изменения клиента по порядку, а затем отдаёт всё, что
+// изменилось после token, вместе с только что применённым: так клиент узнаёт
+// id и поля, которые выставил сервер. Изменения по uid идемпотентны, поэтому
+// после ошибки, в том числе ErrInvalidSyncToken, запрос можно повторить.
+func (s *TaskService) Sync(ctx context.Context, token string, changes []model.SyncChange) (model.TaskChanges, []SyncResult, error) {
+	if s.sync == nil {
+		return model.TaskChanges{}, nil, ErrSyncDisabled
+	}
+	if len(changes) > maxSyncChanges {
+		return model.TaskChanges{}, nil, &ValidationError{Err: fmt.Errorf("at most %d changes per sync", maxSyncChanges)}
+	}
+
+	results := make([]SyncResult, len(changes))
+	for i, change := range changes {
+		results[i] = SyncResult{UID: change.UID}
+		results[i].Created, results[i].Conflicts, results[i].Err = s.applyChange(ctx, change)
+	}
+	got, err := s.sync.ChangesSince(ctx, token)
+	if err != nil {
+		return model.TaskChanges{}, nil, err
+	}
+	return got, results, nil
+}
+
+// applyChange: upsert неизвестного uid создаёт задачу, известного - заменяет
+// её как Update или, с Base, сливает с ней по полям; delete уже удалённой
+// или невидимой задачи ничего не делает
+func (s *TaskService) applyChange(ctx context.Context, change model.SyncChange) (created bool, conflicts []model.TaskConflict, err error) {
+	if err := s.validate.Struct(change); err != nil {
+		return false, nil, &ValidationError{Err: err}
+	}
+	uid := uuid.MustParse(change.UID).String()
+	id, err := s.store.TaskIDByUID(ctx, uid)
+	notFound := errors.Is(err, storage.ErrNotFound)
+	if err != nil && !notFound {
+		return false, nil, err
+	}
+
+	if change.Op == model.SyncDelete {
+		if notFound {
+			return false, nil, nil
+		}
+		return false, nil, s.Delete(ctx, id)
+	}
+	task := *change.Task
+	task.ID, task.UID = id, uid
+	switch {
+	case notFound:
+		// TaskIDByUID не видит чужих задач, и uid может оказаться занят: тогда
+		// вставка не пройдёт, а изменение получит ErrTaskUIDTaken - конфликт,
+		// а не сбой базы
+		err := s.Create(ctx, &task)
+		return err == nil, nil, err
+	case change.Base == nil:
+		return false, nil, s.Update(ctx, &task)
+	}
+
+	current, err := s.store.GetTask(ctx, id)
+	if err != nil {
+		return false, nil, err
+	}
+	// часы клиента могут спешить: правка из будущего выигрывала бы всегда
+	changedAt := s.now()
+	if change.ChangedAt != nil && change.ChangedAt.Before(changedAt) {
+		changedAt = *change.ChangedAt
+	}
+	merged, conflicts := merge(*change.Base, task, current, !changedAt.Before(current.UpdatedAt))
+	if err := s.Update(ctx, &merged); err != nil {
+		return false, nil, err
+	}
+	if len(conflicts) > 0 {
+		if err := s.sync.AddConflicts(ctx, conflicts); err != nil {
+			return false, nil, err
+		}
+	}
+	return false, conflicts, nil
+}
@@ -0,0 +1,518 @@
+// Package service - бизнес-правила задач, общие для всех транспортов
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/search"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var (
+	ErrOpenSubtasks  = errors.New("task has open subtasks")
+	ErrInvalidParent = errors.New("invalid parent task")
+	// ErrHistoryDisabled - хранилище не ведёт журнал (STORAGE_MODE не events)
+	ErrHistoryDisabled = errors.New("task history is not enabled")
+)
+
+// ValidationError - входные данные не прошли проверку
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+type TaskService struct {
+	store   storage.TaskStore
+	history storage.TaskHistory
+	reads   storage.ReadModelStore
+	quotas  storage.QuotaStore
+	// duplicates - поиск дублей для Create с RejectDuplicates
+	duplicates storage.DuplicateStore
+	sync       storage.SyncStore
+	locations  storage.LocationStore
+	snooze     storage.SnoozeStore
+	flags      storage.FlagStore
+	delegation *delegations
+	// engine - внешний поисковый движок для Search
+	engine search.Engine
+	// fuzzy - поиск с опечатками, если точный нашёл меньше limit
+	fuzzy          storage.FuzzySearchStore
+	fuzzyThreshold float64
+	events         events.Publisher
+	now            func() time.Time
+	validate       *validator.Validate
+
+	maxActiveTasks      int
+	autoCompleteParents bool
+}
+
+type Option func(*TaskService)
+
+func WithPublisher(p events.Publisher) Option {
+	return func(s *TaskService) { s.events = p }
+}
+
+// WithHistory включает историю задач и запросы на прошлый момент
+func WithHistory(h storage.TaskHistory) Option {
+	return func(s *TaskService) { s.history = h }
+}
+
+// WithReadModels переводит статистику и поиск на модели чтения; без них
+// они считаются по полному списку задач
+func WithReadModels(r storage.ReadModelStore) Option {
+	return func(s *TaskService) { s.reads = r }
+}
+
+// WithSearchEngine переводит поиск на внешний движок; индекс наполняет
+// search.Indexer, подписанный на события задач
+func WithSearchEngine(e search.Engine) Option {
+	return func(s *TaskService) { s.engine = e }
+}
+
+// WithFuzzySearch дополняет выдачу Search задачами, похожими на запрос не
+// меньше чем на threshold, - на случай опечаток без внешнего движка
+func WithFuzzySearch(store storage.FuzzySearchStore, threshold float64) Option {
+	return func(s *TaskService) { s.fuzzy, s.fuzzyThreshold = store, threshold }
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(s *TaskService) { s.now = now }
+}
+
+func NewTaskService(store storage.TaskStore, opts ...Option) *TaskService {
+	s := &TaskService{
+		store:    store,
+		events:   events.Nop{},
+		now:      time.Now,
+		validate: validator.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// KeepCompletedAt - не ставить завершённой задаче время завершения "сейчас",
+// если оно задано: для импорта из других сервисов
+func KeepCompletedAt() CreateOption {
+	return func(o *createOptions) { o.keepCompletedAt = true }
+}
+
+func (s *TaskService) Create(ctx context.Context, task *model.Task, opts ...CreateOption) error {
+	var o createOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := s.validate.Struct(task); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := s.checkParent(ctx, task); err != nil {
+		return err
+	}
+	if err := s.checkQuota(ctx, task); err != nil {
+		return err
+	}
+	if o.rejectDuplicates {
+		if err := s.checkDuplicates(ctx, task); err != nil {
+			return err
+		}
+	}
+
+	if !o.keepCompletedAt || task.Status != model.StatusDone {
+		task.CompletedAt = nil
+	}
+	// отложить и закрепить можно только уже созданную задачу
+	task.SnoozedUntil, task.Pinned = nil, false
+	if task.Status == model.StatusDone && task.CompletedAt == nil {
+		now := s.now()
+		task.CompletedAt = &now
+	}
+	// UUIDv7 растёт со временем, поэтому индекс по uid не фрагментируется
+	if task.UID == "" {
+		uid, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		task.UID = uid.String()
+	}
+
+	if err := s.store.CreateTask(ctx, task); err != nil {
+		return err
+	}
+	s.publish(ctx, events.TaskCreated, task.ID, task)
+	return nil
+}
+
+// List - видимые задачи; закреплённые пользователем идут первыми
+func (s *TaskService) List(ctx context.Context) ([]model.Task, error) {
+	tasks, err := s.store.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.markPinned(ctx, tasks); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(tasks, func(a, b model.Task) int {
+		switch {
+		case a.Pinned == b.Pinned:
+			return 0
+		case a.Pinned:
+			return -1
+		}
+		return 1
+	})
+	return tasks, nil
+}
+
+func (s *TaskService) Get(ctx context.Context, id int) (model.Task, error) {
+	task, err := s.store.GetTask(ctx, id)
+	if err != nil {
+		return model.Task{}, err
+	}
+	pinned, _, err := s.pins(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	task.Pinned = pinned[task.ID]
+	return task, nil
+}
+
+// ResolveID переводит ссылку на задачу из адреса в числовой id: ссылкой
+// может быть сам id или uid задачи
+func (s *TaskService) ResolveID(ctx context.Context, ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+	uid, err := uuid.Parse(ref)
+	if err != nil {
+		return 0, &ValidationError{Err: errors.New("invalid task id, expected a number or a UUID")}
+	}
+	return s.store.TaskIDByUID(ctx, uid.String())
+}
+
+// maxBatchIDs - сколько задач можно запросить в GetMany за раз
+const maxBatchIDs = 100
+
+// GetMany - задачи ids одним запросом в порядке ids, повторы схлопываются.
+// missing - id, которых нет или которые не видны пользователю.
+func (s *TaskService) GetMany(ctx context.Context, ids []int) (tasks []model.Task, missing []int, err error) {
+	if len(ids) == 0 {
+		return nil, nil, &ValidationError{Err: errors.New("ids are required")}
+	}
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > maxBatchIDs {
+		return nil, nil, &ValidationError{Err: fmt.Errorf("at most %d ids per request", maxBatchIDs)}
+	}
+
+	found, err := s.store.GetTasks(ctx, unique)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.markPinned(ctx, found); err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[int]model.Task, len(found))
+	for _, t := range found {
+		byID[t.ID] = t
+	}
+	tasks, missing = []model.Task{}, []int{}
+	for _, id := range unique {
+		if t, ok := byID[id]; ok {
+			tasks = append(tasks, t)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return tasks, missing, nil
+}
+
+// Stats - число видимых задач по статусам
+func (s *TaskService) Stats(ctx context.Context) (model.TaskStats, error) {
+	if s.reads != nil {
+		return s.reads.TaskStats(ctx)
+	}
+	tasks, err := s.store.ListTasks(ctx)
+	if err != nil {
+		return model.TaskStats{}, err
+	}
+	var stats model.TaskStats
+	for _, t := range tasks {
+		stats.Add(t.Status, 1)
+		if stats.UpdatedAt == nil || t.UpdatedAt.After(*stats.UpdatedAt) {
+			updated := t.UpdatedAt
+			stats.UpdatedAt = &updated
+		}
+	}
+	return stats, nil
+}
+
+// Count - число видимых задач в статусе status или всех, если он пуст;
+// берётся из тех же счётчиков, что и Stats, без чтения самих задач
+func (s *TaskService) Count(ctx context.Context, status string) (int, error) {
+	if status != "" && !slices.Contains(model.Statuses, status) {
+		return 0, &ValidationError{Err: fmt.Errorf("unknown status %q", status)}
+	}
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Of(status), nil
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Search ищет по заголовку, описанию и имени проекта, а если нашлось меньше
+// limit - ещё и с опечатками (WithFuzzySearch); с внешним движком - по
+// заголовку и описанию его средствами
+func (s *TaskService) Search(ctx context.Context, query string, limit int) ([]model.Task, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, &ValidationError{Err: errors.New("search query is required")}
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	if s.engine != nil {
+		return s.searchEngine(ctx, query, limit)
+	}
+	found, err := s.searchExact(ctx, query, limit)
+	if err != nil || s.fuzzy == nil || len(found) == limit {
+		return found, err
+	}
+	similar, err := s.fuzzy.FuzzySearchTasks(ctx, query, s.fuzzyThreshold, limit)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(found))
+	for _, t := range found {
+		seen[t.ID] = true
+	}
+	for _, t := range similar {
+		if !seen[t.ID] && len(found) < limit {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// searchExact - полнотекстовый поиск по моделям чтения или подстрока без них
+func (s *TaskService) searchExact(ctx context.Context, query string, limit int) ([]model.Task, error) {
+	if s.reads != nil {
+		return s.reads.SearchTasks(ctx, query, limit)
+	}
+	tasks, err := s.store.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := []model.Task{}
+	q := strings.ToLower(query)
+	for _, t := range tasks {
+		if len(found) == limit {
+			break
+		}
+		if strings.Contains(strings.ToLower(t.Title), q) || strings.Contains(strings.ToLower(t.Description), q) {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// searchEngine берёт кандидатов из движка с запасом на задачи проектов,
+// закрытых для пользователя, и оставляет видимые в порядке релевантности
+func (s *TaskService) searchEngine(ctx context.Context, query string, limit int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.engine.Search(ctx, search.Query{Text: query, OwnerID: owner, Limit: 2 * limit})
+	if err != nil {
+		return nil, err
+	}
+	found := []model.Task{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+	visible, err := s.store.GetTasks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]model.Task, len(visible))
+	for _, t := range visible {
+		byID[t.ID] = t
+	}
+	for _, id := range ids {
+		if t, ok := byID[id]; ok && len(found) < limit {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// HistoryEnabled - ведётся ли журнал изменений задач
+func (s *TaskService) HistoryEnabled() bool { return s.history != nil }
+
+// History - все изменения задачи по порядку, включая удаление
+func (s *TaskService) History(ctx context.Context, id int) ([]model.TaskEvent, error) {
+	if s.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	return s.history.TaskEvents(ctx, id)
+}
+
+// GetAt - задача в том виде, в каком она была в момент at
+func (s *TaskService) GetAt(ctx context.Context, id int, at time.Time) (model.Task, error) {
+	if s.history == nil {
+		return model.Task{}, ErrHistoryDisabled
+	}
+	return s.history.TaskAt(ctx, id, at)
+}
+
+// Update заменяет задачу целиком; переход в done фиксирует completed_at,
+// возврат из done его сбрасывает.
+func (s *TaskService) Update(ctx context.Context, task *model.Task) error {
+	if err := s.validate.Struct(task); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := s.checkParent(ctx, task); err != nil {
+		return err
+	}
+
+	old, err := s.store.GetTask(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	// uid не меняется, progress зависит от подзадач, а не от тела запроса,
+	// отсрочку меняет только Snooze
+	task.UID, task.Progress, task.SnoozedUntil = old.UID, old.Progress, old.SnoozedUntil
+	completed := task.Status == model.StatusDone && old.Status != model.StatusDone
+	if completed {
+		if err := s.checkReview(ctx, task.ID); err != nil {
+			return err
+		}
+	}
+	switch {
+	case completed:
+		now := s.now()
+		task.CompletedAt = &now
+	case task.Status == model.StatusDone:
+		task.CompletedAt = old.CompletedAt
+	default:
+		task.CompletedAt = nil
+	}
+
+	if err := s.store.UpdateTask(ctx, task); err != nil {
+		return err
+	}
+	s.publish(ctx, events.TaskUpdated, task.ID, task)
+	if completed {
+		s.publish(ctx, events.TaskCompleted, task.ID, task)
+		if s.autoCompleteParents && task.ParentID != nil {
+			s.completeParent(ctx, *task.ParentID)
+		}
+	}
+	// закрепление личное, подписчикам событий его не отдаём; правка уже
+	// сохранена, поэтому ошибка здесь её не отменяет
+	if pinned, _, err := s.pins(ctx); err == nil {
+		task.Pinned = pinned[task.ID]
+	}
+	return nil
+}
+
+// WithAutoCompleteParents завершает родителя, когда завершена последняя
+// его подзадача; дальше по цепочке - так же
+func WithAutoCompleteParents() Option {
+	return func(s *TaskService) { s.autoCompleteParents = true }
+}
+
+// completeParent переводит родителя в done, если все подзадачи завершены.
+// Подзадача уже сохранена, поэтому ошибка здесь её правку не отменяет:
+// например, родитель в проекте, где у пользователя только просмотр.
+// Счётчики подзадач ведёт база под блокировкой строки родителя, так что из
+// одновременно завершённых последних подзадач родителя увидит готовым хотя
+// бы одна; повтор завершения Update не считает новым переходом.
+func (s *TaskService) completeParent(ctx context.Context, id int) {
+	parent, err := s.store.GetTask(ctx, id)
+	if err != nil || parent.Status == model.StatusDone || parent.Progress == nil || *parent.Progress < 100 {
+		return
+	}
+	parent.Status = model.StatusDone
+	_ = s.Update(ctx, &parent)
+}
+
+// Delete удаляет задачу вместе с завершёнными подзадачами;
+// задачу с незавершёнными подзадачами удалить нельзя.
+func (s *TaskService) Delete(ctx context.Context, id int) error {
+	open, err := s.store.CountOpenSubtasks(ctx, id)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return ErrOpenSubtasks
+	}
+	// последнее состояние - подписчикам, чтобы понять, чья и откуда задача;
+	// о доступности решает DeleteTask
+	var last *model.Task
+	if task, err := s.store.GetTask(ctx, id); err == nil {
+		last = &task
+	}
+
+	if err := s.store.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+	s.publish(ctx, events.TaskDeleted, id, last)
+	return nil
+}
+
+func (s *TaskService) checkParent(ctx context.Context, task *model.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	if *task.ParentID == task.ID {
+		return ErrInvalidParent
+	}
+	if _, err := s.store.GetTask(ctx, *task.ParentID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrInvalidParent
+		}
+		return err
+	}
+	return nil
+}
+
+// publish отправляет копию задачи, чтобы подписчики не видели дальнейших изменений
+func (s *TaskService) publish(ctx context.Context, typ string, id int, task *model.Task) {
+	e := events.Event{Type: typ, TaskID: id, OccurredAt: s.now()}
+	if task != nil {
+		t := *task
+		e.Task = &t
+	}
+	s.events.Publish(ctx, e)
+}
@@ -0,0 +1,793 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/events"
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/search"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+type recorder struct {
+	events []events.Event
+}
+
+func (r *recorder) Publish(_ context.Context, e events.Event) { r.events = append(r.events, e) }
+
+func (r *recorder) types() []string {
+	var types []string
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func userContext(userID int) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{UserID: userID, Scheme: auth.SchemeJWT})
+}
+
+func newService(now time.Time) (*service.TaskService, *recorder) {
+	rec := &recorder{}
+	svc := service.NewTaskService(storage.NewMemory(),
+		service.WithPublisher(rec),
+		service.WithClock(func() time.Time { return now }))
+	return svc, rec
+}
+
+func TestCompletionSetsCompletedAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	svc, rec := newService(now)
+	ctx := userContext(1)
+
+	task := &model.Task{Title: "Write report", Status: model.StatusTodo}
+	if err := svc.Create(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt != nil {
+		t.Fatalf("completed_at = %v, want nil", task.CompletedAt)
+	}
+
+	task.Status = model.StatusDone
+	if err := svc.Update(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(now) {
+		t.Fatalf("completed_at = %v, want %v", task.CompletedAt, now)
+	}
+
+	// Повторное сохранение в done не сдвигает дату завершения
+	task.Title = "Write the report"
+	if err := svc.Update(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(now) {
+		t.Fatalf("completed_at after re-save = %v, want %v", task.CompletedAt, now)
+	}
+
+	task.Status = model.StatusInProgress
+	if err := svc.Update(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt != nil {
+		t.Fatalf("completed_at after reopen = %v, want nil", task.CompletedAt)
+	}
+
+	want := []string{events.TaskCreated, events.TaskUpdated, events.TaskCompleted, events.TaskUpdated, events.TaskUpdated}
+	if got := rec.types(); !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteWithOpenSubtasks(t *testing.T) {
+	svc, rec := newService(time.Now())
+	ctx := userContext(1)
+
+	parent := &model.Task{Title: "Move house", Status: model.StatusTodo}
+	if err := svc.Create(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+	child := &model.Task{Title: "Pack books", Status: model.StatusTodo, ParentID: &parent.ID}
+	if err := svc.Create(ctx, child); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Delete(ctx, parent.ID); !errors.Is(err, service.ErrOpenSubtasks) {
+		t.Fatalf("err = %v, want ErrOpenSubtasks", err)
+	}
+
+	child.Status = model.StatusDone
+	if err := svc.Update(ctx, child); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Delete(ctx, parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Get(ctx, child.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("child err = %v, want ErrNotFound", err)
+	}
+	if last := rec.events[len(rec.events)-1]; last.Type != events.TaskDeleted || last.TaskID != parent.ID {
+		t.Fatalf("last event = %+v, want task.deleted for %d", last, parent.ID)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	svc, rec := newService(time.Now())
+	ctx := userContext(1)
+
+	var invalid *service.ValidationError
+	if err := svc.Create(ctx, &model.Task{Title: "x", Status: model.StatusTodo}); !errors.As(err, &invalid) {
+		t.Fatalf("err = %v, want ValidationError", err)
+	}
+	missing := 99
+	if err := svc.Create(ctx, &model.Task{Title: "Orphan", Status: model.StatusTodo, ParentID: &missing}); !errors.Is(err, service.ErrInvalidParent) {
+		t.Fatalf("err = %v, want ErrInvalidParent", err)
+	}
+	if len(rec.events) != 0 {
+		t.Fatalf("events = %v, want none", rec.types())
+	}
+}
+
+func TestWidgetOrdersOpenTasks(t *testing.T) {
+	svc, _ := newService(time.Now())
+	ctx := userContext(1)
+	for _, task := range []*model.Task{
+		{Title: "Old todo", Status: model.StatusTodo},
+		{Title: "Finished", Status: model.StatusDone},
+		{Title: "Started", Status: model.StatusInProgress},
+		{Title: "New todo", Status: model.StatusTodo},
+	} {
+		if err := svc.Create(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := svc.Widget(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, task := range w.Tasks {
+		titles = append(titles, task.Title)
+	}
+	if want := []string{"Started", "Old todo"}; !slices.Equal(titles, want) || w.OpenCount != 3 {
+		t.Errorf("widget = %v (open %d), want %v (open 3)", titles, w.OpenCount, want)
+	}
+}
+
+func TestActivityInterleavesCreatedAndCompleted(t *testing.T) {
+	base := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	done := base.Add(3 * time.Hour)
+	items := service.Activity([]model.Task{
+		{ID: 1, CreatedAt: base, CompletedAt: &done},
+		{ID: 2, CreatedAt: base.Add(time.Hour)},
+	})
+
+	var got []string
+	for _, it := range items {
+		got = append(got, fmt.Sprintf("%d:%s", it.Task.ID, it.Kind))
+	}
+	if want := []string{"1:completed", "2:created", "1:created"}; !slices.Equal(got, want) {
+		t.Errorf("activity = %v, want %v", got, want)
+	}
+}
+
+func TestPrintGroups(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, msk)
+	at := func(day, hour int) *time.Time {
+		d := time.Date(2026, 3, day, hour, 0, 0, 0, msk)
+		return &d
+	}
+	tasks := []model.Task{
+		{ID: 1, Status: model.StatusTodo, DueAt: at(11, 9)},
+		{ID: 2, Status: model.StatusDone, DueAt: at(8, 9)},
+		{ID: 3, Status: model.StatusTodo},
+		{ID: 4, Status: model.StatusInProgress, DueAt: at(9, 18)},
+		{ID: 5, Status: model.StatusTodo, DueAt: at(10, 1)},
+		{ID: 6, Status: model.StatusTodo, DueAt: at(8, 9)},
+	}
+	layout := func(groups []service.PrintGroup) []string {
+		var got []string
+		for _, g := range groups {
+			line := g.Title + ":"
+			for _, t := range g.Tasks {
+				line += fmt.Sprintf(" %d", t.ID)
+			}
+			got = append(got, line)
+		}
+		return got
+	}
+
+	groups, err := service.PrintGroups(tasks, service.PrintOptions{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"To do: 6 5 1 3", "In progress: 4", "Done: 2"}; !slices.Equal(layout(groups), want) {
+		t.Errorf("by status = %v, want %v", layout(groups), want)
+	}
+
+	// дни - по часовому поясу листа: срок 01:00 MSK 10 марта в Москве сегодня,
+	// а в UTC это ещё 9 марта, и задача просрочена
+	groups, err = service.PrintGroups(tasks, service.PrintOptions{GroupBy: service.PrintByDue, Location: msk}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Overdue: 6 4", "Sunday, 8 March 2026: 2", "Tuesday, 10 March 2026: 5",
+		"Wednesday, 11 March 2026: 1", "No due date: 3"}
+	if !slices.Equal(layout(groups), want) {
+		t.Errorf("by due = %v, want %v", layout(groups), want)
+	}
+
+	groups, err = service.PrintGroups(tasks, service.PrintOptions{GroupBy: service.PrintByDue, Status: model.StatusTodo}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Overdue: 6 5", "Wednesday, 11 March 2026: 1", "No due date: 3"}; !slices.Equal(layout(groups), want) {
+		t.Errorf("todo by due in UTC = %v, want %v", layout(groups), want)
+	}
+
+	var invalid *service.ValidationError
+	if _, err := service.PrintGroups(tasks, service.PrintOptions{GroupBy: "owner"}, now); !errors.As(err, &invalid) {
+		t.Errorf("group=owner: err = %v, want ValidationError", err)
+	}
+	if _, err := service.PrintGroups(tasks, service.PrintOptions{Status: "later"}, now); !errors.As(err, &invalid) {
+		t.Errorf("status=later: err = %v, want ValidationError", err)
+	}
+}
+
+func TestStatsAndSearchWithoutReadModels(t *testing.T) {
+	svc, _ := newService(time.Now())
+	ctx := userContext(1)
+	for _, task := range []*model.Task{
+		{Title: "Buy milk", Status: model.StatusTodo},
+		{Title: "Call plumber", Description: "Kitchen sink", Status: model.StatusInProgress},
+		{Title: "Pay rent", Status: model.StatusDone},
+	} {
+		if err := svc.Create(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.Create(userContext(2), &model.Task{Title: "Other sink", Status: model.StatusTodo}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := svc.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Todo != 1 || stats.InProgress != 1 || stats.Done != 1 || stats.Total != 3 {
+		t.Errorf("stats = %+v, want 1/1/1 of 3", stats)
+	}
+
+	found, err := svc.Search(ctx, "SINK", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Title != "Call plumber" {
+		t.Errorf("search = %v, want only the own task", found)
+	}
+
+	var invalid *service.ValidationError
+	if _, err := svc.Search(ctx, "  ", 0); !errors.As(err, &invalid) {
+		t.Errorf("empty query: err = %v, want ValidationError", err)
+	}
+}
+
+func TestCreateEnforcesActiveTaskQuota(t *testing.T) {
+	mem := storage.NewMemory()
+	svc := service.NewTaskService(mem, service.WithQuotas(mem, 2))
+	ctx := userContext(1)
+
+	for _, title := range []string{"First task", "Second task"} {
+		if err := svc.Create(ctx, &model.Task{Title: title, Status: model.StatusTodo}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var quota *service.QuotaError
+	err := svc.Create(ctx, &model.Task{Title: "Third task", Status: model.StatusInProgress})
+	if !errors.As(err, &quota) || quota.Quota != service.QuotaActiveTasks || quota.Used != 2 || quota.Limit != 2 {
+		t.Fatalf("err = %v, want active_tasks quota 2 of 2", err)
+	}
+
+	// завершённые задачи квоту не занимают, у другого пользователя она своя
+	if err := svc.Create(ctx, &model.Task{Title: "Already done", Status: model.StatusDone}); err != nil {
+		t.Errorf("done task: %v", err)
+	}
+	if err := svc.Create(userContext(2), &model.Task{Title: "Other user", Status: model.StatusTodo}); err != nil {
+		t.Errorf("other user: %v", err)
+	}
+}
+
+func TestCreateRejectsDuplicates(t *testing.T) {
+	mem := storage.NewMemory()
+	svc := service.NewTaskService(mem, service.WithDuplicateCheck(mem))
+	ctx := userContext(1)
+
+	milk := &model.Task{Title: "Buy milk", Status: model.StatusTodo}
+	if err := svc.Create(ctx, milk); err != nil {
+		t.Fatal(err)
+	}
+	var dup *service.DuplicateError
+	err := svc.Create(ctx, &model.Task{Title: "  buy MILK!", Status: model.StatusTodo}, service.RejectDuplicates())
+	if !errors.As(err, &dup) || len(dup.Candidates) != 1 || dup.Candidates[0].ID != milk.ID {
+		t.Fatalf("err = %v, want duplicate of task %d", err, milk.ID)
+	}
+
+	// без RejectDuplicates, у другого пользователя и после завершения - можно
+	if err := svc.Create(ctx, &model.Task{Title: "Buy milk", Status: model.StatusTodo}); err != nil {
+		t.Errorf("without check: %v", err)
+	}
+	if err := svc.Create(userContext(2), &model.Task{Title: "Buy milk", Status: model.StatusTodo}, service.RejectDuplicates()); err != nil {
+		t.Errorf("other user: %v", err)
+	}
+	if err := svc.Create(ctx, &model.Task{Title: "Buy bread", Status: model.StatusTodo}, service.RejectDuplicates()); err != nil {
+		t.Errorf("different title: %v", err)
+	}
+}
+
+func TestParentProgressAndAutoComplete(t *testing.T) {
+	rec := &recorder{}
+	svc := service.NewTaskService(storage.NewMemory(), service.WithPublisher(rec), service.WithAutoCompleteParents())
+	ctx := userContext(1)
+
+	parent := &model.Task{Title: "Move house", Status: model.StatusInProgress}
+	if err := svc.Create(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+	var children []*model.Task
+	for _, title := range []string{"Pack boxes", "Hire a van", "Clean up"} {
+		child := &model.Task{Title: title, Status: model.StatusTodo, ParentID: &parent.ID}
+		if err := svc.Create(ctx, child); err != nil {
+			t.Fatal(err)
+		}
+		children = append(children, child)
+	}
+
+	progress := func() *int {
+		t.Helper()
+		got, err := svc.Get(ctx, parent.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status == model.StatusDone && got.CompletedAt == nil {
+			t.Fatal("parent done without completed_at")
+		}
+		parent = &got
+		return got.Progress
+	}
+	if p := progress(); p == nil || *p != 0 {
+		t.Fatalf("progress = %v, want 0", p)
+	}
+	for i, want := range []int{33, 66} {
+		children[i].Status = model.StatusDone
+		if err := svc.Update(ctx, children[i]); err != nil {
+			t.Fatal(err)
+		}
+		if p := progress(); p == nil || *p != want || parent.Status != model.StatusInProgress {
+			t.Fatalf("after %d done: progress = %v, status %s; want %d, in_progress", i+1, p, parent.Status, want)
+		}
+	}
+
+	children[2].Status = model.StatusDone
+	if err := svc.Update(ctx, children[2]); err != nil {
+		t.Fatal(err)
+	}
+	if p := progress(); p == nil || *p != 100 || parent.Status != model.StatusDone {
+		t.Fatalf("progress = %v, status %s; want 100, done", p, parent.Status)
+	}
+	if n := len(slices.DeleteFunc(rec.types(), func(typ string) bool { return typ != events.TaskCompleted })); n != 4 {
+		t.Errorf("task.completed events = %d, want 4 (three subtasks and the parent)", n)
+	}
+	if leaf, _ := svc.Get(ctx, children[0].ID); leaf.Progress != nil {
+		t.Errorf("subtask without children has progress %d", *leaf.Progress)
+	}
+}
+
+func TestSyncDeltas(t *testing.T) {
+	mem := storage.NewMemory()
+	svc := service.NewTaskService(mem, service.WithSync(mem))
+	phone, laptop := userContext(1), userContext(1)
+
+	// первый телефон создаёт задачу офлайн, второй клиент получает всё
+	const uid = "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b"
+	first, results, err := svc.Sync(phone, "", []model.SyncChange{
+		{Op: model.SyncUpsert, UID: uid, Task: &model.Task{Title: "Buy milk", Status: model.StatusTodo}},
+	})
+	if err != nil || len(results) != 1 || !results[0].Created || results[0].Err != nil {
+		t.Fatalf("Sync = %+v, %v", results, err)
+	}
+	if len(first.Tasks) != 1 || first.Tasks[0].UID != uid || first.Tasks[0].ID == 0 {
+		t.Fatalf("tasks = %+v, want the created task with an id", first.Tasks)
+	}
+	other := &model.Task{Title: "Call mom", Status: model.StatusTodo}
+	if err := svc.Create(laptop, other); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Create(userContext(2), &model.Task{Title: "Not mine", Status: model.StatusTodo}); err != nil {
+		t.Fatal(err)
+	}
+
+	// с токеном - только изменённое после него и надгробия
+	if err := svc.Delete(laptop, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	next, results, err := svc.Sync(phone, first.Token, []model.SyncChange{
+		{Op: model.SyncUpsert, UID: uid, Task: &model.Task{Title: "Buy oat milk", Status: model.StatusDone}},
+		{Op: model.SyncDelete, UID: "01928a6b-0000-7000-8000-000000000000"},
+		{Op: model.SyncUpsert, UID: "not-a-uuid", Task: &model.Task{Title: "Broken", Status: model.StatusTodo}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var invalid *service.ValidationError
+	if results[0].Err != nil || results[0].Created || results[1].Err != nil || !errors.As(results[2].Err, &invalid) {
+		t.Fatalf("results = %+v", results)
+	}
+	if len(next.Tasks) != 1 || next.Tasks[0].Title != "Buy oat milk" || next.Tasks[0].CompletedAt == nil {
+		t.Fatalf("tasks = %+v, want only the edited task", next.Tasks)
+	}
+	if len(next.Deleted) != 1 || next.Deleted[0].ID != other.ID || next.Deleted[0].UID != other.UID {
+		t.Fatalf("deleted = %+v, want task %d", next.Deleted, other.ID)
+	}
+
+	// без изменений - пусто и тот же токен
+	idle, _, err := svc.Sync(phone, next.Token, nil)
+	if err != nil || len(idle.Tasks) != 0 || len(idle.Deleted) != 0 || idle.Token != next.Token {
+		t.Fatalf("idle sync = %+v, %v", idle, err)
+	}
+	if _, _, err := svc.Sync(phone, "garbage", nil); !errors.Is(err, storage.ErrInvalidSyncToken) {
+		t.Fatalf("err = %v, want ErrInvalidSyncToken", err)
+	}
+	if _, _, err := service.NewTaskService(mem).Sync(phone, "", nil); !errors.Is(err, service.ErrSyncDisabled) {
+		t.Fatalf("err = %v, want ErrSyncDisabled", err)
+	}
+}
+
+func TestSyncMergesConcurrentEdits(t *testing.T) {
+	mem := storage.NewMemory()
+	svc := service.NewTaskService(mem, service.WithSync(mem))
+	ctx := userContext(1)
+
+	task := &model.Task{Title: "Plan trip", Description: "Book hotel", Status: model.StatusTodo}
+	if err := svc.Create(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	base := *task
+	server := *task
+	server.Description, server.Status = "Book hostel", model.StatusInProgress
+	if err := svc.Update(ctx, &server); err != nil {
+		t.Fatal(err)
+	}
+
+	// офлайн-правка позже серверной: поля клиента и сервера сливаются, в
+	// описании побеждает клиент, а серверная версия остаётся в конфликте
+	local := base
+	local.Title, local.Description = "Plan summer trip", "Book hotel and car"
+	_, results, err := svc.Sync(ctx, "", []model.SyncChange{{Op: model.SyncUpsert, UID: task.UID, Task: &local, Base: &base}})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("Sync: %v, %v", err, results[0].Err)
+	}
+	got, err := svc.Get(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Plan summer trip" || got.Description != "Book hotel and car" || got.Status != model.StatusInProgress {
+		t.Fatalf("merged = %q, %q, %s", got.Title, got.Description, got.Status)
+	}
+	if c := results[0].Conflicts; len(c) != 1 || c[0].Field != "description" ||
+		string(c[0].Kept) != `"Book hotel and car"` || string(c[0].Discarded) != `"Book hostel"` {
+		t.Fatalf("conflicts = %+v", c)
+	}
+
+	// правка, сделанная раньше серверной, проигрывает
+	stale := base
+	stale.Description = "Ask a friend"
+	hourAgo := time.Now().Add(-time.Hour)
+	_, results, err = svc.Sync(ctx, "", []model.SyncChange{
+		{Op: model.SyncUpsert, UID: task.UID, Task: &stale, Base: &base, ChangedAt: &hourAgo}})
+	if err != nil || results[0].Err != nil || len(results[0].Conflicts) != 1 {
+		t.Fatalf("Sync: %v, %+v", err, results)
+	}
+	if got, _ := svc.Get(ctx, task.ID); got.Description != "Book hotel and car" {
+		t.Fatalf("description = %q, want the newer edit kept", got.Description)
+	}
+
+	conflicts, err := svc.Conflicts(ctx)
+	if err != nil || len(conflicts) != 2 || string(conflicts[0].Discarded) != `"Ask a friend"` {
+		t.Fatalf("Conflicts = %+v, %v", conflicts, err)
+	}
+	if err := svc.ResolveConflict(userContext(2), conflicts[0].ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("other user: err = %v, want ErrNotFound", err)
+	}
+	if err := svc.ResolveConflict(ctx, conflicts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts, _ := svc.Conflicts(ctx); len(conflicts) != 1 {
+		t.Fatalf("after resolve: %d conflicts, want 1", len(conflicts))
+	}
+}
+
+// fakeEngine отдаёт заранее заданную выдачу и запоминает запрос
+type fakeEngine struct {
+	ids   []int
+	query search.Query
+}
+
+func (f *fakeEngine) Index(context.Context, ...search.Document) error { return nil }
+func (f *fakeEngine) Delete(context.Context, int) error               { return nil }
+func (f *fakeEngine) Search(_ context.Context, q search.Query) ([]int, error) {
+	f.query = q
+	return f.ids, nil
+}
+
+func TestSearchEngine(t *testing.T) {
+	mem := storage.NewMemory()
+	engine := &fakeEngine{}
+	svc := service.NewTaskService(mem, service.WithSearchEngine(engine))
+	ctx := userContext(1)
+
+	var mine []int
+	for _, title := range []string{"Buy milk", "Buy bread"} {
+		task := &model.Task{Title: title, Status: model.StatusTodo}
+		if err := svc.Create(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+		mine = append(mine, task.ID)
+	}
+	foreign := &model.Task{Title: "Buy milk too", Status: model.StatusTodo}
+	if err := svc.Create(userContext(2), foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	// порядок движка сохраняется, чужое и несуществующее отбрасывается
+	engine.ids = []int{mine[1], foreign.ID, 999, mine[0]}
+	found, err := svc.Search(ctx, "buy mlik", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, task := range found {
+		got = append(got, task.ID)
+	}
+	if want := []int{mine[1], mine[0]}; !slices.Equal(got, want) {
+		t.Fatalf("Search = %v, want %v", got, want)
+	}
+	if engine.query.Text != "buy mlik" || engine.query.OwnerID == nil || *engine.query.OwnerID != 1 || engine.query.Limit != 20 {
+		t.Errorf("engine query = %+v", engine.query)
+	}
+}
+
+func TestSearchFuzzyFallback(t *testing.T) {
+	mem := storage.NewMemory()
+	svc := service.NewTaskService(mem, service.WithFuzzySearch(mem, 0.4))
+	ctx := userContext(1)
+	for _, title := range []string{"Buy groceries", "Grocery list for the party", "Call mom"} {
+		if err := svc.Create(ctx, &model.Task{Title: title, Status: model.StatusTodo}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.Create(userContext(2), &model.Task{Title: "Buy groceries", Status: model.StatusTodo}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := svc.Search(ctx, "grocerys", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, task := range found {
+		titles = append(titles, task.Title)
+	}
+	if want := []string{"Grocery list for the party", "Buy groceries"}; !slices.Equal(titles, want) {
+		t.Fatalf("Search = %q, want %q", titles, want)
+	}
+
+	// точное совпадение идёт первым, похожие - после
+	found, err = svc.Search(ctx, "groceries", 10)
+	if err != nil || len(found) != 2 || found[0].Title != "Buy groceries" {
+		t.Fatalf("Search = %+v, %v; want the exact match first", found, err)
+	}
+}
+
+// fakeUsers - пользователь id с адресом user<id>@example.com
+type fakeUsers struct {
+	storage.UserStore
+}
+
+func (fakeUsers) GetUser(_ context.Context, id int) (model.User, error) {
+	return model.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id)}, nil
+}
+
+// chanMailer отдаёт письма в канал: поручения шлют их в фоне
+type chanMailer chan mail.Message
+
+func (m chanMailer) Send(_ context.Context, msg mail.Message) error {
+	m <- msg
+	return nil
+}
+
+func (m chanMailer) next(t *testing.T) mail.Message {
+	t.Helper()
+	select {
+	case msg := <-m:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no notification sent")
+		return mail.Message{}
+	}
+}
+
+func TestDelegationReview(t *testing.T) {
+	store := storage.NewMemory()
+	mails := make(chanMailer, 10)
+	svc := service.NewTaskService(store,
+		service.WithDelegations(store, fakeUsers{}, mails, "https://todo.example.com/", zerolog.Nop()))
+	delegator, delegate := userContext(1), userContext(2)
+	// в памяти задачу видит только владелец, так что за «чужого» её
+	// пытается завершить системный контекст
+	system := auth.WithPrincipal(context.Background(), auth.System)
+
+	project := 1
+	task := &model.Task{Title: "Quarterly report", Status: model.StatusTodo, ProjectID: &project}
+	if err := svc.Create(delegator, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Delegate(delegator, task.ID, service.DelegateRequest{UserID: 1}); err == nil {
+		t.Error("delegating to yourself succeeded")
+	}
+	d, err := svc.Delegate(delegator, task.ID, service.DelegateRequest{UserID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != model.DelegationActive || d.TaskTitle != task.Title {
+		t.Fatalf("delegation = %+v", d)
+	}
+	if msg := mails.next(t); msg.To != "user2@example.com" ||
+		!strings.Contains(msg.Body, fmt.Sprintf("https://todo.example.com/tasks/%d", task.ID)) {
+		t.Errorf("delegate notified with %+v", msg)
+	}
+
+	done := *task
+	done.Status = model.StatusDone
+	if err := svc.Update(system, &done); !errors.Is(err, service.ErrReviewRequired) {
+		t.Fatalf("complete before approval: err = %v, want ErrReviewRequired", err)
+	}
+	if _, err := svc.Approve(delegator, task.ID); !errors.Is(err, service.ErrDelegationState) {
+		t.Errorf("approve before submit: err = %v, want ErrDelegationState", err)
+	}
+	if _, err := svc.SubmitForReview(delegator, task.ID); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("delegator submits: err = %v, want ErrForbidden", err)
+	}
+
+	if _, err := svc.SubmitForReview(delegate, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if msg := mails.next(t); msg.To != "user1@example.com" {
+		t.Errorf("review request sent to %s, want the delegator", msg.To)
+	}
+	if _, err := svc.Approve(delegate, task.ID); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("delegate approves: err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Reject(delegator, task.ID, " "); err == nil {
+		t.Error("reject without comment succeeded")
+	}
+	// комментарий попадает в письмо как есть, а не как часть формата
+	d, err = svc.Reject(delegator, task.ID, "Add the March numbers, 100% done otherwise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != model.DelegationRejected || d.Comment != "Add the March numbers, 100% done otherwise" {
+		t.Errorf("rejected delegation = %+v", d)
+	}
+	if msg := mails.next(t); msg.To != "user2@example.com" ||
+		!strings.Contains(msg.Body, "\n\nAdd the March numbers, 100% done otherwise\n\n") {
+		t.Errorf("rejection notice = %+v", msg)
+	}
+
+	if _, err := svc.SubmitForReview(delegate, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	mails.next(t)
+	if d, err = svc.Approve(delegator, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if d.State != model.DelegationApproved {
+		t.Errorf("state = %s, want approved", d.State)
+	}
+	if got, _ := svc.Get(delegator, task.ID); got.Status != model.StatusDone {
+		t.Errorf("status after approval = %s, want done", got.Status)
+	}
+	if msg := mails.next(t); msg.To != "user2@example.com" || !strings.HasPrefix(msg.Subject, "Approved") {
+		t.Errorf("approval notice = %+v", msg)
+	}
+
+	list, err := svc.Delegations(delegate)
+	if err != nil || len(list) != 1 || list[0].TaskID != task.ID {
+		t.Errorf("delegate's delegations = %+v, %v", list, err)
+	}
+}
+
+func TestDelegatorCompletesDirectly(t *testing.T) {
+	store := storage.NewMemory()
+	svc := service.NewTaskService(store, service.WithDelegations(store, fakeUsers{}, nil, "", zerolog.Nop()))
+	ctx := userContext(1)
+
+	project := 1
+	task := &model.Task{Title: "Book venue", Status: model.StatusTodo, ProjectID: &project}
+	if err := svc.Create(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Delegate(ctx, task.ID, service.DelegateRequest{UserID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	task.Status = model.StatusDone
+	if err := svc.Update(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := svc.Delegation(ctx, task.ID); d.State != model.DelegationApproved {
+		t.Errorf("state after delegator completes = %s, want approved", d.State)
+	}
+}
+
+// исполнитель не может передать поручение себе и сам принять работу
+func TestOnlyDelegatorRedelegates(t *testing.T) {
+	store := storage.NewMemory()
+	svc := service.NewTaskService(store, service.WithDelegations(store, fakeUsers{}, nil, "", zerolog.Nop()))
+	delegator, delegate := userContext(1), userContext(2)
+
+	project := 1
+	task := &model.Task{Title: "Quarterly report", Status: model.StatusTodo, ProjectID: &project}
+	if err := svc.Create(delegator, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Delegate(delegator, task.ID, service.DelegateRequest{UserID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Delegate(delegate, task.ID, service.DelegateRequest{UserID: 3}); !errors.Is(err, service.ErrForbidden) {
+		t.Fatalf("delegate re-delegates: err = %v, want ErrForbidden", err)
+	}
+	if d, _ := svc.Delegation(delegator, task.ID); d.DelegatorID != 1 || d.DelegateID != 2 {
+		t.Fatalf("delegation = %+v, want it unchanged", d)
+	}
+
+	// поручивший передаёт задачу другому, и прежний исполнитель ей больше не распоряжается
+	d, err := svc.Delegate(delegator, task.ID, service.DelegateRequest{UserID: 3})
+	if err != nil || d.DelegatorID != 1 || d.DelegateID != 3 || d.State != model.DelegationActive {
+		t.Fatalf("re-delegate = %+v, %v", d, err)
+	}
+	if _, err := svc.Delegate(delegate, task.ID, service.DelegateRequest{UserID: 4}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("former delegate re-delegates: err = %v, want ErrNotFound", err)
+	}
+}
+
+// заголовок задачи с переводом строки не дописывает заголовков в письмо
+func TestDelegationMailSubjectIsOneLine(t *testing.T) {
+	store := storage.NewMemory()
+	mails := make(chanMailer, 1)
+	svc := service.NewTaskService(store, service.WithDelegations(store, fakeUsers{}, mails, "", zerolog.Nop()))
+	delegator := userContext(1)
+
+	project := 1
+	task := &model.Task{Title: "Report\r\nBcc: attacker@example.com", Status: model.StatusTodo, ProjectID: &project}
+	if err := svc.Create(delegator, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Delegate(delegator, task.ID, service.DelegateRequest{UserID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := mails.next(t); msg.Subject != "Task delegated to you: Report Bcc: attacker@example.com" {
+		t.Errorf("subject = %q, want the title on one line", msg.Subject)
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrInvalidCode        = errors.New("invalid verification code")
+	ErrTooManyAttempts    = errors.New("too many failed verification attempts")
+)
+
+const (
+	totpIssuer        = "todo-app"
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+	// После maxCodeFailures неверных кодов проверка блокируется на codeLockout,
+	// иначе шесть цифр перебираются за время жизни challenge-токена
+	maxCodeFailures = 5
+	codeLockout     = 15 * time.Minute
+)
+
+// TOTPEnrollment - данные для добавления аккаунта в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"provisioning_uri"`
+}
+
+// codeLimiter считает неверные коды второго фактора по пользователям
+type codeLimiter struct {
+	mu       sync.Mutex
+	failures map[int][]time.Time
+}
+
+func (l *codeLimiter) blocked(userID int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.failures[userID][:0]
+	for _, t := range l.failures[userID] {
+		if now.Sub(t) < codeLockout {
+			recent = append(recent, t)
+		}
+	}
+	l.failures[userID] = recent
+	return len(recent) >= maxCodeFailures
+}
+
+func (l *codeLimiter) fail(userID int, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failures[userID] = append(l.failures[userID], now)
+}
+
+func (l *codeLimiter) reset(userID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, userID)
+}
+
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID int) (TOTPEnrollment, error) {
+	u, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if u.TOTPEnabled {
+		return TOTPEnrollment{}, ErrTOTPAlreadyEnabled
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if err := s.users.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return TOTPEnrollment{}, err
+	}
+	return TOTPEnrollment{Secret: secret, URI: auth.TOTPURI(totpIssuer, u.Email, secret)}, nil
+}
+
+// EnableTOTP подтверждает привязку кодом из приложения и возвращает
+// одноразовые коды восстановления - показать их можно только сейчас
+func (s *AuthService) EnableTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	u, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err := s.verifyTOTP(ctx, userID, u.TOTPSecret, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = auth.NewRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = auth.HashToken(codes[i])
+	}
+	if err := s.users.EnableTOTP(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP требует действующий код или код восстановления
+func (s *AuthService) DisableTOTP(ctx context.Context, userID int, code string) error {
+	if err := s.verifySecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.users.DisableTOTP(ctx, userID)
+}
+
+// ResetTOTP - для администратора: снять 2FA с пользователя, потерявшего устройство
+func (s *AuthService) ResetTOTP(ctx context.Context, userID int) error {
+	if err := s.users.DisableTOTP(ctx, userID); err != nil {
+		return err
+	}
+	s.codes.reset(userID)
+	return nil
+}
+
+// CompleteLogin - второй шаг входа: challenge-токен из Login и код
+func (s *AuthService) CompleteLogin(ctx context.Context, challenge, code, userAgent string) (Login, error) {
+	userID, err := s.jwt.ParseChallenge(challenge)
+	if err != nil {
+		return Login{}, ErrInvalidCredentials
+	}
+	if err := s.verifySecondFactor(ctx, userID, code); err != nil {
+		return Login{}, err
+	}
+	u, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return Login{}, err
+	}
+	return s.issue(ctx, u, userAgent)
+}
+
+// verifySecondFactor принимает код TOTP или неиспользованный код восстановления
+func (s *AuthService) verifySecondFactor(ctx context.Context, userID int, code string) error {
+	u, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	if len(code) == 6 {
+		return s.verifyTOTP(ctx, userID, u.TOTPSecret, code)
+	}
+
+	if s.codes.blocked(userID, s.now()) {
+		return ErrTooManyAttempts
+	}
+	ok, err := s.users.UseRecoveryCode(ctx, userID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.codes.fail(userID, s.now())
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *AuthService) verifyTOTP(ctx context.Context, userID int, secret, code string) error {
+	now := s.now()
+	if s.codes.blocked(userID, now) {
+		return ErrTooManyAttempts
+	}
+	step, ok := auth.VerifyTOTP(secret, code, now)
+	if !ok {
+		s.codes.fail(userID, now)
+		return ErrInvalidCode
+	}
+	fresh, err := s.users.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+	s.codes.reset(userID)
+	return nil
+}
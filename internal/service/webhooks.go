@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var ErrInvalidWebhook = errors.New("webhook not found")
+
+// Лимиты полей задачи; длинный payload обрезается, а не отклоняется
+const (
+	maxTitleLen       = 100
+	maxDescriptionLen = 500
+)
+
+// WebhookService - входящие вебхуки: чужой JSON по шаблону становится задачей
+// владельца вебхука
+type WebhookService struct {
+	hooks     storage.WebhookStore
+	tasks     *TaskService
+	projects  storage.WorkspaceStore
+	validate  *validator.Validate
+	publicURL string
+}
+
+func NewWebhookService(hooks storage.WebhookStore, tasks *TaskService, projects storage.WorkspaceStore, publicURL string) *WebhookService {
+	return &WebhookService{
+		hooks:     hooks,
+		tasks:     tasks,
+		projects:  projects,
+		validate:  validator.New(),
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}
+}
+
+func (s *WebhookService) Create(ctx context.Context, hook *model.InboundWebhook) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.validate.Struct(hook); err != nil {
+		return &ValidationError{Err: err}
+	}
+	for _, tmpl := range []string{hook.TitleTemplate, hook.DescriptionTemplate} {
+		if err := checkTemplate(tmpl); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	if hook.ProjectID != nil {
+		p, err := s.projects.GetProject(ctx, *hook.ProjectID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.ErrInvalidProject
+		}
+		if err != nil {
+			return err
+		}
+		if p.Role == model.ProjectViewer {
+			return storage.ErrReadOnly
+		}
+	}
+
+	token, err := auth.NewToken("whk_")
+	if err != nil {
+		return err
+	}
+	hook.UserID = uid
+	if err := s.hooks.CreateInboundWebhook(ctx, hook, auth.HashToken(token)); err != nil {
+		return err
+	}
+	hook.URL = s.publicURL + "/hooks/" + url.PathEscape(token)
+	return nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]model.InboundWebhook, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.hooks.ListInboundWebhooks(ctx, uid)
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id int) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return s.hooks.DeleteInboundWebhook(ctx, uid, id)
+}
+
+// Receive создаёт задачу из payload; запрос аутентифицирует только токен в URL
+func (s *WebhookService) Receive(ctx context.Context, token string, payload []byte) (model.Task, error) {
+	hook, err := s.hooks.InboundWebhookByToken(ctx, auth.HashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.Task{}, ErrInvalidWebhook
+	}
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return model.Task{}, &ValidationError{Err: errors.New("payload must be valid JSON")}
+	}
+
+	task := model.Task{
+		ProjectID:   hook.ProjectID,
+		Title:       truncate(strings.TrimSpace(renderTemplate(hook.TitleTemplate, doc)), maxTitleLen),
+		Description: truncate(renderTemplate(hook.DescriptionTemplate, doc), maxDescriptionLen),
+		Status:      model.StatusTodo,
+	}
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserID: hook.UserID, Role: model.RoleUser, Scheme: auth.SchemeWebhook})
+	if err := s.tasks.Create(ctx, &task); err != nil {
+		return model.Task{}, err
+	}
+	return task, nil
+}
+
+// truncate обрезает строку до n символов, не разрывая UTF-8
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+const (
+	defaultWidgetLimit = 5
+	maxWidgetLimit     = 20
+)
+
+// WidgetTask - задача в компактном виде для виджета
+type WidgetTask struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+// Widget - данные виджета "мои ближайшие задачи"
+type Widget struct {
+	Tasks       []WidgetTask `json:"tasks"`
+	OpenCount   int          `json:"open_count"`
+	GeneratedAt time.Time    `json:"generated_at"`
+}
+
+// Widget возвращает до limit незавершённых и не отложенных задач: сначала начатые, затем
+// остальные, в каждой группе - самые давние
+func (s *TaskService) Widget(ctx context.Context, limit int) (Widget, error) {
+	if limit <= 0 {
+		limit = defaultWidgetLimit
+	}
+	limit = min(limit, maxWidgetLimit)
+
+	tasks, err := s.store.ListTasks(ctx)
+	if err != nil {
+		return Widget{}, err
+	}
+	open := make([]model.Task, 0, len(tasks))
+	now := s.now()
+	for _, t := range tasks {
+		if t.Status != model.StatusDone && !t.Snoozed(now) {
+			open = append(open, t)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		a, b := open[i], open[j]
+		if (a.Status == model.StatusInProgress) != (b.Status == model.StatusInProgress) {
+			return a.Status == model.StatusInProgress
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	w := Widget{Tasks: []WidgetTask{}, OpenCount: len(open), GeneratedAt: s.now().UTC()}
+	for _, t := range open[:min(limit, len(open))] {
+		w.Tasks = append(w.Tasks, WidgetTask{ID: t.ID, Title: t.Title, Status: t.Status})
+	}
+	return w, nil
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/mail"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+var (
+	ErrForbidden = errors.New("insufficient workspace permissions")
+	// ErrOwnerImmutable - роль владельца пространства нельзя снять или сменить
+	ErrOwnerImmutable     = errors.New("workspace owner cannot be removed or demoted")
+	ErrNotWorkspaceMember = errors.New("user is not a member of the workspace")
+)
+
+// WorkspaceService - пространства, команды и проекты; все методы проверяют
+// роль текущего пользователя
+type WorkspaceService struct {
+	store     storage.WorkspaceStore
+	snapshots storage.SnapshotStore
+	archives  *archiveJobs
+	validate  *validator.Validate
+	now       func() time.Time
+	mailer    mail.Mailer
+	publicURL string
+	log       zerolog.Logger
+}
+
+type WorkspaceOption func(*WorkspaceService)
+
+// WithInviteMailer включает отправку приглашений письмом; publicURL - внешний
+// адрес для ссылок приглашений
+func WithInviteMailer(m mail.Mailer, publicURL string, logger zerolog.Logger) WorkspaceOption {
+	return func(s *WorkspaceService) {
+		s.mailer = m
+		s.publicURL = strings.TrimRight(publicURL, "/")
+		s.log = logger
+	}
+}
+
+func NewWorkspaceService(store storage.WorkspaceStore, opts ...WorkspaceOption) *WorkspaceService {
+	s := &WorkspaceService{store: store, validate: validator.New(), now: time.Now, log: zerolog.Nop()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// currentUserID - пользователь из ctx; у System нет членства в пространствах
+func currentUserID(ctx context.Context) (int, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.IsSystem() {
+		return 0, auth.ErrUnauthenticated
+	}
+	return p.UserID, nil
+}
+
+// role возвращает роль текущего пользователя в пространстве не ниже min.
+// Не участнику пространство не видно вовсе: ErrNotFound, а не ErrForbidden.
+func (s *WorkspaceService) role(ctx context.Context, workspaceID int, min string) (string, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return "", err
+	}
+	ws, err := s.store.GetWorkspace(ctx, workspaceID, uid)
+	if err != nil {
+		return "", err
+	}
+	if model.WorkspaceRoleRank(ws.Role) < model.WorkspaceRoleRank(min) {
+		return "", ErrForbidden
+	}
+	return ws.Role, nil
+}
+
+func (s *WorkspaceService) Create(ctx context.Context, ws *model.Workspace) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.validate.Struct(ws); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return s.store.CreateWorkspace(ctx, ws, uid)
+}
+
+func (s *WorkspaceService) List(ctx context.Context) ([]model.Workspace, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.ListWorkspaces(ctx, uid)
+}
+
+func (s *WorkspaceService) Get(ctx context.Context, id int) (model.Workspace, error) {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	return s.store.GetWorkspace(ctx, id, uid)
+}
+
+func (s *WorkspaceService) Members(ctx context.Context, workspaceID int) ([]model.Member, error) {
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceMember); err != nil {
+		return nil, err
+	}
+	return s.store.ListWorkspaceMembers(ctx, workspaceID)
+}
+
+// SetMember добавляет пользователя в пространство или меняет его роль.
+// Назначать администраторов может только владелец.
+func (s *WorkspaceService) SetMember(ctx context.Context, workspaceID, userID int, role string) error {
+	if role != model.WorkspaceAdmin && role != model.WorkspaceMember {
+		return &ValidationError{Err: fmt.Errorf("role must be %q or %q", model.WorkspaceAdmin, model.WorkspaceMember)}
+	}
+	callerRole, err := s.role(ctx, workspaceID, model.WorkspaceAdmin)
+	if err != nil {
+		return err
+	}
+	if role == model.WorkspaceAdmin && callerRole != model.WorkspaceOwner {
+		return ErrForbidden
+	}
+	if err := s.checkNotOwner(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+	err = s.store.SetWorkspaceMember(ctx, workspaceID, userID, role)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &ValidationError{Err: fmt.Errorf("user %d does not exist", userID)}
+	}
+	return err
+}
+
+// RemoveMember исключает пользователя; участник может выйти сам
+func (s *WorkspaceService) RemoveMember(ctx context.Context, workspaceID, userID int) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	min := model.WorkspaceAdmin
+	if userID == uid {
+		min = model.WorkspaceMember
+	}
+	if _, err := s.role(ctx, workspaceID, min); err != nil {
+		return err
+	}
+	if err := s.checkNotOwner(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+	return s.store.RemoveWorkspaceMember(ctx, workspaceID, userID)
+}
+
+func (s *WorkspaceService) checkNotOwner(ctx context.Context, workspaceID, userID int) error {
+	ws, err := s.store.GetWorkspace(ctx, workspaceID, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ws.Role == model.WorkspaceOwner {
+		return ErrOwnerImmutable
+	}
+	return nil
+}
+
+// CreateTeam создаёт команду с проектом по умолчанию; создатель - её лидер
+func (s *WorkspaceService) CreateTeam(ctx context.Context, team *model.Team) error {
+	if err := s.validate.Struct(team); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if _, err := s.role(ctx, team.WorkspaceID, model.WorkspaceAdmin); err != nil {
+		return err
+	}
+	if err := s.store.CreateTeam(ctx, team); err != nil {
+		return err
+	}
+	uid, _ := currentUserID(ctx)
+	return s.store.SetTeamMember(ctx, team.ID, uid, model.TeamLead)
+}
+
+func (s *WorkspaceService) Teams(ctx context.Context, workspaceID int) ([]model.Team, error) {
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceMember); err != nil {
+		return nil, err
+	}
+	return s.store.ListTeams(ctx, workspaceID)
+}
+
+// team возвращает команду, если текущий пользователь - участник её пространства.
+// manage требует лидерства в команде или роли admin в пространстве,
+// иначе достаточно состоять в команде.
+func (s *WorkspaceService) team(ctx context.Context, teamID int, manage bool) (model.Team, error) {
+	team, err := s.store.GetTeam(ctx, teamID)
+	if err != nil {
+		return model.Team{}, err
+	}
+	wsRole, err := s.role(ctx, team.WorkspaceID, model.WorkspaceMember)
+	if err != nil {
+		return model.Team{}, err
+	}
+	if model.WorkspaceRoleRank(wsRole) >= model.WorkspaceRoleRank(model.WorkspaceAdmin) {
+		return team, nil
+	}
+
+	uid, _ := currentUserID(ctx)
+	teamRole, err := s.store.TeamRole(ctx, teamID, uid)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && manage && teamRole != model.TeamLead) {
+		return model.Team{}, ErrForbidden
+	}
+	return team, err
+}
+
+func (s *WorkspaceService) Team(ctx context.Context, teamID int) (model.Team, error) {
+	return s.team(ctx, teamID, false)
+}
+
+func (s *WorkspaceService) TeamMembers(ctx context.Context, teamID int) ([]model.Member, error) {
+	if _, err := s.team(ctx, teamID, false); err != nil {
+		return nil, err
+	}
+	return s.store.ListTeamMembers(ctx, teamID)
+}
+
+// SetTeamMember добавляет в команду участника того же пространства
+func (s *WorkspaceService) SetTeamMember(ctx context.Context, teamID, userID int, role string) error {
+	if role != model.TeamLead && role != model.TeamMember {
+		return &ValidationError{Err: fmt.Errorf("role must be %q or %q", model.TeamLead, model.TeamMember)}
+	}
+	team, err := s.team(ctx, teamID, true)
+	if err != nil {
+		return err
+	}
+	if _, err := s.store.GetWorkspace(ctx, team.WorkspaceID, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotWorkspaceMember
+		}
+		return err
+	}
+	return s.store.SetTeamMember(ctx, teamID, userID, role)
+}
+
+func (s *WorkspaceService) RemoveTeamMember(ctx context.Context, teamID, userID int) error {
+	uid, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.team(ctx, teamID, userID != uid); err != nil {
+		return err
+	}
+	return s.store.RemoveTeamMember(ctx, teamID, userID)
+}
+
+// TeamTasks - задачи всех проектов команды
+func (s *WorkspaceService) TeamTasks(ctx context.Context, teamID int) ([]model.Task, error) {
+	if _, err := s.team(ctx, teamID, false); err != nil {
+		return nil, err
+	}
+	return s.store.ListTeamTasks(ctx, teamID)
+}
+
+// TeamBoard - доска проекта команды по умолчанию
+func (s *WorkspaceService) TeamBoard(ctx context.Context, teamID, perColumn int) (model.Board, error) {
+	team, err := s.team(ctx, teamID, false)
+	if err != nil {
+		return model.Board{}, err
+	}
+	return s.ProjectBoard(ctx, team.DefaultProjectID, perColumn)
+}
+
+// CreateProject: общий проект создаёт admin пространства, проект команды -
+// ещё и её лидер
+func (s *WorkspaceService) CreateProject(ctx context.Context, p *model.Project) error {
+	if err := s.validate.Struct(p); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if p.TeamID == nil {
+		if _, err := s.role(ctx, p.WorkspaceID, model.WorkspaceAdmin); err != nil {
+			return err
+		}
+	} else {
+		team, err := s.team(ctx, *p.TeamID, true)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && team.WorkspaceID != p.WorkspaceID) {
+			return &ValidationError{Err: fmt.Errorf("team %d not found in workspace", *p.TeamID)}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.IsDefault = false
+	if err := s.store.CreateProject(ctx, p); err != nil {
+		return err
+	}
+	// создатель - администратор проекта, даже если он приватный
+	uid, _ := currentUserID(ctx)
+	p.Role = model.ProjectAdmin
+	return s.store.SetProjectMember(ctx, p.ID, uid, model.ProjectAdmin)
+}
+
+func (s *WorkspaceService) Projects(ctx context.Context, workspaceID int) ([]model.Project, error) {
+	if _, err := s.role(ctx, workspaceID, model.WorkspaceMember); err != nil {
+		return nil, err
+	}
+	return s.store.ListProjects(ctx, workspaceID)
+}
+
+// ProjectBoard - доска проекта. С perColumn > 0 в колонке только последние
+// изменённые задачи, а Count берётся из модели чтения task_counts.
+func (s *WorkspaceService) ProjectBoard(ctx context.Context, projectID, perColumn int) (model.Board, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return model.Board{}, err
+	}
+	stats, err := s.store.ProjectTaskStats(ctx, projectID)
+	if err != nil {
+		return model.Board{}, err
+	}
+
+	var tasks []model.Task
+	if perColumn > 0 {
+		tasks, err = s.store.ListBoardTasks(ctx, projectID, perColumn)
+	} else {
+		tasks, err = s.store.ListProjectTasks(ctx, projectID)
+	}
+	if err != nil {
+		return model.Board{}, err
+	}
+
+	board := model.NewBoard(projectID, tasks)
+	// счётчики меняются и при удалении, которое не видно по updated_at задач
+	if stats.UpdatedAt != nil && stats.UpdatedAt.After(board.UpdatedAt) {
+		board.UpdatedAt = *stats.UpdatedAt
+	}
+	if perColumn <= 0 {
+		return board, nil
+	}
+	for i := range board.Columns {
+		col := &board.Columns[i]
+		switch col.Status {
+		case model.StatusTodo:
+			col.Count = stats.Todo
+		case model.StatusInProgress:
+			col.Count = stats.InProgress
+		case model.StatusDone:
+			col.Count = stats.Done
+		}
+	}
+	return board, nil
+}
+
+// ProjectStats - счётчики задач проекта
+func (s *WorkspaceService) ProjectStats(ctx context.Context, projectID int) (model.TaskStats, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return model.TaskStats{}, err
+	}
+	return s.store.ProjectTaskStats(ctx, projectID)
+}
@@ -0,0 +1,509 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/blob"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// fakeWorkspaces хранит только членство и названия - этого хватает для
+// проверки ролей и писем
+type fakeWorkspaces struct {
+	storage.WorkspaceStore
+	names   map[int]string
+	members map[int]map[int]string // workspace -> user -> role
+	teams   map[int]model.Team
+	team    map[int]map[int]string  // team -> user -> role
+	invites map[string]model.Invite // хеш токена -> приглашение
+	project map[int]string          // user -> роль в единственном проекте 1
+}
+
+func newFakeWorkspaces() *fakeWorkspaces {
+	return &fakeWorkspaces{
+		names:   map[int]string{},
+		members: map[int]map[int]string{},
+		teams:   map[int]model.Team{},
+		team:    map[int]map[int]string{},
+		invites: map[string]model.Invite{},
+		project: map[int]string{},
+	}
+}
+
+func (f *fakeWorkspaces) CreateWorkspace(_ context.Context, ws *model.Workspace, ownerID int) error {
+	ws.ID = len(f.members) + 1
+	ws.Role = model.WorkspaceOwner
+	f.names[ws.ID] = ws.Name
+	f.members[ws.ID] = map[int]string{ownerID: model.WorkspaceOwner}
+	return nil
+}
+
+func (f *fakeWorkspaces) GetWorkspace(_ context.Context, id, userID int) (model.Workspace, error) {
+	role, ok := f.members[id][userID]
+	if !ok {
+		return model.Workspace{}, storage.ErrNotFound
+	}
+	return model.Workspace{ID: id, Name: f.names[id], Role: role}, nil
+}
+
+func (f *fakeWorkspaces) SetWorkspaceMember(_ context.Context, workspaceID, userID int, role string) error {
+	f.members[workspaceID][userID] = role
+	return nil
+}
+
+func (f *fakeWorkspaces) CreateTeam(_ context.Context, team *model.Team) error {
+	team.ID = len(f.teams) + 1
+	f.teams[team.ID] = *team
+	f.team[team.ID] = map[int]string{}
+	return nil
+}
+
+func (f *fakeWorkspaces) GetTeam(_ context.Context, id int) (model.Team, error) {
+	team, ok := f.teams[id]
+	if !ok {
+		return model.Team{}, storage.ErrNotFound
+	}
+	return team, nil
+}
+
+func (f *fakeWorkspaces) TeamRole(_ context.Context, teamID, userID int) (string, error) {
+	role, ok := f.team[teamID][userID]
+	if !ok {
+		return "", storage.ErrNotFound
+	}
+	return role, nil
+}
+
+func (f *fakeWorkspaces) SetTeamMember(_ context.Context, teamID, userID int, role string) error {
+	f.team[teamID][userID] = role
+	return nil
+}
+
+func (f *fakeWorkspaces) ListTeamTasks(context.Context, int) ([]model.Task, error) { return nil, nil }
+
+func (f *fakeWorkspaces) CreateInvite(_ context.Context, inv *model.Invite, tokenHash string) error {
+	inv.ID = len(f.invites) + 1
+	f.invites[tokenHash] = *inv
+	return nil
+}
+
+func (f *fakeWorkspaces) InviteByToken(_ context.Context, tokenHash string) (model.Invite, error) {
+	inv, ok := f.invites[tokenHash]
+	if !ok {
+		return model.Invite{}, storage.ErrNotFound
+	}
+	return inv, nil
+}
+
+func (f *fakeWorkspaces) AcceptInvite(ctx context.Context, tokenHash string, userID int) (model.Invite, error) {
+	inv, err := f.InviteByToken(ctx, tokenHash)
+	if err != nil {
+		return model.Invite{}, err
+	}
+	delete(f.invites, tokenHash)
+	if _, ok := f.members[inv.WorkspaceID][userID]; !ok {
+		f.members[inv.WorkspaceID][userID] = inv.Role
+	}
+	return inv, nil
+}
+
+func (f *fakeWorkspaces) GetProject(ctx context.Context, id int) (model.Project, error) {
+	p, _ := auth.FromContext(ctx)
+	role, ok := f.project[p.UserID]
+	if id != 1 || !ok {
+		return model.Project{}, storage.ErrNotFound
+	}
+	return model.Project{ID: 1, WorkspaceID: 1, Role: role}, nil
+}
+
+func (f *fakeWorkspaces) SetProjectMember(_ context.Context, _, userID int, role string) error {
+	f.project[userID] = role
+	return nil
+}
+
+func TestWorkspaceRoles(t *testing.T) {
+	store := newFakeWorkspaces()
+	svc := service.NewWorkspaceService(store)
+	owner, admin, member, outsider := userContext(1), userContext(2), userContext(3), userContext(4)
+
+	ws := &model.Workspace{Name: "Acme"}
+	if err := svc.Create(owner, ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetMember(owner, ws.ID, 2, model.WorkspaceAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetMember(admin, ws.ID, 3, model.WorkspaceMember); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.SetMember(admin, ws.ID, 3, model.WorkspaceAdmin); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("admin promotes to admin: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.SetMember(admin, ws.ID, 1, model.WorkspaceMember); !errors.Is(err, service.ErrOwnerImmutable) {
+		t.Errorf("demote owner: err = %v, want ErrOwnerImmutable", err)
+	}
+	if _, err := svc.Teams(outsider, ws.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("outsider lists teams: err = %v, want ErrNotFound", err)
+	}
+	if err := svc.CreateTeam(member, &model.Team{WorkspaceID: ws.ID, Name: "Ops"}); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("member creates team: err = %v, want ErrForbidden", err)
+	}
+
+	team := &model.Team{WorkspaceID: ws.ID, Name: "Backend"}
+	if err := svc.CreateTeam(admin, team); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := store.TeamRole(admin, team.ID, 2); role != model.TeamLead {
+		t.Errorf("creator team role = %q, want %q", role, model.TeamLead)
+	}
+
+	if _, err := svc.TeamTasks(member, team.ID); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("non-member reads team tasks: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.SetTeamMember(admin, team.ID, 4, model.TeamMember); !errors.Is(err, service.ErrNotWorkspaceMember) {
+		t.Errorf("add outsider to team: err = %v, want ErrNotWorkspaceMember", err)
+	}
+	if err := svc.SetTeamMember(admin, team.ID, 3, model.TeamMember); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.TeamTasks(member, team.ID); err != nil {
+		t.Errorf("team member reads team tasks: %v", err)
+	}
+	if err := svc.SetTeamMember(member, team.ID, 3, model.TeamLead); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("member promotes self: err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestInviteAcceptance(t *testing.T) {
+	store := newFakeWorkspaces()
+	svc := service.NewWorkspaceService(store)
+	owner := userContext(1)
+
+	ws := &model.Workspace{Name: "Acme"}
+	if err := svc.Create(owner, ws); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := svc.Invite(owner, ws.ID, service.InviteRequest{Email: "Bob@Example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := path.Base(inv.URL)
+
+	if _, err := svc.Invite(userContext(2), ws.ID, service.InviteRequest{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("outsider invites: err = %v, want ErrNotFound", err)
+	}
+
+	eve := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 3, Email: "eve@example.com"})
+	if _, err := svc.AcceptInvite(eve, token); !errors.Is(err, service.ErrInviteEmailMismatch) {
+		t.Errorf("accept with other email: err = %v, want ErrInviteEmailMismatch", err)
+	}
+
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Email: "bob@example.com"})
+	joined, err := svc.AcceptInvite(bob, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined.ID != ws.ID || joined.Role != model.WorkspaceMember {
+		t.Errorf("joined = %+v, want member of workspace %d", joined, ws.ID)
+	}
+	if _, err := svc.AcceptInvite(bob, token); !errors.Is(err, service.ErrInvalidInvite) {
+		t.Errorf("second accept: err = %v, want ErrInvalidInvite", err)
+	}
+}
+
+func TestInviteMailSubjectIsOneLine(t *testing.T) {
+	mails := make(chanMailer, 1)
+	svc := service.NewWorkspaceService(newFakeWorkspaces(), service.WithInviteMailer(mails, "", zerolog.Nop()))
+	owner := userContext(1)
+
+	ws := &model.Workspace{Name: "Acme\r\nBcc: victim@example.com"}
+	if err := svc.Create(owner, ws); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Invite(owner, ws.ID, service.InviteRequest{Email: "bob@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := mails.next(t); msg.Subject != "You are invited to Acme Bcc: victim@example.com" {
+		t.Errorf("subject = %q, want the workspace name on one line", msg.Subject)
+	}
+}
+
+func TestProjectMembersRequireProjectAdmin(t *testing.T) {
+	store := newFakeWorkspaces()
+	svc := service.NewWorkspaceService(store)
+	if err := svc.Create(userContext(1), &model.Workspace{Name: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	store.members[1][2] = model.WorkspaceMember
+	store.project[1] = model.ProjectAdmin
+	store.project[2] = model.ProjectEditor
+
+	if err := svc.SetProjectMember(userContext(2), 1, 2, model.ProjectAdmin); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("editor promotes self: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.SetProjectMember(userContext(1), 1, 3, model.ProjectViewer); !errors.Is(err, service.ErrNotWorkspaceMember) {
+		t.Errorf("add outsider: err = %v, want ErrNotWorkspaceMember", err)
+	}
+	if err := svc.SetProjectMember(userContext(1), 1, 2, model.ProjectViewer); err != nil {
+		t.Fatal(err)
+	}
+	if store.project[2] != model.ProjectViewer {
+		t.Errorf("role = %q, want %q", store.project[2], model.ProjectViewer)
+	}
+	if _, err := svc.Project(userContext(3), 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("outsider reads project: err = %v, want ErrNotFound", err)
+	}
+}
+
+// fakeSnapshots запоминает снимки и последнее восстановленное состояние
+type fakeSnapshots struct {
+	storage.SnapshotStore
+	current  model.WorkspaceState
+	snaps    []model.Snapshot
+	restored *model.WorkspaceState
+}
+
+func (f *fakeSnapshots) WorkspaceState(context.Context, int) (model.WorkspaceState, error) {
+	return f.current, nil
+}
+
+func (f *fakeSnapshots) CreateSnapshot(_ context.Context, snap *model.Snapshot) error {
+	snap.ID = len(f.snaps) + 1
+	f.snaps = append(f.snaps, *snap)
+	return nil
+}
+
+func (f *fakeSnapshots) GetSnapshot(_ context.Context, _, id int) (model.Snapshot, error) {
+	if id < 1 || id > len(f.snaps) {
+		return model.Snapshot{}, storage.ErrNotFound
+	}
+	return f.snaps[id-1], nil
+}
+
+func (f *fakeSnapshots) RestoreWorkspace(_ context.Context, _ int, state model.WorkspaceState) (model.RestoreResult, error) {
+	f.restored = &state
+	return model.RestoreResult{Restored: len(state.Tasks)}, nil
+}
+
+func TestRestoreTakesBackupFirst(t *testing.T) {
+	store := newFakeWorkspaces()
+	snaps := &fakeSnapshots{current: model.WorkspaceState{Tasks: []model.Task{{ID: 1, Title: "Before"}}}}
+	svc := service.NewWorkspaceService(store, service.WithSnapshots(snaps))
+	owner, member := userContext(1), userContext(2)
+
+	ws := &model.Workspace{Name: "Acme"}
+	if err := svc.Create(owner, ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetMember(owner, ws.ID, 2, model.WorkspaceMember); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.CreateSnapshot(member, ws.ID, "nightly"); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("member snapshots: err = %v, want ErrForbidden", err)
+	}
+	snap, err := svc.CreateSnapshot(owner, ws.ID, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snaps.current = model.WorkspaceState{Tasks: []model.Task{{ID: 1, Title: "Broken"}, {ID: 2, Title: "Extra"}}}
+	res, err := svc.Restore(owner, ws.ID, service.RestoreRequest{SnapshotID: &snap.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snaps.restored == nil || len(snaps.restored.Tasks) != 1 || snaps.restored.Tasks[0].Title != "Before" {
+		t.Errorf("restored = %+v, want the snapshot state", snaps.restored)
+	}
+	backup, _ := snaps.GetSnapshot(owner, ws.ID, res.BackupID)
+	if backup.State == nil || len(backup.State.Tasks) != 2 {
+		t.Errorf("backup = %+v, want the state before restore", backup)
+	}
+
+	var invalid *service.ValidationError
+	if _, err := svc.Restore(owner, ws.ID, service.RestoreRequest{}); !errors.As(err, &invalid) {
+		t.Errorf("empty request: err = %v, want ValidationError", err)
+	}
+	at := snap.CreatedAt
+	if _, err := svc.Restore(owner, ws.ID, service.RestoreRequest{At: &at}); !errors.Is(err, service.ErrPointInTimeDisabled) {
+		t.Errorf("restore to time without journal: err = %v, want ErrPointInTimeDisabled", err)
+	}
+}
+
+func (f *fakeWorkspaces) ListWorkspaceMembers(_ context.Context, workspaceID int) ([]model.Member, error) {
+	var out []model.Member
+	for id, role := range f.members[workspaceID] {
+		out = append(out, model.Member{UserID: id, Role: role})
+	}
+	return out, nil
+}
+
+// fakeAvatars - пользователи с ключами аватаров
+type fakeAvatars struct {
+	storage.UserStore
+	keys map[int]string
+}
+
+func (f fakeAvatars) GetUser(_ context.Context, id int) (model.User, error) {
+	return model.User{ID: id, AvatarKey: f.keys[id]}, nil
+}
+
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(body)
+	}
+	return files
+}
+
+func TestArchive(t *testing.T) {
+	store := newFakeWorkspaces()
+	snaps := &fakeSnapshots{current: model.WorkspaceState{Tasks: []model.Task{{ID: 1, Title: "Ship it"}}}}
+	blobs := blob.Filesystem{Dir: t.TempDir()}
+	if err := blobs.Put(context.Background(), "avatars/1", "image/png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewWorkspaceService(store, service.WithSnapshots(snaps),
+		service.WithArchives(blobs, fakeAvatars{keys: map[int]string{1: "avatars/1"}}))
+	owner, member := userContext(1), userContext(2)
+
+	ws := &model.Workspace{Name: "Acme"}
+	if err := svc.Create(owner, ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetMember(owner, ws.ID, 2, model.WorkspaceMember); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.Archive(member, ws.ID, false); !errors.Is(err, service.ErrForbidden) {
+		t.Errorf("member archive: err = %v, want ErrForbidden", err)
+	}
+
+	// небольшое пространство - сразу в ответ
+	write, job, err := svc.Archive(owner, ws.ID, false)
+	if err != nil || job != nil {
+		t.Fatalf("Archive = %v, %v; want an inline writer", job, err)
+	}
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	files := readArchive(t, buf.Bytes())
+	if !strings.Contains(files["workspace.json"], `"Ship it"`) || files["avatars/1.png"] != "png" ||
+		!strings.Contains(files["members.json"], `"user_id": 2`) {
+		t.Errorf("archive files = %v", files)
+	}
+	if _, ok := files["avatars/2.png"]; ok {
+		t.Error("archive has an avatar of a user without one")
+	}
+
+	// в фоне - через задание
+	_, job, err = svc.Archive(owner, ws.ID, true)
+	if err != nil || job == nil || job.Status != model.ArchiveRunning {
+		t.Fatalf("async Archive = %+v, %v; want a running job", job, err)
+	}
+	if _, err := svc.ArchiveJob(member, job.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("someone else's job: err = %v, want ErrNotFound", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := svc.ArchiveJob(owner, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status == model.ArchiveDone {
+			if got.Progress != 100 || got.FilesDone != 3 || got.Size == 0 {
+				t.Errorf("finished job = %+v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v, want it to finish", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	f, _, err := svc.OpenArchive(owner, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if files := readArchive(t, data); len(files) != 3 {
+		t.Errorf("downloaded archive files = %v", files)
+	}
+}
+
+// gatedSnapshots отдаёт состояние пространства, только когда его отпустят
+type gatedSnapshots struct {
+	*fakeSnapshots
+	release chan struct{}
+}
+
+func (g gatedSnapshots) WorkspaceState(ctx context.Context, id int) (model.WorkspaceState, error) {
+	select {
+	case <-g.release:
+	case <-time.After(2 * time.Second):
+		return model.WorkspaceState{}, errors.New("workspace state was not released")
+	}
+	return g.fakeSnapshots.WorkspaceState(ctx, id)
+}
+
+// async-запрос не ждёт чтения пространства: оно идёт уже в задании
+func TestArchiveLoadsStateInBackground(t *testing.T) {
+	snaps := gatedSnapshots{&fakeSnapshots{current: model.WorkspaceState{Tasks: []model.Task{{ID: 1, Title: "Ship it"}}}},
+		make(chan struct{})}
+	svc := service.NewWorkspaceService(newFakeWorkspaces(), service.WithSnapshots(snaps),
+		service.WithArchives(blob.Filesystem{Dir: t.TempDir()}, fakeAvatars{}))
+	owner := userContext(1)
+	ws := &model.Workspace{Name: "Acme"}
+	if err := svc.Create(owner, ws); err != nil {
+		t.Fatal(err)
+	}
+
+	_, job, err := svc.Archive(owner, ws.ID, true)
+	if err != nil || job == nil || job.Status != model.ArchiveRunning {
+		t.Fatalf("async Archive = %+v, %v; want a running job", job, err)
+	}
+	close(snaps.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := svc.ArchiveJob(owner, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.ArchiveRunning {
+			if got.Status != model.ArchiveDone {
+				t.Fatalf("job = %+v, want it done", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v, want it to finish", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// ErrUnavailable возвращается, пока предохранитель разомкнут
+var ErrUnavailable = errors.New("storage unavailable")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker размыкается после failureThreshold подряд идущих сбоев
+// и пропускает один пробный запрос по истечении cooldown.
+type circuitBreaker struct {
+	mu               sync.Mutex
+	state            breakerState
+	failures         int
+	openedAt         time.Time
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+}
+
+func newCircuitBreaker(failureThreshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		now:              time.Now,
+	}
+}
+
+// allow сообщает, можно ли выполнить запрос; если нет - сколько ждать.
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		elapsed := b.now().Sub(b.openedAt)
+		if elapsed < b.cooldown {
+			return false, b.cooldown - elapsed
+		}
+		b.state = breakerHalfOpen
+		return true, 0
+	case breakerHalfOpen:
+		// Пробный запрос уже выполняется
+		return false, b.cooldown
+	default:
+		return true, 0
+	}
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isInfraError(err) {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// isInfraError отделяет недоступность базы от обычных ошибок запроса:
+// если Postgres ответил (нет строки, нарушение ограничения), база жива.
+// Ошибки хранилища вроде ErrTaskUIDTaken - тоже ответ базы, только уже
+// переведённый. Отмена контекста - клиент ушёл, не дождавшись ответа, о базе она ничего
+// не говорит; DeadlineExceeded, напротив, - база не уложилась в queryTimeout.
+func isInfraError(err error) bool {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidProject) || errors.Is(err, ErrReadOnly) ||
+		errors.Is(err, ErrTaskUIDTaken) || errors.Is(err, auth.ErrUnauthenticated) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code[:2] {
+		case "08", "53", "57":
+			// connection exception, insufficient resources, operator intervention
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// UnavailableError несёт время, через которое стоит повторить запрос
+type UnavailableError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err == nil {
+		return ErrUnavailable.Error()
+	}
+	return ErrUnavailable.Error() + ": " + e.Err.Error()
+}
+
+func (e *UnavailableError) Unwrap() error { return ErrUnavailable }
+
+// BreakerStore оборачивает TaskStore предохранителем
+type BreakerStore struct {
+	next    TaskStore
+	breaker *circuitBreaker
+}
+
+// NewBreakerStore размыкает цепь после failures сбоев подряд на cooldown
+func NewBreakerStore(next TaskStore, failures int, cooldown time.Duration) *BreakerStore {
+	return &BreakerStore{next: next, breaker: newCircuitBreaker(failures, cooldown)}
+}
+
+func (s *BreakerStore) do(fn func() error) error {
+	ok, wait := s.breaker.allow()
+	if !ok {
+		return &UnavailableError{RetryAfter: wait}
+	}
+	err := fn()
+	s.breaker.record(err)
+	if isInfraError(err) {
+		return &UnavailableError{RetryAfter: s.breaker.cooldown, Err: err}
+	}
+	return err
+}
+
+func (s *BreakerStore) CreateTask(ctx context.Context, task *model.Task) error {
+	return s.do(func() error { return s.next.CreateTask(ctx, task) })
+}
+
+func (s *BreakerStore) ListTasks(ctx context.Context) ([]model.Task, error) {
+	var tasks []model.Task
+	err := s.do(func() (err error) {
+		tasks, err = s.next.ListTasks(ctx)
+		return err
+	})
+	return tasks, err
+}
+
+func (s *BreakerStore) GetTask(ctx context.Context, id int) (model.Task, error) {
+	var task model.Task
+	err := s.do(func() (err error) {
+		task, err = s.next.GetTask(ctx, id)
+		return err
+	})
+	return task, err
+}
+
+func (s *BreakerStore) GetTasks(ctx context.Context, ids []int) ([]model.Task, error) {
+	var tasks []model.Task
+	err := s.do(func() (err error) {
+		tasks, err = s.next.GetTasks(ctx, ids)
+		return err
+	})
+	return tasks, err
+}
+
+func (s *BreakerStore) TaskIDByUID(ctx context.Context, uid string) (int, error) {
+	var id int
+	err := s.do(func() (err error) {
+		id, err = s.next.TaskIDByUID(ctx, uid)
+		return err
+	})
+	return id, err
+}
+
+func (s *BreakerStore) UpdateTask(ctx context.Context, task *model.Task) error {
+	return s.do(func() error { return s.next.UpdateTask(ctx, task) })
+}
+
+func (s *BreakerStore) DeleteTask(ctx context.Context, id int) error {
+	return s.do(func() error { return s.next.DeleteTask(ctx, id) })
+}
+
+func (s *BreakerStore) CountOpenSubtasks(ctx context.Context, id int) (int, error) {
+	var n int
+	err := s.do(func() (err error) {
+		n, err = s.next.CountOpenSubtasks(ctx, id)
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// stubStore - TaskStore, в котором переопределены только нужные тесту методы
+type stubStore struct {
+	TaskStore
+	list func() ([]model.Task, error)
+}
+
+func (s *stubStore) ListTasks(context.Context) ([]model.Task, error) { return s.list() }
+
+func (s *stubStore) GetTask(context.Context, int) (model.Task, error) {
+	return model.Task{}, ErrNotFound
+}
+
+func TestBreakerStore(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	dbErr := errors.New("dial tcp: connection refused")
+	stub := &stubStore{list: func() ([]model.Task, error) {
+		calls++
+		return nil, dbErr
+	}}
+	s := NewBreakerStore(stub, 2, 10*time.Second)
+	s.breaker.now = func() time.Time { return now }
+
+	for range 2 {
+		if _, err := s.ListTasks(context.Background()); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("err = %v, want ErrUnavailable", err)
+		}
+	}
+
+	// Предохранитель разомкнут - хранилище не вызывается
+	_, err := s.ListTasks(context.Background())
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) || unavailable.RetryAfter != 10*time.Second {
+		t.Fatalf("err = %v, want UnavailableError with 10s retry", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+
+	// После cooldown пробный запрос проходит и замыкает цепь
+	now = now.Add(11 * time.Second)
+	stub.list = func() ([]model.Task, error) {
+		calls++
+		return nil, nil
+	}
+	if _, err := s.ListTasks(context.Background()); err != nil {
+		t.Fatalf("probe err = %v", err)
+	}
+	if _, err := s.ListTasks(context.Background()); err != nil {
+		t.Fatalf("err after recovery = %v", err)
+	}
+	if calls != 4 {
+		t.Fatalf("calls = %d, want 4", calls)
+	}
+}
+
+func TestBreakerIgnoresNotFound(t *testing.T) {
+	s := NewBreakerStore(&stubStore{}, 1, time.Minute)
+
+	for range 3 {
+		if _, err := s.GetTask(context.Background(), 1); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+	}
+}
+
+func TestBreakerIgnoresCanceled(t *testing.T) {
+	stub := &stubStore{list: func() ([]model.Task, error) {
+		return nil, fmt.Errorf("query tasks: %w", context.Canceled)
+	}}
+	s := NewBreakerStore(stub, 1, time.Minute)
+
+	for range 3 {
+		_, err := s.ListTasks(context.Background())
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		var unavailable *UnavailableError
+		if errors.As(err, &unavailable) {
+			t.Fatal("canceled request counted as an outage")
+		}
+	}
+
+	// Таймаут запроса к базе - по-прежнему сбой
+	stub.list = func() ([]model.Task, error) { return nil, context.DeadlineExceeded }
+	if _, err := s.ListTasks(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("deadline err = %v, want ErrUnavailable", err)
+	}
+}
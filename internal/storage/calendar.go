@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// CalendarStore - подключения Google Calendar и связь событий с задачами
+type CalendarStore interface {
+	// SaveCalendarLink создаёт подключение или обновляет токены и настройки
+	SaveCalendarLink(ctx context.Context, link *model.CalendarLink) error
+	CalendarLink(ctx context.Context, userID int) (model.CalendarLink, error)
+	ListCalendarLinks(ctx context.Context) ([]model.CalendarLink, error)
+	DeleteCalendarLink(ctx context.Context, userID int) error
+
+	CalendarEventByTask(ctx context.Context, taskID int) (model.CalendarEvent, error)
+	CalendarEvent(ctx context.Context, userID int, eventID string) (model.CalendarEvent, error)
+	SaveCalendarEvent(ctx context.Context, e model.CalendarEvent) error
+	DeleteCalendarEvent(ctx context.Context, taskID int) error
+}
+
+const calendarLinkColumns = `user_id, calendar_id, access_token, refresh_token, token_expiry, sync_token, pull_events, created_at`
+
+func scanCalendarLink(row pgx.Row, l *model.CalendarLink) error {
+	err := row.Scan(&l.UserID, &l.CalendarID, &l.AccessToken, &l.RefreshToken, &l.TokenExpiry,
+		&l.SyncToken, &l.PullEvents, &l.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) SaveCalendarLink(ctx context.Context, link *model.CalendarLink) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO calendar_links (user_id, calendar_id, access_token, refresh_token, token_expiry, sync_token, pull_events)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (user_id) DO UPDATE SET calendar_id = $2, access_token = $3, refresh_token = $4,
+		     token_expiry = $5, sync_token = $6, pull_events = $7
+		 RETURNING created_at`,
+		link.UserID, link.CalendarID, link.AccessToken, link.RefreshToken, link.TokenExpiry,
+		link.SyncToken, link.PullEvents).Scan(&link.CreatedAt)
+}
+
+func (s *Postgres) CalendarLink(ctx context.Context, userID int) (model.CalendarLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var l model.CalendarLink
+	err := scanCalendarLink(s.pool.QueryRow(ctx,
+		"SELECT "+calendarLinkColumns+" FROM calendar_links WHERE user_id = $1", userID), &l)
+	return l, err
+}
+
+func (s *Postgres) ListCalendarLinks(ctx context.Context) ([]model.CalendarLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+calendarLinkColumns+" FROM calendar_links ORDER BY user_id")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.CalendarLink, error) {
+		var l model.CalendarLink
+		err := scanCalendarLink(row, &l)
+		return l, err
+	})
+}
+
+func (s *Postgres) DeleteCalendarLink(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM calendar_links WHERE user_id = $1", userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func scanCalendarEvent(row pgx.Row, e *model.CalendarEvent) error {
+	err := row.Scan(&e.UserID, &e.EventID, &e.TaskID, &e.Pulled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) CalendarEventByTask(ctx context.Context, taskID int) (model.CalendarEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var e model.CalendarEvent
+	err := scanCalendarEvent(s.pool.QueryRow(ctx,
+		"SELECT user_id, event_id, task_id, pulled FROM calendar_events WHERE task_id = $1", taskID), &e)
+	return e, err
+}
+
+func (s *Postgres) CalendarEvent(ctx context.Context, userID int, eventID string) (model.CalendarEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var e model.CalendarEvent
+	err := scanCalendarEvent(s.pool.QueryRow(ctx,
+		"SELECT user_id, event_id, task_id, pulled FROM calendar_events WHERE user_id = $1 AND event_id = $2",
+		userID, eventID), &e)
+	return e, err
+}
+
+func (s *Postgres) SaveCalendarEvent(ctx context.Context, e model.CalendarEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO calendar_events (user_id, event_id, task_id, pulled) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (task_id) DO UPDATE SET user_id = $1, event_id = $2, pulled = $4`,
+		e.UserID, e.EventID, e.TaskID, e.Pulled)
+	return err
+}
+
+func (s *Postgres) DeleteCalendarEvent(ctx context.Context, taskID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, "DELETE FROM calendar_events WHERE task_id = $1", taskID)
+	return err
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+const delegationColumns = `d.task_id, t.title, d.delegator_id, d.delegate_id, d.state, d.comment, d.created_at, d.updated_at`
+
+func scanDelegation(row pgx.Row, d *model.Delegation) error {
+	return row.Scan(&d.TaskID, &d.TaskTitle, &d.DelegatorID, &d.DelegateID, &d.State, &d.Comment, &d.CreatedAt, &d.UpdatedAt)
+}
+
+func (s *Postgres) Delegate(ctx context.Context, id, delegateID int) (model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	if owner == nil {
+		return model.Delegation{}, ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var d model.Delegation
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var (
+			projectID *int
+			writable  bool
+			role      *string
+		)
+		err := tx.QueryRow(ctx, `SELECT project_id, `+writeFilter+`, project_role($3, project_id) FROM tasks
+			WHERE `+ownerFilter+` AND id = $2 FOR UPDATE`, owner, id, delegateID).Scan(&projectID, &writable, &role)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrNotFound
+		case err != nil:
+			return err
+		case !writable:
+			return ErrReadOnly
+		case projectID == nil || role == nil || *role == model.ProjectViewer:
+			return ErrInvalidDelegate
+		}
+		// чужое поручение не заменяется: иначе исполнитель передал бы задачу
+		// себе и принял бы свою же работу
+		err = scanDelegation(tx.QueryRow(ctx, `WITH d AS (
+				INSERT INTO task_delegations (task_id, delegator_id, delegate_id) VALUES ($1, $2, $3)
+				ON CONFLICT (task_id) DO UPDATE SET delegate_id = EXCLUDED.delegate_id, state = 'delegated',
+				    comment = '', created_at = now(), updated_at = now()
+				WHERE task_delegations.delegator_id = EXCLUDED.delegator_id
+				RETURNING *)
+			SELECT `+delegationColumns+` FROM d JOIN tasks t ON t.id = d.task_id`, id, *owner, delegateID), &d)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrDelegatedByOther
+		}
+		return err
+	})
+	return d, err
+}
+
+func (s *Postgres) TaskDelegation(ctx context.Context, id int) (model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var d model.Delegation
+	err = scanDelegation(s.pool.QueryRow(ctx, `SELECT `+delegationColumns+`
+		FROM task_delegations d JOIN tasks t ON t.id = d.task_id
+		WHERE d.task_id = $2 AND d.task_id IN (SELECT id FROM tasks WHERE `+ownerFilter+`)`, owner, id), &d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Delegation{}, ErrNotFound
+	}
+	return d, err
+}
+
+func (s *Postgres) SetDelegationState(ctx context.Context, id int, from []string, state, comment string) (model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var d model.Delegation
+	err = scanDelegation(s.pool.QueryRow(ctx, `WITH d AS (
+			UPDATE task_delegations SET state = $4, comment = $5, updated_at = now()
+			WHERE task_id = $2 AND state = ANY($3) AND task_id IN (SELECT id FROM tasks WHERE `+ownerFilter+`)
+			RETURNING *)
+		SELECT `+delegationColumns+` FROM d JOIN tasks t ON t.id = d.task_id`, owner, id, from, state, comment), &d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Delegation{}, ErrNotFound
+	}
+	return d, err
+}
+
+func (s *Postgres) DeleteDelegation(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM task_delegations
+		WHERE task_id = $2 AND task_id IN (SELECT id FROM tasks WHERE `+ownerFilter+`)`, owner, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) Delegations(ctx context.Context) ([]model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT `+delegationColumns+`
+		FROM task_delegations d JOIN tasks t ON t.id = d.task_id
+		WHERE (d.delegator_id = $1 OR d.delegate_id = $1) AND `+ownerFilter+`
+		ORDER BY d.created_at DESC, d.task_id DESC`, owner)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Delegation, error) {
+		var d model.Delegation
+		err := scanDelegation(row, &d)
+		return d, err
+	})
+}
@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// EventStore - TaskStore режима event sourcing: каждое изменение - запись в
+// task_events, а tasks - проекция журнала, обновляемая в той же транзакции.
+// Чтение идёт из проекции, поэтому видимость и фильтры те же, что у Postgres.
+type EventStore struct {
+	*Postgres
+}
+
+func NewEventStore(pool *pgxpool.Pool) *EventStore {
+	return &EventStore{Postgres: NewPostgres(pool)}
+}
+
+func (s *EventStore) CreateTask(ctx context.Context, task *model.Task) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := s.checkProject(ctx, owner, task.ProjectID); err != nil {
+		return err
+	}
+
+	task.OwnerID = owner
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var id int
+		if err := tx.QueryRow(ctx, "SELECT nextval(pg_get_serial_sequence('tasks', 'id'))").Scan(&id); err != nil {
+			return err
+		}
+		created, err := s.append(ctx, tx, owner, model.Task{},
+			model.TaskEvent{TaskID: id, Type: model.TaskEventCreated, Data: *task})
+		if err != nil {
+			return uidTaken(err)
+		}
+		*task = created
+		return nil
+	})
+}
+
+// UpdateTask пишет только изменившееся: правку полей и смену статуса
+// отдельными событиями; запрос без изменений журнал не трогает
+func (s *EventStore) UpdateTask(ctx context.Context, task *model.Task) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := s.checkProject(ctx, owner, task.ProjectID); err != nil {
+		return err
+	}
+
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var old model.Task
+		if err := scanTask(tx.QueryRow(ctx,
+			"SELECT "+taskColumns+" FROM tasks WHERE "+writeFilter+" AND id = $2 FOR UPDATE", owner, task.ID), &old); err != nil {
+			return err
+		}
+
+		var evs []model.TaskEvent
+		if task.Title != old.Title || task.Description != old.Description || !sameInt(task.ParentID, old.ParentID) ||
+			!sameInt(task.ProjectID, old.ProjectID) || !sameTime(task.DueAt, old.DueAt) ||
+			!sameLocation(task.Location, old.Location) {
+			evs = append(evs, model.TaskEvent{TaskID: task.ID, Type: model.TaskEventEdited, Data: *task})
+		}
+		if task.Status != old.Status || !sameTime(task.CompletedAt, old.CompletedAt) {
+			evs = append(evs, model.TaskEvent{TaskID: task.ID, Type: model.TaskEventStatusChanged, Data: *task})
+		}
+
+		next, err := s.append(ctx, tx, owner, old, evs...)
+		if err != nil {
+			return err
+		}
+		*task = next
+		return nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.readOnlyOr(ctx, owner, task.ID, ErrNotFound)
+	}
+	return err
+}
+
+// DeleteTask записывает удаление задачи и всех её подзадач, которые иначе
+// исчезли бы из проекции каскадом без следа в журнале
+func (s *EventStore) DeleteTask(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var found bool
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth FROM tasks WHERE `+writeFilter+` AND id = $2
+			UNION ALL
+			SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id
+		) SELECT id FROM tree ORDER BY depth DESC`, owner, id)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+		found = len(ids) > 0
+
+		for _, taskID := range ids {
+			if _, err := s.append(ctx, tx, owner, model.Task{},
+				model.TaskEvent{TaskID: taskID, Type: model.TaskEventDeleted}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && !found {
+		return s.readOnlyOr(ctx, owner, id, nil)
+	}
+	return err
+}
+
+// append пишет события в журнал и переносит результат в проекцию
+func (s *EventStore) append(ctx context.Context, tx pgx.Tx, actor *int, state model.Task, evs ...model.TaskEvent) (model.Task, error) {
+	exists := true
+	for _, e := range evs {
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO task_events (task_id, type, data, actor_id) VALUES ($1, $2, $3, $4)
+			 RETURNING seq, occurred_at`,
+			e.TaskID, e.Type, eventData(e), actor).Scan(&e.Seq, &e.OccurredAt); err != nil {
+			return model.Task{}, err
+		}
+		state, exists = e.Apply(state)
+		if err := project(ctx, tx, e.TaskID, state, exists); err != nil {
+			return model.Task{}, err
+		}
+	}
+	return state, nil
+}
+
+// eventData - поля, которые задаёт событие, в формате JSON задачи
+func eventData(e model.TaskEvent) map[string]any {
+	t := e.Data
+	switch e.Type {
+	case model.TaskEventCreated:
+		return map[string]any{"uid": t.UID, "owner_id": t.OwnerID, "parent_id": t.ParentID, "project_id": t.ProjectID,
+			"title": t.Title, "description": t.Description, "status": t.Status,
+			"due_at": t.DueAt, "location": t.Location, "completed_at": t.CompletedAt}
+	case model.TaskEventEdited:
+		return map[string]any{"title": t.Title, "description": t.Description,
+			"parent_id": t.ParentID, "project_id": t.ProjectID, "due_at": t.DueAt, "location": t.Location}
+	case model.TaskEventStatusChanged:
+		return map[string]any{"status": t.Status, "completed_at": t.CompletedAt}
+	}
+	return map[string]any{}
+}
+
+// project записывает состояние задачи в проекцию tasks
+func project(ctx context.Context, tx pgx.Tx, id int, t model.Task, exists bool) error {
+	if !exists {
+		_, err := tx.Exec(ctx, "DELETE FROM tasks WHERE id = $1", id)
+		return err
+	}
+	// id задачи уникален только вместе с workspace_id, поэтому сначала
+	// обновление: смена проекта переносит строку в секцию нового пространства.
+	// uid у существующей строки не меняется, при вставке из журнала до его
+	// появления он пуст - тогда его выдаёт база.
+	tag, err := tx.Exec(ctx, `UPDATE tasks SET user_id = $2, parent_id = $3, project_id = $4, title = $5,
+	              description = $6, status = $7, due_at = $8, completed_at = $9, created_at = $10, updated_at = $11,
+	              location = $12
+	          WHERE id = $1`,
+		id, t.OwnerID, t.ParentID, t.ProjectID, t.Title, t.Description, t.Status,
+		t.DueAt, t.CompletedAt, t.CreatedAt, t.UpdatedAt, t.Location)
+	if err != nil || tag.RowsAffected() > 0 {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO tasks (id, workspace_id, user_id, parent_id, project_id, title, description, status,
+	                        due_at, completed_at, created_at, updated_at, uid, location)
+	          VALUES ($1, `+taskWorkspace("$4")+`, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+	                  COALESCE(NULLIF($12, '')::uuid, gen_random_uuid()), $13)`,
+		id, t.OwnerID, t.ParentID, t.ProjectID, t.Title, t.Description, t.Status,
+		t.DueAt, t.CompletedAt, t.CreatedAt, t.UpdatedAt, t.UID, t.Location)
+	return err
+}
+
+// TaskEvents - журнал задачи, в том числе удалённой. Доступ решает последнее
+// известное состояние задачи и текущие права на её проект.
+func (s *EventStore) TaskEvents(ctx context.Context, id int) ([]model.TaskEvent, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT seq, task_id, type, data, actor_id, occurred_at FROM task_events WHERE task_id = $1 ORDER BY seq", id)
+	if err != nil {
+		return nil, err
+	}
+	evs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.TaskEvent, error) {
+		var e model.TaskEvent
+		err := row.Scan(&e.Seq, &e.TaskID, &e.Type, &e.Data, &e.ActorID, &e.OccurredAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var last model.Task
+	for _, e := range evs {
+		if t, exists := e.Apply(last); exists {
+			last = t
+		}
+	}
+	if len(evs) == 0 {
+		return nil, ErrNotFound
+	}
+	var visible bool
+	if err := s.pool.QueryRow(ctx,
+		"SELECT $1::int IS NULL OR ($3::int IS NULL AND $2::int = $1) OR $3 IN (SELECT accessible_projects($1))",
+		owner, last.OwnerID, last.ProjectID).Scan(&visible); err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrNotFound
+	}
+	return evs, nil
+}
+
+// TaskAt - состояние задачи на момент at, восстановленное из журнала
+func (s *EventStore) TaskAt(ctx context.Context, id int, at time.Time) (model.Task, error) {
+	evs, err := s.TaskEvents(ctx, id)
+	if err != nil {
+		return model.Task{}, err
+	}
+	var (
+		task   model.Task
+		exists bool
+	)
+	for _, e := range evs {
+		if e.OccurredAt.After(at) {
+			break
+		}
+		task, exists = e.Apply(task)
+	}
+	if !exists {
+		return model.Task{}, ErrNotFound
+	}
+	return task, nil
+}
+
+// Rebuild пересчитывает из журнала строки проекции и удаляет задачи, удалённые
+// по журналу. Задачи без журнала (например, из seed) не трогает; удалённые
+// каскадом вместе с пользователем или проектом не восстанавливает.
+func (s *EventStore) Rebuild(ctx context.Context) (int, error) {
+	var n int
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "LOCK TABLE task_events IN EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+		rows, err := tx.Query(ctx, `SELECT e.seq, e.task_id, e.type, e.data, e.actor_id, e.occurred_at
+			FROM task_events e JOIN tasks t ON t.id = e.task_id ORDER BY e.task_id, e.seq`)
+		if err != nil {
+			return err
+		}
+		type state struct {
+			task   model.Task
+			exists bool
+		}
+		var ids []int
+		states := map[int]*state{}
+		for rows.Next() {
+			var e model.TaskEvent
+			if err := rows.Scan(&e.Seq, &e.TaskID, &e.Type, &e.Data, &e.ActorID, &e.OccurredAt); err != nil {
+				rows.Close()
+				return err
+			}
+			st, ok := states[e.TaskID]
+			if !ok {
+				st = &state{}
+				states[e.TaskID] = st
+				ids = append(ids, e.TaskID)
+			}
+			st.task, st.exists = e.Apply(st.task)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := project(ctx, tx, id, states[id].task, states[id].exists); err != nil {
+				return err
+			}
+		}
+		n = len(ids)
+		return nil
+	})
+	return n, err
+}
+
+func sameInt(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+}
+
+func sameLocation(a, b *model.Location) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// SetTaskPinned не требует права на запись: закрепление видно только
+// самому пользователю, поэтому закрепить можно и задачу из проекта, где он
+// только viewer
+func (s *Postgres) SetTaskPinned(ctx context.Context, id int, pinned bool) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, `INSERT INTO task_flags (user_id, task_id, pinned)
+		SELECT $1, id, $3 FROM tasks WHERE `+ownerFilter+` AND id = $2
+		ON CONFLICT (user_id, task_id) DO UPDATE SET pinned = EXCLUDED.pinned, updated_at = now()`, owner, id, pinned)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) TaskFlags(ctx context.Context) ([]model.TaskFlag, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT task_id, pinned, updated_at FROM task_flags
+		WHERE user_id = $1 AND task_id IN (SELECT id FROM tasks WHERE `+ownerFilter+`)`, owner)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.TaskFlag, error) {
+		var f model.TaskFlag
+		err := row.Scan(&f.TaskID, &f.Pinned, &f.UpdatedAt)
+		return f, err
+	})
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// GitHubStore - связи проектов с репозиториями и соответствие issues задачам
+type GitHubStore interface {
+	// SaveGitHubLink создаёт связь или заменяет существующую у проекта
+	SaveGitHubLink(ctx context.Context, link *model.GitHubLink) error
+	GitHubLink(ctx context.Context, projectID int) (model.GitHubLink, error)
+	ListGitHubLinks(ctx context.Context) ([]model.GitHubLink, error)
+	DeleteGitHubLink(ctx context.Context, projectID int) error
+	SetGitHubSynced(ctx context.Context, projectID int, at time.Time) error
+
+	GitHubIssue(ctx context.Context, projectID, number int) (model.GitHubIssue, error)
+	GitHubIssueByTask(ctx context.Context, taskID int) (model.GitHubIssue, error)
+	SaveGitHubIssue(ctx context.Context, issue model.GitHubIssue) error
+}
+
+const githubLinkColumns = `project_id, user_id, repo, login, token, webhook_secret, created_at, last_synced_at`
+
+func scanGitHubLink(row pgx.Row, l *model.GitHubLink) error {
+	err := row.Scan(&l.ProjectID, &l.UserID, &l.Repo, &l.Login, &l.Token, &l.WebhookSecret,
+		&l.CreatedAt, &l.LastSyncedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) SaveGitHubLink(ctx context.Context, link *model.GitHubLink) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// при смене репозитория старые соответствия issues теряют смысл
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM github_issues i USING github_links l
+		 WHERE l.project_id = i.project_id AND i.project_id = $1 AND l.repo <> $2`,
+		link.ProjectID, link.Repo); err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO github_links (project_id, user_id, repo, login, token, webhook_secret)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (project_id) DO UPDATE SET user_id = $2, repo = $3, login = $4, token = $5,
+		     webhook_secret = $6, created_at = now(), last_synced_at = NULL
+		 RETURNING created_at`,
+		link.ProjectID, link.UserID, link.Repo, link.Login, link.Token, link.WebhookSecret).Scan(&link.CreatedAt)
+	if err != nil {
+		return mapForeignKey(err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Postgres) GitHubLink(ctx context.Context, projectID int) (model.GitHubLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var l model.GitHubLink
+	err := scanGitHubLink(s.pool.QueryRow(ctx,
+		"SELECT "+githubLinkColumns+" FROM github_links WHERE project_id = $1", projectID), &l)
+	return l, err
+}
+
+func (s *Postgres) ListGitHubLinks(ctx context.Context) ([]model.GitHubLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+githubLinkColumns+" FROM github_links ORDER BY project_id")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.GitHubLink, error) {
+		var l model.GitHubLink
+		err := scanGitHubLink(row, &l)
+		return l, err
+	})
+}
+
+func (s *Postgres) DeleteGitHubLink(ctx context.Context, projectID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM github_links WHERE project_id = $1", projectID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) SetGitHubSynced(ctx context.Context, projectID int, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, "UPDATE github_links SET last_synced_at = $2 WHERE project_id = $1", projectID, at)
+	return err
+}
+
+func scanGitHubIssue(row pgx.Row, i *model.GitHubIssue) error {
+	err := row.Scan(&i.ProjectID, &i.Number, &i.TaskID, &i.Status, &i.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) GitHubIssue(ctx context.Context, projectID, number int) (model.GitHubIssue, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var i model.GitHubIssue
+	err := scanGitHubIssue(s.pool.QueryRow(ctx,
+		`SELECT project_id, issue_number, task_id, status, issue_updated_at
+		 FROM github_issues WHERE project_id = $1 AND issue_number = $2`, projectID, number), &i)
+	return i, err
+}
+
+func (s *Postgres) GitHubIssueByTask(ctx context.Context, taskID int) (model.GitHubIssue, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var i model.GitHubIssue
+	err := scanGitHubIssue(s.pool.QueryRow(ctx,
+		`SELECT project_id, issue_number, task_id, status, issue_updated_at
+		 FROM github_issues WHERE task_id = $1`, taskID), &i)
+	return i, err
+}
+
+func (s *Postgres) SaveGitHubIssue(ctx context.Context, i model.GitHubIssue) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO github_issues (project_id, issue_number, task_id, status, issue_updated_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (project_id, issue_number) DO UPDATE SET task_id = $3, status = $4, issue_updated_at = $5`,
+		i.ProjectID, i.Number, i.TaskID, i.Status, i.UpdatedAt)
+	return err
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// JiraStore - связи проектов с Jira и соответствие issues задачам
+type JiraStore interface {
+	// SaveJiraLink создаёт связь или заменяет существующую у проекта
+	SaveJiraLink(ctx context.Context, link *model.JiraLink) error
+	JiraLink(ctx context.Context, projectID int) (model.JiraLink, error)
+	ListJiraLinks(ctx context.Context) ([]model.JiraLink, error)
+	DeleteJiraLink(ctx context.Context, projectID int) error
+	SetJiraSynced(ctx context.Context, projectID int, at time.Time) error
+
+	JiraIssue(ctx context.Context, projectID int, key string) (model.JiraIssue, error)
+	JiraIssueByTask(ctx context.Context, taskID int) (model.JiraIssue, error)
+	SaveJiraIssue(ctx context.Context, issue model.JiraIssue) error
+}
+
+const jiraLinkColumns = `project_id, user_id, base_url, email, token, jql, status_map, webhook_secret, created_at, last_synced_at`
+
+func scanJiraLink(row pgx.Row, l *model.JiraLink) error {
+	err := row.Scan(&l.ProjectID, &l.UserID, &l.BaseURL, &l.Email, &l.Token, &l.JQL, &l.StatusMap, &l.WebhookSecret,
+		&l.CreatedAt, &l.LastSyncedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) SaveJiraLink(ctx context.Context, link *model.JiraLink) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// в другом экземпляре Jira те же ключи означают другие issues
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM jira_issues i USING jira_links l
+		 WHERE l.project_id = i.project_id AND i.project_id = $1 AND l.base_url <> $2`,
+		link.ProjectID, link.BaseURL); err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO jira_links (project_id, user_id, base_url, email, token, jql, status_map, webhook_secret)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (project_id) DO UPDATE SET user_id = $2, base_url = $3, email = $4, token = $5,
+		     jql = $6, status_map = $7, webhook_secret = $8, created_at = now(), last_synced_at = NULL
+		 RETURNING created_at`,
+		link.ProjectID, link.UserID, link.BaseURL, link.Email, link.Token, link.JQL, link.StatusMap,
+		link.WebhookSecret).Scan(&link.CreatedAt)
+	if err != nil {
+		return mapForeignKey(err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Postgres) JiraLink(ctx context.Context, projectID int) (model.JiraLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var l model.JiraLink
+	err := scanJiraLink(s.pool.QueryRow(ctx,
+		"SELECT "+jiraLinkColumns+" FROM jira_links WHERE project_id = $1", projectID), &l)
+	return l, err
+}
+
+func (s *Postgres) ListJiraLinks(ctx context.Context) ([]model.JiraLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+jiraLinkColumns+" FROM jira_links ORDER BY project_id")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.JiraLink, error) {
+		var l model.JiraLink
+		err := scanJiraLink(row, &l)
+		return l, err
+	})
+}
+
+func (s *Postgres) DeleteJiraLink(ctx context.Context, projectID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM jira_links WHERE project_id = $1", projectID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) SetJiraSynced(ctx context.Context, projectID int, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, "UPDATE jira_links SET last_synced_at = $2 WHERE project_id = $1", projectID, at)
+	return err
+}
+
+func scanJiraIssue(row pgx.Row, i *model.JiraIssue) error {
+	err := row.Scan(&i.ProjectID, &i.Key, &i.TaskID, &i.Status, &i.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) JiraIssue(ctx context.Context, projectID int, key string) (model.JiraIssue, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var i model.JiraIssue
+	err := scanJiraIssue(s.pool.QueryRow(ctx,
+		`SELECT project_id, issue_key, task_id, status, issue_updated_at
+		 FROM jira_issues WHERE project_id = $1 AND issue_key = $2`, projectID, key), &i)
+	return i, err
+}
+
+func (s *Postgres) JiraIssueByTask(ctx context.Context, taskID int) (model.JiraIssue, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var i model.JiraIssue
+	err := scanJiraIssue(s.pool.QueryRow(ctx,
+		`SELECT project_id, issue_key, task_id, status, issue_updated_at
+		 FROM jira_issues WHERE task_id = $1`, taskID), &i)
+	return i, err
+}
+
+func (s *Postgres) SaveJiraIssue(ctx context.Context, i model.JiraIssue) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO jira_issues (project_id, issue_key, task_id, status, issue_updated_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (project_id, issue_key) DO UPDATE SET task_id = $3, status = $4, issue_updated_at = $5`,
+		i.ProjectID, i.Key, i.TaskID, i.Status, i.UpdatedAt)
+	return err
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// NearbyTasks отбирает кандидатов по earth_box через GiST-индекс, а точное
+// расстояние earth_distance отсекает углы квадрата
+func (s *Postgres) NearbyTasks(ctx context.Context, lat, lon, radius float64, limit int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks
+		WHERE `+ownerFilter+` AND status <> 'done' AND location IS NOT NULL
+		  AND earth_box(ll_to_earth($2, $3), $4) @> task_earth(location)
+		  AND earth_distance(ll_to_earth($2, $3), task_earth(location)) <= $4
+		ORDER BY earth_distance(ll_to_earth($2, $3), task_earth(location)), id
+		LIMIT $5`, owner, lat, lon, radius, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+}
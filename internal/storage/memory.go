@@ -0,0 +1,610 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Memory - хранилище в памяти для тестов без PostgreSQL
+type Memory struct {
+	mu     sync.RWMutex
+	tasks  map[int]model.Task
+	nextID int
+
+	// для ChangesSince: номер последнего изменения каждой задачи и надгробия
+	seq            int64
+	changed        map[int]int64
+	tombstones     []memTombstone
+	conflicts      []model.TaskConflict
+	nextConflictID int
+	// flags - отметки пользователей на задачах: user_id -> task_id -> отметка
+	flags map[int]map[int]model.TaskFlag
+	// delegations - поручения по id задачи
+	delegations map[int]model.Delegation
+}
+
+type memTombstone struct {
+	model.Tombstone
+	owner *int
+	seq   int64
+}
+
+func NewMemory() *Memory {
+	return &Memory{tasks: make(map[int]model.Task), nextID: 1, changed: make(map[int]int64),
+		flags: make(map[int]map[int]model.TaskFlag), delegations: make(map[int]model.Delegation)}
+}
+
+// visible повторяет ownerFilter из Postgres без учёта проектов:
+// участников пространств в памяти нет, поэтому видны только свои задачи
+func visible(owner *int, t model.Task) bool {
+	return owner == nil || (t.OwnerID != nil && *t.OwnerID == *owner)
+}
+
+// touch отмечает изменение задачи t и её родителя, progress которого зависит
+// от подзадач; вызывается под блокировкой
+func (s *Memory) touch(t model.Task) {
+	ids := []int{t.ID}
+	if t.ParentID != nil {
+		ids = append(ids, *t.ParentID)
+	}
+	for _, id := range ids {
+		if _, ok := s.tasks[id]; ok {
+			s.seq++
+			s.changed[id] = s.seq
+		}
+	}
+}
+
+// touchProgress обновляет updated_at родителя t, progress которого
+// изменился вместе с t, как триггер tasks_subtask_counts; вызывается под
+// блокировкой
+func (s *Memory) touchProgress(t model.Task, now time.Time) {
+	if t.ParentID == nil {
+		return
+	}
+	if p, ok := s.tasks[*t.ParentID]; ok {
+		p.UpdatedAt = now
+		s.tasks[p.ID] = p
+	}
+}
+
+// withProgress считает progress задачи по подзадачам, как счётчики в
+// Postgres; вызывается под блокировкой
+func (s *Memory) withProgress(t model.Task) model.Task {
+	var total, done int
+	for _, c := range s.tasks {
+		if c.ParentID != nil && *c.ParentID == t.ID {
+			total++
+			if c.Status == model.StatusDone {
+				done++
+			}
+		}
+	}
+	t.SetProgress(total, done)
+	return t
+}
+
+func (s *Memory) CreateTask(ctx context.Context, task *model.Task) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tasks {
+		if task.UID != "" && t.UID == task.UID {
+			return ErrTaskUIDTaken
+		}
+	}
+	now := time.Now().UTC()
+	task.ID = s.nextID
+	task.OwnerID = owner
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	s.nextID++
+	s.tasks[task.ID] = *task
+	s.tombstones = slices.DeleteFunc(s.tombstones, func(d memTombstone) bool { return d.UID == task.UID })
+	s.touch(*task)
+	s.touchProgress(*task, now)
+	return nil
+}
+
+func (s *Memory) ListTasks(ctx context.Context) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tasks []model.Task
+	for _, t := range s.tasks {
+		if visible(owner, t) {
+			tasks = append(tasks, s.withProgress(t))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (s *Memory) GetTask(ctx context.Context, id int) (model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok || !visible(owner, task) {
+		return model.Task{}, ErrNotFound
+	}
+	return s.withProgress(task), nil
+}
+
+func (s *Memory) GetTasks(ctx context.Context, ids []int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tasks []model.Task
+	for _, id := range ids {
+		if task, ok := s.tasks[id]; ok && visible(owner, task) {
+			tasks = append(tasks, s.withProgress(task))
+		}
+	}
+	return tasks, nil
+}
+
+func (s *Memory) TaskIDByUID(ctx context.Context, uid string) (int, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tasks {
+		if t.UID == uid && visible(owner, t) {
+			return t.ID, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (s *Memory) UpdateTask(ctx context.Context, task *model.Task) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.tasks[task.ID]
+	if !ok || !visible(owner, old) {
+		return ErrNotFound
+	}
+	task.OwnerID = old.OwnerID
+	task.CreatedAt = old.CreatedAt
+	task.UpdatedAt = time.Now().UTC()
+	s.tasks[task.ID] = *task
+	s.touch(old)
+	s.touch(*task)
+	if !sameInt(old.ParentID, task.ParentID) || old.Status != task.Status {
+		s.touchProgress(old, task.UpdatedAt)
+		s.touchProgress(*task, task.UpdatedAt)
+	}
+	return nil
+}
+
+func (s *Memory) DeleteTask(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.tasks[id]; ok && visible(owner, t) {
+		s.deleteTree(id)
+		s.touch(t)
+		s.touchProgress(t, time.Now().UTC())
+	}
+	return nil
+}
+
+// deleteTree повторяет ON DELETE CASCADE для подзадач
+func (s *Memory) deleteTree(id int) {
+	t := s.tasks[id]
+	delete(s.tasks, id)
+	delete(s.changed, id)
+	delete(s.delegations, id)
+	s.seq++
+	s.tombstones = append(s.tombstones, memTombstone{
+		Tombstone: model.Tombstone{ID: id, UID: t.UID, DeletedAt: time.Now().UTC()}, owner: t.OwnerID, seq: s.seq})
+	for childID, t := range s.tasks {
+		if t.ParentID != nil && *t.ParentID == id {
+			s.deleteTree(childID)
+		}
+	}
+}
+
+func (s *Memory) CountOpenSubtasks(ctx context.Context, id int) (int, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, t := range s.tasks {
+		if t.ParentID != nil && *t.ParentID == id && t.Status != model.StatusDone && visible(owner, t) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Memory) SimilarOpenTasks(ctx context.Context, title string, projectID *int, limit int) ([]model.Task, error) {
+	tasks, err := s.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	want := model.NormalizeTitle(title)
+	var similar []model.Task
+	for _, t := range tasks {
+		if len(similar) < limit && t.Status != model.StatusDone && sameInt(t.ProjectID, projectID) &&
+			model.NormalizeTitle(t.Title) == want {
+			similar = append(similar, t)
+		}
+	}
+	return similar, nil
+}
+
+// FuzzySearchTasks сравнивает по model.WordSimilarity - приближению
+// word_similarity из pg_trgm
+func (s *Memory) FuzzySearchTasks(ctx context.Context, query string, threshold float64, limit int) ([]model.Task, error) {
+	tasks, err := s.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	score := make(map[int]float64, len(tasks))
+	var found []model.Task
+	for _, t := range tasks {
+		sim := max(model.WordSimilarity(query, t.Title), model.WordSimilarity(query, t.Description))
+		if sim >= threshold {
+			score[t.ID] = sim
+			found = append(found, t)
+		}
+	}
+	slices.SortFunc(found, func(a, b model.Task) int {
+		if c := cmp.Compare(score[b.ID], score[a.ID]); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return found[:min(limit, len(found))], nil
+}
+
+func (s *Memory) NearbyTasks(ctx context.Context, lat, lon, radius float64, limit int) ([]model.Task, error) {
+	tasks, err := s.ListTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dist := make(map[int]float64, len(tasks))
+	var near []model.Task
+	for _, t := range tasks {
+		if t.Location == nil || t.Status == model.StatusDone {
+			continue
+		}
+		if d := model.Distance(lat, lon, t.Location.Lat, t.Location.Lon); d <= radius {
+			dist[t.ID] = d
+			near = append(near, t)
+		}
+	}
+	slices.SortFunc(near, func(a, b model.Task) int {
+		if c := cmp.Compare(dist[a.ID], dist[b.ID]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return near[:min(limit, len(near))], nil
+}
+
+func (s *Memory) SnoozeTask(ctx context.Context, id int, until *time.Time) (model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok || !visible(owner, task) {
+		return model.Task{}, ErrNotFound
+	}
+	task.SnoozedUntil = until
+	task.UpdatedAt = time.Now().UTC()
+	s.tasks[id] = task
+	s.touch(task)
+	return s.withProgress(task), nil
+}
+
+func (s *Memory) WakeTasks(_ context.Context, now time.Time) ([]model.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var woken []model.Task
+	for id, t := range s.tasks {
+		if t.SnoozedUntil == nil || t.SnoozedUntil.After(now) {
+			continue
+		}
+		t.SnoozedUntil = nil
+		t.UpdatedAt = time.Now().UTC()
+		s.tasks[id] = t
+		s.touch(t)
+		woken = append(woken, s.withProgress(t))
+	}
+	sort.Slice(woken, func(i, j int) bool { return woken[i].ID < woken[j].ID })
+	return woken, nil
+}
+
+func (s *Memory) SetTaskPinned(ctx context.Context, id int, pinned bool) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if owner == nil || !ok || !visible(owner, task) {
+		return ErrNotFound
+	}
+	if s.flags[*owner] == nil {
+		s.flags[*owner] = make(map[int]model.TaskFlag)
+	}
+	s.flags[*owner][id] = model.TaskFlag{TaskID: id, Pinned: pinned, UpdatedAt: time.Now().UTC()}
+	return nil
+}
+
+func (s *Memory) TaskFlags(ctx context.Context) ([]model.TaskFlag, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var flags []model.TaskFlag
+	for id, f := range s.flags[*owner] {
+		if t, ok := s.tasks[id]; ok && visible(owner, t) {
+			flags = append(flags, f)
+		}
+	}
+	return flags, nil
+}
+
+// Delegate не проверяет роль исполнителя в проекте: проектов в памяти нет,
+// достаточно того, что задача в проекте. Стороны поручения видят задачу, как
+// редактор проекта в Postgres.
+func (s *Memory) Delegate(ctx context.Context, id, delegateID int) (model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	prev, delegated := s.delegation(owner, id)
+	if owner == nil || !ok || !visible(owner, task) && !delegated {
+		return model.Delegation{}, ErrNotFound
+	}
+	if task.ProjectID == nil {
+		return model.Delegation{}, ErrInvalidDelegate
+	}
+	if delegated && prev.DelegatorID != *owner {
+		return model.Delegation{}, ErrDelegatedByOther
+	}
+	now := time.Now().UTC()
+	d := model.Delegation{TaskID: id, TaskTitle: task.Title, DelegatorID: *owner, DelegateID: delegateID,
+		State: model.DelegationActive, CreatedAt: now, UpdatedAt: now}
+	s.delegations[id] = d
+	return d, nil
+}
+
+// delegation - поручение задачи с её текущим заголовком. В Postgres
+// исполнитель видит задачу как редактор проекта; проектов в памяти нет,
+// поэтому сторонам поручения оно видно и так. Вызывается под блокировкой.
+func (s *Memory) delegation(owner *int, id int) (model.Delegation, bool) {
+	d, ok := s.delegations[id]
+	task, exists := s.tasks[id]
+	if !ok || !exists || !visible(owner, task) && (owner == nil || (*owner != d.DelegatorID && *owner != d.DelegateID)) {
+		return model.Delegation{}, false
+	}
+	d.TaskTitle = task.Title
+	return d, true
+}
+
+func (s *Memory) TaskDelegation(ctx context.Context, id int) (model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.delegation(owner, id)
+	if !ok {
+		return model.Delegation{}, ErrNotFound
+	}
+	return d, nil
+}
+
+func (s *Memory) SetDelegationState(ctx context.Context, id int, from []string, state, comment string) (model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.delegation(owner, id)
+	if !ok || !slices.Contains(from, d.State) {
+		return model.Delegation{}, ErrNotFound
+	}
+	d.State, d.Comment, d.UpdatedAt = state, comment, time.Now().UTC()
+	s.delegations[id] = d
+	return d, nil
+}
+
+func (s *Memory) DeleteDelegation(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.delegation(owner, id); !ok {
+		return ErrNotFound
+	}
+	delete(s.delegations, id)
+	return nil
+}
+
+func (s *Memory) Delegations(ctx context.Context) ([]model.Delegation, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []model.Delegation
+	for id := range s.delegations {
+		if d, ok := s.delegation(owner, id); ok && (d.DelegatorID == *owner || d.DelegateID == *owner) {
+			list = append(list, d)
+		}
+	}
+	slices.SortFunc(list, func(a, b model.Delegation) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.TaskID, a.TaskID)
+	})
+	return list, nil
+}
+
+func (s *Memory) CountActiveTasks(ctx context.Context) (int, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, t := range s.tasks {
+		if owner != nil && t.OwnerID != nil && *t.OwnerID == *owner && t.Status != model.StatusDone {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Memory) ChangesSince(ctx context.Context, token string) (model.TaskChanges, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.TaskChanges{}, err
+	}
+	var since int64
+	if token != "" {
+		if since, err = strconv.ParseInt(token, 10, 64); err != nil {
+			return model.TaskChanges{}, ErrInvalidSyncToken
+		}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := model.TaskChanges{Tasks: []model.Task{}, Deleted: []model.Tombstone{}, Token: strconv.FormatInt(s.seq, 10)}
+	for id, t := range s.tasks {
+		if s.changed[id] > since && visible(owner, t) {
+			changes.Tasks = append(changes.Tasks, s.withProgress(t))
+		}
+	}
+	sort.Slice(changes.Tasks, func(i, j int) bool { return changes.Tasks[i].ID < changes.Tasks[j].ID })
+	if token == "" {
+		return changes, nil
+	}
+	for _, d := range s.tombstones {
+		if d.seq > since && visible(owner, model.Task{OwnerID: d.owner}) {
+			changes.Deleted = append(changes.Deleted, d.Tombstone)
+		}
+	}
+	return changes, nil
+}
+
+func (s *Memory) AddConflicts(ctx context.Context, conflicts []model.TaskConflict) error {
+	actor, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range conflicts {
+		s.nextConflictID++
+		conflicts[i].ID, conflicts[i].ActorID, conflicts[i].DetectedAt = s.nextConflictID, actor, time.Now().UTC()
+		s.conflicts = append(s.conflicts, conflicts[i])
+	}
+	return nil
+}
+
+func (s *Memory) TaskConflicts(ctx context.Context) ([]model.TaskConflict, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conflicts := []model.TaskConflict{}
+	for _, c := range slices.Backward(s.conflicts) {
+		if t, ok := s.tasks[c.TaskID]; ok && visible(owner, t) {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts, nil
+}
+
+func (s *Memory) DeleteConflict(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.conflicts {
+		if t, ok := s.tasks[c.TaskID]; ok && c.ID == id && visible(owner, t) {
+			s.conflicts = slices.Delete(s.conflicts, i, i+1)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
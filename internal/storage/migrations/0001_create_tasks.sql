@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS tasks (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    status      TEXT        NOT NULL DEFAULT 'todo',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+ALTER TABLE tasks
+    ADD COLUMN completed_at TIMESTAMPTZ,
+    ADD COLUMN parent_id    INTEGER REFERENCES tasks (id) ON DELETE CASCADE;
+
+CREATE INDEX tasks_parent_id_idx ON tasks (parent_id) WHERE parent_id IS NOT NULL;
+
+UPDATE tasks SET completed_at = updated_at WHERE status = 'done';
@@ -0,0 +1,30 @@
+CREATE TABLE users (
+    id            SERIAL PRIMARY KEY,
+    email         TEXT        NOT NULL UNIQUE,
+    password_hash TEXT        NOT NULL,
+    role          TEXT        NOT NULL DEFAULT 'user',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE api_keys (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL,
+    key_hash     TEXT        NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE TABLE sessions (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT        NOT NULL UNIQUE,
+    user_agent TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Задачи, созданные до появления пользователей, остаются без владельца
+ALTER TABLE tasks ADD COLUMN user_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+CREATE INDEX tasks_user_id_idx ON tasks (user_id);
@@ -0,0 +1,10 @@
+-- Сессия = устройство: cookie-токен, текущий refresh-токен и предыдущий,
+-- по которому распознаётся повторное использование украденного токена
+ALTER TABLE sessions
+    ADD COLUMN refresh_token_hash    TEXT UNIQUE,
+    ADD COLUMN previous_refresh_hash TEXT,
+    ADD COLUMN last_used_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN revoked_at            TIMESTAMPTZ;
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_previous_refresh_hash_idx ON sessions (previous_refresh_hash);
@@ -0,0 +1,13 @@
+ALTER TABLE users
+    ADD COLUMN totp_secret    TEXT,
+    ADD COLUMN totp_enabled   BOOLEAN NOT NULL DEFAULT false,
+    -- Последний принятый шаг TOTP: код нельзя использовать повторно
+    ADD COLUMN totp_last_step BIGINT  NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id        SERIAL PRIMARY KEY,
+    user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT    NOT NULL,
+    used_at   TIMESTAMPTZ,
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,6 @@
+-- Использованные magic-link токены: ссылка одноразовая
+CREATE TABLE used_magic_links (
+    jti        TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
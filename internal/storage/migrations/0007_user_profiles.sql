@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN timezone     TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN locale       TEXT NOT NULL DEFAULT 'en',
+    ADD COLUMN avatar_key   TEXT;
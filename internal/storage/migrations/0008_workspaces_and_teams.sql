@@ -0,0 +1,59 @@
+CREATE TABLE workspaces (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE workspace_members (
+    workspace_id INTEGER     NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id      INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role         TEXT        NOT NULL DEFAULT 'member',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id);
+
+CREATE TABLE teams (
+    id           SERIAL PRIMARY KEY,
+    workspace_id INTEGER     NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (workspace_id, name)
+);
+
+CREATE TABLE team_members (
+    team_id    INTEGER     NOT NULL REFERENCES teams (id) ON DELETE CASCADE,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role       TEXT        NOT NULL DEFAULT 'member',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (team_id, user_id)
+);
+CREATE INDEX team_members_user_id_idx ON team_members (user_id);
+
+-- Проект без команды общий для всего пространства; у каждой команды есть
+-- проект по умолчанию - её доска
+CREATE TABLE projects (
+    id           SERIAL PRIMARY KEY,
+    workspace_id INTEGER     NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    team_id      INTEGER REFERENCES teams (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL,
+    is_default   BOOLEAN     NOT NULL DEFAULT false,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX projects_workspace_id_idx ON projects (workspace_id);
+CREATE UNIQUE INDEX projects_team_default_idx ON projects (team_id) WHERE is_default;
+
+ALTER TABLE tasks ADD COLUMN project_id INTEGER REFERENCES projects (id) ON DELETE CASCADE;
+CREATE INDEX tasks_project_id_idx ON tasks (project_id);
+
+-- Проекты, задачи которых видит пользователь: общие проекты его пространств,
+-- проекты его команд и все проекты пространств, где он owner или admin
+CREATE FUNCTION accessible_projects(uid INTEGER) RETURNS SETOF INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT p.id
+    FROM projects p
+    JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = uid
+    WHERE p.team_id IS NULL
+       OR wm.role IN ('owner', 'admin')
+       OR EXISTS (SELECT 1 FROM team_members tm WHERE tm.team_id = p.team_id AND tm.user_id = uid)
+$$;
@@ -0,0 +1,15 @@
+-- Приглашение без email - ссылка, которую может принять любой, у кого она есть
+CREATE TABLE workspace_invites (
+    id           SERIAL PRIMARY KEY,
+    workspace_id INTEGER     NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    email        TEXT,
+    role         TEXT        NOT NULL DEFAULT 'member',
+    token_hash   TEXT        NOT NULL UNIQUE,
+    invited_by   INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    accepted_at  TIMESTAMPTZ,
+    accepted_by  INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    revoked_at   TIMESTAMPTZ
+);
+CREATE INDEX workspace_invites_workspace_id_idx ON workspace_invites (workspace_id);
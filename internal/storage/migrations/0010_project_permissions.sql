@@ -0,0 +1,49 @@
+ALTER TABLE projects ADD COLUMN visibility TEXT NOT NULL DEFAULT 'workspace';
+
+-- Явная роль в проекте перекрывает роль в пространстве и команде
+CREATE TABLE project_members (
+    project_id INTEGER     NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role       TEXT        NOT NULL DEFAULT 'editor',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (project_id, user_id)
+);
+CREATE INDEX project_members_user_id_idx ON project_members (user_id);
+
+-- Действующая роль пользователя в проекте (viewer, editor, admin) или NULL.
+-- Приватный проект доступен только его явным участникам.
+CREATE FUNCTION project_role(uid INTEGER, pid INTEGER) RETURNS TEXT
+LANGUAGE sql STABLE AS $$
+    SELECT coalesce(
+        (SELECT pm.role FROM project_members pm
+         JOIN projects p ON p.id = pm.project_id
+         JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = pm.user_id
+         WHERE pm.project_id = pid AND pm.user_id = uid),
+        (SELECT CASE
+                    WHEN p.visibility = 'private' THEN NULL
+                    WHEN wm.role IN ('owner', 'admin') THEN 'admin'
+                    WHEN p.team_id IS NULL THEN 'editor'
+                    WHEN EXISTS (SELECT 1 FROM team_members tm WHERE tm.team_id = p.team_id AND tm.user_id = uid)
+                        THEN 'editor'
+                END
+         FROM projects p
+         JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = uid
+         WHERE p.id = pid))
+$$;
+
+CREATE OR REPLACE FUNCTION accessible_projects(uid INTEGER) RETURNS SETOF INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT p.id
+    FROM projects p
+    JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = uid
+    WHERE project_role(uid, p.id) IS NOT NULL
+$$;
+
+-- Проекты, в которых пользователь может менять задачи
+CREATE FUNCTION writable_projects(uid INTEGER) RETURNS SETOF INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT p.id
+    FROM projects p
+    JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = uid
+    WHERE project_role(uid, p.id) IN ('editor', 'admin')
+$$;
@@ -0,0 +1,13 @@
+-- Публичная ссылка только на чтение: ровно на задачу или на проект
+CREATE TABLE share_links (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    task_id    INTEGER REFERENCES tasks (id) ON DELETE CASCADE,
+    project_id INTEGER REFERENCES projects (id) ON DELETE CASCADE,
+    token_hash TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    CHECK ((task_id IS NULL) <> (project_id IS NULL))
+);
+CREATE INDEX share_links_user_id_idx ON share_links (user_id);
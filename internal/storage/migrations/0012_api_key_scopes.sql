@@ -0,0 +1,2 @@
+-- Пустой список - полный доступ, как у ключей до появления областей
+ALTER TABLE api_keys ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,13 @@
+-- Входящий вебхук: произвольный JSON по секретному URL превращается в задачу
+CREATE TABLE inbound_webhooks (
+    id                   SERIAL PRIMARY KEY,
+    user_id              INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name                 TEXT        NOT NULL,
+    project_id           INTEGER REFERENCES projects (id) ON DELETE CASCADE,
+    title_template       TEXT        NOT NULL,
+    description_template TEXT        NOT NULL DEFAULT '',
+    token_hash           TEXT        NOT NULL UNIQUE,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at         TIMESTAMPTZ
+);
+CREATE INDEX inbound_webhooks_user_id_idx ON inbound_webhooks (user_id);
@@ -0,0 +1,23 @@
+-- Проект, связанный с репозиторием GitHub: issues, назначенные владельцу
+-- связи, синхронизируются с задачами проекта
+CREATE TABLE github_links (
+    project_id     INTEGER PRIMARY KEY REFERENCES projects (id) ON DELETE CASCADE,
+    user_id        INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    repo           TEXT        NOT NULL,
+    login          TEXT        NOT NULL,
+    token          TEXT        NOT NULL,
+    webhook_secret TEXT        NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_synced_at TIMESTAMPTZ
+);
+
+-- Статус, о котором обе стороны последний раз договорились: меняется только
+-- то, что изменилось с тех пор, а события старше issue_updated_at пропускаются
+CREATE TABLE github_issues (
+    project_id       INTEGER     NOT NULL REFERENCES github_links (project_id) ON DELETE CASCADE,
+    issue_number     INTEGER     NOT NULL,
+    task_id          INTEGER     NOT NULL UNIQUE REFERENCES tasks (id) ON DELETE CASCADE,
+    status           TEXT        NOT NULL,
+    issue_updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (project_id, issue_number)
+);
@@ -0,0 +1,24 @@
+-- Проект, связанный с Jira: задачами становятся issues, отобранные JQL
+CREATE TABLE jira_links (
+    project_id     INTEGER PRIMARY KEY REFERENCES projects (id) ON DELETE CASCADE,
+    user_id        INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    base_url       TEXT        NOT NULL,
+    email          TEXT        NOT NULL DEFAULT '',
+    token          TEXT        NOT NULL,
+    jql            TEXT        NOT NULL,
+    -- статус Jira -> статус задачи; остальные сопоставляются по категории
+    status_map     JSONB       NOT NULL DEFAULT '{}',
+    webhook_secret TEXT        NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_synced_at TIMESTAMPTZ
+);
+
+-- Как и github_issues: последний согласованный статус и время изменения issue
+CREATE TABLE jira_issues (
+    project_id       INTEGER     NOT NULL REFERENCES jira_links (project_id) ON DELETE CASCADE,
+    issue_key        TEXT        NOT NULL,
+    task_id          INTEGER     NOT NULL UNIQUE REFERENCES tasks (id) ON DELETE CASCADE,
+    status           TEXT        NOT NULL,
+    issue_updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (project_id, issue_key)
+);
@@ -0,0 +1,24 @@
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMPTZ;
+
+-- Подключённый Google Calendar: задачи со сроком попадают в отдельный календарь
+CREATE TABLE calendar_links (
+    user_id       INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    calendar_id   TEXT        NOT NULL,
+    access_token  TEXT        NOT NULL,
+    refresh_token TEXT        NOT NULL,
+    token_expiry  TIMESTAMPTZ NOT NULL,
+    -- токен инкрементальной выдачи событий; пустой - полная синхронизация
+    sync_token    TEXT        NOT NULL DEFAULT '',
+    pull_events   BOOLEAN     NOT NULL DEFAULT false,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- task_id без внешнего ключа: после удаления задачи по нему удаляется событие
+CREATE TABLE calendar_events (
+    user_id  INTEGER NOT NULL REFERENCES calendar_links (user_id) ON DELETE CASCADE,
+    event_id TEXT    NOT NULL,
+    task_id  INTEGER NOT NULL UNIQUE,
+    -- pulled: событие создано в календаре, задача - из него
+    pulled   BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (user_id, event_id)
+);
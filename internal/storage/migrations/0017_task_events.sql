@@ -0,0 +1,24 @@
+-- Журнал событий задач для режима STORAGE_MODE=events; tasks в этом режиме -
+-- проекция журнала. task_id без внешнего ключа: история удалённых задач остаётся.
+CREATE TABLE task_events (
+    seq         BIGSERIAL PRIMARY KEY,
+    task_id     INTEGER     NOT NULL,
+    type        TEXT        NOT NULL,
+    -- поля задачи, которые задаёт событие; остальные не меняются
+    data        JSONB       NOT NULL DEFAULT '{}',
+    actor_id    INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX task_events_task_id_idx ON task_events (task_id, seq);
+
+-- Уже существующие задачи начинают историю со снимка на момент последнего
+-- изменения: более раннее состояние не известно
+INSERT INTO task_events (task_id, type, data, actor_id, occurred_at)
+SELECT id, 'task.created',
+       jsonb_build_object('owner_id', user_id, 'parent_id', parent_id, 'project_id', project_id,
+                          'title', title, 'description', description, 'status', status,
+                          'due_at', due_at, 'completed_at', completed_at, 'created_at', created_at),
+       user_id, updated_at
+FROM tasks
+ORDER BY id;
@@ -0,0 +1,105 @@
+-- Модели чтения для доски, статистики и поиска. Их обновляют триггеры в той же
+-- транзакции, что и запись в tasks, поэтому они всегда согласованы с задачами.
+
+-- Число задач по статусам: проекта или личных задач пользователя вне проектов
+CREATE TABLE task_counts (
+    project_id INTEGER REFERENCES projects (id) ON DELETE CASCADE,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    status     TEXT        NOT NULL,
+    count      INTEGER     NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((project_id IS NULL) <> (user_id IS NULL))
+);
+
+CREATE UNIQUE INDEX task_counts_project_idx ON task_counts (project_id, status) WHERE project_id IS NOT NULL;
+CREATE UNIQUE INDEX task_counts_user_idx ON task_counts (user_id, status) WHERE user_id IS NOT NULL;
+
+-- Поисковый документ задачи: заголовок, описание и имя проекта
+CREATE TABLE task_search (
+    task_id  INTEGER PRIMARY KEY REFERENCES tasks (id) ON DELETE CASCADE,
+    document TSVECTOR NOT NULL
+);
+
+CREATE INDEX task_search_document_idx ON task_search USING GIN (document);
+
+CREATE FUNCTION task_document(title TEXT, description TEXT, pid INTEGER) RETURNS TSVECTOR
+LANGUAGE sql STABLE AS $$
+    SELECT setweight(to_tsvector('simple', title), 'A')
+        || setweight(to_tsvector('simple', description), 'B')
+        || setweight(to_tsvector('simple', coalesce((SELECT name FROM projects WHERE id = pid), '')), 'C')
+$$;
+
+-- Уменьшение только обновляет строку: при каскадном удалении проекта или
+-- пользователя вставка ссылалась бы на удаляемую запись
+CREATE FUNCTION task_counts_add(pid INTEGER, uid INTEGER, st TEXT, delta INTEGER) RETURNS void
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF delta < 0 THEN
+        UPDATE task_counts SET count = count + delta, updated_at = now()
+        WHERE status = st AND (project_id = pid OR (pid IS NULL AND user_id = uid));
+    ELSIF pid IS NOT NULL THEN
+        INSERT INTO task_counts (project_id, status, count) VALUES (pid, st, delta)
+        ON CONFLICT (project_id, status) WHERE project_id IS NOT NULL
+        DO UPDATE SET count = task_counts.count + delta, updated_at = now();
+    ELSIF uid IS NOT NULL THEN
+        INSERT INTO task_counts (user_id, status, count) VALUES (uid, st, delta)
+        ON CONFLICT (user_id, status) WHERE user_id IS NOT NULL
+        DO UPDATE SET count = task_counts.count + delta, updated_at = now();
+    END IF;
+END
+$$;
+
+CREATE FUNCTION tasks_refresh_read_models() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND (OLD.project_id, OLD.user_id, OLD.status)
+                            IS DISTINCT FROM (NEW.project_id, NEW.user_id, NEW.status)) THEN
+        PERFORM task_counts_add(OLD.project_id, OLD.user_id, OLD.status, -1);
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+
+    IF TG_OP = 'INSERT' OR (OLD.project_id, OLD.user_id, OLD.status)
+                           IS DISTINCT FROM (NEW.project_id, NEW.user_id, NEW.status) THEN
+        PERFORM task_counts_add(NEW.project_id, NEW.user_id, NEW.status, 1);
+    END IF;
+    IF TG_OP = 'INSERT' OR (OLD.title, OLD.description, OLD.project_id)
+                           IS DISTINCT FROM (NEW.title, NEW.description, NEW.project_id) THEN
+        INSERT INTO task_search (task_id, document)
+        VALUES (NEW.id, task_document(NEW.title, NEW.description, NEW.project_id))
+        ON CONFLICT (task_id) DO UPDATE SET document = EXCLUDED.document;
+    END IF;
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER tasks_read_models
+    AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_refresh_read_models();
+
+-- После переименования проекта документы его задач ищутся по новому имени
+CREATE FUNCTION projects_refresh_task_search() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE task_search s SET document = task_document(t.title, t.description, t.project_id)
+    FROM tasks t
+    WHERE t.id = s.task_id AND t.project_id = NEW.id;
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER projects_task_search
+    AFTER UPDATE OF name ON projects
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION projects_refresh_task_search();
+
+INSERT INTO task_counts (project_id, status, count)
+SELECT project_id, status, count(*) FROM tasks WHERE project_id IS NOT NULL GROUP BY project_id, status;
+
+INSERT INTO task_counts (user_id, status, count)
+SELECT user_id, status, count(*) FROM tasks
+WHERE project_id IS NULL AND user_id IS NOT NULL GROUP BY user_id, status;
+
+INSERT INTO task_search (task_id, document)
+SELECT id, task_document(title, description, project_id) FROM tasks;
@@ -0,0 +1,13 @@
+-- Логические снимки пространства: проекты и их задачи в JSON
+CREATE TABLE workspace_snapshots (
+    id            SERIAL PRIMARY KEY,
+    workspace_id  INTEGER     NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    label         TEXT        NOT NULL DEFAULT '',
+    created_by    INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    project_count INTEGER     NOT NULL,
+    task_count    INTEGER     NOT NULL,
+    state         JSONB       NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX workspace_snapshots_workspace_id_idx ON workspace_snapshots (workspace_id, created_at DESC);
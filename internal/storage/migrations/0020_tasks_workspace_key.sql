@@ -0,0 +1,20 @@
+-- Ключ арендатора у задач: пространство их проекта, NULL у личных задач.
+-- Его заполняет триггер; запросы одного пространства фильтруют по нему и идут
+-- по своему участку индекса, а при секционировании tasks - по своей секции.
+ALTER TABLE tasks ADD COLUMN workspace_id INTEGER REFERENCES workspaces (id) ON DELETE CASCADE;
+
+CREATE FUNCTION tasks_set_workspace() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.workspace_id := (SELECT workspace_id FROM projects WHERE id = NEW.project_id);
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER tasks_workspace
+    BEFORE INSERT OR UPDATE OF project_id ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_set_workspace();
+
+UPDATE tasks t SET workspace_id = p.workspace_id FROM projects p WHERE p.id = t.project_id;
+
+CREATE INDEX tasks_workspace_id_idx ON tasks (workspace_id, project_id, id) WHERE workspace_id IS NOT NULL;
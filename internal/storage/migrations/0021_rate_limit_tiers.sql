@@ -0,0 +1,30 @@
+-- Тарифы ограничения частоты: ровный поток в минуту и допустимый всплеск
+CREATE TABLE rate_limit_tiers (
+    name                TEXT PRIMARY KEY,
+    requests_per_minute INTEGER NOT NULL CHECK (requests_per_minute > 0),
+    burst               INTEGER NOT NULL CHECK (burst > 0)
+);
+
+INSERT INTO rate_limit_tiers (name, requests_per_minute, burst) VALUES
+    ('standard', 120, 30),
+    ('pro', 600, 100),
+    ('enterprise', 3000, 500);
+
+ALTER TABLE users ADD COLUMN rate_limit_tier TEXT NOT NULL DEFAULT 'standard'
+    REFERENCES rate_limit_tiers (name) ON UPDATE CASCADE;
+-- NULL - тариф владельца ключа
+ALTER TABLE api_keys ADD COLUMN rate_limit_tier TEXT
+    REFERENCES rate_limit_tiers (name) ON UPDATE CASCADE ON DELETE SET NULL;
+
+-- Счётчики запросов по суткам для отчёта; api_key_id = 0 - без ключа.
+-- Ссылки на ключ нет: отчёт переживает удаление ключа.
+CREATE TABLE rate_limit_usage (
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    api_key_id INTEGER NOT NULL DEFAULT 0,
+    day        DATE    NOT NULL,
+    requests   BIGINT  NOT NULL DEFAULT 0,
+    limited    BIGINT  NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, api_key_id, day)
+);
+
+CREATE INDEX rate_limit_usage_day_idx ON rate_limit_usage (day);
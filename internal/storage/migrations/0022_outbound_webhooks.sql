@@ -0,0 +1,42 @@
+-- Исходящий вебхук: события задач пользователя уходят POST-запросом на url,
+-- подписанные секретом. events пуст - все типы событий.
+CREATE TABLE outbound_webhooks (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url        TEXT        NOT NULL,
+    events     TEXT[]      NOT NULL DEFAULT '{}',
+    secret     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX outbound_webhooks_user_id_idx ON outbound_webhooks (user_id);
+
+-- Доставка события на один вебхук. failed - попытки кончились (dead letter),
+-- такую доставку можно повторить вручную.
+CREATE TABLE webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      INTEGER     NOT NULL REFERENCES outbound_webhooks (id) ON DELETE CASCADE,
+    event_id        TEXT        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    -- тело храним байтами: подпись считается по нему как есть
+    payload         BYTEA       NOT NULL,
+    status          TEXT        NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ
+);
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id DESC);
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- Каждая попытка доставки: код ответа или ошибка соединения
+CREATE TABLE webhook_delivery_attempts (
+    id           BIGSERIAL PRIMARY KEY,
+    delivery_id  BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempted_at TIMESTAMPTZ NOT NULL,
+    status_code  INTEGER,
+    error        TEXT        NOT NULL DEFAULT '',
+    duration_ms  INTEGER     NOT NULL
+);
+CREATE INDEX webhook_delivery_attempts_delivery_id_idx ON webhook_delivery_attempts (delivery_id);
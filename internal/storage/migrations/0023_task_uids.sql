@@ -0,0 +1,5 @@
+-- Глобальный идентификатор задачи: UUIDv7 выдаёт приложение (или офлайн-
+-- клиент заранее), числовой id остаётся ключом для связей между таблицами.
+-- DEFAULT - для старых строк и прямых вставок вроде seed.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS uid UUID NOT NULL DEFAULT gen_random_uuid();
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_uid_key ON tasks (uid);
@@ -0,0 +1,8 @@
+-- Сравнение заголовков для поиска дублей: как model.NormalizeTitle - нижний
+-- регистр, только буквы и цифры, слова через один пробел
+CREATE OR REPLACE FUNCTION normalize_title(title TEXT) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT btrim(regexp_replace(lower(title), '[^[:alnum:]]+', ' ', 'g'))
+$$;
+
+CREATE INDEX IF NOT EXISTS tasks_open_title_idx ON tasks (normalize_title(title)) WHERE status <> 'done';
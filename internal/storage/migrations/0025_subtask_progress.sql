@@ -0,0 +1,35 @@
+-- Счётчики подзадач у родителя для progress. Их ведёт триггер в той же
+-- транзакции, что и запись подзадачи: UPDATE строки родителя берёт её
+-- блокировку, поэтому одновременные правки подзадач не теряют приращений.
+ALTER TABLE tasks
+    ADD COLUMN subtasks_total INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN subtasks_done  INTEGER NOT NULL DEFAULT 0;
+
+CREATE FUNCTION tasks_refresh_subtask_counts() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND (OLD.parent_id, OLD.status) IS NOT DISTINCT FROM (NEW.parent_id, NEW.status) THEN
+        RETURN NEW;
+    END IF;
+    IF TG_OP <> 'INSERT' AND OLD.parent_id IS NOT NULL THEN
+        UPDATE tasks SET subtasks_total = subtasks_total - 1,
+                         subtasks_done = subtasks_done - (OLD.status = 'done')::int
+        WHERE id = OLD.parent_id;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.parent_id IS NOT NULL THEN
+        UPDATE tasks SET subtasks_total = subtasks_total + 1,
+                         subtasks_done = subtasks_done + (NEW.status = 'done')::int
+        WHERE id = NEW.parent_id;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER tasks_subtask_counts
+    AFTER INSERT OR UPDATE OF parent_id, status OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_refresh_subtask_counts();
+
+UPDATE tasks p SET subtasks_total = c.total, subtasks_done = c.done
+FROM (SELECT parent_id, count(*) AS total, count(*) FILTER (WHERE status = 'done') AS done
+      FROM tasks WHERE parent_id IS NOT NULL GROUP BY parent_id) c
+WHERE p.id = c.parent_id;
@@ -0,0 +1,46 @@
+-- Дельта-синхронизация офлайн-клиентов. У строки tasks - номер транзакции,
+-- изменившей её последней, удаление оставляет надгробие. Токен синхронизации -
+-- xmin снимка, которым читались изменения: всё, что записали транзакции до
+-- него, клиент уже видел. Так изменения не теряются при параллельных записях,
+-- а повторно приходят лишь строки транзакций, шедших во время чтения.
+ALTER TABLE tasks ADD COLUMN changed_xid XID8 NOT NULL DEFAULT '0';
+CREATE INDEX tasks_changed_xid_idx ON tasks (changed_xid);
+
+-- Видимость надгробия - как у задачи в момент удаления
+CREATE TABLE task_tombstones (
+    uid         UUID PRIMARY KEY,
+    task_id     INTEGER     NOT NULL,
+    user_id     INTEGER,
+    project_id  INTEGER,
+    deleted_xid XID8        NOT NULL DEFAULT pg_current_xact_id(),
+    deleted_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX task_tombstones_deleted_xid_idx ON task_tombstones (deleted_xid);
+
+CREATE FUNCTION tasks_track_changes() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO task_tombstones (uid, task_id, user_id, project_id)
+        VALUES (OLD.uid, OLD.id, OLD.user_id, OLD.project_id)
+        ON CONFLICT (uid) DO UPDATE SET task_id = EXCLUDED.task_id, user_id = EXCLUDED.user_id,
+            project_id = EXCLUDED.project_id, deleted_xid = EXCLUDED.deleted_xid, deleted_at = EXCLUDED.deleted_at;
+        RETURN NULL;
+    END IF;
+    -- задача вернулась, например из снимка: надгробие больше не нужно
+    IF TG_OP = 'INSERT' THEN
+        DELETE FROM task_tombstones WHERE uid = NEW.uid;
+    END IF;
+    NEW.changed_xid := pg_current_xact_id();
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER tasks_track_changes
+    BEFORE INSERT OR UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_track_changes();
+
+CREATE TRIGGER tasks_track_deletes
+    AFTER DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_track_changes();
@@ -0,0 +1,13 @@
+-- Конфликт синхронизации: поле задачи изменили и офлайн-клиент, и кто-то
+-- ещё. В задаче остаётся kept, discarded - проигравшее значение, которое
+-- иначе пропало бы молча. Видимость - как у самой задачи.
+CREATE TABLE task_conflicts (
+    id          SERIAL PRIMARY KEY,
+    task_id     INTEGER     NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    field       TEXT        NOT NULL,
+    kept        JSONB       NOT NULL,
+    discarded   JSONB       NOT NULL,
+    actor_id    INTEGER     REFERENCES users (id) ON DELETE SET NULL,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX task_conflicts_task_id_idx ON task_conflicts (task_id);
@@ -0,0 +1,7 @@
+-- Нечёткий поиск по опечаткам, когда нет внешнего движка: триграммные
+-- индексы для операторов word_similarity (<%) по заголовку и описанию.
+-- Порог задаёт запрос через pg_trgm.word_similarity_threshold.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX tasks_title_trgm_idx ON tasks USING GIN (title gin_trgm_ops);
+CREATE INDEX tasks_description_trgm_idx ON tasks USING GIN (description gin_trgm_ops);
@@ -0,0 +1,17 @@
+-- Место задачи: {"lat": .., "lon": .., "label": ".."} в градусах WGS 84.
+-- Поиск поблизости - через earthdistance: task_earth переводит место в точку
+-- на сфере, GiST-индекс по ней отвечает на earth_box.
+CREATE EXTENSION IF NOT EXISTS cube;
+CREATE EXTENSION IF NOT EXISTS earthdistance;
+
+ALTER TABLE tasks ADD COLUMN location JSONB CHECK (location IS NULL OR (
+    jsonb_typeof(location->'lat') = 'number' AND jsonb_typeof(location->'lon') = 'number'
+    AND (location->>'lat')::float8 BETWEEN -90 AND 90
+    AND (location->>'lon')::float8 BETWEEN -180 AND 180));
+
+CREATE FUNCTION task_earth(location JSONB) RETURNS earth
+LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE AS $$
+    SELECT ll_to_earth((location->>'lat')::float8, (location->>'lon')::float8)
+$$;
+
+CREATE INDEX tasks_location_idx ON tasks USING GIST (task_earth(location)) WHERE location IS NOT NULL;
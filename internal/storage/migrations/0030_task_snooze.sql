@@ -0,0 +1,5 @@
+-- Отложенные задачи: до snoozed_until их нет в списке по умолчанию и в
+-- напоминаниях. Фоновая задача снимает истёкшие отсрочки, индекс - для неё.
+ALTER TABLE tasks ADD COLUMN snoozed_until TIMESTAMPTZ;
+
+CREATE INDEX tasks_snoozed_until_idx ON tasks (snoozed_until) WHERE snoozed_until IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Личные отметки пользователя на задачах: у каждого свои закреплённые.
+-- Снятие отметки не удаляет строку, а обновляет updated_at - по нему список
+-- задач понимает, что изменился порядок.
+CREATE TABLE task_flags (
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    task_id    INTEGER     NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    pinned     BOOLEAN     NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, task_id)
+);
+
+CREATE INDEX task_flags_task_id_idx ON task_flags (task_id);
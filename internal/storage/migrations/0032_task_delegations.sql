@@ -0,0 +1,16 @@
+-- Поручения задач другим участникам проекта: у задачи не больше одного.
+-- Исполнитель сдаёт работу (pending_review), поручивший принимает её или
+-- возвращает с комментарием.
+CREATE TABLE task_delegations (
+    task_id      INTEGER     PRIMARY KEY REFERENCES tasks (id) ON DELETE CASCADE,
+    delegator_id INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    delegate_id  INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    state        TEXT        NOT NULL DEFAULT 'delegated'
+                 CHECK (state IN ('delegated', 'pending_review', 'approved', 'rejected')),
+    comment      TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX task_delegations_delegator_idx ON task_delegations (delegator_id);
+CREATE INDEX task_delegations_delegate_idx ON task_delegations (delegate_id);
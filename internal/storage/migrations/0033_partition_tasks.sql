@@ -0,0 +1,170 @@
+-- Секционирование tasks по пространству: 16 hash-секций по workspace_id.
+-- Все задачи пространства лежат в одной секции, запросы с условием по
+-- workspace_id читают только её. У личных задач workspace_id = 0.
+--
+-- Первичный ключ секционированной таблицы обязан включать ключ секций, поэтому
+-- он теперь (id, workspace_id). Внешние ключи других таблиц смотрят в реестр
+-- task_ids: в нём по строке на задачу, его ведут триггеры tasks. Там же
+-- глобальная уникальность uid. Задача при переносе в другое пространство
+-- переезжает в другую секцию (DELETE + INSERT внутри UPDATE), а её строка в
+-- реестре остаётся, поэтому ссылки на задачу не теряются.
+--
+-- Ключ секций нельзя менять в BEFORE INSERT триггере, поэтому при вставке
+-- workspace_id передаёт сам запрос; триггер tasks_workspace лишь проверяет его
+-- и проставляет при смене проекта.
+
+ALTER TABLE tasks RENAME TO tasks_unpartitioned;
+ALTER TABLE tasks_unpartitioned DISABLE TRIGGER USER;
+ALTER TABLE tasks_unpartitioned DROP CONSTRAINT tasks_workspace_id_fkey;
+UPDATE tasks_unpartitioned SET workspace_id = 0 WHERE workspace_id IS NULL;
+
+CREATE TABLE tasks (LIKE tasks_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS)
+    PARTITION BY HASH (workspace_id);
+ALTER TABLE tasks ALTER COLUMN workspace_id SET DEFAULT 0, ALTER COLUMN workspace_id SET NOT NULL;
+
+DO $$
+BEGIN
+    FOR i IN 0..15 LOOP
+        EXECUTE format('CREATE TABLE tasks_p%s PARTITION OF tasks FOR VALUES WITH (MODULUS 16, REMAINDER %s)', i, i);
+    END LOOP;
+END
+$$;
+
+INSERT INTO tasks SELECT * FROM tasks_unpartitioned;
+
+-- Вместе со старой таблицей уходят её индексы, триггеры и внешние ключи
+-- других таблиц на неё; последовательность id остаётся у новой
+ALTER SEQUENCE tasks_id_seq OWNED BY tasks.id;
+DROP TABLE tasks_unpartitioned CASCADE;
+
+CREATE TABLE task_ids (
+    id  INTEGER PRIMARY KEY,
+    uid UUID    NOT NULL,
+    CONSTRAINT tasks_uid_key UNIQUE (uid)
+);
+
+INSERT INTO task_ids (id, uid) SELECT id, uid FROM tasks;
+
+ALTER TABLE tasks
+    ADD PRIMARY KEY (id, workspace_id),
+    ADD FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    ADD FOREIGN KEY (parent_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+
+ALTER TABLE share_links ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+ALTER TABLE github_issues ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+ALTER TABLE jira_issues ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+ALTER TABLE task_search ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+ALTER TABLE task_conflicts ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+ALTER TABLE task_flags ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+ALTER TABLE task_delegations ADD FOREIGN KEY (task_id) REFERENCES task_ids (id) ON DELETE CASCADE;
+
+CREATE INDEX tasks_uid_idx ON tasks (uid);
+CREATE INDEX tasks_parent_id_idx ON tasks (parent_id) WHERE parent_id IS NOT NULL;
+CREATE INDEX tasks_user_id_idx ON tasks (user_id);
+CREATE INDEX tasks_project_id_idx ON tasks (project_id);
+CREATE INDEX tasks_workspace_id_idx ON tasks (workspace_id, project_id, id) WHERE workspace_id <> 0;
+CREATE INDEX tasks_open_title_idx ON tasks (normalize_title(title)) WHERE status <> 'done';
+CREATE INDEX tasks_changed_xid_idx ON tasks (changed_xid);
+CREATE INDEX tasks_title_trgm_idx ON tasks USING GIN (title gin_trgm_ops);
+CREATE INDEX tasks_description_trgm_idx ON tasks USING GIN (description gin_trgm_ops);
+CREATE INDEX tasks_location_idx ON tasks USING GIST (task_earth(location)) WHERE location IS NOT NULL;
+CREATE INDEX tasks_snoozed_until_idx ON tasks (snoozed_until) WHERE snoozed_until IS NOT NULL;
+
+-- Вставка уже идёт в секцию по workspace_id из запроса: расхождение с
+-- проектом - ошибка вызывающего, а не повод молча положить задачу не туда
+CREATE OR REPLACE FUNCTION tasks_set_workspace() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    ws INTEGER := coalesce((SELECT workspace_id FROM projects WHERE id = NEW.project_id), 0);
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.workspace_id <> ws THEN
+        RAISE EXCEPTION 'task % is inserted with workspace_id %, its project is in %', NEW.id, NEW.workspace_id, ws
+            USING ERRCODE = 'check_violation';
+    END IF;
+    NEW.workspace_id := ws;
+    RETURN NEW;
+END
+$$;
+
+-- Переезд задачи между секциями - это удаление и вставка: вставка застаёт
+-- строку реестра на месте, удаление её не трогает. Другая задача с тем же id
+-- на месте строки реестра - нарушение ключа.
+CREATE FUNCTION tasks_register() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO task_ids (id, uid) VALUES (NEW.id, NEW.uid)
+        ON CONFLICT (id) DO UPDATE SET uid = EXCLUDED.uid WHERE task_ids.uid = EXCLUDED.uid;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'task % already exists', NEW.id
+                USING ERRCODE = 'unique_violation', CONSTRAINT = 'tasks_pkey';
+        END IF;
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'UPDATE' THEN
+        IF NEW.uid <> OLD.uid THEN
+            UPDATE task_ids SET uid = NEW.uid WHERE id = NEW.id;
+        END IF;
+        RETURN NEW;
+    END IF;
+    -- AFTER DELETE: после переезда задача уже лежит в новой секции
+    IF NOT EXISTS (SELECT 1 FROM tasks WHERE id = OLD.id) THEN
+        DELETE FROM task_ids WHERE id = OLD.id;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+-- Надгробие - только настоящему удалению, а не переезду в другую секцию
+CREATE OR REPLACE FUNCTION tasks_track_changes() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF EXISTS (SELECT 1 FROM tasks WHERE id = OLD.id) THEN
+            RETURN NULL;
+        END IF;
+        INSERT INTO task_tombstones (uid, task_id, user_id, project_id)
+        VALUES (OLD.uid, OLD.id, OLD.user_id, OLD.project_id)
+        ON CONFLICT (uid) DO UPDATE SET task_id = EXCLUDED.task_id, user_id = EXCLUDED.user_id,
+            project_id = EXCLUDED.project_id, deleted_xid = EXCLUDED.deleted_xid, deleted_at = EXCLUDED.deleted_at;
+        RETURN NULL;
+    END IF;
+    -- задача вернулась, например из снимка: надгробие больше не нужно
+    IF TG_OP = 'INSERT' THEN
+        DELETE FROM task_tombstones WHERE uid = NEW.uid;
+    END IF;
+    NEW.changed_xid := pg_current_xact_id();
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER tasks_workspace
+    BEFORE INSERT OR UPDATE OF project_id ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_set_workspace();
+
+CREATE TRIGGER tasks_register
+    BEFORE INSERT OR UPDATE OF uid ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_register();
+
+CREATE TRIGGER tasks_unregister
+    AFTER DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_register();
+
+CREATE TRIGGER tasks_read_models
+    AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_refresh_read_models();
+
+CREATE TRIGGER tasks_subtask_counts
+    AFTER INSERT OR UPDATE OF parent_id, status OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_refresh_subtask_counts();
+
+CREATE TRIGGER tasks_track_changes
+    BEFORE INSERT OR UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_track_changes();
+
+CREATE TRIGGER tasks_track_deletes
+    AFTER DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_track_changes();
+
+ANALYZE tasks;
@@ -0,0 +1,23 @@
+-- Смена счётчиков подзадач меняет progress родителя, поэтому должна менять и
+-- его updated_at: иначе If-Modified-Since отдаёт 304 с устаревшим progress.
+CREATE OR REPLACE FUNCTION tasks_refresh_subtask_counts() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND (OLD.parent_id, OLD.status) IS NOT DISTINCT FROM (NEW.parent_id, NEW.status) THEN
+        RETURN NEW;
+    END IF;
+    IF TG_OP <> 'INSERT' AND OLD.parent_id IS NOT NULL THEN
+        UPDATE tasks SET subtasks_total = subtasks_total - 1,
+                         subtasks_done = subtasks_done - (OLD.status = 'done')::int,
+                         updated_at = now()
+        WHERE id = OLD.parent_id;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.parent_id IS NOT NULL THEN
+        UPDATE tasks SET subtasks_total = subtasks_total + 1,
+                         subtasks_done = subtasks_done + (NEW.status = 'done')::int,
+                         updated_at = now()
+        WHERE id = NEW.parent_id;
+    END IF;
+    RETURN NULL;
+END
+$$;
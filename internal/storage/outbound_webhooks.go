@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// ErrLeaseLost - срок, на который доставка была забрана, истёк, и её
+// забрал другой экземпляр или пользователь поставил её в очередь заново
+var ErrLeaseLost = errors.New("webhook delivery lease lost")
+
+// OutboundWebhookStore - исходящие вебхуки и очередь их доставок
+type OutboundWebhookStore interface {
+	CreateOutboundWebhook(ctx context.Context, hook *model.OutboundWebhook) error
+	ListOutboundWebhooks(ctx context.Context, userID int) ([]model.OutboundWebhook, error)
+	DeleteOutboundWebhook(ctx context.Context, userID, id int) error
+
+	// EnqueueWebhookDeliveries ставит событие в очередь каждому вебхуку
+	// пользователя, подписанному на eventType
+	EnqueueWebhookDeliveries(ctx context.Context, userID int, eventID, eventType string, payload []byte) error
+	// ClaimWebhookDeliveries забирает до limit доставок, срок которых наступил
+	// к now, и откладывает их до leaseUntil, чтобы другой экземпляр не
+	// отправил их одновременно
+	ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]DueDelivery, error)
+	// RecordWebhookAttempt сохраняет попытку и новый статус доставки, если
+	// она всё ещё отложена до leaseUntil, иначе возвращает ErrLeaseLost;
+	// nextAttemptAt - когда повторить, nil - больше не повторять
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, leaseUntil time.Time, attempt model.WebhookAttempt,
+		status string, nextAttemptAt *time.Time) error
+
+	ListWebhookDeliveries(ctx context.Context, userID, webhookID int, status string, limit int) ([]model.WebhookDelivery, error)
+	WebhookDelivery(ctx context.Context, userID, webhookID int, id int64) (model.WebhookDelivery, error)
+	// RedeliverWebhook возвращает доставку в очередь с новым циклом попыток
+	RedeliverWebhook(ctx context.Context, userID, webhookID int, id int64) error
+}
+
+// DueDelivery - доставка вместе с адресом и секретом её вебхука
+type DueDelivery struct {
+	model.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row pgx.Row, d *model.WebhookDelivery, extra ...any) error {
+	var payload []byte
+	err := row.Scan(append([]any{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.DeliveredAt}, extra...)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	d.Payload = payload
+	return err
+}
+
+func (s *Postgres) CreateOutboundWebhook(ctx context.Context, hook *model.OutboundWebhook) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO outbound_webhooks (user_id, url, events, secret) VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		hook.UserID, hook.URL, hook.Events, hook.Secret,
+	).Scan(&hook.ID, &hook.CreatedAt)
+}
+
+func (s *Postgres) ListOutboundWebhooks(ctx context.Context, userID int) ([]model.OutboundWebhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT id, user_id, url, events, created_at FROM outbound_webhooks WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.OutboundWebhook, error) {
+		var h model.OutboundWebhook
+		err := row.Scan(&h.ID, &h.UserID, &h.URL, &h.Events, &h.CreatedAt)
+		return h, err
+	})
+}
+
+func (s *Postgres) DeleteOutboundWebhook(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM outbound_webhooks WHERE user_id = $1 AND id = $2", userID, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) EnqueueWebhookDeliveries(ctx context.Context, userID int, eventID, eventType string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at)
+		 SELECT id, $2, $3, $4, now() FROM outbound_webhooks
+		 WHERE user_id = $1 AND (cardinality(events) = 0 OR $3 = ANY (events))`,
+		userID, eventID, eventType, payload)
+	return err
+}
+
+func (s *Postgres) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]DueDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`UPDATE webhook_deliveries d SET next_attempt_at = $2
+		 FROM outbound_webhooks w
+		 WHERE w.id = d.webhook_id AND d.id IN (
+		     SELECT id FROM webhook_deliveries
+		     WHERE status = 'pending' AND next_attempt_at <= $1
+		     ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+		 RETURNING `+deliveryColumns+`, w.url, w.secret`,
+		now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (DueDelivery, error) {
+		var d DueDelivery
+		err := scanDelivery(row, &d.WebhookDelivery, &d.URL, &d.Secret)
+		return d, err
+	})
+}
+
+func (s *Postgres) RecordWebhookAttempt(ctx context.Context, deliveryID int64, leaseUntil time.Time, attempt model.WebhookAttempt,
+	status string, nextAttemptAt *time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE webhook_deliveries SET attempts = attempts + 1, status = $2, next_attempt_at = $3,
+			     last_error = $4, delivered_at = CASE WHEN $2 = 'delivered' THEN $5 END
+			 WHERE id = $1 AND status = 'pending' AND next_attempt_at = $6`,
+			deliveryID, status, nextAttemptAt, attempt.Error, attempt.AttemptedAt, leaseUntil)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrLeaseLost
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			deliveryID, attempt.AttemptedAt, attempt.StatusCode, attempt.Error, attempt.DurationMS)
+		return err
+	})
+}
+
+func (s *Postgres) ListWebhookDeliveries(ctx context.Context, userID, webhookID int, status string, limit int) ([]model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries d
+		 JOIN outbound_webhooks w ON w.id = d.webhook_id
+		 WHERE w.user_id = $1 AND d.webhook_id = $2 AND ($3 = '' OR d.status = $3)
+		 ORDER BY d.id DESC LIMIT $4`,
+		userID, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.WebhookDelivery, error) {
+		var d model.WebhookDelivery
+		err := scanDelivery(row, &d)
+		return d, err
+	})
+}
+
+func (s *Postgres) WebhookDelivery(ctx context.Context, userID, webhookID int, id int64) (model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var d model.WebhookDelivery
+	err := scanDelivery(s.pool.QueryRow(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries d
+		 JOIN outbound_webhooks w ON w.id = d.webhook_id
+		 WHERE w.user_id = $1 AND d.webhook_id = $2 AND d.id = $3`,
+		userID, webhookID, id), &d)
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT attempted_at, status_code, error, duration_ms FROM webhook_delivery_attempts
+		 WHERE delivery_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return d, err
+	}
+	d.History, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.WebhookAttempt, error) {
+		var a model.WebhookAttempt
+		err := row.Scan(&a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMS)
+		return a, err
+	})
+	return d, err
+}
+
+func (s *Postgres) RedeliverWebhook(ctx context.Context, userID, webhookID int, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE webhook_deliveries d SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+		 FROM outbound_webhooks w
+		 WHERE w.id = d.webhook_id AND w.user_id = $1 AND d.webhook_id = $2 AND d.id = $3`,
+		userID, webhookID, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
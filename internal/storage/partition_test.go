@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// TestTasksPartitionedByWorkspace проверяет триггеры секционированной tasks
+// на настоящей базе: TEST_DATABASE_URL, например
+// postgres://postgres@localhost/todo_test. Без неё тест пропускается.
+func TestTasksPartitionedByWorkspace(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := Connect(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if err := Migrate(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	pg := NewPostgres(pool)
+
+	user := model.User{Email: fmt.Sprintf("partition-%d@example.com", time.Now().UnixNano()), Role: model.RoleUser}
+	if err := pg.CreateUser(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, "DELETE FROM users WHERE id = $1", user.ID) })
+	owner := auth.WithPrincipal(ctx, &auth.Principal{UserID: user.ID, Scheme: auth.SchemeJWT})
+
+	var projects [2]model.Project
+	for i := range projects {
+		ws := model.Workspace{Name: fmt.Sprintf("Partition test %d", i)}
+		if err := pg.CreateWorkspace(ctx, &ws, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pool.Exec(ctx, "DELETE FROM workspaces WHERE id = $1", ws.ID) })
+		projects[i] = model.Project{WorkspaceID: ws.ID, Name: "Board"}
+		if err := pg.CreateProject(ctx, &projects[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	workspaceOf := func(id int) int {
+		t.Helper()
+		var ws int
+		if err := pool.QueryRow(ctx, "SELECT workspace_id FROM tasks WHERE id = $1", id).Scan(&ws); err != nil {
+			t.Fatal(err)
+		}
+		return ws
+	}
+
+	personal := &model.Task{Title: "Personal", Status: model.StatusTodo}
+	if err := pg.CreateTask(owner, personal); err != nil {
+		t.Fatal(err)
+	}
+	if ws := workspaceOf(personal.ID); ws != 0 {
+		t.Errorf("personal task workspace_id = %d, want 0", ws)
+	}
+
+	task := &model.Task{Title: "Shared", Status: model.StatusTodo, ProjectID: &projects[0].ID}
+	if err := pg.CreateTask(owner, task); err != nil {
+		t.Fatal(err)
+	}
+	if ws := workspaceOf(task.ID); ws != projects[0].WorkspaceID {
+		t.Errorf("project task workspace_id = %d, want %d", ws, projects[0].WorkspaceID)
+	}
+	if err := pg.SetTaskPinned(owner, task.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Перенос в проект другого пространства переносит строку в его секцию;
+	// ссылки на задачу остаются, надгробия нет
+	task.ProjectID = &projects[1].ID
+	if err := pg.UpdateTask(owner, task); err != nil {
+		t.Fatal(err)
+	}
+	if ws := workspaceOf(task.ID); ws != projects[1].WorkspaceID {
+		t.Errorf("moved task workspace_id = %d, want %d", ws, projects[1].WorkspaceID)
+	}
+	var rows, flags, tombstones int
+	if err := pool.QueryRow(ctx, `SELECT (SELECT count(*) FROM tasks WHERE id = $1),
+			(SELECT count(*) FROM task_flags WHERE task_id = $1),
+			(SELECT count(*) FROM task_tombstones WHERE task_id = $1)`, task.ID).Scan(&rows, &flags, &tombstones); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 || flags != 1 || tombstones != 0 {
+		t.Errorf("after move: %d rows, %d flags, %d tombstones; want 1, 1, 0", rows, flags, tombstones)
+	}
+
+	// uid уникален во всех секциях
+	dup := &model.Task{Title: "Duplicate", Status: model.StatusTodo, UID: task.UID}
+	if err := pg.CreateTask(owner, dup); !errors.Is(err, ErrTaskUIDTaken) {
+		t.Errorf("duplicate uid in another workspace: err = %v, want ErrTaskUIDTaken", err)
+	}
+
+	if err := pg.DeleteTask(owner, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	var registered bool
+	if err := pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM task_ids WHERE id = $1),
+			(SELECT count(*) FROM task_flags WHERE task_id = $1),
+			(SELECT count(*) FROM task_tombstones WHERE task_id = $1)`, task.ID).Scan(&registered, &flags, &tombstones); err != nil {
+		t.Fatal(err)
+	}
+	if registered || flags != 0 || tombstones != 1 {
+		t.Errorf("after delete: registered %v, %d flags, %d tombstones; want false, 0, 1", registered, flags, tombstones)
+	}
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Таймаут на запрос, включая ожидание соединения из пула
+const queryTimeout = 5 * time.Second
+
+const taskColumns = `id, uid::text, user_id, parent_id, project_id, title, description, status, due_at, location, snoozed_until,
+	completed_at, created_at, updated_at, subtasks_total, subtasks_done`
+
+// Видимость задач: свои вне проектов и задачи доступных проектов;
+// $owner IS NULL только у auth.System. Доступ к задаче в проекте всегда
+// решает проект, даже для её автора - иначе приватный проект протекает.
+const ownerFilter = `($1::int IS NULL OR (project_id IS NULL AND user_id = $1)
+	OR project_id IN (SELECT accessible_projects($1)))`
+
+// writeFilter - как ownerFilter, но без проектов, где пользователь только viewer
+const writeFilter = `($1::int IS NULL OR (project_id IS NULL AND user_id = $1)
+	OR project_id IN (SELECT writable_projects($1)))`
+
+type Postgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) *Postgres {
+	return &Postgres{pool: pool}
+}
+
+// Connect открывает пул соединений с PostgreSQL
+func Connect(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	config.MaxConns = 20
+	config.MinConns = 5
+	config.HealthCheckPeriod = 1 * time.Minute
+	config.MaxConnLifetime = 2 * time.Hour
+
+	return pgxpool.NewWithConfig(ctx, config)
+}
+
+func scanTask(row pgx.Row, t *model.Task) error {
+	var total, done int
+	err := row.Scan(&t.ID, &t.UID, &t.OwnerID, &t.ParentID, &t.ProjectID, &t.Title, &t.Description, &t.Status,
+		&t.DueAt, &t.Location, &t.SnoozedUntil, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt, &total, &done)
+	t.SetProgress(total, done)
+	return err
+}
+
+func (s *Postgres) CreateTask(ctx context.Context, task *model.Task) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := s.checkProject(ctx, owner, task.ProjectID); err != nil {
+		return err
+	}
+
+	task.OwnerID = owner
+	query := `INSERT INTO tasks (user_id, parent_id, project_id, title, description, status, completed_at, due_at, uid,
+	                             location, workspace_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE(NULLIF($9, '')::uuid, gen_random_uuid()), $10,
+	                  ` + taskWorkspace("$3") + `)
+	          RETURNING id, uid::text, created_at, updated_at`
+	err = s.pool.QueryRow(ctx, query,
+		owner, task.ParentID, task.ProjectID, task.Title, task.Description, task.Status, task.CompletedAt, task.DueAt, task.UID,
+		task.Location).
+		Scan(&task.ID, &task.UID, &task.CreatedAt, &task.UpdatedAt)
+	return uidTaken(err)
+}
+
+// taskWorkspace - пространство задачи с проектом из параметра param, 0 у
+// личной. tasks секционирована по workspace_id, и секцию для новой строки
+// выбирает сам INSERT - триггер только сверяет её с проектом.
+func taskWorkspace(param string) string {
+	return "coalesce((SELECT workspace_id FROM projects WHERE id = " + param + "), 0)"
+}
+
+// uidTaken переводит нарушение уникальности uid в ErrTaskUIDTaken
+func uidTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "tasks_uid_key" {
+		return ErrTaskUIDTaken
+	}
+	return err
+}
+
+// checkProject проверяет, что задачу можно положить в проект projectID
+func (s *Postgres) checkProject(ctx context.Context, owner, projectID *int) error {
+	if projectID == nil || owner == nil {
+		return nil
+	}
+	var role *string
+	err := s.pool.QueryRow(ctx, "SELECT project_role($1, $2)", *owner, *projectID).Scan(&role)
+	if err != nil {
+		return err
+	}
+	switch {
+	case role == nil:
+		return ErrInvalidProject
+	case *role == "viewer":
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// readOnlyOr отличает задачу, которую пользователь видит, но не может менять,
+// от отсутствующей: возвращает ErrReadOnly или notFound
+func (s *Postgres) readOnlyOr(ctx context.Context, owner *int, id int, notFound error) error {
+	var visible bool
+	err := s.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tasks WHERE "+ownerFilter+" AND id = $2)", owner, id).Scan(&visible)
+	if err != nil {
+		return err
+	}
+	if visible {
+		return ErrReadOnly
+	}
+	return notFound
+}
+
+func (s *Postgres) ListTasks(ctx context.Context) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+taskColumns+" FROM tasks WHERE "+ownerFilter, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	for rows.Next() {
+		var t model.Task
+		if err := scanTask(rows, &t); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *Postgres) GetTask(ctx context.Context, id int) (model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var task model.Task
+	err = scanTask(s.pool.QueryRow(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE "+ownerFilter+" AND id = $2", owner, id), &task)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Task{}, ErrNotFound
+	}
+	return task, err
+}
+
+func (s *Postgres) GetTasks(ctx context.Context, ids []int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE "+ownerFilter+" AND id = ANY($2)", owner, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	for rows.Next() {
+		var t model.Task
+		if err := scanTask(rows, &t); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *Postgres) TaskIDByUID(ctx context.Context, uid string) (int, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var id int
+	err = s.pool.QueryRow(ctx, "SELECT id FROM tasks WHERE "+ownerFilter+" AND uid = $2::uuid", owner, uid).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (s *Postgres) UpdateTask(ctx context.Context, task *model.Task) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if err := s.checkProject(ctx, owner, task.ProjectID); err != nil {
+		return err
+	}
+
+	query := `UPDATE tasks SET parent_id=$2, project_id=$8, title=$3, description=$4, status=$5, completed_at=$6, due_at=$9,
+	          location=$10, updated_at=now()
+	          WHERE ` + writeFilter + ` AND id=$7 RETURNING user_id, created_at, updated_at`
+	err = s.pool.QueryRow(ctx, query,
+		owner, task.ParentID, task.Title, task.Description, task.Status, task.CompletedAt, task.ID, task.ProjectID, task.DueAt,
+		task.Location).
+		Scan(&task.OwnerID, &task.CreatedAt, &task.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.readOnlyOr(ctx, owner, task.ID, ErrNotFound)
+	}
+	return err
+}
+
+func (s *Postgres) DeleteTask(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM tasks WHERE "+writeFilter+" AND id=$2", owner, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return s.readOnlyOr(ctx, owner, id, nil)
+	}
+	return err
+}
+
+func (s *Postgres) CountOpenSubtasks(ctx context.Context, id int) (int, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var n int
+	err = s.pool.QueryRow(ctx,
+		"SELECT count(*) FROM tasks WHERE "+ownerFilter+" AND parent_id = $2 AND status <> 'done'",
+		owner, id).Scan(&n)
+	return n, err
+}
+
+func (s *Postgres) SimilarOpenTasks(ctx context.Context, title string, projectID *int, limit int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+taskColumns+" FROM tasks WHERE "+ownerFilter+`
+		AND status <> 'done' AND project_id IS NOT DISTINCT FROM $3
+		AND normalize_title(title) = normalize_title($2)
+		ORDER BY updated_at DESC LIMIT $4`, owner, title, projectID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+}
+
+func (s *Postgres) CountActiveTasks(ctx context.Context) (int, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var n int
+	err = s.pool.QueryRow(ctx,
+		"SELECT count(*) FROM tasks WHERE user_id = $1 AND status <> 'done'", owner).Scan(&n)
+	return n, err
+}
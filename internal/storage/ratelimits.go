@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// ErrUnknownTier - тарифа с таким именем нет
+var ErrUnknownTier = errors.New("unknown rate limit tier")
+
+// RateLimitStore - тарифы ограничения частоты, их назначение и учёт запросов
+type RateLimitStore interface {
+	RateLimitTier(ctx context.Context, userID, apiKeyID int) (model.RateLimitTier, error)
+	AddRateLimitUsage(ctx context.Context, usage []model.RateLimitUsage) error
+
+	ListRateLimitTiers(ctx context.Context) ([]model.RateLimitTier, error)
+	// SaveRateLimitTier создаёт тариф или меняет лимиты существующего
+	SaveRateLimitTier(ctx context.Context, tier model.RateLimitTier) error
+	SetUserRateLimitTier(ctx context.Context, userID int, tier string) error
+	// SetAPIKeyRateLimitTier; пустой tier - ключ снова живёт по тарифу владельца
+	SetAPIKeyRateLimitTier(ctx context.Context, keyID int, tier string) error
+	// RateLimitUsage - счётчики с суток since, новые и самые активные первыми
+	RateLimitUsage(ctx context.Context, since time.Time) ([]model.RateLimitUsage, error)
+}
+
+func (s *Postgres) RateLimitTier(ctx context.Context, userID, apiKeyID int) (model.RateLimitTier, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var t model.RateLimitTier
+	err := s.pool.QueryRow(ctx,
+		`SELECT t.name, t.requests_per_minute, t.burst FROM users u
+		 LEFT JOIN api_keys k ON k.id = $2 AND k.user_id = u.id
+		 JOIN rate_limit_tiers t ON t.name = coalesce(k.rate_limit_tier, u.rate_limit_tier)
+		 WHERE u.id = $1`, userID, apiKeyID).Scan(&t.Name, &t.RequestsPerMinute, &t.Burst)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// AddRateLimitUsage пропускает счётчики уже удалённых пользователей
+func (s *Postgres) AddRateLimitUsage(ctx context.Context, usage []model.RateLimitUsage) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		for _, u := range usage {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO rate_limit_usage (user_id, api_key_id, day, requests, limited)
+				 SELECT id, $2, $3, $4, $5 FROM users WHERE id = $1
+				 ON CONFLICT (user_id, api_key_id, day) DO UPDATE
+				 SET requests = rate_limit_usage.requests + EXCLUDED.requests,
+				     limited = rate_limit_usage.limited + EXCLUDED.limited`,
+				u.UserID, u.APIKeyID, u.Day, u.Requests, u.Limited); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Postgres) ListRateLimitTiers(ctx context.Context) ([]model.RateLimitTier, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT name, requests_per_minute, burst FROM rate_limit_tiers ORDER BY requests_per_minute, name")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.RateLimitTier, error) {
+		var t model.RateLimitTier
+		err := row.Scan(&t.Name, &t.RequestsPerMinute, &t.Burst)
+		return t, err
+	})
+}
+
+func (s *Postgres) SaveRateLimitTier(ctx context.Context, tier model.RateLimitTier) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO rate_limit_tiers (name, requests_per_minute, burst) VALUES ($1, $2, $3)
+		 ON CONFLICT (name) DO UPDATE SET requests_per_minute = EXCLUDED.requests_per_minute, burst = EXCLUDED.burst`,
+		tier.Name, tier.RequestsPerMinute, tier.Burst)
+	return err
+}
+
+func (s *Postgres) SetUserRateLimitTier(ctx context.Context, userID int, tier string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "UPDATE users SET rate_limit_tier = $2 WHERE id = $1", userID, tier)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return mapUnknownTier(err)
+}
+
+func (s *Postgres) SetAPIKeyRateLimitTier(ctx context.Context, keyID int, tier string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE api_keys SET rate_limit_tier = nullif($2, '') WHERE id = $1", keyID, tier)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return mapUnknownTier(err)
+}
+
+func mapUnknownTier(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrUnknownTier
+	}
+	return err
+}
+
+func (s *Postgres) RateLimitUsage(ctx context.Context, since time.Time) ([]model.RateLimitUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT r.user_id, u.email, r.api_key_id, coalesce(k.rate_limit_tier, u.rate_limit_tier),
+		        r.day, r.requests, r.limited
+		 FROM rate_limit_usage r
+		 JOIN users u ON u.id = r.user_id
+		 LEFT JOIN api_keys k ON k.id = r.api_key_id
+		 WHERE r.day >= $1::date
+		 ORDER BY r.day DESC, r.requests DESC`, since)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.RateLimitUsage, error) {
+		var u model.RateLimitUsage
+		err := row.Scan(&u.UserID, &u.Email, &u.APIKeyID, &u.Tier, &u.Day, &u.Requests, &u.Limited)
+		return u, err
+	})
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Личные задачи пользователя и задачи доступных ему проектов в task_counts
+const countsFilter = `($1::int IS NULL OR user_id = $1 OR project_id IN (SELECT accessible_projects($1)))`
+
+func (s *Postgres) TaskStats(ctx context.Context) (model.TaskStats, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.TaskStats{}, err
+	}
+	return s.taskStats(ctx, "SELECT status, count, updated_at FROM task_counts WHERE "+countsFilter, owner)
+}
+
+// ProjectTaskStats не проверяет доступ к проекту: это делает вызывающий
+func (s *Postgres) ProjectTaskStats(ctx context.Context, projectID int) (model.TaskStats, error) {
+	return s.taskStats(ctx, "SELECT status, count, updated_at FROM task_counts WHERE project_id = $1", projectID)
+}
+
+func (s *Postgres) taskStats(ctx context.Context, query string, arg any) (model.TaskStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, query, arg)
+	if err != nil {
+		return model.TaskStats{}, err
+	}
+	defer rows.Close()
+
+	var stats model.TaskStats
+	for rows.Next() {
+		var (
+			status  string
+			n       int
+			updated time.Time
+		)
+		if err := rows.Scan(&status, &n, &updated); err != nil {
+			return model.TaskStats{}, err
+		}
+		stats.Add(status, n)
+		if stats.UpdatedAt == nil || updated.After(*stats.UpdatedAt) {
+			stats.UpdatedAt = &updated
+		}
+	}
+	return stats, rows.Err()
+}
+
+func (s *Postgres) SearchTasks(ctx context.Context, query string, limit int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT `+taskColumns+`
+		FROM tasks JOIN task_search ON task_search.task_id = tasks.id,
+		     websearch_to_tsquery('simple', $2) q
+		WHERE `+ownerFilter+` AND document @@ q
+		ORDER BY ts_rank(document, q) DESC, id DESC
+		LIMIT $3`, owner, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+}
+
+// FuzzySearchTasks задаёт порог на время транзакции, чтобы <% шёл по
+// триграммным индексам; сравнение с порогом в WHERE индексы бы не использовало
+func (s *Postgres) FuzzySearchTasks(ctx context.Context, query string, threshold float64, limit int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var tasks []model.Task
+	err = pgx.BeginTxFunc(ctx, s.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+			strconv.FormatFloat(threshold, 'f', -1, 64)); err != nil {
+			return err
+		}
+		rows, err := tx.Query(ctx, `SELECT `+taskColumns+` FROM tasks
+			WHERE `+ownerFilter+` AND ($2 <% title OR $2 <% description)
+			ORDER BY greatest(word_similarity($2, title), word_similarity($2, description)) DESC, id DESC
+			LIMIT $3`, owner, query, limit)
+		if err != nil {
+			return err
+		}
+		tasks, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+			var t model.Task
+			err := scanTask(row, &t)
+			return t, err
+		})
+		return err
+	})
+	return tasks, err
+}
+
+func (s *Postgres) ListBoardTasks(ctx context.Context, projectID, perColumn int) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT `+taskColumns+` FROM (
+			SELECT *, row_number() OVER (PARTITION BY status ORDER BY updated_at DESC, id DESC) AS n
+			FROM tasks WHERE `+ownerFilter+` AND workspace_id = `+projectWorkspace+` AND project_id = $2
+		) t WHERE n <= $3 ORDER BY id`, owner, projectID, perColumn)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// ErrRefreshTokenReused - предъявлен уже заменённый refresh-токен;
+// сессия отозвана, так как токен, вероятно, украден
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// SessionStore - сессии устройств с cookie и refresh-токенами
+type SessionStore interface {
+	CreateSession(ctx context.Context, sess *model.Session, tokenHash, refreshHash string) error
+	PrincipalBySession(ctx context.Context, tokenHash string) (*auth.Principal, error)
+	// RotateRefreshToken заменяет refresh-токен и продлевает сессию до expiresAt
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (model.Session, error)
+	ListSessions(ctx context.Context, userID int) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID, id int) error
+}
+
+func (s *Postgres) CreateSession(ctx context.Context, sess *model.Session, tokenHash, refreshHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO sessions (user_id, token_hash, refresh_token_hash, user_agent, expires_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, last_used_at`,
+		sess.UserID, tokenHash, refreshHash, sess.UserAgent, sess.ExpiresAt).
+		Scan(&sess.ID, &sess.CreatedAt, &sess.LastUsedAt)
+}
+
+func (s *Postgres) PrincipalBySession(ctx context.Context, tokenHash string) (*auth.Principal, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var p auth.Principal
+	err := s.pool.QueryRow(ctx,
+		`SELECT u.id, u.email, u.role, s.id FROM sessions s JOIN users u ON u.id = s.user_id
+		 WHERE s.token_hash = $1 AND s.expires_at > now() AND s.revoked_at IS NULL`,
+		tokenHash).Scan(&p.UserID, &p.Email, &p.Role, &p.SessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &p, err
+}
+
+func (s *Postgres) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (model.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var sess model.Session
+	err := s.pool.QueryRow(ctx,
+		`UPDATE sessions SET previous_refresh_hash = refresh_token_hash, refresh_token_hash = $2,
+		        last_used_at = now(), expires_at = $3
+		 WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > now()
+		 RETURNING id, user_id, user_agent, created_at, last_used_at, expires_at`,
+		oldHash, newHash, expiresAt).
+		Scan(&sess.ID, &sess.UserID, &sess.UserAgent, &sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return sess, err
+	}
+
+	// Старый токен после ротации - отзываем всю сессию
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE sessions SET revoked_at = now()
+		 WHERE previous_refresh_hash = $1 AND revoked_at IS NULL`, oldHash)
+	if err != nil {
+		return model.Session{}, err
+	}
+	if tag.RowsAffected() > 0 {
+		return model.Session{}, ErrRefreshTokenReused
+	}
+	return model.Session{}, ErrNotFound
+}
+
+func (s *Postgres) ListSessions(ctx context.Context, userID int) ([]model.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, user_id, user_agent, created_at, last_used_at, expires_at FROM sessions
+		 WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		 ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Session, error) {
+		var sess model.Session
+		err := row.Scan(&sess.ID, &sess.UserID, &sess.UserAgent, &sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt)
+		return sess, err
+	})
+}
+
+func (s *Postgres) RevokeSession(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE sessions SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// ShareStore - публичные ссылки на задачи и проекты
+type ShareStore interface {
+	CreateShareLink(ctx context.Context, link *model.ShareLink, tokenHash string) error
+	ListShareLinks(ctx context.Context, userID int) ([]model.ShareLink, error)
+	RevokeShareLink(ctx context.Context, userID, id int) error
+	// ShareLinkByToken - действующая (не отозванная и не истёкшая) ссылка
+	ShareLinkByToken(ctx context.Context, tokenHash string) (model.ShareLink, error)
+}
+
+const shareColumns = `id, user_id, task_id, project_id, created_at, expires_at`
+
+const activeShare = `revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())`
+
+func scanShareLink(row pgx.Row, l *model.ShareLink) error {
+	err := row.Scan(&l.ID, &l.UserID, &l.TaskID, &l.ProjectID, &l.CreatedAt, &l.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) CreateShareLink(ctx context.Context, link *model.ShareLink, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO share_links (user_id, task_id, project_id, token_hash, expires_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		link.UserID, link.TaskID, link.ProjectID, tokenHash, link.ExpiresAt).Scan(&link.ID, &link.CreatedAt)
+}
+
+func (s *Postgres) ListShareLinks(ctx context.Context, userID int) ([]model.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+shareColumns+" FROM share_links WHERE user_id = $1 AND "+activeShare+" ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.ShareLink, error) {
+		var l model.ShareLink
+		err := scanShareLink(row, &l)
+		return l, err
+	})
+}
+
+func (s *Postgres) RevokeShareLink(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE share_links SET revoked_at = now() WHERE user_id = $1 AND id = $2 AND "+activeShare, userID, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) ShareLinkByToken(ctx context.Context, tokenHash string) (model.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var l model.ShareLink
+	err := scanShareLink(s.pool.QueryRow(ctx,
+		"SELECT "+shareColumns+" FROM share_links WHERE token_hash = $1 AND "+activeShare, tokenHash), &l)
+	return l, err
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// SnapshotStore - снимки пространства и восстановление из них. Права не
+// проверяет: это делает сервис, пространство целиком видно только admin.
+type SnapshotStore interface {
+	// WorkspaceState - текущие проекты пространства и их задачи
+	WorkspaceState(ctx context.Context, workspaceID int) (model.WorkspaceState, error)
+	CreateSnapshot(ctx context.Context, snap *model.Snapshot) error
+	// ListSnapshots - снимки без State, новые первыми
+	ListSnapshots(ctx context.Context, workspaceID int) ([]model.Snapshot, error)
+	// GetSnapshot - снимок вместе с State
+	GetSnapshot(ctx context.Context, workspaceID, id int) (model.Snapshot, error)
+	// RestoreWorkspace возвращает проекты и задачи пространства к state. Проекты,
+	// которых нет в state, и их задачи не трогает.
+	RestoreWorkspace(ctx context.Context, workspaceID int, state model.WorkspaceState) (model.RestoreResult, error)
+}
+
+// PointInTimeStore - состояние пространства на прошлый момент по журналу
+// task_events; есть только у EventStore
+type PointInTimeStore interface {
+	WorkspaceStateAt(ctx context.Context, workspaceID int, at time.Time) (model.WorkspaceState, error)
+}
+
+const snapshotColumns = `id, workspace_id, label, created_by, project_count, task_count, created_at`
+
+func (s *Postgres) WorkspaceState(ctx context.Context, workspaceID int) (model.WorkspaceState, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	projects, err := s.workspaceProjects(ctx, workspaceID)
+	if err != nil {
+		return model.WorkspaceState{}, err
+	}
+	rows, err := s.pool.Query(ctx, "SELECT "+taskColumns+" FROM tasks WHERE workspace_id = $1 ORDER BY id", workspaceID)
+	if err != nil {
+		return model.WorkspaceState{}, err
+	}
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+	if err != nil {
+		return model.WorkspaceState{}, err
+	}
+	return model.WorkspaceState{Projects: projects, Tasks: tasks}, nil
+}
+
+// workspaceProjects - все проекты пространства без роли текущего пользователя
+func (s *Postgres) workspaceProjects(ctx context.Context, workspaceID int) ([]model.Project, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE workspace_id = $2 ORDER BY id", nil, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Project, error) {
+		var p model.Project
+		err := scanProject(row, &p)
+		p.Role = ""
+		return p, err
+	})
+}
+
+func (s *Postgres) CreateSnapshot(ctx context.Context, snap *model.Snapshot) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var state model.WorkspaceState
+	if snap.State != nil {
+		state = *snap.State
+	}
+	snap.ProjectCount, snap.TaskCount = len(state.Projects), len(state.Tasks)
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO workspace_snapshots (workspace_id, label, created_by, project_count, task_count, state)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		snap.WorkspaceID, snap.Label, snap.CreatedBy, snap.ProjectCount, snap.TaskCount, state).
+		Scan(&snap.ID, &snap.CreatedAt)
+}
+
+func (s *Postgres) ListSnapshots(ctx context.Context, workspaceID int) ([]model.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+snapshotColumns+
+		" FROM workspace_snapshots WHERE workspace_id = $1 ORDER BY created_at DESC, id DESC", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Snapshot, error) {
+		var snap model.Snapshot
+		err := row.Scan(&snap.ID, &snap.WorkspaceID, &snap.Label, &snap.CreatedBy,
+			&snap.ProjectCount, &snap.TaskCount, &snap.CreatedAt)
+		return snap, err
+	})
+}
+
+func (s *Postgres) GetSnapshot(ctx context.Context, workspaceID, id int) (model.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var (
+		snap  model.Snapshot
+		state model.WorkspaceState
+	)
+	err := s.pool.QueryRow(ctx, "SELECT "+snapshotColumns+
+		", state FROM workspace_snapshots WHERE workspace_id = $1 AND id = $2", workspaceID, id).
+		Scan(&snap.ID, &snap.WorkspaceID, &snap.Label, &snap.CreatedBy,
+			&snap.ProjectCount, &snap.TaskCount, &snap.CreatedAt, &state)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Snapshot{}, ErrNotFound
+	}
+	snap.State = &state
+	return snap, err
+}
+
+func (s *Postgres) RestoreWorkspace(ctx context.Context, workspaceID int, state model.WorkspaceState) (model.RestoreResult, error) {
+	return s.restoreWorkspace(ctx, workspaceID, state, false)
+}
+
+// RestoreWorkspace в режиме event sourcing пишет восстановление в журнал:
+// task.restored для возвращённых задач и task.deleted для удалённых
+func (s *EventStore) RestoreWorkspace(ctx context.Context, workspaceID int, state model.WorkspaceState) (model.RestoreResult, error) {
+	return s.restoreWorkspace(ctx, workspaceID, state, true)
+}
+
+// restoreTimeout - восстановление большого пространства дольше обычного запроса
+const restoreTimeout = time.Minute
+
+func (s *Postgres) restoreWorkspace(ctx context.Context, workspaceID int, state model.WorkspaceState, journal bool) (model.RestoreResult, error) {
+	var actor *int
+	if p, ok := auth.FromContext(ctx); ok && !p.IsSystem() {
+		actor = &p.UserID
+	}
+	ctx, cancel := context.WithTimeout(ctx, restoreTimeout)
+	defer cancel()
+
+	var res model.RestoreResult
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// одно восстановление пространства за раз
+		if err := tx.QueryRow(ctx, "SELECT id FROM workspaces WHERE id = $1 FOR UPDATE", workspaceID).
+			Scan(new(int)); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		// Удалённые проекты возвращаются с прежним id; команда - если она ещё есть
+		projectIDs := make([]int, 0, len(state.Projects))
+		for _, p := range state.Projects {
+			if _, err := tx.Exec(ctx, `WITH team AS (SELECT id FROM teams WHERE id = $3 AND workspace_id = $2)
+				INSERT INTO projects (id, workspace_id, team_id, name, visibility, is_default, created_at)
+				VALUES ($1, $2, (SELECT id FROM team), $4, $5, $6 AND EXISTS (SELECT 1 FROM team), $7)
+				ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, visibility = EXCLUDED.visibility
+				WHERE projects.workspace_id = EXCLUDED.workspace_id`,
+				p.ID, workspaceID, p.TeamID, p.Name, p.Visibility, p.IsDefault, p.CreatedAt); err != nil {
+				return err
+			}
+			projectIDs = append(projectIDs, p.ID)
+		}
+
+		// Сначала задачи без родителя, затем связи: порядок в снимке не важен
+		restored := make([]int, 0, len(state.Tasks))
+		for _, t := range state.Tasks {
+			// id задачи уникален только вместе с workspace_id: своя задача
+			// обновляется, вставляется лишь id, которого нет ни в одном
+			// пространстве, иначе задача пропускается
+			tag, err := tx.Exec(ctx, `UPDATE tasks SET user_id = $2, parent_id = NULL, project_id = $3, title = $4,
+				    description = $5, status = $6, due_at = $7, completed_at = $8, created_at = $9, updated_at = now(),
+				    location = $11
+				WHERE id = $1 AND workspace_id = $10
+				  AND ($2::int IS NULL OR EXISTS (SELECT 1 FROM users WHERE id = $2))
+				  AND $3 IN (SELECT id FROM projects WHERE workspace_id = $10)`,
+				t.ID, t.OwnerID, t.ProjectID, t.Title, t.Description, t.Status, t.DueAt, t.CompletedAt, t.CreatedAt,
+				workspaceID, t.Location)
+			if err == nil && tag.RowsAffected() == 0 {
+				tag, err = tx.Exec(ctx, `INSERT INTO tasks (id, workspace_id, user_id, parent_id, project_id, title,
+					                               description, status, due_at, completed_at, created_at, updated_at, uid, location)
+					SELECT $1, $10, $2, NULL, $3, $4, $5, $6, $7, $8, $9, now(), COALESCE(NULLIF($11, '')::uuid, gen_random_uuid()), $12
+					WHERE ($2::int IS NULL OR EXISTS (SELECT 1 FROM users WHERE id = $2))
+					  AND $3 IN (SELECT id FROM projects WHERE workspace_id = $10)
+					  AND NOT EXISTS (SELECT 1 FROM task_ids WHERE id = $1)`,
+					t.ID, t.OwnerID, t.ProjectID, t.Title, t.Description, t.Status, t.DueAt, t.CompletedAt, t.CreatedAt,
+					workspaceID, t.UID, t.Location)
+			}
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				res.Skipped++
+				continue
+			}
+			restored = append(restored, t.ID)
+		}
+		for _, t := range state.Tasks {
+			if t.ParentID == nil {
+				continue
+			}
+			if _, err := tx.Exec(ctx,
+				"UPDATE tasks SET parent_id = $2 WHERE id = $1 AND EXISTS (SELECT 1 FROM tasks WHERE id = $2)",
+				t.ID, *t.ParentID); err != nil {
+				return err
+			}
+		}
+		res.Restored = len(restored)
+
+		rows, err := tx.Query(ctx,
+			"DELETE FROM tasks WHERE workspace_id = $3 AND project_id = ANY($1) AND NOT (id = ANY($2)) RETURNING id",
+			projectIDs, restored, workspaceID)
+		if err != nil {
+			return err
+		}
+		deleted, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+		res.Deleted = len(deleted)
+
+		if !journal {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO task_events (task_id, type, data, actor_id)
+			SELECT id, 'task.restored',
+			       jsonb_build_object('owner_id', user_id, 'parent_id', parent_id, 'project_id', project_id,
+			                          'title', title, 'description', description, 'status', status,
+			                          'due_at', due_at, 'completed_at', completed_at, 'created_at', created_at),
+			       $2
+			FROM tasks WHERE id = ANY($1) ORDER BY id`, restored, actor); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO task_events (task_id, type, actor_id)
+			SELECT id, 'task.deleted', $2 FROM unnest($1::int[]) AS id`, deleted, actor)
+		return err
+	})
+	return res, err
+}
+
+// WorkspaceStateAt восстанавливает задачи пространства на момент at из журнала.
+// Истории проектов нет: берутся текущие проекты, а задачи проектов, удалённых
+// с тех пор, пропускаются.
+func (s *EventStore) WorkspaceStateAt(ctx context.Context, workspaceID int, at time.Time) (model.WorkspaceState, error) {
+	ctx, cancel := context.WithTimeout(ctx, restoreTimeout)
+	defer cancel()
+
+	projects, err := s.workspaceProjects(ctx, workspaceID)
+	if err != nil {
+		return model.WorkspaceState{}, err
+	}
+	inWorkspace := make(map[int]bool, len(projects))
+	for _, p := range projects {
+		inWorkspace[p.ID] = true
+	}
+
+	// задачи, которые хоть раз были в проектах пространства
+	rows, err := s.pool.Query(ctx, `SELECT seq, task_id, type, data, actor_id, occurred_at FROM task_events
+		WHERE occurred_at <= $2 AND task_id IN (
+			SELECT task_id FROM task_events
+			WHERE (data->>'project_id')::int IN (SELECT id FROM projects WHERE workspace_id = $1))
+		ORDER BY task_id, seq`, workspaceID, at)
+	if err != nil {
+		return model.WorkspaceState{}, err
+	}
+	evs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.TaskEvent, error) {
+		var e model.TaskEvent
+		err := row.Scan(&e.Seq, &e.TaskID, &e.Type, &e.Data, &e.ActorID, &e.OccurredAt)
+		return e, err
+	})
+	if err != nil {
+		return model.WorkspaceState{}, err
+	}
+
+	state := model.WorkspaceState{Projects: projects, Tasks: []model.Task{}}
+	for i := 0; i < len(evs); {
+		var (
+			task   model.Task
+			exists bool
+		)
+		j := i
+		for ; j < len(evs) && evs[j].TaskID == evs[i].TaskID; j++ {
+			task, exists = evs[j].Apply(task)
+		}
+		if exists && task.ProjectID != nil && inWorkspace[*task.ProjectID] {
+			state.Tasks = append(state.Tasks, task)
+		}
+		i = j
+	}
+	return state, nil
+}
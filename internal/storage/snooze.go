@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// SnoozeTask меняет только snoozed_until: остальные поля задачи и её
+// журнал событий отсрочка не трогает
+func (s *Postgres) SnoozeTask(ctx context.Context, id int, until *time.Time) (model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var task model.Task
+	err = scanTask(s.pool.QueryRow(ctx, `UPDATE tasks SET snoozed_until = $3, updated_at = now()
+		WHERE `+writeFilter+` AND id = $2 RETURNING `+taskColumns, owner, id, until), &task)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Task{}, s.readOnlyOr(ctx, owner, id, ErrNotFound)
+	}
+	return task, err
+}
+
+func (s *Postgres) WakeTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `UPDATE tasks SET snoozed_until = NULL, updated_at = now()
+		WHERE snoozed_until <= $1 RETURNING `+taskColumns, now)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+}
@@ -0,0 +1,134 @@
+// Package storage - хранение задач: PostgreSQL, память и предохранитель
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidProject - проекта нет или он недоступен пользователю
+	ErrInvalidProject = errors.New("invalid project")
+	// ErrReadOnly - задача видна, но роль в проекте не позволяет её менять
+	ErrReadOnly = errors.New("project is read-only for this user")
+	// ErrTaskUIDTaken - задача с таким uid уже есть
+	ErrTaskUIDTaken = errors.New("task uid already exists")
+	// ErrInvalidSyncToken - токен синхронизации выдан не этим сервером
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+	// ErrInvalidDelegate - задача не в проекте или исполнитель не может её менять
+	ErrInvalidDelegate = errors.New("task can only be delegated to an editor of its project")
+	// ErrDelegatedByOther - задачу уже поручил кто-то другой, и заменить его
+	// поручение может только он
+	ErrDelegatedByOther = errors.New("task is delegated by another user")
+)
+
+// TaskStore - слой хранения задач
+type TaskStore interface {
+	CreateTask(ctx context.Context, task *model.Task) error
+	ListTasks(ctx context.Context) ([]model.Task, error)
+	GetTask(ctx context.Context, id int) (model.Task, error)
+	// GetTasks - видимые задачи из ids одним запросом, в любом порядке;
+	// недоступных и несуществующих в ответе нет
+	GetTasks(ctx context.Context, ids []int) ([]model.Task, error)
+	// TaskIDByUID - числовой id видимой задачи по её uid
+	TaskIDByUID(ctx context.Context, uid string) (int, error)
+	UpdateTask(ctx context.Context, task *model.Task) error
+	DeleteTask(ctx context.Context, id int) error
+	// CountOpenSubtasks - число незавершённых подзадач задачи id
+	CountOpenSubtasks(ctx context.Context, id int) (int, error)
+}
+
+// TaskHistory - журнал изменений задач; есть только в режиме event sourcing
+type TaskHistory interface {
+	TaskEvents(ctx context.Context, id int) ([]model.TaskEvent, error)
+	// TaskAt - состояние задачи на момент at
+	TaskAt(ctx context.Context, id int, at time.Time) (model.Task, error)
+}
+
+// QuotaStore - подсчёты для квот на создание
+type QuotaStore interface {
+	// CountActiveTasks - незавершённые задачи, автор которых - текущий пользователь
+	CountActiveTasks(ctx context.Context) (int, error)
+}
+
+// DuplicateStore ищет возможные дубли новой задачи
+type DuplicateStore interface {
+	// SimilarOpenTasks - видимые незавершённые задачи проекта projectID (nil -
+	// личные задачи пользователя), заголовок которых совпадает с title с
+	// точностью до регистра, пробелов и знаков препинания
+	SimilarOpenTasks(ctx context.Context, title string, projectID *int, limit int) ([]model.Task, error)
+}
+
+// SyncStore - изменения задач для дельта-синхронизации офлайн-клиентов
+type SyncStore interface {
+	// ChangesSince - видимые задачи, изменённые после token, и надгробия
+	// удалённых за то же время; пустой token - все задачи без надгробий
+	ChangesSince(ctx context.Context, token string) (model.TaskChanges, error)
+	// AddConflicts сохраняет конфликты слияния; ID и DetectedAt заполняются
+	AddConflicts(ctx context.Context, conflicts []model.TaskConflict) error
+	// TaskConflicts - конфликты видимых задач, новые первыми
+	TaskConflicts(ctx context.Context) ([]model.TaskConflict, error)
+	// DeleteConflict убирает разобранный конфликт видимой задачи
+	DeleteConflict(ctx context.Context, id int) error
+}
+
+// ReadModelStore - денормализованные модели чтения, которые обновляются
+// триггерами на каждую запись в tasks
+type ReadModelStore interface {
+	// TaskStats - счётчики по всем задачам, видимым текущему пользователю
+	TaskStats(ctx context.Context) (model.TaskStats, error)
+	// SearchTasks - видимые задачи по полнотекстовому запросу, лучшие первыми
+	SearchTasks(ctx context.Context, query string, limit int) ([]model.Task, error)
+}
+
+// LocationStore ищет задачи по месту
+type LocationStore interface {
+	// NearbyTasks - видимые незавершённые задачи, место которых не дальше
+	// radius метров от точки (lat, lon), ближайшие первыми
+	NearbyTasks(ctx context.Context, lat, lon, radius float64, limit int) ([]model.Task, error)
+}
+
+// SnoozeStore откладывает задачи и возвращает их по истечении отсрочки
+type SnoozeStore interface {
+	// SnoozeTask откладывает видимую задачу id до until, nil - снимает отсрочку
+	SnoozeTask(ctx context.Context, id int, until *time.Time) (model.Task, error)
+	// WakeTasks снимает все отсрочки, истёкшие к now, и отдаёт эти задачи
+	WakeTasks(ctx context.Context, now time.Time) ([]model.Task, error)
+}
+
+// FlagStore - личные отметки пользователя на задачах
+type FlagStore interface {
+	// SetTaskPinned закрепляет видимую задачу id за текущим пользователем
+	// или снимает закрепление
+	SetTaskPinned(ctx context.Context, id int, pinned bool) error
+	// TaskFlags - отметки текущего пользователя на видимых ему задачах
+	TaskFlags(ctx context.Context) ([]model.TaskFlag, error)
+}
+
+// DelegationStore - поручения задач другим пользователям
+type DelegationStore interface {
+	// Delegate поручает задачу id, которую текущий пользователь может менять,
+	// пользователю delegateID - редактору её проекта; прежнее поручение задачи
+	// заменяется, если его дал тот же пользователь, иначе ErrDelegatedByOther
+	Delegate(ctx context.Context, id, delegateID int) (model.Delegation, error)
+	// TaskDelegation - поручение видимой задачи id
+	TaskDelegation(ctx context.Context, id int) (model.Delegation, error)
+	// SetDelegationState переводит поручение задачи id в state, если оно
+	// сейчас в одном из состояний from; иначе ErrNotFound
+	SetDelegationState(ctx context.Context, id int, from []string, state, comment string) (model.Delegation, error)
+	DeleteDelegation(ctx context.Context, id int) error
+	// Delegations - поручения, где текущий пользователь поручивший или
+	// исполнитель, новые первыми
+	Delegations(ctx context.Context) ([]model.Delegation, error)
+}
+
+// FuzzySearchStore - поиск с опечатками по триграммам
+type FuzzySearchStore interface {
+	// FuzzySearchTasks - видимые задачи, заголовок или описание которых похожи
+	// на query не меньше чем на threshold (0..1), самые похожие первыми
+	FuzzySearchTasks(ctx context.Context, query string, threshold float64, limit int) ([]model.Task, error)
+}
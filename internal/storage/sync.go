@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// ChangesSince читает задачи и надгробия одним снимком и отдаёт его xmin как
+// следующий токен (см. 0026_delta_sync.sql). Долгая транзакция рядом держит
+// xmin, и тогда одни и те же строки приходят несколько раз подряд.
+func (s *Postgres) ChangesSince(ctx context.Context, token string) (model.TaskChanges, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.TaskChanges{}, err
+	}
+	since := token
+	if since == "" {
+		since = "0"
+	} else if _, err := strconv.ParseUint(since, 10, 64); err != nil {
+		return model.TaskChanges{}, ErrInvalidSyncToken
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	changes := model.TaskChanges{Tasks: []model.Task{}, Deleted: []model.Tombstone{}}
+	opts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err = pgx.BeginTxFunc(ctx, s.pool, opts, func(tx pgx.Tx) error {
+		// первый запрос транзакции задаёт её снимок
+		if err := tx.QueryRow(ctx, "SELECT pg_snapshot_xmin(pg_current_snapshot())::text").Scan(&changes.Token); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, "SELECT "+taskColumns+" FROM tasks WHERE "+ownerFilter+`
+			AND changed_xid >= $2::xid8 ORDER BY id`, owner, since)
+		if err != nil {
+			return err
+		}
+		tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+			var t model.Task
+			err := scanTask(row, &t)
+			return t, err
+		})
+		if err != nil {
+			return err
+		}
+		changes.Tasks = append(changes.Tasks, tasks...)
+
+		// при полной синхронизации у клиента нечего удалять
+		if token == "" {
+			return nil
+		}
+		rows, err = tx.Query(ctx, "SELECT task_id, uid::text, deleted_at FROM task_tombstones WHERE "+ownerFilter+`
+			AND deleted_xid >= $2::xid8 ORDER BY deleted_xid`, owner, since)
+		if err != nil {
+			return err
+		}
+		deleted, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Tombstone, error) {
+			var d model.Tombstone
+			err := row.Scan(&d.ID, &d.UID, &d.DeletedAt)
+			return d, err
+		})
+		changes.Deleted = append(changes.Deleted, deleted...)
+		return err
+	})
+	if err != nil {
+		return model.TaskChanges{}, err
+	}
+	return changes, nil
+}
+
+func (s *Postgres) AddConflicts(ctx context.Context, conflicts []model.TaskConflict) error {
+	actor, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		for i := range conflicts {
+			c := &conflicts[i]
+			c.ActorID = actor
+			if err := tx.QueryRow(ctx,
+				`INSERT INTO task_conflicts (task_id, field, kept, discarded, actor_id) VALUES ($1, $2, $3, $4, $5)
+				 RETURNING id, detected_at`,
+				c.TaskID, c.Field, c.Kept, c.Discarded, actor).Scan(&c.ID, &c.DetectedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Postgres) TaskConflicts(ctx context.Context) ([]model.TaskConflict, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT id, task_id, field, kept, discarded, actor_id, detected_at FROM task_conflicts
+		WHERE task_id IN (SELECT id FROM tasks WHERE `+ownerFilter+`) ORDER BY id DESC`, owner)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.TaskConflict, error) {
+		var c model.TaskConflict
+		err := row.Scan(&c.ID, &c.TaskID, &c.Field, &c.Kept, &c.Discarded, &c.ActorID, &c.DetectedAt)
+		return c, err
+	})
+}
+
+func (s *Postgres) DeleteConflict(ctx context.Context, id int) error {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM task_conflicts
+		WHERE id = $2 AND task_id IN (SELECT id FROM tasks WHERE `+writeFilter+`)`, owner, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+var ErrEmailTaken = errors.New("email already registered")
+
+// UserStore - пользователи и их учётные данные
+type UserStore interface {
+	CreateUser(ctx context.Context, u *model.User) error
+	GetUser(ctx context.Context, id int) (model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (model.User, error)
+	UpdateProfile(ctx context.Context, u *model.User) error
+	// SetAvatarKey меняет ключ аватара и возвращает прежний ("" - не было)
+	SetAvatarKey(ctx context.Context, userID int, key string) (string, error)
+
+	CreateAPIKey(ctx context.Context, key *model.APIKey, hash string) error
+	ListAPIKeys(ctx context.Context, userID int) ([]model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id int) error
+	PrincipalByAPIKey(ctx context.Context, hash string) (*auth.Principal, error)
+
+	// SetTOTPSecret сохраняет секрет, ещё не подтверждённый кодом
+	SetTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, recoveryHashes []string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	// UseTOTPStep отмечает шаг использованным; false - код уже предъявлялся
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error)
+
+	// UseMagicLink отмечает ссылку использованной; false - уже использована
+	UseMagicLink(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+}
+
+const userColumns = `id, email, password_hash, role, display_name, timezone, locale,
+	coalesce(avatar_key, ''), coalesce(totp_secret, ''), totp_enabled, created_at`
+
+func scanUser(row pgx.Row, u *model.User) error {
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.DisplayName, &u.Timezone, &u.Locale,
+		&u.AvatarKey, &u.TOTPSecret, &u.TOTPEnabled, &u.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) CreateUser(ctx context.Context, u *model.User) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO users (email, password_hash, role) VALUES ($1, $2, $3) RETURNING id, created_at`,
+		u.Email, u.PasswordHash, u.Role).Scan(&u.ID, &u.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (s *Postgres) GetUser(ctx context.Context, id int) (model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var u model.User
+	err := scanUser(s.pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id), &u)
+	return u, err
+}
+
+func (s *Postgres) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var u model.User
+	err := scanUser(s.pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE lower(email) = lower($1)", email), &u)
+	return u, err
+}
+
+func (s *Postgres) UpdateProfile(ctx context.Context, u *model.User) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE users SET display_name = $2, timezone = $3, locale = $4 WHERE id = $1",
+		u.ID, u.DisplayName, u.Timezone, u.Locale)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) SetAvatarKey(ctx context.Context, userID int, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var old string
+	err := s.pool.QueryRow(ctx,
+		`UPDATE users u SET avatar_key = nullif($2, '')
+		 FROM (SELECT id, avatar_key FROM users WHERE id = $1 FOR UPDATE) prev
+		 WHERE u.id = prev.id
+		 RETURNING coalesce(prev.avatar_key, '')`, userID, key).Scan(&old)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return old, err
+}
+
+func (s *Postgres) CreateAPIKey(ctx context.Context, key *model.APIKey, hash string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, created_at`,
+		key.UserID, key.Name, key.Prefix, hash, key.Scopes).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (s *Postgres) ListAPIKeys(ctx context.Context, userID int) ([]model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, user_id, name, prefix, scopes, created_at, last_used_at FROM api_keys
+		 WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.APIKey, error) {
+		var k model.APIKey
+		err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt)
+		return k, err
+	})
+}
+
+func (s *Postgres) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) PrincipalByAPIKey(ctx context.Context, hash string) (*auth.Principal, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var p auth.Principal
+	err := s.pool.QueryRow(ctx,
+		`UPDATE api_keys k SET last_used_at = now() FROM users u
+		 WHERE k.key_hash = $1 AND u.id = k.user_id
+		 RETURNING u.id, u.email, u.role, k.id, k.scopes`, hash).Scan(&p.UserID, &p.Email, &p.Role, &p.APIKeyID, &p.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &p, err
+}
+
+func (s *Postgres) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE users SET totp_secret = $2 WHERE id = $1 AND NOT totp_enabled", userID, secret)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) EnableTOTP(ctx context.Context, userID int, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "UPDATE users SET totp_enabled = true WHERE id = $1", userID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			"INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])",
+			userID, recoveryHashes)
+		return err
+	})
+}
+
+func (s *Postgres) DisableTOTP(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0 WHERE id = $1", userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+		return err
+	})
+}
+
+func (s *Postgres) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2", userID, step)
+	return tag.RowsAffected() > 0, err
+}
+
+func (s *Postgres) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE recovery_codes SET used_at = now()
+		 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, hash)
+	return tag.RowsAffected() > 0, err
+}
+
+func (s *Postgres) UseMagicLink(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// Заодно чистим записи о ссылках, которые и так уже истекли
+	if _, err := s.pool.Exec(ctx, "DELETE FROM used_magic_links WHERE expires_at < now()"); err != nil {
+		return false, err
+	}
+	tag, err := s.pool.Exec(ctx,
+		"INSERT INTO used_magic_links (jti, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		jti, expiresAt)
+	return tag.RowsAffected() > 0, err
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// WebhookStore - входящие вебхуки пользователей
+type WebhookStore interface {
+	CreateInboundWebhook(ctx context.Context, hook *model.InboundWebhook, tokenHash string) error
+	ListInboundWebhooks(ctx context.Context, userID int) ([]model.InboundWebhook, error)
+	DeleteInboundWebhook(ctx context.Context, userID, id int) error
+	// InboundWebhookByToken находит вебхук и отмечает время использования
+	InboundWebhookByToken(ctx context.Context, tokenHash string) (model.InboundWebhook, error)
+}
+
+const webhookColumns = `id, user_id, name, project_id, title_template, description_template, created_at, last_used_at`
+
+func scanInboundWebhook(row pgx.Row, h *model.InboundWebhook) error {
+	err := row.Scan(&h.ID, &h.UserID, &h.Name, &h.ProjectID, &h.TitleTemplate, &h.DescriptionTemplate,
+		&h.CreatedAt, &h.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) CreateInboundWebhook(ctx context.Context, hook *model.InboundWebhook, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO inbound_webhooks (user_id, name, project_id, title_template, description_template, token_hash)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		hook.UserID, hook.Name, hook.ProjectID, hook.TitleTemplate, hook.DescriptionTemplate, tokenHash,
+	).Scan(&hook.ID, &hook.CreatedAt)
+}
+
+func (s *Postgres) ListInboundWebhooks(ctx context.Context, userID int) ([]model.InboundWebhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+webhookColumns+" FROM inbound_webhooks WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.InboundWebhook, error) {
+		var h model.InboundWebhook
+		err := scanInboundWebhook(row, &h)
+		return h, err
+	})
+}
+
+func (s *Postgres) DeleteInboundWebhook(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM inbound_webhooks WHERE user_id = $1 AND id = $2", userID, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) InboundWebhookByToken(ctx context.Context, tokenHash string) (model.InboundWebhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var h model.InboundWebhook
+	err := scanInboundWebhook(s.pool.QueryRow(ctx,
+		"UPDATE inbound_webhooks SET last_used_at = now() WHERE token_hash = $1 RETURNING "+webhookColumns,
+		tokenHash), &h)
+	return h, err
+}
@@ -0,0 +1,511 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+var ErrTeamExists = errors.New("team with this name already exists")
+
+// WorkspaceStore - рабочие пространства, команды и проекты
+//
+// Проекты и задачи команд ограничиваются Principal из ctx так же, как задачи
+// в TaskStore; проверка ролей - забота сервиса.
+type WorkspaceStore interface {
+	CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID int) error
+	ListWorkspaces(ctx context.Context, userID int) ([]model.Workspace, error)
+	// GetWorkspace возвращает пространство с ролью userID; ErrNotFound, если он не участник
+	GetWorkspace(ctx context.Context, id, userID int) (model.Workspace, error)
+	ListWorkspaceMembers(ctx context.Context, workspaceID int) ([]model.Member, error)
+	// SetWorkspaceMember добавляет участника или меняет его роль
+	SetWorkspaceMember(ctx context.Context, workspaceID, userID int, role string) error
+	// RemoveWorkspaceMember удаляет участника и из всех команд пространства
+	RemoveWorkspaceMember(ctx context.Context, workspaceID, userID int) error
+
+	// CreateTeam создаёт команду вместе с её проектом по умолчанию
+	CreateTeam(ctx context.Context, team *model.Team) error
+	ListTeams(ctx context.Context, workspaceID int) ([]model.Team, error)
+	GetTeam(ctx context.Context, id int) (model.Team, error)
+	// TeamRole - роль userID в команде; ErrNotFound, если он не в команде
+	TeamRole(ctx context.Context, teamID, userID int) (string, error)
+	ListTeamMembers(ctx context.Context, teamID int) ([]model.Member, error)
+	SetTeamMember(ctx context.Context, teamID, userID int, role string) error
+	RemoveTeamMember(ctx context.Context, teamID, userID int) error
+	ListTeamTasks(ctx context.Context, teamID int) ([]model.Task, error)
+
+	CreateProject(ctx context.Context, p *model.Project) error
+	// ListProjects - доступные проекты пространства
+	ListProjects(ctx context.Context, workspaceID int) ([]model.Project, error)
+	// GetProject возвращает проект с ролью в нём текущего пользователя
+	GetProject(ctx context.Context, id int) (model.Project, error)
+	UpdateProject(ctx context.Context, p *model.Project) error
+	ListProjectMembers(ctx context.Context, projectID int) ([]model.Member, error)
+	SetProjectMember(ctx context.Context, projectID, userID int, role string) error
+	RemoveProjectMember(ctx context.Context, projectID, userID int) error
+	ListProjectTasks(ctx context.Context, projectID int) ([]model.Task, error)
+	// ListBoardTasks - не больше perColumn последних задач каждого статуса
+	ListBoardTasks(ctx context.Context, projectID, perColumn int) ([]model.Task, error)
+	// ProjectTaskStats - счётчики задач проекта из модели чтения
+	ProjectTaskStats(ctx context.Context, projectID int) (model.TaskStats, error)
+
+	CreateInvite(ctx context.Context, inv *model.Invite, tokenHash string) error
+	// ListInvites - непринятые, неотозванные и не истёкшие приглашения
+	ListInvites(ctx context.Context, workspaceID int) ([]model.Invite, error)
+	RevokeInvite(ctx context.Context, workspaceID, id int) error
+	// InviteByToken - действующее приглашение по хешу токена
+	InviteByToken(ctx context.Context, tokenHash string) (model.Invite, error)
+	// AcceptInvite гасит приглашение и добавляет userID в пространство.
+	// Уже состоящему участнику роль только повышается.
+	AcceptInvite(ctx context.Context, tokenHash string, userID int) (model.Invite, error)
+}
+
+// Колонки проекта; роль вычисляется для $1, у System - полный доступ
+const projectColumns = `id, workspace_id, team_id, name, visibility, is_default,
+	CASE WHEN $1::int IS NULL THEN 'admin' ELSE coalesce(project_role($1, id), '') END, created_at`
+
+// projectWorkspace - пространство проекта $2: условие по tasks.workspace_id
+// сужает запрос до данных одного арендатора
+const projectWorkspace = `(SELECT workspace_id FROM projects WHERE id = $2)`
+
+// Видимость проектов, как у задач в ownerFilter
+const projectFilter = `($1::int IS NULL OR id IN (SELECT accessible_projects($1)))`
+
+func scanProject(row pgx.Row, p *model.Project) error {
+	err := row.Scan(&p.ID, &p.WorkspaceID, &p.TeamID, &p.Name, &p.Visibility, &p.IsDefault, &p.Role, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) CreateWorkspace(ctx context.Context, ws *model.Workspace, ownerID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "INSERT INTO workspaces (name) VALUES ($1) RETURNING id, created_at", ws.Name).
+			Scan(&ws.ID, &ws.CreatedAt)
+		if err != nil {
+			return err
+		}
+		ws.Role = model.WorkspaceOwner
+		_, err = tx.Exec(ctx, "INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)",
+			ws.ID, ownerID, ws.Role)
+		return err
+	})
+}
+
+func (s *Postgres) ListWorkspaces(ctx context.Context, userID int) ([]model.Workspace, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT w.id, w.name, m.role, w.created_at FROM workspaces w
+		 JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = $1
+		 ORDER BY w.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Workspace, error) {
+		var w model.Workspace
+		err := row.Scan(&w.ID, &w.Name, &w.Role, &w.CreatedAt)
+		return w, err
+	})
+}
+
+func (s *Postgres) GetWorkspace(ctx context.Context, id, userID int) (model.Workspace, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var w model.Workspace
+	err := s.pool.QueryRow(ctx,
+		`SELECT w.id, w.name, m.role, w.created_at FROM workspaces w
+		 JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = $2
+		 WHERE w.id = $1`, id, userID).Scan(&w.ID, &w.Name, &w.Role, &w.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Workspace{}, ErrNotFound
+	}
+	return w, err
+}
+
+func (s *Postgres) listMembers(ctx context.Context, query string, id int) ([]model.Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Member, error) {
+		var m model.Member
+		err := row.Scan(&m.UserID, &m.Email, &m.DisplayName, &m.Role, &m.CreatedAt)
+		return m, err
+	})
+}
+
+func (s *Postgres) ListWorkspaceMembers(ctx context.Context, workspaceID int) ([]model.Member, error) {
+	return s.listMembers(ctx,
+		`SELECT u.id, u.email, u.display_name, m.role, m.created_at FROM workspace_members m
+		 JOIN users u ON u.id = m.user_id WHERE m.workspace_id = $1 ORDER BY m.created_at, u.id`, workspaceID)
+}
+
+func (s *Postgres) SetWorkspaceMember(ctx context.Context, workspaceID, userID int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		workspaceID, userID, role)
+	return mapForeignKey(err)
+}
+
+func (s *Postgres) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM team_members WHERE user_id = $2
+			 AND team_id IN (SELECT id FROM teams WHERE workspace_id = $1)`, workspaceID, userID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM project_members WHERE user_id = $2
+			 AND project_id IN (SELECT id FROM projects WHERE workspace_id = $1)`, workspaceID, userID); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, "DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+			workspaceID, userID)
+		if err == nil && tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+func (s *Postgres) CreateTeam(ctx context.Context, team *model.Team) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "INSERT INTO teams (workspace_id, name) VALUES ($1, $2) RETURNING id, created_at",
+			team.WorkspaceID, team.Name).Scan(&team.ID, &team.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx,
+			`INSERT INTO projects (workspace_id, team_id, name, is_default) VALUES ($1, $2, $3, true)
+			 RETURNING id`, team.WorkspaceID, team.ID, team.Name).Scan(&team.DefaultProjectID)
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTeamExists
+	}
+	return err
+}
+
+const teamColumns = `t.id, t.workspace_id, t.name, coalesce(p.id, 0), t.created_at`
+
+const teamFrom = `teams t LEFT JOIN projects p ON p.team_id = t.id AND p.is_default`
+
+func scanTeam(row pgx.Row, t *model.Team) error {
+	err := row.Scan(&t.ID, &t.WorkspaceID, &t.Name, &t.DefaultProjectID, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) ListTeams(ctx context.Context, workspaceID int) ([]model.Team, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+teamColumns+" FROM "+teamFrom+" WHERE t.workspace_id = $1 ORDER BY t.name", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Team, error) {
+		var t model.Team
+		err := scanTeam(row, &t)
+		return t, err
+	})
+}
+
+func (s *Postgres) GetTeam(ctx context.Context, id int) (model.Team, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var t model.Team
+	err := scanTeam(s.pool.QueryRow(ctx, "SELECT "+teamColumns+" FROM "+teamFrom+" WHERE t.id = $1", id), &t)
+	return t, err
+}
+
+func (s *Postgres) TeamRole(ctx context.Context, teamID, userID int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var role string
+	err := s.pool.QueryRow(ctx, "SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2",
+		teamID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return role, err
+}
+
+func (s *Postgres) ListTeamMembers(ctx context.Context, teamID int) ([]model.Member, error) {
+	return s.listMembers(ctx,
+		`SELECT u.id, u.email, u.display_name, m.role, m.created_at FROM team_members m
+		 JOIN users u ON u.id = m.user_id WHERE m.team_id = $1 ORDER BY m.created_at, u.id`, teamID)
+}
+
+func (s *Postgres) SetTeamMember(ctx context.Context, teamID, userID int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		teamID, userID, role)
+	return mapForeignKey(err)
+}
+
+func (s *Postgres) RemoveTeamMember(ctx context.Context, teamID, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM team_members WHERE team_id = $1 AND user_id = $2", teamID, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) ListTeamTasks(ctx context.Context, teamID int) ([]model.Task, error) {
+	return s.listTasksWhere(ctx, `workspace_id = (SELECT workspace_id FROM teams WHERE id = $2)
+		AND project_id IN (SELECT id FROM projects WHERE team_id = $2)`, teamID)
+}
+
+func (s *Postgres) CreateProject(ctx context.Context, p *model.Project) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if p.Visibility == "" {
+		p.Visibility = model.VisibilityWorkspace
+	}
+	err := s.pool.QueryRow(ctx,
+		"INSERT INTO projects (workspace_id, team_id, name, visibility) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		p.WorkspaceID, p.TeamID, p.Name, p.Visibility).Scan(&p.ID, &p.CreatedAt)
+	return mapForeignKey(err)
+}
+
+func (s *Postgres) UpdateProject(ctx context.Context, p *model.Project) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "UPDATE projects SET name = $2, visibility = $3 WHERE id = $1",
+		p.ID, p.Name, p.Visibility)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) ListProjectMembers(ctx context.Context, projectID int) ([]model.Member, error) {
+	return s.listMembers(ctx,
+		`SELECT u.id, u.email, u.display_name, m.role, m.created_at FROM project_members m
+		 JOIN users u ON u.id = m.user_id WHERE m.project_id = $1 ORDER BY m.created_at, u.id`, projectID)
+}
+
+func (s *Postgres) SetProjectMember(ctx context.Context, projectID, userID int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		projectID, userID, role)
+	return mapForeignKey(err)
+}
+
+func (s *Postgres) RemoveProjectMember(ctx context.Context, projectID, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM project_members WHERE project_id = $1 AND user_id = $2",
+		projectID, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) ListProjects(ctx context.Context, workspaceID int) ([]model.Project, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE "+projectFilter+" AND workspace_id = $2 ORDER BY id",
+		owner, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Project, error) {
+		var p model.Project
+		err := scanProject(row, &p)
+		return p, err
+	})
+}
+
+func (s *Postgres) GetProject(ctx context.Context, id int) (model.Project, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return model.Project{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var p model.Project
+	err = scanProject(s.pool.QueryRow(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE "+projectFilter+" AND id = $2", owner, id), &p)
+	return p, err
+}
+
+func (s *Postgres) ListProjectTasks(ctx context.Context, projectID int) ([]model.Task, error) {
+	return s.listTasksWhere(ctx, "workspace_id = "+projectWorkspace+" AND project_id = $2", projectID)
+}
+
+// listTasksWhere - видимые задачи с дополнительным условием по $2
+func (s *Postgres) listTasksWhere(ctx context.Context, cond string, arg any) ([]model.Task, error) {
+	owner, err := auth.OwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE "+ownerFilter+" AND "+cond+" ORDER BY id", owner, arg)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Task, error) {
+		var t model.Task
+		err := scanTask(row, &t)
+		return t, err
+	})
+}
+
+// mapForeignKey - ссылка на несуществующего пользователя или команду
+func mapForeignKey(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+const inviteColumns = `i.id, i.workspace_id, w.name, coalesce(i.email, ''), i.role, i.invited_by, i.created_at, i.expires_at`
+
+const pendingInvite = `i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > now()`
+
+func scanInvite(row pgx.Row, inv *model.Invite) error {
+	err := row.Scan(&inv.ID, &inv.WorkspaceID, &inv.WorkspaceName, &inv.Email, &inv.Role,
+		&inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) CreateInvite(ctx context.Context, inv *model.Invite, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO workspace_invites (workspace_id, email, role, token_hash, invited_by, expires_at)
+		 VALUES ($1, nullif($2, ''), $3, $4, $5, $6) RETURNING id, created_at`,
+		inv.WorkspaceID, inv.Email, inv.Role, tokenHash, inv.InvitedBy, inv.ExpiresAt).
+		Scan(&inv.ID, &inv.CreatedAt)
+}
+
+func (s *Postgres) ListInvites(ctx context.Context, workspaceID int) ([]model.Invite, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+inviteColumns+" FROM workspace_invites i JOIN workspaces w ON w.id = i.workspace_id"+
+			" WHERE i.workspace_id = $1 AND "+pendingInvite+" ORDER BY i.id", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Invite, error) {
+		var inv model.Invite
+		err := scanInvite(row, &inv)
+		return inv, err
+	})
+}
+
+func (s *Postgres) RevokeInvite(ctx context.Context, workspaceID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE workspace_invites i SET revoked_at = now()
+		 WHERE i.workspace_id = $1 AND i.id = $2 AND `+pendingInvite, workspaceID, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Postgres) InviteByToken(ctx context.Context, tokenHash string) (model.Invite, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var inv model.Invite
+	err := scanInvite(s.pool.QueryRow(ctx,
+		"SELECT "+inviteColumns+" FROM workspace_invites i JOIN workspaces w ON w.id = i.workspace_id"+
+			" WHERE i.token_hash = $1 AND "+pendingInvite, tokenHash), &inv)
+	return inv, err
+}
+
+func (s *Postgres) AcceptInvite(ctx context.Context, tokenHash string, userID int) (model.Invite, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var inv model.Invite
+	err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		// FOR UPDATE: одновременное принятие одной ссылки проходит только раз
+		err := scanInvite(tx.QueryRow(ctx,
+			"SELECT "+inviteColumns+" FROM workspace_invites i JOIN workspaces w ON w.id = i.workspace_id"+
+				" WHERE i.token_hash = $1 AND "+pendingInvite+" FOR UPDATE OF i", tokenHash), &inv)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx,
+			"UPDATE workspace_invites SET accepted_at = now(), accepted_by = $2 WHERE id = $1",
+			inv.ID, userID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)
+			 ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role
+			 WHERE workspace_members.role = 'member'`,
+			inv.WorkspaceID, userID, inv.Role)
+		return err
+	})
+	return inv, err
+}
@@ -0,0 +1,80 @@
+// Package systemd - уведомления менеджеру служб по протоколу sd_notify:
+// готовность, остановка и пинги watchdog. Вне systemd (нет NOTIFY_SOCKET)
+// все вызовы ничего не делают.
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Состояния для Notify
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify отправляет состояние в сокет из NOTIFY_SOCKET. Имя с @ - сокет
+// в абстрактном пространстве имён Linux.
+func Notify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval - WatchdogSec службы из WATCHDOG_USEC; 0 - watchdog не
+// включён или предназначен другому процессу (WATCHDOG_PID)
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog пингует watchdog вдвое чаще interval, пока check проходит.
+// Зависший процесс перестаёт отвечать на check, пинги прекращаются, и
+// systemd перезапускает службу. check == nil - пинговать всегда.
+func RunWatchdog(ctx context.Context, interval time.Duration, check func(context.Context) error, logger zerolog.Logger) {
+	t := time.NewTicker(interval / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if check != nil {
+			cctx, cancel := context.WithTimeout(ctx, interval/2)
+			err := check(cctx)
+			cancel()
+			if err != nil {
+				logger.Warn().Err(err).Msg("Health check failed, skipping watchdog ping")
+				continue
+			}
+		}
+		if err := Notify(Watchdog); err != nil {
+			logger.Error().Err(err).Msg("Failed to ping systemd watchdog")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package systemd_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/systemd"
+)
+
+func TestNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	if err := systemd.Notify(systemd.Ready); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Fatalf("read %q, %v; want READY=1", buf[:n], err)
+	}
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := systemd.Notify(systemd.Ready); err != nil {
+		t.Fatalf("notify outside systemd: %v", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if got := systemd.WatchdogInterval(); got != 30*time.Second {
+		t.Fatalf("interval = %v, want 30s", got)
+	}
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := systemd.WatchdogInterval(); got != 0 {
+		t.Fatalf("interval for another pid = %v, want 0", got)
+	}
+	t.Setenv("WATCHDOG_USEC", "")
+	t.Setenv("WATCHDOG_PID", "")
+	if got := systemd.WatchdogInterval(); got != 0 {
+		t.Fatalf("interval without watchdog = %v, want 0", got)
+	}
+}
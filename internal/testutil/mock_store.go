@@ -0,0 +1,78 @@
+package testutil
+
+import (
+	"context"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// MockTaskStore - подменяемое хранилище: каждый метод делегирует
+// полю-функции, незаданные методы ведут себя как пустая база.
+type MockTaskStore struct {
+	CreateTaskFunc  func(ctx context.Context, task *model.Task) error
+	ListTasksFunc   func(ctx context.Context) ([]model.Task, error)
+	GetTaskFunc     func(ctx context.Context, id int) (model.Task, error)
+	GetTasksFunc    func(ctx context.Context, ids []int) ([]model.Task, error)
+	TaskIDByUIDFunc func(ctx context.Context, uid string) (int, error)
+	UpdateTaskFunc  func(ctx context.Context, task *model.Task) error
+	DeleteTaskFunc  func(ctx context.Context, id int) error
+
+	CountOpenSubtasksFunc func(ctx context.Context, id int) (int, error)
+}
+
+func (m *MockTaskStore) CreateTask(ctx context.Context, task *model.Task) error {
+	if m.CreateTaskFunc == nil {
+		return nil
+	}
+	return m.CreateTaskFunc(ctx, task)
+}
+
+func (m *MockTaskStore) ListTasks(ctx context.Context) ([]model.Task, error) {
+	if m.ListTasksFunc == nil {
+		return nil, nil
+	}
+	return m.ListTasksFunc(ctx)
+}
+
+func (m *MockTaskStore) GetTask(ctx context.Context, id int) (model.Task, error) {
+	if m.GetTaskFunc == nil {
+		return model.Task{}, storage.ErrNotFound
+	}
+	return m.GetTaskFunc(ctx, id)
+}
+
+func (m *MockTaskStore) GetTasks(ctx context.Context, ids []int) ([]model.Task, error) {
+	if m.GetTasksFunc == nil {
+		return nil, nil
+	}
+	return m.GetTasksFunc(ctx, ids)
+}
+
+func (m *MockTaskStore) TaskIDByUID(ctx context.Context, uid string) (int, error) {
+	if m.TaskIDByUIDFunc == nil {
+		return 0, storage.ErrNotFound
+	}
+	return m.TaskIDByUIDFunc(ctx, uid)
+}
+
+func (m *MockTaskStore) UpdateTask(ctx context.Context, task *model.Task) error {
+	if m.UpdateTaskFunc == nil {
+		return storage.ErrNotFound
+	}
+	return m.UpdateTaskFunc(ctx, task)
+}
+
+func (m *MockTaskStore) DeleteTask(ctx context.Context, id int) error {
+	if m.DeleteTaskFunc == nil {
+		return nil
+	}
+	return m.DeleteTaskFunc(ctx, id)
+}
+
+func (m *MockTaskStore) CountOpenSubtasks(ctx context.Context, id int) (int, error) {
+	if m.CountOpenSubtasksFunc == nil {
+		return 0, nil
+	}
+	return m.CountOpenSubtasksFunc(ctx, id)
+}
@@ -0,0 +1,202 @@
+// Package testutil - помощники для тестов HTTP-обработчиков поверх Fiber
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Upiter5/todo-app/internal/auth"
+	"github.com/Upiter5/todo-app/internal/config"
+	apihttp "github.com/Upiter5/todo-app/internal/http"
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/service"
+	"github.com/Upiter5/todo-app/internal/storage"
+)
+
+// Harness выполняет запросы к приложению без запуска сетевого сервера
+type Harness struct {
+	t       testing.TB
+	app     *fiber.App
+	jwt     *auth.JWT
+	headers http.Header
+}
+
+func New(t testing.TB, app *fiber.App) *Harness {
+	t.Helper()
+	return &Harness{t: t, app: app, headers: http.Header{}}
+}
+
+// NewServer поднимает приложение поверх store; nil - пустое хранилище в памяти.
+// Запросы принимают JWT, выпущенные через AsUser.
+func NewServer(t testing.TB, store storage.TaskStore, opts ...apihttp.Option) *Harness {
+	t.Helper()
+	if store == nil {
+		store = storage.NewMemory()
+	}
+	cfg := config.Default()
+	jwt := auth.NewJWT(cfg.JWTSecret, cfg.AccessTokenTTL)
+	opts = append([]apihttp.Option{apihttp.WithAuthenticators(jwt)}, opts...)
+
+	h := New(t, apihttp.NewServer(cfg, service.NewTaskService(store), opts...).App())
+	h.jwt = jwt
+	return h
+}
+
+// WithHeader возвращает копию Harness, добавляющую заголовок к каждому запросу
+func (h *Harness) WithHeader(key, value string) *Harness {
+	clone := &Harness{t: h.t, app: h.app, jwt: h.jwt, headers: h.headers.Clone()}
+	clone.headers.Set(key, value)
+	return clone
+}
+
+// AsUser - запросы от имени пользователя userID с выпущенным для него JWT
+func (h *Harness) AsUser(userID int) *Harness {
+	h.t.Helper()
+	if h.jwt == nil {
+		h.t.Fatal("AsUser requires a Harness created by NewServer")
+	}
+	token, _, err := h.jwt.Issue(model.User{ID: userID, Email: fmt.Sprintf("user%d@example.com", userID), Role: model.RoleUser}, 0)
+	if err != nil {
+		h.t.Fatalf("issue token: %v", err)
+	}
+	return h.WithToken(token)
+}
+
+// WithToken - запросы от имени пользователя с Bearer-токеном
+func (h *Harness) WithToken(token string) *Harness {
+	return h.WithHeader(fiber.HeaderAuthorization, "Bearer "+token)
+}
+
+// Do отправляет запрос; body сериализуется в JSON, если это не []byte/string
+func (h *Harness) Do(method, path string, body any) *Response {
+	h.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for k, v := range h.headers {
+		req.Header[k] = v
+	}
+	if body != nil && req.Header.Get(fiber.HeaderContentType) == "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+
+	resp, err := h.app.Test(req, -1)
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatalf("read response body: %v", err)
+	}
+	return &Response{t: h.t, Status: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+func (h *Harness) Get(path string) *Response { return h.Do(http.MethodGet, path, nil) }
+
+func (h *Harness) Post(path string, body any) *Response { return h.Do(http.MethodPost, path, body) }
+
+func (h *Harness) Put(path string, body any) *Response { return h.Do(http.MethodPut, path, body) }
+
+func (h *Harness) Delete(path string) *Response { return h.Do(http.MethodDelete, path, nil) }
+
+// Response - ответ сервера с методами-проверками
+type Response struct {
+	t      testing.TB
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (r *Response) AssertStatus(want int) *Response {
+	r.t.Helper()
+	if r.Status != want {
+		r.t.Fatalf("status = %d, want %d; body: %s", r.Status, want, r.Body)
+	}
+	return r
+}
+
+func (r *Response) AssertHeader(key, want string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != want {
+		r.t.Fatalf("header %s = %q, want %q", key, got, want)
+	}
+	return r
+}
+
+// DecodeJSON разбирает тело ответа в v
+func (r *Response) DecodeJSON(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("decode response %s: %v", r.Body, err)
+	}
+}
+
+// AssertJSON сравнивает тело с ожидаемым JSON. Объекты сравниваются
+// по подмножеству: поля, которых нет в want (id, даты), не проверяются.
+func (r *Response) AssertJSON(want string) *Response {
+	r.t.Helper()
+	var got, exp any
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		r.t.Fatalf("decode response %s: %v", r.Body, err)
+	}
+	if err := json.Unmarshal([]byte(want), &exp); err != nil {
+		r.t.Fatalf("decode expected JSON %s: %v", want, err)
+	}
+	if !jsonContains(got, exp) {
+		r.t.Fatalf("response JSON mismatch\n got: %s\nwant: %s", r.Body, want)
+	}
+	return r
+}
+
+func jsonContains(got, want any) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if gv, ok := g[k]; !ok || !jsonContains(gv, v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !jsonContains(g[i], w[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(got, want)
+	}
+}
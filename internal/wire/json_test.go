@@ -0,0 +1,143 @@
+package wire_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+func TestMarshalJSONMatchesEncodingJSON(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	done := time.Date(2026, 5, 2, 8, 0, 0, 123456789, msk)
+	tricky := sampleTask()
+	tricky.Title = "<b>Tom & \"Jerry\"</b>\\    \x00\x1f\b\f\n\r\t"
+	tricky.Description = "bad utf-8: \xff\xfe, emoji: 🙂, кириллица"
+	tricky.CompletedAt = &done
+	tricky.DescriptionHTML = "<p>hi</p>"
+	tricky.Location = &model.Location{Lat: 1e-7, Lon: -179.99999999, Label: "Café"}
+
+	tasks := []model.Task{
+		{},
+		sampleTask(),
+		tricky,
+		{ID: 7, Location: &model.Location{Lat: 12345678901234567890123, Lon: 0}},
+	}
+	for _, task := range tasks {
+		want, err := json.Marshal(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := wire.MarshalJSON(task)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("MarshalJSON(task) =\n%s, %v\nwant\n%s", got, err, want)
+		}
+	}
+
+	for _, v := range []any{tasks, []model.Task{}, []model.Task(nil), &tasks[1], map[string]int{"count": 1}, []any{1, "a"}} {
+		want, _ := json.Marshal(v)
+		if got, err := wire.MarshalJSON(v); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("MarshalJSON(%T) = %s, %v; want %s", v, got, err, want)
+		}
+	}
+}
+
+// AppendTaskJSON написан вручную: новое поле model.Task, которое забыли
+// дописать в него, этот тест находит сам - задача заполняется целиком
+func TestAppendTaskJSONCoversAllFields(t *testing.T) {
+	var task model.Task
+	fill(reflect.ValueOf(&task).Elem())
+
+	got := wire.AppendTaskJSON(nil, task)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatalf("AppendTaskJSON = %s: %v", got, err)
+	}
+	typ := reflect.TypeOf(task)
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if _, ok := fields[name]; !ok {
+			t.Errorf("AppendTaskJSON has no %q for model.Task.%s", name, typ.Field(i).Name)
+		}
+	}
+	if want, _ := json.Marshal(task); !bytes.Equal(got, want) {
+		t.Errorf("AppendTaskJSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// fill задаёт каждому полю непустое значение, чтобы omitempty ничего не прятал
+func fill(v reflect.Value) {
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Date(2026, 5, 2, 8, 0, 0, 0, time.UTC)))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fill(key)
+		fill(elem)
+		v.SetMapIndex(key, elem)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(7)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(7)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	}
+}
+
+func benchmarkTasks() []model.Task {
+	tasks := make([]model.Task, 1000)
+	for i := range tasks {
+		tasks[i] = sampleTask()
+		tasks[i].ID = i + 1
+		tasks[i].Description = "Купить молоко, хлеб и что-нибудь к чаю по дороге домой"
+	}
+	return tasks
+}
+
+func BenchmarkMarshalTasks(b *testing.B) {
+	tasks := benchmarkTasks()
+	for _, bc := range []struct {
+		name    string
+		marshal func(any) ([]byte, error)
+	}{{"encoding/json", json.Marshal}, {"wire", wire.MarshalJSON}} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var size int
+			for range b.N {
+				data, err := bc.marshal(tasks)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.SetBytes(int64(size))
+		})
+	}
+}
@@ -0,0 +1,319 @@
+// Package wire - компактные кодировки ответов и тел запросов для клиентов,
+// которым дорог трафик: MessagePack и Protocol Buffers. JSON остаётся
+// основной кодировкой, эти - альтернатива по заголовкам Accept и Content-Type.
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	MIMEMsgpack  = "application/msgpack"
+	MIMEProtobuf = "application/protobuf"
+)
+
+var ErrMalformed = errors.New("malformed message")
+
+// MarshalMsgpack кодирует v в MessagePack с теми же именами полей и
+// значениями, что и в JSON: время - строкой RFC 3339
+func MarshalMsgpack(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return appendMsgpack(nil, generic), nil
+}
+
+func appendMsgpack(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return appendInt(b, n)
+		}
+		f, _ := v.Float64()
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f))
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+		return append(b, v...)
+	case []any:
+		b = appendHeader(b, len(v), 0x90, 0xdc)
+		for _, item := range v {
+			b = appendMsgpack(b, item)
+		}
+		return b
+	case map[string]any:
+		b = appendHeader(b, len(v), 0x80, 0xde)
+		// порядок ключей постоянный, чтобы одинаковые ответы совпадали побайтно
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			b = appendMsgpack(appendMsgpack(b, k), v[k])
+		}
+		return b
+	}
+	panic(fmt.Sprintf("wire: unexpected %T", v))
+}
+
+func appendInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(b, 0xd0, byte(int8(n)))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(n)))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(n)))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
+
+// appendHeader - длина массива (fix=0x90, wide=0xdc) или словаря (0x80, 0xde)
+func appendHeader(b []byte, n int, fix, wide byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, wide), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, wide+1), uint32(n))
+}
+
+// MsgpackToJSON перекодирует тело MessagePack в JSON, чтобы разбирать его
+// так же строго, как JSON. Расширение timestamp (-1) становится строкой RFC 3339.
+func MsgpackToJSON(data []byte) ([]byte, error) {
+	d := &msgpackDecoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w: trailing bytes after value", ErrMalformed)
+	}
+	return json.Marshal(v)
+}
+
+// maxDepth ограничивает вложенность, чтобы тело не исчерпало стек
+const maxDepth = 32
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nesting too deep", ErrMalformed)
+	}
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapOf(int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.arrayOf(int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6, 0xd9, 0xda, 0xdb:
+		size := map[byte]int{0xc4: 1, 0xc5: 2, 0xc6: 4, 0xd9: 1, 0xda: 2, 0xdb: 4}[c]
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (c - 0xcc))
+		if err == nil && n > math.MaxInt64 {
+			return float64(n), nil
+		}
+		return int64(n), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := d.uint(size)
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayOf(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapOf(int(n), depth)
+	case 0xd6, 0xd7, 0xc7:
+		return d.timestamp(c)
+	}
+	return nil, fmt.Errorf("%w: unsupported type 0x%02x", ErrMalformed, c)
+}
+
+func (d *msgpackDecoder) str(n int) (any, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) arrayOf(n int, depth int) (any, error) {
+	if n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("%w: array longer than data", ErrMalformed)
+	}
+	items := make([]any, 0, n)
+	for range n {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) mapOf(n int, depth int) (any, error) {
+	if n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("%w: map longer than data", ErrMalformed)
+	}
+	m := make(map[string]any, n)
+	for range n {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map key must be a string", ErrMalformed)
+		}
+		if m[key], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// timestamp - расширение -1: fixext 4 (секунды), fixext 8 (наносекунды и
+// секунды) или ext 8 длиной 12
+func (d *msgpackDecoder) timestamp(c byte) (any, error) {
+	size := map[byte]int{0xd6: 4, 0xd7: 8}[c]
+	if c == 0xc7 {
+		n, err := d.uint(1)
+		if err != nil {
+			return nil, err
+		}
+		size = int(n)
+	}
+	kind, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	if int8(kind[0]) != -1 {
+		return nil, fmt.Errorf("%w: unsupported extension %d", ErrMalformed, int8(kind[0]))
+	}
+	var sec, nsec int64
+	switch size {
+	case 4:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		sec = int64(n)
+	case 8:
+		n, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		nsec, sec = int64(n>>34), int64(n&(1<<34-1))
+	case 12:
+		ns, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		nsec, sec = int64(ns), int64(s)
+	default:
+		return nil, fmt.Errorf("%w: timestamp of %d bytes", ErrMalformed, size)
+	}
+	return time.Unix(sec, nsec).UTC(), nil
+}
@@ -0,0 +1,293 @@
+package wire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+)
+
+// Номера полей - из task.proto; менять их нельзя, только добавлять новые
+const (
+	fieldID           = 1
+	fieldOwnerID      = 2
+	fieldParentID     = 3
+	fieldProjectID    = 4
+	fieldTitle        = 5
+	fieldDescription  = 6
+	fieldStatus       = 7
+	fieldDueAt        = 8
+	fieldCompletedAt  = 9
+	fieldCreatedAt    = 10
+	fieldUpdatedAt    = 11
+	fieldUID          = 12
+	fieldProgress     = 13
+	fieldLocation     = 14
+	fieldSnoozedUntil = 15
+	fieldPinned       = 16
+
+	fieldTasks = 1
+
+	fieldSeconds = 1
+	fieldNanos   = 2
+
+	fieldLat   = 1
+	fieldLon   = 2
+	fieldLabel = 3
+)
+
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+// MarshalProto кодирует задачу (сообщение Task) или список задач (TaskList);
+// false - для такого значения в task.proto нет сообщения
+func MarshalProto(v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case model.Task:
+		return appendTask(nil, v), true
+	case *model.Task:
+		return appendTask(nil, *v), true
+	case []model.Task:
+		var b []byte
+		for _, t := range v {
+			b = appendBytes(b, fieldTasks, appendTask(nil, t))
+		}
+		return b, true
+	}
+	return nil, false
+}
+
+func appendTask(b []byte, t model.Task) []byte {
+	if t.ID != 0 {
+		b = appendVarint(appendTag(b, fieldID, wireVarint), uint64(int64(t.ID)))
+	}
+	// optional: nil не пишем, а 0 пишем, чтобы различать их при разборе
+	for _, f := range []struct {
+		num int
+		v   *int
+	}{{fieldOwnerID, t.OwnerID}, {fieldParentID, t.ParentID}, {fieldProjectID, t.ProjectID}, {fieldProgress, t.Progress}} {
+		if f.v != nil {
+			b = appendVarint(appendTag(b, f.num, wireVarint), uint64(int64(*f.v)))
+		}
+	}
+	for _, f := range []struct {
+		num int
+		v   string
+	}{{fieldTitle, t.Title}, {fieldDescription, t.Description}, {fieldStatus, t.Status}, {fieldUID, t.UID}} {
+		if f.v != "" {
+			b = appendBytes(b, f.num, []byte(f.v))
+		}
+	}
+	for _, f := range []struct {
+		num int
+		v   *time.Time
+	}{{fieldDueAt, t.DueAt}, {fieldCompletedAt, t.CompletedAt}, {fieldCreatedAt, &t.CreatedAt}, {fieldUpdatedAt, &t.UpdatedAt},
+		{fieldSnoozedUntil, t.SnoozedUntil}} {
+		if f.v != nil && !f.v.IsZero() {
+			b = appendBytes(b, f.num, appendTimestamp(nil, *f.v))
+		}
+	}
+	if t.Pinned {
+		b = appendVarint(appendTag(b, fieldPinned, wireVarint), 1)
+	}
+	if l := t.Location; l != nil {
+		// координаты пишем и нулевые: 0 - законная широта
+		loc := appendDouble(nil, fieldLat, l.Lat)
+		loc = appendDouble(loc, fieldLon, l.Lon)
+		if l.Label != "" {
+			loc = appendBytes(loc, fieldLabel, []byte(l.Label))
+		}
+		b = appendBytes(b, fieldLocation, loc)
+	}
+	return b
+}
+
+func appendDouble(b []byte, num int, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(appendTag(b, num, wire64), math.Float64bits(v))
+}
+
+func appendTimestamp(b []byte, t time.Time) []byte {
+	if s := t.Unix(); s != 0 {
+		b = appendVarint(appendTag(b, fieldSeconds, wireVarint), uint64(s))
+	}
+	if n := t.Nanosecond(); n != 0 {
+		b = appendVarint(appendTag(b, fieldNanos, wireVarint), uint64(n))
+	}
+	return b
+}
+
+func appendTag(b []byte, num, wireType int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytes(b []byte, num int, data []byte) []byte {
+	b = appendVarint(appendTag(b, num, wireBytes), uint64(len(data)))
+	return append(b, data...)
+}
+
+// UnmarshalTask разбирает сообщение Task. Неизвестные поля пропускаются,
+// как принято в Protocol Buffers.
+func UnmarshalTask(data []byte, t *model.Task) error {
+	*t = model.Task{}
+	return eachField(data, func(num int, v uint64, raw []byte) error {
+		switch num {
+		case fieldID:
+			t.ID = int(int32(v))
+		case fieldOwnerID, fieldParentID, fieldProjectID, fieldProgress:
+			n := int(int32(v))
+			switch num {
+			case fieldOwnerID:
+				t.OwnerID = &n
+			case fieldParentID:
+				t.ParentID = &n
+			case fieldProgress:
+				t.Progress = &n
+			default:
+				t.ProjectID = &n
+			}
+		case fieldTitle:
+			t.Title = string(raw)
+		case fieldDescription:
+			t.Description = string(raw)
+		case fieldStatus:
+			t.Status = string(raw)
+		case fieldUID:
+			t.UID = string(raw)
+		case fieldPinned:
+			t.Pinned = v != 0
+		case fieldLocation:
+			loc, err := unmarshalLocation(raw)
+			if err != nil {
+				return err
+			}
+			t.Location = &loc
+		case fieldDueAt, fieldCompletedAt, fieldCreatedAt, fieldUpdatedAt, fieldSnoozedUntil:
+			ts, err := unmarshalTimestamp(raw)
+			if err != nil {
+				return err
+			}
+			switch num {
+			case fieldDueAt:
+				t.DueAt = &ts
+			case fieldCompletedAt:
+				t.CompletedAt = &ts
+			case fieldCreatedAt:
+				t.CreatedAt = ts
+			case fieldSnoozedUntil:
+				t.SnoozedUntil = &ts
+			default:
+				t.UpdatedAt = ts
+			}
+		}
+		return nil
+	})
+}
+
+// UnmarshalTaskList разбирает сообщение TaskList
+func UnmarshalTaskList(data []byte) ([]model.Task, error) {
+	var tasks []model.Task
+	err := eachField(data, func(num int, _ uint64, raw []byte) error {
+		if num != fieldTasks {
+			return nil
+		}
+		var t model.Task
+		if err := UnmarshalTask(raw, &t); err != nil {
+			return err
+		}
+		tasks = append(tasks, t)
+		return nil
+	})
+	return tasks, err
+}
+
+func unmarshalLocation(data []byte) (model.Location, error) {
+	var l model.Location
+	err := eachField(data, func(num int, v uint64, raw []byte) error {
+		switch num {
+		case fieldLat:
+			l.Lat = math.Float64frombits(v)
+		case fieldLon:
+			l.Lon = math.Float64frombits(v)
+		case fieldLabel:
+			l.Label = string(raw)
+		}
+		return nil
+	})
+	return l, err
+}
+
+func unmarshalTimestamp(data []byte) (time.Time, error) {
+	var sec, nsec int64
+	err := eachField(data, func(num int, v uint64, _ []byte) error {
+		switch num {
+		case fieldSeconds:
+			sec = int64(v)
+		case fieldNanos:
+			nsec = int64(int32(v))
+		}
+		return nil
+	})
+	return time.Unix(sec, nsec).UTC(), err
+}
+
+// eachField вызывает fn для каждого поля: v - значение varint или fixed64,
+// raw - байты поля с длиной; поля fixed32 только пропускаются
+func eachField(data []byte, fn func(num int, v uint64, raw []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("%w: bad tag", ErrMalformed)
+		}
+		data = data[n:]
+		num, wireType := int(tag>>3), int(tag&7)
+		if num == 0 {
+			return fmt.Errorf("%w: field number 0", ErrMalformed)
+		}
+
+		var (
+			v   uint64
+			raw []byte
+		)
+		switch wireType {
+		case wireVarint:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("%w: bad varint in field %d", ErrMalformed, num)
+			}
+			data = data[n:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return fmt.Errorf("%w: bad length of field %d", ErrMalformed, num)
+			}
+			raw, data = data[n:n+int(size)], data[n+int(size):]
+		case wire64:
+			if len(data) < 8 {
+				return fmt.Errorf("%w: truncated field %d", ErrMalformed, num)
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wire32:
+			if len(data) < 4 {
+				return fmt.Errorf("%w: truncated field %d", ErrMalformed, num)
+			}
+			data = data[4:]
+			continue
+		default:
+			return fmt.Errorf("%w: unsupported wire type %d", ErrMalformed, wireType)
+		}
+		if err := fn(num, v, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package wire_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Upiter5/todo-app/internal/model"
+	"github.com/Upiter5/todo-app/internal/wire"
+)
+
+func sampleTask() model.Task {
+	owner, project, progress := 3, 0, 50
+	due := time.Date(2026, 5, 1, 17, 30, 0, 250, time.UTC)
+	snoozed := time.Date(2026, 4, 20, 8, 0, 0, 0, time.UTC)
+	return model.Task{ID: 42, UID: "01928a6b-3c4d-7e8f-9a0b-1c2d3e4f5a6b", OwnerID: &owner, ProjectID: &project, Title: "Пример задачи",
+		Status: model.StatusInProgress, DueAt: &due, Progress: &progress,
+		Location: &model.Location{Lat: 0, Lon: -0.1276, Label: "Магазин у дома"}, SnoozedUntil: &snoozed, Pinned: true,
+		CreatedAt: time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2026, 4, 2, 9, 0, 0, 0, time.UTC)}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	tasks := []model.Task{sampleTask(), {ID: 300000, Title: "Second", Status: model.StatusTodo}}
+	data, err := wire.MarshalMsgpack(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := wire.MsgpackToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []model.Task
+	if err := json.Unmarshal(back, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tasks) {
+		t.Errorf("round trip = %+v, want %+v", got, tasks)
+	}
+
+	// fixext 8 timestamp: 1 с + 500 нс от эпохи в поле "due_at"
+	ts := []byte{0x81, 0xa6, 'd', 'u', 'e', '_', 'a', 't', 0xd7, 0xff, 0, 0, 0x07, 0xd0, 0, 0, 0, 1}
+	js, err := wire.MsgpackToJSON(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"due_at":"1970-01-01T00:00:01.0000005Z"}`; string(js) != want {
+		t.Errorf("timestamp = %s, want %s", js, want)
+	}
+
+	if _, err := wire.MsgpackToJSON([]byte{0x92, 0x01}); err == nil {
+		t.Error("truncated array: want error")
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	task := sampleTask()
+	data, ok := wire.MarshalProto(task)
+	if !ok {
+		t.Fatal("task is not marshalable")
+	}
+	var got model.Task
+	if err := wire.UnmarshalTask(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, task) {
+		t.Errorf("task = %+v, want %+v", got, task)
+	}
+
+	list, _ := wire.MarshalProto([]model.Task{task, task})
+	tasks, err := wire.UnmarshalTaskList(list)
+	if err != nil || len(tasks) != 2 || tasks[1].ID != 42 {
+		t.Errorf("list = %+v, %v; want two tasks", tasks, err)
+	}
+
+	if _, ok := wire.MarshalProto(model.TaskStats{}); ok {
+		t.Error("stats have no proto message")
+	}
+}